@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+)
+
+type activityReq struct {
+	Page    int `form:"page,default=1" binding:"min=1"`
+	PerPage int `form:"per_page,default=20" binding:"min=1,max=100"`
+}
+
+// Activity handler pages through the audit events of the current user, newest first
+func (h *Handler) Activity(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req activityReq
+
+	if ok := bindQuery(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	events, err := h.UserService.Activity(ctx, authUser.UID, req.PerPage, (req.Page-1)*req.PerPage)
+	if err != nil {
+		log.Printf("Failed to get activity of user: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":   events,
+		"page":     req.Page,
+		"per_page": req.PerPage,
+	})
+}
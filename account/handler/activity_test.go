@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestActivity(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	authUser := &model.User{UID: uid, Email: "bob@bob.com"}
+
+	newRouter := func(mockUserService *mocks.MockUserService) *gin.Engine {
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", authUser)
+		})
+
+		NewHandler(&Config{
+			Engine:      router,
+			UserService: mockUserService,
+		})
+
+		return router
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		events := []*model.AuditEvent{
+			{UID: &uid, ActorID: &uid, Type: model.AuditSignin, Outcome: model.AuditSuccess, IP: "203.0.113.7"},
+		}
+		mockUserService.On("Activity", mock.Anything, uid, 10, 10).Return(events, nil)
+
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/me/activity?page=2&per_page=10", nil)
+		assert.NoError(t, err)
+
+		newRouter(mockUserService).ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"events":   events,
+			"page":     2,
+			"per_page": 10,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid page", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/me/activity?page=0", nil)
+		assert.NoError(t, err)
+
+		newRouter(mockUserService).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "Activity")
+	})
+
+	t.Run("Client info reaches services", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		var info model.ClientInfo
+		mockUserService.On("Activity", mock.Anything, uid, 20, 0).
+			Run(func(args mock.Arguments) {
+				info = model.ClientInfoFrom(args.Get(0).(context.Context))
+			}).
+			Return([]*model.AuditEvent{}, nil)
+
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/me/activity", nil)
+		assert.NoError(t, err)
+		request.Header.Set("User-Agent", "test-agent")
+
+		newRouter(mockUserService).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "test-agent", info.UserAgent)
+	})
+}
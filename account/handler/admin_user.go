@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"time"
+)
+
+type adminUsersReq struct {
+	Email   string `form:"email"`
+	Name    string `form:"name"`
+	Status  string `form:"status"`
+	Page    int    `form:"page,default=1" binding:"min=1"`
+	PerPage int    `form:"per_page,default=20" binding:"min=1,max=100"`
+}
+
+type userStatusReq struct {
+	Status    string     `json:"status" binding:"required,oneof=active suspended pending_deletion"`
+	Reason    string     `json:"reason" binding:"max=500"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type adminSearchReq struct {
+	Query string `form:"q" binding:"required"`
+	Limit int    `form:"limit,default=20" binding:"min=1,max=100"`
+}
+
+// AdminUsers handler lists a page of users matching the query filters
+func (h *Handler) AdminUsers(c *gin.Context) {
+	var req adminUsersReq
+
+	if ok := bindQuery(c, &req); !ok {
+		return
+	}
+
+	filter := &model.UserFilter{
+		Email:  req.Email,
+		Name:   req.Name,
+		Status: req.Status,
+		Limit:  req.PerPage,
+		Offset: (req.Page - 1) * req.PerPage,
+	}
+
+	ctx := c.Request.Context()
+	users, total, err := h.UserService.List(ctx, filter)
+	if err != nil {
+		log.Printf("Failed to list users: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":    users,
+		"total":    total,
+		"page":     req.Page,
+		"per_page": req.PerPage,
+	})
+}
+
+// SearchUsers handler finds users by email, name or handle
+func (h *Handler) SearchUsers(c *gin.Context) {
+	var req adminSearchReq
+
+	if ok := bindQuery(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	users, err := h.UserService.Search(ctx, req.Query, req.Limit)
+	if err != nil {
+		log.Printf("Failed to search users: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users": users,
+	})
+}
+
+// AdminUser handler returns the details of a user
+func (h *Handler) AdminUser(c *gin.Context) {
+	user, ok := h.adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": user,
+	})
+}
+
+// UpdateUser handler edits the name and email of a user
+func (h *Handler) UpdateUser(c *gin.Context) {
+	var req detailsReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	user, ok := h.adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	user.Name = req.Name
+	user.Email = req.Email
+	if req.Website != "" {
+		user.Website = req.Website
+	}
+
+	ctx := c.Request.Context()
+	if err := h.UserService.UpdateDetails(ctx, user); err != nil {
+		log.Printf("Failed to update user: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": user,
+	})
+}
+
+// ResetUserImage handler removes the profile image of a user
+func (h *Handler) ResetUserImage(c *gin.Context) {
+	uid, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.UserService.DeleteProfileImage(ctx, uid); err != nil {
+		log.Printf("Failed to delete profile image: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
+
+// SignoutUser handler signs a user out of all sessions
+func (h *Handler) SignoutUser(c *gin.Context) {
+	uid, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.TokenService.Signout(ctx, uid); err != nil {
+		log.Printf("Failed to sign user out: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
+
+// ResetUserPassword handler mails a password reset link to a user
+func (h *Handler) ResetUserPassword(c *gin.Context) {
+	user, ok := h.adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.UserService.RequestPasswordReset(ctx, user.Email); err != nil {
+		log.Printf("Failed to request password reset: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "success",
+	})
+}
+
+// SetUserStatus handler suspends, reactivates or schedules the deletion of a user
+// Suspended users are signed out of all sessions at once
+func (h *Handler) SetUserStatus(c *gin.Context) {
+	var req userStatusReq
+
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	uid, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.UserService.SetStatus(ctx, uid, req.Status, req.Reason, req.ExpiresAt)
+	if err != nil {
+		log.Printf("Failed to set user status: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if user.Status == model.UserStatusSuspended {
+		if err := h.TokenService.Revoke(ctx, uid); err != nil {
+			log.Printf("Failed to revoke tokens of suspended user: %v\n", err.Error())
+			c.JSON(apperrors.Status(err), gin.H{
+				"error": err,
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": user,
+	})
+}
+
+// adminTargetUser fetches the user of an admin route, responding
+// with the error if there is none
+func (h *Handler) adminTargetUser(c *gin.Context) (*model.User, bool) {
+	uid, ok := userIDParam(c)
+	if !ok {
+		return nil, false
+	}
+
+	user, err := h.UserService.Get(c.Request.Context(), uid)
+	if err != nil {
+		log.Printf("Unable to find user: %v\n%v", uid, err)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return nil, false
+	}
+
+	return user, true
+}
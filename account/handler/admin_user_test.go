@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdminUsers(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	mockUser := &model.User{UID: uid, Email: "bob@bob.com", Name: "Bobby", Website: "https://bob.me"}
+
+	newRouter := func(mockUserService *mocks.MockUserService, mockTokenService *mocks.MockTokenService) *gin.Engine {
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Engine:       router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+		})
+
+		return router
+	}
+
+	serve := func(router *gin.Engine, method string, url string, body io.Reader) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(method, url, body)
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("List", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		filter := &model.UserFilter{Email: "bob", Status: model.UserStatusActive, Limit: 10, Offset: 20}
+		mockUserService.On("List", mock.Anything, filter).Return([]*model.User{mockUser}, int64(21), nil)
+
+		rr := serve(newRouter(mockUserService, nil), http.MethodGet, "/admin/users?email=bob&status=active&page=3&per_page=10", nil)
+
+		respBody, err := json.Marshal(gin.H{
+			"page":     3,
+			"per_page": 10,
+			"total":    21,
+			"users":    []*model.User{mockUser},
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("List with invalid page size", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		rr := serve(newRouter(mockUserService, nil), http.MethodGet, "/admin/users?per_page=1000", nil)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "List")
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		mockUserService.On("Search", mock.Anything, "bob", 20).Return([]*model.User{mockUser}, nil)
+
+		rr := serve(newRouter(mockUserService, nil), http.MethodGet, "/admin/users/search?q=bob", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		mockUserService.On("Get", mock.Anything, uid).Return(mockUser, nil)
+
+		rr := serve(newRouter(mockUserService, nil), http.MethodGet, "/admin/users/"+uid.String(), nil)
+
+		respBody, err := json.Marshal(gin.H{
+			"user": mockUser,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Get with invalid id", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		rr := serve(newRouter(mockUserService, nil), http.MethodGet, "/admin/users/not-a-uuid", nil)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "Get")
+	})
+
+	t.Run("Get unknown user", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		mockUserService.On("Get", mock.Anything, uid).Return(nil, apperrors.NewNotFound("uid", uid.String()))
+
+		rr := serve(newRouter(mockUserService, nil), http.MethodGet, "/admin/users/"+uid.String(), nil)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Update keeps website", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		user := *mockUser
+		mockUserService.On("Get", mock.Anything, uid).Return(&user, nil)
+		mockUserService.On("UpdateDetails", mock.Anything, mock.MatchedBy(func(u *model.User) bool {
+			return u.UID == uid && u.Name == "Robert" && u.Email == "robert@bob.com" && u.Website == "https://bob.me"
+		})).Return(nil)
+
+		reqBody, err := json.Marshal(gin.H{
+			"name":  "Robert",
+			"email": "robert@bob.com",
+		})
+		assert.NoError(t, err)
+
+		rr := serve(newRouter(mockUserService, nil), http.MethodPut, "/admin/users/"+uid.String(), bytes.NewBuffer(reqBody))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Reset image", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		mockUserService.On("DeleteProfileImage", mock.Anything, uid).Return(nil)
+
+		rr := serve(newRouter(mockUserService, nil), http.MethodDelete, "/admin/users/"+uid.String()+"/image", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Force sign-out", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+
+		mockTokenService.On("Signout", mock.Anything, uid).Return(nil)
+
+		rr := serve(newRouter(nil, mockTokenService), http.MethodPost, "/admin/users/"+uid.String()+"/signout", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Password reset", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		mockUserService.On("Get", mock.Anything, uid).Return(mockUser, nil)
+		mockUserService.On("RequestPasswordReset", mock.Anything, "bob@bob.com").Return(nil)
+
+		rr := serve(newRouter(mockUserService, nil), http.MethodPost, "/admin/users/"+uid.String()+"/password-reset", nil)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		mockUserService.AssertExpectations(t)
+	})
+}
+
+func TestSetUserStatus(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	setStatus := func(router *gin.Engine, body gin.H) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(body)
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPut, "/admin/users/"+uid.String()+"/status", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	newRouter := func(mockUserService *mocks.MockUserService, mockTokenService *mocks.MockTokenService) *gin.Engine {
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Engine:       router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+		})
+
+		return router
+	}
+
+	t.Run("Suspend revokes tokens", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		suspended := &model.User{UID: uid, Status: model.UserStatusSuspended, StatusReason: "spam"}
+		mockUserService.On("SetStatus", mock.Anything, uid, model.UserStatusSuspended, "spam", (*time.Time)(nil)).Return(suspended, nil)
+		mockTokenService.On("Revoke", mock.Anything, uid).Return(nil)
+
+		rr := setStatus(newRouter(mockUserService, mockTokenService), gin.H{
+			"status": "suspended",
+			"reason": "spam",
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertExpectations(t)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Reactivate keeps tokens", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		active := &model.User{UID: uid, Status: model.UserStatusActive}
+		mockUserService.On("SetStatus", mock.Anything, uid, model.UserStatusActive, "", (*time.Time)(nil)).Return(active, nil)
+
+		rr := setStatus(newRouter(mockUserService, mockTokenService), gin.H{
+			"status": "active",
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockTokenService.AssertNotCalled(t, "Revoke")
+	})
+
+	t.Run("Unknown status", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		rr := setStatus(newRouter(mockUserService, nil), gin.H{
+			"status": "banned",
+		})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "SetStatus")
+	})
+}
@@ -30,37 +30,68 @@ func bindData(ctx *gin.Context, req interface{}) bool {
 
 	// Bind incoming json to struct and check for validation errors
 	if err := ctx.ShouldBind(req); err != nil {
-		log.Printf("Error binding data: %+v\n", err)
-
-		if errs, ok := err.(validator.ValidationErrors); ok {
-			// could probably extract this, it is also in middleware_auth_user
-			var invalidArgs []invalidArgument
-
-			for _, err := range errs {
-				invalidArgs = append(invalidArgs, invalidArgument{
-					err.Field(),
-					err.Value().(string),
-					err.Tag(),
-					err.Param(),
-				})
-			}
-
-			err := apperrors.NewBadRequest("Invalid request parameters. See invalidArgs")
-
-			ctx.JSON(err.Status(), gin.H{
-				"error":       err,
-				"invalidArgs": invalidArgs,
-			})
-			return false
-		}
+		respondWithBindError(ctx, err)
+		return false
+	}
 
-		// if we aren't able to properly extract validation errors,
-		// we'll fall back and return an internal server error
-		fallBack := apperrors.NewInternal()
+	return true
+}
 
-		ctx.JSON(fallBack.Status(), apperrors.Response{Error: fallBack})
+// bindQuery is the helper of bindData for query parameters,
+// returns false if the query is not bound
+func bindQuery(ctx *gin.Context, req interface{}) bool {
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		respondWithBindError(ctx, err)
 		return false
 	}
 
 	return true
 }
+
+// respondWithBindError responds with the invalid fields of a request
+// which could not be bound
+func respondWithBindError(ctx *gin.Context, err error) {
+	log.Printf("Error binding data: %+v\n", err)
+
+	if errs, ok := err.(validator.ValidationErrors); ok {
+		// could probably extract this, it is also in middleware_auth_user
+		var invalidArgs []invalidArgument
+
+		for _, err := range errs {
+			invalidArgs = append(invalidArgs, invalidArgument{
+				err.Field(),
+				fmt.Sprintf("%v", err.Value()),
+				err.Tag(),
+				err.Param(),
+			})
+		}
+
+		err := apperrors.NewBadRequest("Invalid request parameters. See invalidArgs")
+
+		ctx.JSON(err.Status(), gin.H{
+			"error":       err,
+			"invalidArgs": invalidArgs,
+		})
+		return
+	}
+
+	// if we aren't able to properly extract validation errors,
+	// we'll fall back and return an internal server error
+	fallBack := apperrors.NewInternal()
+
+	ctx.JSON(fallBack.Status(), apperrors.Response{Error: fallBack})
+}
+
+// errorResponse is the body of an error response, listing the invalid
+// fields of the request when the error describes them
+func errorResponse(err error) gin.H {
+	body := gin.H{
+		"error": err,
+	}
+
+	if invalidArgs := apperrors.InvalidArgs(err); invalidArgs != nil {
+		body["invalidArgs"] = invalidArgs
+	}
+
+	return body
+}
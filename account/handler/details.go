@@ -25,6 +25,15 @@ func (h *Handler) Details(c *gin.Context) {
 		return
 	}
 
+	// an email without a password would leave the guest unable to sign in
+	if authUser.Guest {
+		err := apperrors.NewBadRequest("Guests choose an email by upgrading their account")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
 	// Should be returned with current imageURL
 	user := &model.User{
 		UID:     authUser.UID,
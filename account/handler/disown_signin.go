@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+)
+
+type disownSigninReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// DisownSignin handler is the "this wasn't me" link of a new device notification
+// It ends every session of the user, whose password must be reset to sign in again
+func (h *Handler) DisownSignin(c *gin.Context) {
+	var req disownSigninReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.UserService.DisownSignin(ctx, req.Token); err != nil {
+		log.Printf("Failed to disown sign-in: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "All sessions were signed out. Check your email to choose a new password",
+	})
+}
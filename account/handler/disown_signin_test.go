@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDisownSignin(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	disown := func(router *gin.Engine, body gin.H) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(body)
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/disown", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	newRouter := func(mockUserService *mocks.MockUserService) *gin.Engine {
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Engine:      router,
+			UserService: mockUserService,
+		})
+
+		return router
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		mockUserService.On("DisownSignin", mock.Anything, "token").Return(&model.User{UID: uid}, nil)
+
+		rr := disown(newRouter(mockUserService), gin.H{
+			"token": "token",
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		mockUserService.On("DisownSignin", mock.Anything, "token").Return(nil, apperrors.NewBadRequest("Invalid or expired link"))
+
+		rr := disown(newRouter(mockUserService), gin.H{
+			"token": "token",
+		})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		rr := disown(newRouter(mockUserService), gin.H{})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "DisownSignin")
+	})
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"log"
+	"net/http"
+)
+
+// exportResp adds the download link to an export once it is ready
+type exportResp struct {
+	*model.Export
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+func (h *Handler) newExportResp(export *model.Export) *exportResp {
+	resp := &exportResp{Export: export}
+	if export.Status == model.ExportReady {
+		resp.DownloadURL = fmt.Sprintf("%s/exports/%s", h.BaseURL, export.DownloadToken)
+	}
+
+	return resp
+}
+
+// Export handler starts assembling an archive of the current user's data
+func (h *Handler) Export(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	ctx := c.Request.Context()
+	export, err := h.ExportService.Request(ctx, authUser.UID)
+	if err != nil {
+		log.Printf("Failed to request data export: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"export": h.newExportResp(export),
+	})
+}
+
+// ExportStatus handler returns the state of an export of the current user
+func (h *Handler) ExportStatus(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	exportID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		e := apperrors.NewNotFound("export", c.Param("id"))
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	export, err := h.ExportService.Get(ctx, authUser.UID, exportID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"export": h.newExportResp(export),
+	})
+}
+
+// ExportDownload handler serves the archive behind a time-limited link
+// The link itself is the credential, so it is not behind AuthUser
+func (h *Handler) ExportDownload(c *gin.Context) {
+	ctx := c.Request.Context()
+	export, archive, err := h.ExportService.Download(ctx, c.Param("token"))
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%s.zip"`, export.ID))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/zip", archive)
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Accepted", func(t *testing.T) {
+		exportID, _ := uuid.NewRandom()
+		mockExport := &model.Export{
+			ID:            exportID,
+			UID:           uid,
+			Status:        model.ExportPending,
+			DownloadToken: "secrettoken",
+		}
+
+		mockExportService := new(mocks.MockExportService)
+		mockExportService.On("Request", mock.Anything, uid).Return(mockExport, nil)
+
+		rr := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{
+				UID: uid,
+			})
+		})
+
+		NewHandler(&Config{
+			Engine:        router,
+			ExportService: mockExportService,
+		})
+
+		request, err := http.NewRequest(http.MethodPost, "/me/export", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		// pending exports have no download link yet
+		respBody, err := json.Marshal(gin.H{
+			"export": &exportResp{Export: mockExport},
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		assert.NotContains(t, rr.Body.String(), "secrettoken")
+		mockExportService.AssertExpectations(t)
+	})
+
+	t.Run("Ready export has download link", func(t *testing.T) {
+		exportID, _ := uuid.NewRandom()
+		mockExport := &model.Export{
+			ID:            exportID,
+			UID:           uid,
+			Status:        model.ExportReady,
+			DownloadToken: "secrettoken",
+		}
+
+		mockExportService := new(mocks.MockExportService)
+		mockExportService.On("Get", mock.Anything, uid, exportID).Return(mockExport, nil)
+
+		rr := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{
+				UID: uid,
+			})
+		})
+
+		NewHandler(&Config{
+			Engine:        router,
+			ExportService: mockExportService,
+			BaseURL:       "/api/account",
+		})
+
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/account/me/export/%s", exportID), nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"export": &exportResp{
+				Export:      mockExport,
+				DownloadURL: "/api/account/exports/secrettoken",
+			},
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockExportService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid export ID", func(t *testing.T) {
+		mockExportService := new(mocks.MockExportService)
+
+		rr := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{
+				UID: uid,
+			})
+		})
+
+		NewHandler(&Config{
+			Engine:        router,
+			ExportService: mockExportService,
+		})
+
+		request, err := http.NewRequest(http.MethodGet, "/me/export/notauuid", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		mockExportService.AssertNotCalled(t, "Get")
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		exportID, _ := uuid.NewRandom()
+		mockExport := &model.Export{
+			ID:     exportID,
+			UID:    uid,
+			Status: model.ExportReady,
+		}
+		archive := []byte("PK-archive")
+
+		mockExportService := new(mocks.MockExportService)
+		mockExportService.On("Download", mock.Anything, "secrettoken").Return(mockExport, archive, nil)
+
+		rr := httptest.NewRecorder()
+
+		// no user in context, the link is the credential
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:        router,
+			ExportService: mockExportService,
+		})
+
+		request, err := http.NewRequest(http.MethodGet, "/exports/secrettoken", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+		assert.Equal(t, fmt.Sprintf(`attachment; filename="export-%s.zip"`, exportID), rr.Header().Get("Content-Disposition"))
+		assert.Equal(t, archive, rr.Body.Bytes())
+		mockExportService.AssertExpectations(t)
+	})
+
+	t.Run("Download expired link", func(t *testing.T) {
+		mockError := apperrors.NewNotFound("export", "download token")
+
+		mockExportService := new(mocks.MockExportService)
+		mockExportService.On("Download", mock.Anything, "expiredtoken").Return(nil, nil, mockError)
+
+		rr := httptest.NewRecorder()
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:        router,
+			ExportService: mockExportService,
+		})
+
+		request, err := http.NewRequest(http.MethodGet, "/exports/expiredtoken", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"error": mockError,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, mockError.Status(), rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockExportService.AssertExpectations(t)
+	})
+}
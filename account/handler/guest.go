@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+)
+
+// upgradeGuestReq takes the same fields as a sign-up
+type upgradeGuestReq struct {
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required"` // the length is checked by the password policy
+	InviteCode string `json:"invite_code"`                 // required by invite-only sign-up
+	// TermsVersion of the terms of service shown to the user, required while terms are published
+	TermsVersion string `json:"terms_version"`
+}
+
+// Guest handler creates a guest account and returns its tokens
+// Guests created per IP are limited along with the sign-in attempts
+func (h *Handler) Guest(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if h.LockoutService != nil {
+		if err := h.LockoutService.CheckGuest(ctx, c.ClientIP()); err != nil {
+			log.Printf("Rejected guest: %v\n", err.Error())
+			respondWithRetryAfter(c, err)
+			return
+		}
+	}
+
+	user, err := h.UserService.CreateGuest(ctx)
+	if err != nil {
+		log.Printf("Failed to create guest: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, user, "")
+	if err != nil {
+		log.Printf("Failed to create tokens for guest: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"tokens": tokens,
+	})
+}
+
+// UpgradeGuest handler attaches an email and password to the guest account
+// of the user and returns tokens of the upgraded account, or answers like an
+// enumeration-safe sign-up in that mode, after which the user signs in
+// Guests may also upgrade by linking an identity provider account
+func (h *Handler) UpgradeGuest(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req upgradeGuestReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	user := &model.User{
+		UID:           authUser.UID,
+		Email:         req.Email,
+		Password:      req.Password,
+		InviteCode:    req.InviteCode,
+		AcceptedTerms: req.TermsVersion,
+	}
+
+	ctx := c.Request.Context()
+	err := h.UserService.UpgradeGuest(ctx, user)
+
+	if h.EnumerationSafeSignup {
+		h.enumerationSafeSignup(c, user, err)
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to upgrade guest: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), errorResponse(err))
+		return
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, user, "")
+	if err != nil {
+		log.Printf("Failed to create tokens for user: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+	})
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGuest(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	guest := &model.User{UID: uid, Guest: true}
+	tokens := &model.Token{
+		AccessToken:  model.AccessToken{SignedStringToken: "idToken"},
+		RefreshToken: model.RefreshToken{SignedStringToken: "refreshToken"},
+	}
+
+	newRouter := func(mockUserService *mocks.MockUserService, mockTokenService *mocks.MockTokenService) *gin.Engine {
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", guest)
+		})
+
+		NewHandler(&Config{
+			Engine:       router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+		})
+
+		return router
+	}
+
+	post := func(router *gin.Engine, url string, body gin.H) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(body)
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		mockUserService.On("CreateGuest", mock.Anything).Return(guest, nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, guest, "").Return(tokens, nil)
+
+		rr := post(newRouter(mockUserService, mockTokenService), "/guest", gin.H{})
+
+		respBody, err := json.Marshal(gin.H{
+			"tokens": tokens,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Too many guests from an IP", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockLockoutService := new(mocks.MockLockoutService)
+
+		mockLockoutService.On("CheckGuest", mock.Anything, mock.AnythingOfType("string")).Return(apperrors.NewTooManyRequests("Too many guest accounts created. Try again later", time.Hour))
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:         router,
+			UserService:    mockUserService,
+			TokenService:   mockTokenService,
+			LockoutService: mockLockoutService,
+		})
+
+		rr := post(router, "/guest", gin.H{})
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "3600", rr.Header().Get("Retry-After"))
+		mockUserService.AssertNotCalled(t, "CreateGuest", mock.Anything)
+	})
+
+	t.Run("Upgrade keeps uid", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		upgraded := mock.MatchedBy(func(u *model.User) bool {
+			return u.UID == uid && u.Email == "bob@bob.com" && u.Password == "avalidpassword123" && u.AcceptedTerms == "2024-06"
+		})
+		mockUserService.On("UpgradeGuest", mock.Anything, upgraded).Return(nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, upgraded, "").Return(tokens, nil)
+
+		rr := post(newRouter(mockUserService, mockTokenService), "/guest/upgrade", gin.H{
+			"email":         "bob@bob.com",
+			"password":      "avalidpassword123",
+			"terms_version": "2024-06",
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertExpectations(t)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Upgrade to taken email", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		mockUserService.On("UpgradeGuest", mock.Anything, mock.AnythingOfType("*model.User")).Return(apperrors.NewConflict("email", "bob@bob.com"))
+
+		rr := post(newRouter(mockUserService, mockTokenService), "/guest/upgrade", gin.H{
+			"email":    "bob@bob.com",
+			"password": "avalidpassword123",
+		})
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("Guests do not set an email through details", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		rr := httptest.NewRecorder()
+		reqBody, err := json.Marshal(gin.H{
+			"email": "bob@bob.com",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPut, "/details", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+		request.Header.Set("Content-Type", "application/json")
+
+		newRouter(mockUserService, nil).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "UpdateDetails")
+	})
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+type handleReq struct {
+	Handle string `json:"handle" binding:"required"` // the character set is checked by the user service
+}
+
+// profile is the public part of a user, which others may look up by handle
+type profile struct {
+	Handle   string `json:"handle"`
+	Name     string `json:"name"`
+	ImageURL string `json:"image_url"`
+	Website  string `json:"website"`
+}
+
+// HandleAvailable handler tells whether a handle may still be chosen
+func (h *Handler) HandleAvailable(c *gin.Context) {
+	ctx := c.Request.Context()
+	available, err := h.UserService.HandleAvailable(ctx, c.Param("handle"))
+	if err != nil {
+		log.Printf("Failed to check handle availability: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"available": available,
+	})
+}
+
+// SetHandle handler chooses or changes the handle of the current user
+func (h *Handler) SetHandle(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req handleReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.UserService.SetHandle(ctx, authUser.UID, req.Handle)
+	if err != nil {
+		log.Printf("Failed to set handle: %v\n", err.Error())
+		if retryAfter := apperrors.RetryAfter(err); retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+
+		c.JSON(apperrors.Status(err), errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": user,
+	})
+}
+
+// Profile handler returns the public profile of the user with a handle
+// Previous handles redirect to the current one
+func (h *Handler) Profile(c *gin.Context) {
+	handle := c.Param("handle")
+
+	ctx := c.Request.Context()
+	user, err := h.UserService.GetByHandle(ctx, handle)
+	if err != nil {
+		log.Printf("Failed to get user by handle: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if !strings.EqualFold(user.Handle, handle) {
+		c.Redirect(http.StatusMovedPermanently, path.Join(path.Dir(c.Request.URL.Path), user.Handle))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": &profile{
+			Handle:   user.Handle,
+			Name:     user.Name,
+			ImageURL: user.ImageURL,
+			Website:  user.Website,
+		},
+	})
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandleAvailable(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := new(mocks.MockUserService)
+
+	router := gin.Default()
+	NewHandler(&Config{
+		Engine:      router,
+		UserService: mockUserService,
+	})
+
+	available := func(handle string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodGet, "/handles/"+handle+"/available", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Available", func(t *testing.T) {
+		mockUserService.On("HandleAvailable", mock.Anything, "free").Return(true, nil)
+
+		rr := available("free")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"available":true}`, rr.Body.String())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		mockUserService.On("HandleAvailable", mock.Anything, "a").Return(false, apperrors.NewInvalidArgs([]apperrors.InvalidArgument{{Field: "Handle", Value: "a", Tag: "handle"}}))
+
+		rr := available("a")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "invalidArgs")
+	})
+}
+
+func TestSetHandle(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	authUser := &model.User{UID: uid, Email: "bob@bob.com"}
+
+	newRouter := func(mockUserService *mocks.MockUserService) *gin.Engine {
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", authUser)
+		})
+
+		NewHandler(&Config{
+			Engine:      router,
+			UserService: mockUserService,
+		})
+
+		return router
+	}
+
+	setHandle := func(router *gin.Engine, handle string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"handle": handle,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPut, "/handle", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		updated := &model.User{UID: uid, Email: "bob@bob.com", Handle: "bob"}
+
+		mockUserService.On("SetHandle", mock.Anything, uid, "bob").Return(updated, nil)
+
+		rr := setHandle(newRouter(mockUserService), "bob")
+
+		respBody, err := json.Marshal(gin.H{
+			"user": updated,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Cooldown", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		mockUserService.On("SetHandle", mock.Anything, uid, "bob").Return(nil, apperrors.NewTooManyRequests("Handle was changed recently", time.Hour))
+
+		rr := setHandle(newRouter(mockUserService), "bob")
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "3600", rr.Header().Get("Retry-After"))
+	})
+
+	t.Run("Missing handle", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		rr := setHandle(newRouter(mockUserService), "")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "SetHandle", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestProfile(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := new(mocks.MockUserService)
+	mockUser := &model.User{
+		UID:     uuid.New(),
+		Email:   "bob@bob.com",
+		Name:    "Bob",
+		Handle:  "Bob",
+		Website: "https://bob.com",
+	}
+
+	mockUserService.On("GetByHandle", mock.Anything, "bob").Return(mockUser, nil)
+	mockUserService.On("GetByHandle", mock.Anything, "old_bob").Return(mockUser, nil)
+	mockUserService.On("GetByHandle", mock.Anything, "nobody").Return(nil, apperrors.NewNotFound("handle", "nobody"))
+
+	router := gin.Default()
+	NewHandler(&Config{
+		Engine:      router,
+		UserService: mockUserService,
+	})
+
+	profile := func(handle string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodGet, "/users/"+handle, nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Public profile", func(t *testing.T) {
+		rr := profile("bob")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"user":{"handle":"Bob","name":"Bob","image_url":"","website":"https://bob.com"}}`, rr.Body.String())
+	})
+
+	t.Run("Previous handle", func(t *testing.T) {
+		rr := profile("old_bob")
+
+		assert.Equal(t, http.StatusMovedPermanently, rr.Code)
+		assert.Equal(t, "/users/Bob", rr.Header().Get("Location"))
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		rr := profile("nobody")
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
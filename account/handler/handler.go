@@ -10,20 +10,54 @@ import (
 
 // Handler struct holds required services for handler to function
 type Handler struct {
-	UserService  model.UserService
-	TokenService model.TokenService
-	MaxBodyBytes int64
+	UserService      model.UserService
+	TokenService     model.TokenService
+	MFAService       model.MFAService
+	PasskeyService   model.PasskeyService
+	MagicLinkService model.MagicLinkService
+	OAuthService     model.OAuthService
+	LockoutService   model.LockoutService
+	ExportService    model.ExportService
+	InviteService    model.InviteService
+	RoleService      model.RoleService
+	OrgService       model.OrgService
+	BaseURL          string
+	MaxBodyBytes     int64
+	// TermsVersion is the current version of the terms of service, published at TermsURL
+	// Empty for no terms to accept
+	TermsVersion string
+	TermsURL     string
+	// EnumerationSafeSignup answers sign-ups, guest upgrades and provider
+	// sign-ups without revealing registered emails
+	EnumerationSafeSignup bool
 }
 
 // Config will hold services that will eventually be injected into this
 // handler layer on handler initialization
 type Config struct {
-	Engine          *gin.Engine
-	UserService     model.UserService
-	TokenService    model.TokenService
-	BaseURL         string
-	TimeoutDuration time.Duration
-	MaxBodyBytes    int64
+	Engine           *gin.Engine
+	UserService      model.UserService
+	TokenService     model.TokenService
+	MFAService       model.MFAService
+	PasskeyService   model.PasskeyService
+	MagicLinkService model.MagicLinkService
+	OAuthService     model.OAuthService
+	LockoutService   model.LockoutService
+	ExportService    model.ExportService
+	InviteService    model.InviteService
+	RoleService      model.RoleService
+	OrgService       model.OrgService
+	BaseURL          string
+	TimeoutDuration  time.Duration
+	MaxBodyBytes     int64
+	TermsVersion     string
+	TermsURL         string
+	// ServeImages serves profile images under /images, for image
+	// repositories which do not serve them themselves
+	ServeImages bool
+	// EnumerationSafeSignup answers sign-ups, guest upgrades and provider
+	// sign-ups without revealing registered emails
+	EnumerationSafeSignup bool
 }
 
 // NewHandler initializes the handler with required injected services along with http routes
@@ -32,29 +66,135 @@ func NewHandler(c *Config) {
 	// Create an account group
 	// Create a handler (which will later have injected services)
 	h := &Handler{
-		UserService:  c.UserService,
-		TokenService: c.TokenService,
-		MaxBodyBytes: c.MaxBodyBytes,
+		UserService:      c.UserService,
+		TokenService:     c.TokenService,
+		MFAService:       c.MFAService,
+		PasskeyService:   c.PasskeyService,
+		MagicLinkService: c.MagicLinkService,
+		OAuthService:     c.OAuthService,
+		LockoutService:   c.LockoutService,
+		ExportService:    c.ExportService,
+		InviteService:    c.InviteService,
+		RoleService:      c.RoleService,
+		OrgService:       c.OrgService,
+		BaseURL:          c.BaseURL,
+		MaxBodyBytes:     c.MaxBodyBytes,
+		TermsVersion:     c.TermsVersion,
+		TermsURL:         c.TermsURL,
+
+		EnumerationSafeSignup: c.EnumerationSafeSignup,
 	}
 
 	// Create a group, or base url for all routes
 	g := c.Engine.Group(c.BaseURL) // Create a handler (which will later have injected services)
+	g.Use(middleware.ClientInfo())
+	// auth routes need a signed in user who accepted the current terms of
+	// service, admin routes an admin. Tests call handlers without middleware
+	auth, admin := g, g.Group("/admin")
 	if gin.Mode() != gin.TestMode {
 		g.Use(middleware.Timeout(c.TimeoutDuration, apperrors.NewServiceUnavailable()))
 		g.GET("/me", middleware.AuthUser(h.TokenService), h.Me)
 		g.POST("/signout", middleware.AuthUser(h.TokenService), h.Signout)
-		g.PUT("/details", middleware.AuthUser(h.TokenService), h.Details)
-		g.POST("/image", middleware.AuthUser(h.TokenService), h.Image)
-		g.DELETE("/image", middleware.AuthUser(h.TokenService), h.DeleteImage)
+		g.POST("/terms/accept", middleware.AuthUser(h.TokenService), h.AcceptTerms)
+
+		// the routes above stay usable until the current terms of service are accepted
+		auth = g.Group("", middleware.AuthUser(h.TokenService), middleware.RequireTerms(h.TermsVersion))
+		admin = auth.Group("/admin", middleware.RequireRole(model.RoleAdmin))
 	} else {
 		g.GET("/me", h.Me)
 		g.POST("/signout", h.Signout)
-		g.PUT("/details", h.Details)
-		g.POST("/image", h.Image)
-		g.DELETE("/image", h.DeleteImage)
+		g.POST("/terms/accept", h.AcceptTerms)
 	}
 
+	auth.GET("/me/activity", h.Activity)
+	auth.PUT("/details", h.Details)
+	auth.PUT("/password", h.ChangePassword)
+	auth.PUT("/handle", h.SetHandle)
+	auth.POST("/image", h.Image)
+	auth.DELETE("/image", h.DeleteImage)
+	auth.POST("/guest/upgrade", h.UpgradeGuest)
+
+	admin.GET("/users", h.AdminUsers)
+	admin.GET("/users/search", h.SearchUsers)
+	admin.GET("/users/:id", h.AdminUser)
+	admin.PUT("/users/:id", h.UpdateUser)
+	admin.DELETE("/users/:id/image", h.ResetUserImage)
+	admin.POST("/users/:id/signout", h.SignoutUser)
+	admin.POST("/users/:id/password-reset", h.ResetUserPassword)
+	admin.PUT("/users/:id/status", h.SetUserStatus)
+
 	g.POST("/signup", h.Signup)
+	g.POST("/guest", h.Guest)
 	g.POST("/signin", h.Signin)
+	g.POST("/signin/unlock", h.Unlock)
+	g.POST("/signin/disown", h.DisownSignin)
+	g.POST("/signin/link", h.SigninLink)
+	g.POST("/signin/link/verify", h.VerifySigninLink)
+	g.POST("/password/reset", h.RequestPasswordReset)
+	g.POST("/password/reset/confirm", h.ConfirmPasswordReset)
 	g.POST("/tokens", h.Tokens)
+	g.GET("/handles/:handle/available", h.HandleAvailable)
+	g.GET("/users/:handle", h.Profile)
+	g.GET("/terms", h.Terms)
+
+	// the routes of features whose service is left out, as its repository
+	// is not available with every driver, are not registered
+	if h.ExportService != nil {
+		auth.POST("/me/export", h.Export)
+		auth.GET("/me/export/:id", h.ExportStatus)
+		g.GET("/exports/:token", h.ExportDownload)
+	}
+
+	if h.MFAService != nil {
+		auth.POST("/mfa/totp", h.EnrollTOTP)
+		auth.POST("/mfa/totp/confirm", h.ConfirmTOTP)
+		auth.POST("/mfa/totp/disable", h.DisableTOTP)
+		g.POST("/signin/mfa", h.SigninMFA)
+	}
+
+	if h.PasskeyService != nil {
+		auth.POST("/passkeys/register/begin", h.BeginPasskeyRegistration)
+		auth.POST("/passkeys/register/finish", h.FinishPasskeyRegistration)
+		auth.GET("/passkeys", h.Passkeys)
+		auth.DELETE("/passkeys/:id", h.DeletePasskey)
+		g.POST("/signin/passkey/begin", h.BeginPasskeySignin)
+		g.POST("/signin/passkey/finish", h.FinishPasskeySignin)
+	}
+
+	if h.OAuthService != nil {
+		auth.GET("/me/identities", h.Identities)
+		auth.POST("/me/identities/:provider", h.LinkIdentityBegin)
+		auth.POST("/me/identities/:provider/callback", h.LinkIdentityCallback)
+		auth.DELETE("/me/identities/:provider", h.UnlinkIdentity)
+		g.GET("/oauth/providers", h.OAuthProviders)
+		g.POST("/oauth/:provider", h.OAuthBegin)
+		g.POST("/oauth/:provider/callback", h.OAuthCallback)
+	}
+
+	if h.OrgService != nil {
+		auth.PUT("/me/org", h.SwitchOrg)
+		auth.POST("/orgs", h.CreateOrg)
+		auth.GET("/orgs", h.Orgs)
+		auth.GET("/orgs/:id/members", h.OrgMembers)
+		auth.POST("/orgs/:id/invites", h.InviteOrgMember)
+		auth.DELETE("/orgs/:id/members/:uid", h.RemoveOrgMember)
+		auth.PUT("/orgs/:id/members/:uid/role", h.SetOrgMemberRole)
+		auth.POST("/org-invites/accept", h.AcceptOrgInvite)
+	}
+
+	if h.InviteService != nil {
+		admin.POST("/invites", h.CreateInvite)
+		admin.GET("/invites", h.Invites)
+		admin.DELETE("/invites/:id", h.RevokeInvite)
+	}
+
+	if h.RoleService != nil {
+		admin.GET("/users/:id/roles", h.UserRoles)
+		admin.PUT("/users/:id/roles/:role", h.GrantRole)
+		admin.DELETE("/users/:id/roles/:role", h.RevokeRole)
+	}
+
+	if c.ServeImages {
+		g.GET("/images/:name", h.ProfileImage)
+	}
 }
@@ -48,6 +48,27 @@ func (h *Handler) Image(c *gin.Context) {
 		return
 	}
 
+	// the type sent by the client is not checked against the content,
+	// which could be HTML or script served back as an image
+	sniffedType, err := utils.SniffImageFile(imageFileHeader)
+	if err != nil {
+		log.Printf("Unable to read image file: %v\n", err)
+		e := apperrors.NewBadRequest("Unable to parse image from multipart/form-data")
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	if valid := utils.IsAllowedImageType(sniffedType); !valid {
+		log.Println("Image content is not an allowable mime-type")
+		e := apperrors.NewBadRequest("imageFile must be 'image/jpeg' or 'image/png'")
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
 	ctx := c.Request.Context()
 
 	updatedUser, err := h.UserService.SetProfileImage(ctx, authUser.UID, imageFileHeader)
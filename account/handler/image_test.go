@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"bytes"
 	"encoding/json"
 	"github.com/dolong2110/memorization-apps/account/model"
 	"github.com/dolong2110/memorization-apps/account/model/apperrors"
@@ -12,8 +13,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
 	"testing"
 )
 
@@ -152,6 +155,33 @@ func TestImage(t *testing.T) {
 	//	mockUserService.AssertNotCalled(t, "SetProfileImage")
 	//})
 
+	t.Run("Content is not an image", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		// HTML claiming to be a PNG
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", `form-data; name="image_file"; filename="image.png"`)
+		h.Set("Content-Type", "image/png")
+		part, _ := writer.CreatePart(h)
+		part.Write([]byte("<html><script>alert(document.cookie)</script></html>"))
+		writer.Close()
+
+		request, _ := http.NewRequest(http.MethodPost, "/image", body)
+		request.Header.Set("Content-Type", writer.FormDataContentType())
+
+		router.ServeHTTP(rr, request)
+
+		var resp apperrors.Response
+		_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, "imageFile must be 'image/jpeg' or 'image/png'", resp.Error.Message)
+
+		mockUserService.AssertNotCalled(t, "SetProfileImage")
+	})
+
 	t.Run("Error from SetProfileImage", func(t *testing.T) {
 		// create unique context user for this test
 		uid, _ := uuid.NewRandom()
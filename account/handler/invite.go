@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"log"
+	"net/http"
+	"time"
+)
+
+type createInviteReq struct {
+	MaxUses   int   `json:"max_uses" binding:"omitempty,min=1"`
+	ExpiresIn int64 `json:"expires_in" binding:"omitempty,min=1"` // seconds, the configured default if omitted
+}
+
+// CreateInvite handler creates a sign-up invite
+// The code is only part of this response
+func (h *Handler) CreateInvite(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req createInviteReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	maxUses := req.MaxUses
+	if maxUses == 0 {
+		maxUses = 1
+	}
+
+	ctx := c.Request.Context()
+	invite, err := h.InviteService.Create(ctx, authUser.UID, maxUses, time.Duration(req.ExpiresIn)*time.Second)
+	if err != nil {
+		log.Printf("Failed to create invite: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"invite": invite,
+	})
+}
+
+// Invites handler lists all sign-up invites
+func (h *Handler) Invites(c *gin.Context) {
+	ctx := c.Request.Context()
+	invites, err := h.InviteService.List(ctx)
+	if err != nil {
+		log.Printf("Failed to list invites: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invites": invites,
+	})
+}
+
+// RevokeInvite handler makes an invite unusable
+func (h *Handler) RevokeInvite(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		err := apperrors.NewBadRequest("Invalid invite id")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.InviteService.Revoke(ctx, id); err != nil {
+		log.Printf("Failed to revoke invite: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestInvites(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	authUser := &model.User{UID: uid, Email: "admin@bob.com"}
+
+	newRouter := func(mockInviteService *mocks.MockInviteService) *gin.Engine {
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", authUser)
+		})
+
+		NewHandler(&Config{
+			Engine:        router,
+			InviteService: mockInviteService,
+		})
+
+		return router
+	}
+
+	serve := func(router *gin.Engine, method string, url string, body gin.H) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(body)
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(method, url, bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		mockInviteService := new(mocks.MockInviteService)
+		mockInvite := &model.Invite{ID: uuid.New(), Code: "code", MaxUses: 3}
+
+		mockInviteService.On("Create", mock.Anything, uid, 3, 2*time.Hour).Return(mockInvite, nil)
+
+		rr := serve(newRouter(mockInviteService), http.MethodPost, "/admin/invites", gin.H{
+			"max_uses":   3,
+			"expires_in": 7200,
+		})
+
+		respBody, err := json.Marshal(gin.H{
+			"invite": mockInvite,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Create single-use by default", func(t *testing.T) {
+		mockInviteService := new(mocks.MockInviteService)
+		mockInvite := &model.Invite{ID: uuid.New(), Code: "code", MaxUses: 1}
+
+		mockInviteService.On("Create", mock.Anything, uid, 1, time.Duration(0)).Return(mockInvite, nil)
+
+		rr := serve(newRouter(mockInviteService), http.MethodPost, "/admin/invites", gin.H{})
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		mockInviteService.AssertExpectations(t)
+	})
+
+	t.Run("Create with invalid uses", func(t *testing.T) {
+		mockInviteService := new(mocks.MockInviteService)
+
+		rr := serve(newRouter(mockInviteService), http.MethodPost, "/admin/invites", gin.H{
+			"max_uses": -1,
+		})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockInviteService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		mockInviteService := new(mocks.MockInviteService)
+		mockInvites := []*model.Invite{{ID: uuid.New(), MaxUses: 1, Uses: 1}}
+
+		mockInviteService.On("List", mock.Anything).Return(mockInvites, nil)
+
+		rr := serve(newRouter(mockInviteService), http.MethodGet, "/admin/invites", nil)
+
+		respBody, err := json.Marshal(gin.H{
+			"invites": mockInvites,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		mockInviteService := new(mocks.MockInviteService)
+		id := uuid.New()
+
+		mockInviteService.On("Revoke", mock.Anything, id).Return(nil)
+
+		rr := serve(newRouter(mockInviteService), http.MethodDelete, "/admin/invites/"+id.String(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockInviteService.AssertExpectations(t)
+	})
+
+	t.Run("Revoke unknown invite", func(t *testing.T) {
+		mockInviteService := new(mocks.MockInviteService)
+		id := uuid.New()
+
+		mockInviteService.On("Revoke", mock.Anything, id).Return(apperrors.NewNotFound("invite", id.String()))
+
+		rr := serve(newRouter(mockInviteService), http.MethodDelete, "/admin/invites/"+id.String(), nil)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Revoke invalid id", func(t *testing.T) {
+		mockInviteService := new(mocks.MockInviteService)
+
+		rr := serve(newRouter(mockInviteService), http.MethodDelete, "/admin/invites/abc", nil)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockInviteService.AssertNotCalled(t, "Revoke", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+)
+
+type signinLinkReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type verifySigninLinkReq struct {
+	Token string `json:"token" binding:"required"`
+	Nonce string `json:"nonce" binding:"required"`
+}
+
+// SigninLink handler emails a single-use sign-in link
+// The returned nonce must be kept by the browser to verify the link
+func (h *Handler) SigninLink(c *gin.Context) {
+	var req signinLinkReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	nonce, err := h.MagicLinkService.Send(ctx, req.Email)
+	if err != nil {
+		log.Printf("Failed to send sign-in link: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"nonce": nonce,
+	})
+}
+
+// VerifySigninLink handler signs in with the token of a sign-in link
+func (h *Handler) VerifySigninLink(c *gin.Context) {
+	var req verifySigninLinkReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.MagicLinkService.Verify(ctx, req.Token, req.Nonce)
+	if err != nil {
+		log.Printf("Failed to verify sign-in link: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	h.completeSignin(c, user, "sign-in link")
+}
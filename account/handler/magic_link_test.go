@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSigninLink(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Invalid email", func(t *testing.T) {
+		mockMagicLinkService := new(mocks.MockMagicLinkService)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:           router,
+			MagicLinkService: mockMagicLinkService,
+		})
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"email": "notanemail",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/link", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockMagicLinkService.AssertNotCalled(t, "Send")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		mockMagicLinkService := new(mocks.MockMagicLinkService)
+		mockMagicLinkService.On("Send", mock.Anything, "bob@bob.com").Return("nonce", nil)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:           router,
+			MagicLinkService: mockMagicLinkService,
+		})
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"email": "bob@bob.com",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/link", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"nonce": "nonce",
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+}
+
+func TestVerifySigninLink(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockMagicLinkService := new(mocks.MockMagicLinkService)
+
+		mockUser := &model.User{UID: uid, Email: "bob@bob.com"}
+		mockMagicLinkService.On("Verify", mock.Anything, "token", "nonce").Return(mockUser, nil)
+		mockUserService.On("RecordSignin", mock.Anything, mockUser, "sign-in link").Return()
+
+		mockTokenPair := &model.Token{
+			AccessToken:  model.AccessToken{SignedStringToken: "idToken"},
+			RefreshToken: model.RefreshToken{SignedStringToken: "refreshToken"},
+		}
+		mockTokenService.On("NewPairFromUser", mock.Anything, mockUser, "").Return(mockTokenPair, nil)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:           router,
+			UserService:      mockUserService,
+			TokenService:     mockTokenService,
+			MagicLinkService: mockMagicLinkService,
+		})
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"token": "token",
+			"nonce": "nonce",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/link/verify", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"tokens": mockTokenPair,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Enrolled user gets a challenge", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+		mockMagicLinkService := new(mocks.MockMagicLinkService)
+		mockMFAService := new(mocks.MockMFAService)
+
+		mockUser := &model.User{UID: uid, Email: "bob@bob.com"}
+		mockMagicLinkService.On("Verify", mock.Anything, "token", "nonce").Return(mockUser, nil)
+		mockMFAService.On("IsEnrolled", mock.Anything, uid).Return(true, nil)
+		mockMFAService.On("NewChallenge", mock.Anything, uid).Return("challengetoken", nil)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:           router,
+			TokenService:     mockTokenService,
+			MFAService:       mockMFAService,
+			MagicLinkService: mockMagicLinkService,
+		})
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"token": "token",
+			"nonce": "nonce",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/link/verify", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"mfa_required": true,
+			"mfa_token":    "challengetoken",
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("Used link", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+		mockMagicLinkService := new(mocks.MockMagicLinkService)
+
+		mockError := apperrors.NewAuthorization("Invalid or expired sign-in link")
+		mockMagicLinkService.On("Verify", mock.Anything, "token", "nonce").Return(nil, mockError)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:           router,
+			TokenService:     mockTokenService,
+			MagicLinkService: mockMagicLinkService,
+		})
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"token": "token",
+			"nonce": "nonce",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/link/verify", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+)
+
+type totpCodeReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type disableTOTPReq struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type signinMFAReq struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// EnrollTOTP handler starts enrolling an authenticator app for the current user
+func (h *Handler) EnrollTOTP(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	ctx := c.Request.Context()
+	enrollment, err := h.MFAService.EnrollTOTP(ctx, authUser.UID)
+	if err != nil {
+		log.Printf("Failed to enroll totp: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"totp": enrollment,
+	})
+}
+
+// ConfirmTOTP handler activates the pending enrollment with a first code
+func (h *Handler) ConfirmTOTP(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req totpCodeReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	recoveryCodes, err := h.MFAService.ConfirmTOTP(ctx, authUser.UID, req.Code)
+	if err != nil {
+		log.Printf("Failed to confirm totp: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// DisableTOTP handler turns two-factor authentication off, it requires the password
+func (h *Handler) DisableTOTP(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req disableTOTPReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.MFAService.DisableTOTP(ctx, authUser.UID, req.Password); err != nil {
+		log.Printf("Failed to disable totp: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
+
+// SigninMFA handler completes a sign-in started by Signin with a second factor
+func (h *Handler) SigninMFA(c *gin.Context) {
+	var req signinMFAReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// failed codes count against the account like failed passwords,
+	// so the lockout of the account holds for both factors
+	account := ""
+	if h.LockoutService != nil {
+		challengeUser, err := h.MFAService.ChallengeUser(ctx, req.MFAToken)
+		if err != nil {
+			log.Printf("Failed to get user of mfa challenge: %v\n", err.Error())
+			c.JSON(apperrors.Status(err), gin.H{
+				"error": err,
+			})
+			return
+		}
+		account = challengeUser.Email
+
+		if err := h.LockoutService.Check(ctx, account, c.ClientIP()); err != nil {
+			log.Printf("Rejected mfa attempt: %v\n", err.Error())
+			respondWithRetryAfter(c, err)
+			return
+		}
+	}
+
+	user, err := h.MFAService.VerifyChallenge(ctx, req.MFAToken, req.Code)
+	if err != nil {
+		log.Printf("Failed to verify mfa challenge: %v\n", err.Error())
+
+		if h.LockoutService != nil && apperrors.Status(err) == http.StatusUnauthorized {
+			if err := h.LockoutService.RecordFailure(ctx, account, c.ClientIP()); err != nil {
+				log.Printf("Failed to record failed mfa attempt: %v\n", err.Error())
+			}
+		}
+
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if h.LockoutService != nil {
+		if err := h.LockoutService.RecordSuccess(ctx, account); err != nil {
+			log.Printf("Failed to reset failed sign in attempts: %v\n", err.Error())
+		}
+	}
+
+	h.issueSigninTokens(c, user, "second factor")
+}
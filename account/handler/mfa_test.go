@@ -0,0 +1,430 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSigninWithMFA(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	email := "bob@bob.com"
+	password := "pwworksgreat123"
+
+	t.Run("Enrolled user gets a challenge", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockMFAService := new(mocks.MockMFAService)
+
+		mockUserService.
+			On("Signin", mock.Anything, &model.User{Email: email, Password: password}).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(*model.User).UID = uid
+			}).
+			Return(nil)
+		mockMFAService.On("IsEnrolled", mock.Anything, uid).Return(true, nil)
+		mockMFAService.On("NewChallenge", mock.Anything, uid).Return("challengetoken", nil)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:       router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+			MFAService:   mockMFAService,
+		})
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"email":    email,
+			"password": password,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"mfa_required": true,
+			"mfa_token":    "challengetoken",
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockMFAService.AssertExpectations(t)
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("Not enrolled user gets tokens", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockMFAService := new(mocks.MockMFAService)
+
+		mockUserService.
+			On("Signin", mock.Anything, &model.User{Email: email, Password: password}).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(*model.User).UID = uid
+			}).
+			Return(nil)
+		mockMFAService.On("IsEnrolled", mock.Anything, uid).Return(false, nil)
+		mockUserService.On("RecordSignin", mock.Anything, mock.AnythingOfType("*model.User"), "password").Return()
+
+		mockTokenPair := &model.Token{
+			AccessToken:  model.AccessToken{SignedStringToken: "idToken"},
+			RefreshToken: model.RefreshToken{SignedStringToken: "refreshToken"},
+		}
+		mockTokenService.On("NewPairFromUser", mock.Anything, mock.AnythingOfType("*model.User"), "").Return(mockTokenPair, nil)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:       router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+			MFAService:   mockMFAService,
+		})
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"email":    email,
+			"password": password,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"tokens": mockTokenPair,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockMFAService.AssertNotCalled(t, "NewChallenge")
+		mockUserService.AssertExpectations(t)
+	})
+}
+
+func TestSigninMFA(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockMFAService := new(mocks.MockMFAService)
+
+		mockUser := &model.User{UID: uid, Email: "bob@bob.com"}
+		mockMFAService.On("VerifyChallenge", mock.Anything, "challengetoken", "123456").Return(mockUser, nil)
+		mockUserService.On("RecordSignin", mock.Anything, mockUser, "second factor").Return()
+
+		mockTokenPair := &model.Token{
+			AccessToken:  model.AccessToken{SignedStringToken: "idToken"},
+			RefreshToken: model.RefreshToken{SignedStringToken: "refreshToken"},
+		}
+		mockTokenService.On("NewPairFromUser", mock.Anything, mockUser, "").Return(mockTokenPair, nil)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:       router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+			MFAService:   mockMFAService,
+		})
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"mfa_token": "challengetoken",
+			"code":      "123456",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/mfa", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"tokens": mockTokenPair,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockMFAService.AssertExpectations(t)
+		mockTokenService.AssertExpectations(t)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid code", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+		mockMFAService := new(mocks.MockMFAService)
+
+		mockError := apperrors.NewAuthorization("Invalid authentication code")
+		mockMFAService.On("VerifyChallenge", mock.Anything, "challengetoken", "000000").Return(nil, mockError)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:       router,
+			TokenService: mockTokenService,
+			MFAService:   mockMFAService,
+		})
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"mfa_token": "challengetoken",
+			"code":      "000000",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/mfa", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+}
+
+func TestSigninMFALockout(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	mockUser := &model.User{UID: uid, Email: "bob@bob.com"}
+
+	signinMFA := func(router *gin.Engine, code string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"mfa_token": "challengetoken",
+			"code":      code,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/mfa", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	newRouter := func(mockTokenService *mocks.MockTokenService, mockMFAService *mocks.MockMFAService, mockLockoutService *mocks.MockLockoutService) *gin.Engine {
+		router := gin.Default()
+
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("RecordSignin", mock.Anything, mock.Anything, mock.Anything).Return()
+
+		NewHandler(&Config{
+			Engine:         router,
+			UserService:    mockUserService,
+			TokenService:   mockTokenService,
+			MFAService:     mockMFAService,
+			LockoutService: mockLockoutService,
+		})
+
+		return router
+	}
+
+	t.Run("Blocked account", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+		mockMFAService := new(mocks.MockMFAService)
+		mockLockoutService := new(mocks.MockLockoutService)
+
+		mockMFAService.On("ChallengeUser", mock.Anything, "challengetoken").Return(mockUser, nil)
+		mockLockoutService.On("Check", mock.Anything, "bob@bob.com", mock.AnythingOfType("string")).Return(apperrors.NewTooManyRequests("Too many failed sign-in attempts. Try again later", 90*time.Second))
+
+		rr := signinMFA(newRouter(mockTokenService, mockMFAService, mockLockoutService), "123456")
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "90", rr.Header().Get("Retry-After"))
+		mockMFAService.AssertNotCalled(t, "VerifyChallenge")
+	})
+
+	t.Run("Records failure", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+		mockMFAService := new(mocks.MockMFAService)
+		mockLockoutService := new(mocks.MockLockoutService)
+
+		mockMFAService.On("ChallengeUser", mock.Anything, "challengetoken").Return(mockUser, nil)
+		mockLockoutService.On("Check", mock.Anything, "bob@bob.com", mock.AnythingOfType("string")).Return(nil)
+		mockMFAService.On("VerifyChallenge", mock.Anything, "challengetoken", "000000").Return(nil, apperrors.NewAuthorization("Invalid authentication code"))
+		mockLockoutService.On("RecordFailure", mock.Anything, "bob@bob.com", mock.AnythingOfType("string")).Return(nil)
+
+		rr := signinMFA(newRouter(mockTokenService, mockMFAService, mockLockoutService), "000000")
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockLockoutService.AssertExpectations(t)
+		mockLockoutService.AssertNotCalled(t, "RecordSuccess")
+	})
+
+	t.Run("Records success", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+		mockMFAService := new(mocks.MockMFAService)
+		mockLockoutService := new(mocks.MockLockoutService)
+
+		mockMFAService.On("ChallengeUser", mock.Anything, "challengetoken").Return(mockUser, nil)
+		mockLockoutService.On("Check", mock.Anything, "bob@bob.com", mock.AnythingOfType("string")).Return(nil)
+		mockMFAService.On("VerifyChallenge", mock.Anything, "challengetoken", "123456").Return(mockUser, nil)
+		mockLockoutService.On("RecordSuccess", mock.Anything, "bob@bob.com").Return(nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, mockUser, "").Return(&model.Token{}, nil)
+
+		rr := signinMFA(newRouter(mockTokenService, mockMFAService, mockLockoutService), "123456")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockLockoutService.AssertExpectations(t)
+		mockLockoutService.AssertNotCalled(t, "RecordFailure")
+	})
+
+	t.Run("Unknown challenge", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+		mockMFAService := new(mocks.MockMFAService)
+		mockLockoutService := new(mocks.MockLockoutService)
+
+		mockMFAService.On("ChallengeUser", mock.Anything, "challengetoken").Return(nil, apperrors.NewAuthorization("Invalid or expired mfa token"))
+
+		rr := signinMFA(newRouter(mockTokenService, mockMFAService, mockLockoutService), "123456")
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockMFAService.AssertNotCalled(t, "VerifyChallenge")
+		mockLockoutService.AssertNotCalled(t, "RecordFailure")
+	})
+}
+
+func TestTOTPEnrollment(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	mockMFAService := new(mocks.MockMFAService)
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", &model.User{
+			UID: uid,
+		})
+	})
+
+	NewHandler(&Config{
+		Engine:     router,
+		MFAService: mockMFAService,
+	})
+
+	t.Run("Enroll", func(t *testing.T) {
+		mockEnrollment := &model.TOTPEnrollment{
+			Secret:    "JBSWY3DPEHPK3PXP",
+			URI:       "otpauth://totp/memorization-apps:bob%40bob.com?secret=JBSWY3DPEHPK3PXP",
+			QRCodePNG: []byte{0x89, 'P', 'N', 'G'},
+		}
+		mockMFAService.On("EnrollTOTP", mock.Anything, uid).Return(mockEnrollment, nil)
+
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodPost, "/mfa/totp", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"totp": mockEnrollment,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Confirm", func(t *testing.T) {
+		mockCodes := []string{"abcde-12345", "fghij-67890"}
+		mockMFAService.On("ConfirmTOTP", mock.Anything, uid, "123456").Return(mockCodes, nil)
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"code": "123456",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/mfa/totp/confirm", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"recovery_codes": mockCodes,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Disable requires password", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/mfa/totp/disable", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockMFAService.AssertNotCalled(t, "DisableTOTP")
+	})
+
+	t.Run("Disable with wrong password", func(t *testing.T) {
+		mockError := apperrors.NewAuthorization("Invalid password")
+		mockMFAService.On("DisableTOTP", mock.Anything, uid, "wrongpassword").Return(mockError)
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"password": "wrongpassword",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/mfa/totp/disable", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
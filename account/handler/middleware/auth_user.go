@@ -69,7 +69,7 @@ func AuthUser(s model.TokenService) gin.HandlerFunc {
 		}
 
 		// validate ID token here
-		user, err := s.ValidateIDToken(idTokenHeader[1])
+		user, err := s.ValidateIDToken(c.Request.Context(), idTokenHeader[1])
 		if err != nil {
 			err := apperrors.NewAuthorization("Provided token is invalid")
 			c.JSON(err.Status(), gin.H{
@@ -80,6 +80,8 @@ func AuthUser(s model.TokenService) gin.HandlerFunc {
 		}
 
 		c.Set("user", user)
+		// the user is the actor of audit events of this request
+		c.Request = c.Request.WithContext(model.WithActor(c.Request.Context(), user.UID))
 		c.Next()
 	}
 }
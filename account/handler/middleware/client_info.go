@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/gin-gonic/gin"
+)
+
+// ClientInfo adds the IP and user agent of the client to the request
+// context, where services find them for audit events
+func ClientInfo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info := model.ClientInfo{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		}
+
+		c.Request = c.Request.WithContext(model.WithClientInfo(c.Request.Context(), info))
+		c.Next()
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole only lets users with one of the roles through
+// It must be used after AuthUser, which sets the user with
+// the roles of the access token to the context
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := c.MustGet("user").(*model.User)
+		if ok && hasAnyRole(user, roles) {
+			c.Next()
+			return
+		}
+
+		err := apperrors.NewForbidden("Missing the role for this request")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		c.Abort()
+	}
+}
+
+func hasAnyRole(user *model.User, roles []string) bool {
+	for _, userRole := range user.Roles {
+		for _, role := range roles {
+			if userRole == role {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireRole(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	serve := func(user *model.User) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", user)
+		})
+		router.GET("/admin", RequireRole(model.RoleAdmin, "support"), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodGet, "/admin", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Has role", func(t *testing.T) {
+		rr := serve(&model.User{Roles: []string{"support"}})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Missing role", func(t *testing.T) {
+		rr := serve(&model.User{Roles: []string{"editor"}})
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Contains(t, rr.Body.String(), "FORBIDDEN")
+	})
+
+	t.Run("No roles", func(t *testing.T) {
+		rr := serve(&model.User{})
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
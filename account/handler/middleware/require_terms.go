@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireTerms only lets users through who accepted the version of the terms of service
+// It must be used after AuthUser, and lets everyone through for an empty version
+// Guests are let through too, they accept the terms when they upgrade
+func RequireTerms(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := c.MustGet("user").(*model.User)
+		if version == "" || (ok && (user.TermsVersion == version || user.Guest)) {
+			c.Next()
+			return
+		}
+
+		err := apperrors.NewTermsNotAccepted(version)
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		c.Abort()
+	}
+}
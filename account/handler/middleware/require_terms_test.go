@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireTerms(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	serve := func(version string, user *model.User) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", user)
+		})
+		router.GET("/details", RequireTerms(version), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodGet, "/details", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Accepted current version", func(t *testing.T) {
+		rr := serve("2024-01", &model.User{TermsVersion: "2024-01"})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Accepted older version", func(t *testing.T) {
+		rr := serve("2024-06", &model.User{TermsVersion: "2024-01"})
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Contains(t, rr.Body.String(), "TERMS_NOT_ACCEPTED")
+		assert.Contains(t, rr.Body.String(), `"terms":"2024-06"`)
+	})
+
+	t.Run("Guests accept on upgrade", func(t *testing.T) {
+		rr := serve("2024-06", &model.User{Guest: true})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("No terms", func(t *testing.T) {
+		rr := serve("", &model.User{})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
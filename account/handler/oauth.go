@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"log"
+	"net/http"
+)
+
+type oauthCallbackReq struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+	Nonce string `json:"nonce" binding:"required"`
+}
+
+// OAuthProviders handler lists the identity providers users can sign in with
+func (h *Handler) OAuthProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"providers": h.OAuthService.Providers(),
+	})
+}
+
+// OAuthBegin handler returns the URL of a provider to redirect the browser to for signing in
+// The returned nonce must be kept by the browser to complete the callback
+func (h *Handler) OAuthBegin(c *gin.Context) {
+	h.oauthBegin(c, uuid.Nil)
+}
+
+// OAuthCallback handler signs in with the authorization code the provider
+// redirected the browser back with
+func (h *Handler) OAuthCallback(c *gin.Context) {
+	var req oauthCallbackReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.OAuthService.Signin(ctx, c.Param("provider"), req.Code, req.State, req.Nonce)
+	if err != nil {
+		// the service notified the owner of a registered email
+		if h.enumerationSafeConflict(c, err) {
+			return
+		}
+
+		log.Printf("Failed to sign in with %v: %v\n", c.Param("provider"), err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	h.completeSignin(c, user, c.Param("provider"))
+}
+
+// Identities handler lists the provider accounts linked to the current user
+func (h *Handler) Identities(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	ctx := c.Request.Context()
+	identities, err := h.OAuthService.ListIdentities(ctx, authUser.UID)
+	if err != nil {
+		log.Printf("Failed to list identities: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"identities": identities,
+	})
+}
+
+// LinkIdentityBegin handler returns the URL of a provider to redirect the
+// browser to for linking an account to the current user
+func (h *Handler) LinkIdentityBegin(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	h.oauthBegin(c, authUser.UID)
+}
+
+// LinkIdentityCallback handler links the provider account the browser was redirected back with
+func (h *Handler) LinkIdentityCallback(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req oauthCallbackReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	identity, err := h.OAuthService.Link(ctx, authUser.UID, c.Param("provider"), req.Code, req.State, req.Nonce)
+	if err != nil {
+		// guests linking a provider sign up with its email
+		if authUser.Guest && h.enumerationSafeConflict(c, err) {
+			return
+		}
+
+		log.Printf("Failed to link %v identity: %v\n", c.Param("provider"), err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"identity": identity,
+	})
+}
+
+// UnlinkIdentity handler removes the provider account of the current user
+func (h *Handler) UnlinkIdentity(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	ctx := c.Request.Context()
+	if err := h.OAuthService.Unlink(ctx, authUser.UID, c.Param("provider")); err != nil {
+		log.Printf("Failed to unlink %v identity: %v\n", c.Param("provider"), err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
+
+func (h *Handler) oauthBegin(c *gin.Context, uid uuid.UUID) {
+	ctx := c.Request.Context()
+	url, nonce, err := h.OAuthService.AuthCodeURL(ctx, c.Param("provider"), uid)
+	if err != nil {
+		log.Printf("Failed to start %v authorization: %v\n", c.Param("provider"), err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"url":   url,
+		"nonce": nonce,
+	})
+}
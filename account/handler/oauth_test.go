@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOAuthSignin(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Begin", func(t *testing.T) {
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("AuthCodeURL", mock.Anything, "google", uuid.Nil).Return("https://idp.example/authorize?state=abc", "nonce", nil)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:       router,
+			OAuthService: mockOAuthService,
+		})
+
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodPost, "/oauth/google", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"url":   "https://idp.example/authorize?state=abc",
+			"nonce": "nonce",
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Unknown provider", func(t *testing.T) {
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("AuthCodeURL", mock.Anything, "unknown", uuid.Nil).Return("", "", apperrors.NewNotFound("provider", "unknown"))
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:       router,
+			OAuthService: mockOAuthService,
+		})
+
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodPost, "/oauth/unknown", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Callback", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockOAuthService := new(mocks.MockOAuthService)
+
+		mockUser := &model.User{UID: uid, Email: "bob@bob.com"}
+		mockOAuthService.On("Signin", mock.Anything, "google", "code", "state", "nonce").Return(mockUser, nil)
+		mockUserService.On("RecordSignin", mock.Anything, mockUser, "google").Return()
+
+		mockTokenPair := &model.Token{
+			AccessToken:  model.AccessToken{SignedStringToken: "idToken"},
+			RefreshToken: model.RefreshToken{SignedStringToken: "refreshToken"},
+		}
+		mockTokenService.On("NewPairFromUser", mock.Anything, mockUser, "").Return(mockTokenPair, nil)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:       router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+			OAuthService: mockOAuthService,
+		})
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"code":  "code",
+			"state": "state",
+			"nonce": "nonce",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/oauth/google/callback", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"tokens": mockTokenPair,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+}
+
+func TestIdentities(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	mockOAuthService := new(mocks.MockOAuthService)
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", &model.User{
+			UID: uid,
+		})
+	})
+
+	NewHandler(&Config{
+		Engine:       router,
+		OAuthService: mockOAuthService,
+	})
+
+	t.Run("Begin linking", func(t *testing.T) {
+		mockOAuthService.On("AuthCodeURL", mock.Anything, "github", uid).Return("https://idp.example/authorize?state=abc", "nonce", nil)
+
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodPost, "/me/identities/github", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockOAuthService.AssertCalled(t, "AuthCodeURL", mock.Anything, "github", uid)
+	})
+
+	t.Run("Link", func(t *testing.T) {
+		mockIdentity := &model.Identity{Provider: "github", Subject: "12345", UID: uid, Email: "bob@bob.com"}
+		mockOAuthService.On("Link", mock.Anything, uid, "github", "code", "state", "nonce").Return(mockIdentity, nil)
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"code":  "code",
+			"state": "state",
+			"nonce": "nonce",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/me/identities/github/callback", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"identity": mockIdentity,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Link already linked account", func(t *testing.T) {
+		mockOAuthService.On("Link", mock.Anything, uid, "google", "code", "state", "nonce").Return(nil, apperrors.NewConflict("identity", "google"))
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"code":  "code",
+			"state": "state",
+			"nonce": "nonce",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/me/identities/google/callback", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("Unlink", func(t *testing.T) {
+		mockOAuthService.On("Unlink", mock.Anything, uid, "github").Return(nil)
+
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodDelete, "/me/identities/github", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockOAuthService.AssertCalled(t, "Unlink", mock.Anything, uid, "github")
+	})
+}
@@ -0,0 +1,285 @@
+package handler
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"log"
+	"net/http"
+)
+
+type createOrgReq struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type orgInviteReq struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=owner admin member"`
+}
+
+type acceptOrgInviteReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type orgMemberRoleReq struct {
+	Role string `json:"role" binding:"required,oneof=owner admin member"`
+}
+
+// switchOrgReq takes a null org_id to switch back to acting alone
+// A refresh token, if given, is replaced by the new pair
+type switchOrgReq struct {
+	OrgID        *uuid.UUID `json:"org_id"`
+	RefreshToken string     `json:"refresh_token"`
+}
+
+// CreateOrg handler starts an organization owned by the user
+func (h *Handler) CreateOrg(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req createOrgReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	org, err := h.OrgService.Create(ctx, authUser.UID, req.Name)
+	if err != nil {
+		log.Printf("Failed to create organization: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"organization": org,
+	})
+}
+
+// Orgs handler lists the organizations of the user along with their role
+func (h *Handler) Orgs(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	ctx := c.Request.Context()
+	orgs, err := h.OrgService.List(ctx, authUser.UID)
+	if err != nil {
+		log.Printf("Failed to list organizations: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"organizations": orgs,
+	})
+}
+
+// OrgMembers handler lists the members of an organization of the user
+func (h *Handler) OrgMembers(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	members, err := h.OrgService.Members(ctx, authUser.UID, orgID)
+	if err != nil {
+		log.Printf("Failed to list organization members: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"members": members,
+	})
+}
+
+// InviteOrgMember handler mails an invite to join an organization
+func (h *Handler) InviteOrgMember(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req orgInviteReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	invite, err := h.OrgService.Invite(ctx, authUser.UID, orgID, req.Email, req.Role)
+	if err != nil {
+		log.Printf("Failed to invite to organization: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"invite": invite,
+	})
+}
+
+// AcceptOrgInvite handler makes the user a member with the token of an invite link
+func (h *Handler) AcceptOrgInvite(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req acceptOrgInviteReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	org, err := h.OrgService.AcceptInvite(ctx, authUser.UID, req.Token)
+	if err != nil {
+		log.Printf("Failed to accept organization invite: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"organization": org,
+	})
+}
+
+// RemoveOrgMember handler removes a member from an organization, or lets the user leave it
+func (h *Handler) RemoveOrgMember(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+
+	uid, ok := memberIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.OrgService.RemoveMember(ctx, authUser.UID, orgID, uid); err != nil {
+		log.Printf("Failed to remove organization member: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
+
+// SetOrgMemberRole handler changes the role of an organization member
+func (h *Handler) SetOrgMemberRole(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req orgMemberRoleReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+
+	uid, ok := memberIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.OrgService.SetMemberRole(ctx, authUser.UID, orgID, uid, req.Role); err != nil {
+		log.Printf("Failed to set organization member role: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
+
+// SwitchOrg handler sets the active organization of the user and returns
+// a fresh pair of tokens which carry it
+func (h *Handler) SwitchOrg(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req switchOrgReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	prevRefreshTokenID, ok := h.prevRefreshTokenID(c, authUser.UID, req.RefreshToken)
+	if !ok {
+		return
+	}
+
+	user, err := h.OrgService.Switch(ctx, authUser.UID, req.OrgID)
+	if err != nil {
+		log.Printf("Failed to switch organization: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, user, prevRefreshTokenID)
+	if err != nil {
+		log.Printf("Failed to create tokens for uid: %v. Error: %v\n", user.UID, err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+	})
+}
+
+// orgIDParam parses the organization id of a route, responding
+// with a BadRequest if it is invalid
+func orgIDParam(c *gin.Context) (uuid.UUID, bool) {
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		err := apperrors.NewBadRequest("Invalid organization id")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return uuid.Nil, false
+	}
+
+	return orgID, true
+}
+
+// memberIDParam parses the member id of an organization route, responding
+// with a BadRequest if it is invalid
+func memberIDParam(c *gin.Context) (uuid.UUID, bool) {
+	uid, err := uuid.Parse(c.Param("uid"))
+	if err != nil {
+		err := apperrors.NewBadRequest("Invalid user id")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return uuid.Nil, false
+	}
+
+	return uid, true
+}
@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOrgs(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	memberID, _ := uuid.NewRandom()
+	orgID, _ := uuid.NewRandom()
+	authUser := &model.User{UID: uid, Email: "bob@bob.com"}
+
+	newRouter := func(mockOrgService *mocks.MockOrgService, mockTokenService *mocks.MockTokenService) *gin.Engine {
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", authUser)
+		})
+
+		NewHandler(&Config{
+			Engine:       router,
+			OrgService:   mockOrgService,
+			TokenService: mockTokenService,
+		})
+
+		return router
+	}
+
+	serve := func(router *gin.Engine, method string, url string, body gin.H) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		var reqBody io.Reader
+		if body != nil {
+			b, err := json.Marshal(body)
+			assert.NoError(t, err)
+			reqBody = bytes.NewBuffer(b)
+		}
+
+		request, err := http.NewRequest(method, url, reqBody)
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		mockOrgService := new(mocks.MockOrgService)
+
+		org := &model.Organization{ID: orgID, Name: "Word Club", Role: model.OrgRoleOwner}
+		mockOrgService.On("Create", mock.Anything, uid, "Word Club").Return(org, nil)
+
+		rr := serve(newRouter(mockOrgService, nil), http.MethodPost, "/orgs", gin.H{
+			"name": "Word Club",
+		})
+
+		respBody, err := json.Marshal(gin.H{
+			"organization": org,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("List mine", func(t *testing.T) {
+		mockOrgService := new(mocks.MockOrgService)
+
+		orgs := []*model.Organization{{ID: orgID, Name: "Word Club", Role: model.OrgRoleMember}}
+		mockOrgService.On("List", mock.Anything, uid).Return(orgs, nil)
+
+		rr := serve(newRouter(mockOrgService, nil), http.MethodGet, "/orgs", nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockOrgService.AssertExpectations(t)
+	})
+
+	t.Run("Invite", func(t *testing.T) {
+		mockOrgService := new(mocks.MockOrgService)
+
+		invite := &model.OrgInvite{OrgID: orgID, Email: "alice@bob.com", Role: model.OrgRoleAdmin}
+		mockOrgService.On("Invite", mock.Anything, uid, orgID, "alice@bob.com", model.OrgRoleAdmin).Return(invite, nil)
+
+		rr := serve(newRouter(mockOrgService, nil), http.MethodPost, "/orgs/"+orgID.String()+"/invites", gin.H{
+			"email": "alice@bob.com",
+			"role":  "admin",
+		})
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		mockOrgService.AssertExpectations(t)
+	})
+
+	t.Run("Invite with unknown role", func(t *testing.T) {
+		mockOrgService := new(mocks.MockOrgService)
+
+		rr := serve(newRouter(mockOrgService, nil), http.MethodPost, "/orgs/"+orgID.String()+"/invites", gin.H{
+			"email": "alice@bob.com",
+			"role":  "king",
+		})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockOrgService.AssertNotCalled(t, "Invite")
+	})
+
+	t.Run("Accept invite", func(t *testing.T) {
+		mockOrgService := new(mocks.MockOrgService)
+
+		mockOrgService.On("AcceptInvite", mock.Anything, uid, "token").Return(&model.Organization{ID: orgID, Role: model.OrgRoleMember}, nil)
+
+		rr := serve(newRouter(mockOrgService, nil), http.MethodPost, "/org-invites/accept", gin.H{
+			"token": "token",
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockOrgService.AssertExpectations(t)
+	})
+
+	t.Run("Remove member", func(t *testing.T) {
+		mockOrgService := new(mocks.MockOrgService)
+
+		mockOrgService.On("RemoveMember", mock.Anything, uid, orgID, memberID).Return(nil)
+
+		rr := serve(newRouter(mockOrgService, nil), http.MethodDelete, "/orgs/"+orgID.String()+"/members/"+memberID.String(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockOrgService.AssertExpectations(t)
+	})
+
+	t.Run("Change role forbidden", func(t *testing.T) {
+		mockOrgService := new(mocks.MockOrgService)
+
+		mockOrgService.On("SetMemberRole", mock.Anything, uid, orgID, memberID, model.OrgRoleOwner).Return(apperrors.NewForbidden("Only owners may grant or take ownership"))
+
+		rr := serve(newRouter(mockOrgService, nil), http.MethodPut, "/orgs/"+orgID.String()+"/members/"+memberID.String()+"/role", gin.H{
+			"role": "owner",
+		})
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("Switch", func(t *testing.T) {
+		mockOrgService := new(mocks.MockOrgService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		switched := &model.User{UID: uid, ActiveOrgID: &orgID}
+		tokens := &model.Token{
+			AccessToken:  model.AccessToken{SignedStringToken: "idToken"},
+			RefreshToken: model.RefreshToken{SignedStringToken: "refreshToken"},
+		}
+		mockOrgService.On("Switch", mock.Anything, uid, &orgID).Return(switched, nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, switched, "").Return(tokens, nil)
+
+		rr := serve(newRouter(mockOrgService, mockTokenService), http.MethodPut, "/me/org", gin.H{
+			"org_id": orgID,
+		})
+
+		respBody, err := json.Marshal(gin.H{
+			"tokens": tokens,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockOrgService.AssertExpectations(t)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Switch to another user's organization", func(t *testing.T) {
+		mockOrgService := new(mocks.MockOrgService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		mockOrgService.On("Switch", mock.Anything, uid, &orgID).Return(nil, apperrors.NewNotFound("organization", orgID.String()))
+
+		rr := serve(newRouter(mockOrgService, mockTokenService), http.MethodPut, "/me/org", gin.H{
+			"org_id": orgID,
+		})
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+}
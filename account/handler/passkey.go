@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"encoding/base64"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type finishPasskeyRegistrationReq struct {
+	SessionID  string                    `json:"session_id" binding:"required"`
+	Name       string                    `json:"name" binding:"omitempty,max=50"`
+	Credential *model.PasskeyAttestation `json:"credential" binding:"required"`
+}
+
+type beginPasskeySigninReq struct {
+	Email string `json:"email" binding:"omitempty,email"`
+}
+
+type finishPasskeySigninReq struct {
+	SessionID  string                  `json:"session_id" binding:"required"`
+	Credential *model.PasskeyAssertion `json:"credential" binding:"required"`
+}
+
+// BeginPasskeyRegistration handler returns the options to create a passkey for the current user
+func (h *Handler) BeginPasskeyRegistration(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	ctx := c.Request.Context()
+	sessionID, options, err := h.PasskeyService.BeginRegistration(ctx, authUser.UID)
+	if err != nil {
+		log.Printf("Failed to begin passkey registration: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": sessionID,
+		"publicKey":  options,
+	})
+}
+
+// FinishPasskeyRegistration handler stores the passkey created by the browser
+func (h *Handler) FinishPasskeyRegistration(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req finishPasskeyRegistrationReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	credential, err := h.PasskeyService.FinishRegistration(ctx, authUser.UID, req.SessionID, req.Name, req.Credential)
+	if err != nil {
+		log.Printf("Failed to finish passkey registration: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"passkey": credential,
+	})
+}
+
+// Passkeys handler lists the passkeys of the current user
+func (h *Handler) Passkeys(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	ctx := c.Request.Context()
+	credentials, err := h.PasskeyService.ListCredentials(ctx, authUser.UID)
+	if err != nil {
+		log.Printf("Failed to list passkeys: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"passkeys": credentials,
+	})
+}
+
+// DeletePasskey handler removes a passkey of the current user
+func (h *Handler) DeletePasskey(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	credentialID, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(c.Param("id"), "="))
+	if err != nil {
+		err := apperrors.NewBadRequest("Invalid passkey id")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.PasskeyService.DeleteCredential(ctx, authUser.UID, credentialID); err != nil {
+		log.Printf("Failed to delete passkey: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
+
+// BeginPasskeySignin handler returns the options to sign in with a passkey
+// The email is optional, without it the browser offers discoverable passkeys
+func (h *Handler) BeginPasskeySignin(c *gin.Context) {
+	var req beginPasskeySigninReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	sessionID, options, err := h.PasskeyService.BeginLogin(ctx, req.Email)
+	if err != nil {
+		log.Printf("Failed to begin passkey sign-in: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": sessionID,
+		"publicKey":  options,
+	})
+}
+
+// FinishPasskeySignin handler verifies the signed challenge and returns a token pair
+func (h *Handler) FinishPasskeySignin(c *gin.Context) {
+	var req finishPasskeySigninReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.PasskeyService.FinishLogin(ctx, req.SessionID, req.Credential)
+	if err != nil {
+		log.Printf("Failed to sign in with passkey: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if rejectSuspended(c, user) {
+		return
+	}
+
+	h.issueSigninTokens(c, user, "passkey")
+}
@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/fixture"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPasskeys(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	mockPasskeyService := new(mocks.MockPasskeyService)
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", &model.User{
+			UID: uid,
+		})
+	})
+
+	NewHandler(&Config{
+		Engine:         router,
+		PasskeyService: mockPasskeyService,
+	})
+
+	authenticator := fixture.NewAuthenticator("localhost", "http://localhost:3000")
+
+	t.Run("Begin registration", func(t *testing.T) {
+		mockOptions := &model.PasskeyCreationOptions{
+			Challenge: []byte("challenge"),
+			RP:        model.RelyingParty{ID: "localhost", Name: "memorization-apps"},
+		}
+		mockPasskeyService.On("BeginRegistration", mock.Anything, uid).Return("session", mockOptions, nil)
+
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodPost, "/passkeys/register/begin", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"publicKey":  mockOptions,
+			"session_id": "session",
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Finish registration", func(t *testing.T) {
+		attestation := authenticator.Create([]byte("challenge"), nil)
+		mockCredential := &model.PasskeyCredential{ID: authenticator.CredentialID, UID: uid, Name: "Laptop"}
+		mockPasskeyService.On("FinishRegistration", mock.Anything, uid, "session", "Laptop", attestation).Return(mockCredential, nil)
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"session_id": "session",
+			"name":       "Laptop",
+			"credential": attestation,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/passkeys/register/finish", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"passkey": mockCredential,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Finish registration without credential", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"session_id": "session",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/passkeys/register/finish", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		mockPasskeyService.On("DeleteCredential", mock.Anything, uid, authenticator.CredentialID).Return(nil)
+
+		rr := httptest.NewRecorder()
+
+		id := base64.RawURLEncoding.EncodeToString(authenticator.CredentialID)
+		request, err := http.NewRequest(http.MethodDelete, "/passkeys/"+id, nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockPasskeyService.AssertCalled(t, "DeleteCredential", mock.Anything, uid, authenticator.CredentialID)
+	})
+
+	t.Run("Delete unknown passkey", func(t *testing.T) {
+		mockError := apperrors.NewNotFound("passkey", "AAAA")
+		mockPasskeyService.On("DeleteCredential", mock.Anything, uid, []byte{0, 0, 0}).Return(mockError)
+
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodDelete, "/passkeys/AAAA", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestPasskeySignin(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	authenticator := fixture.NewAuthenticator("localhost", "http://localhost:3000")
+	authenticator.UserHandle, _ = uid.MarshalBinary()
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockPasskeyService := new(mocks.MockPasskeyService)
+
+		assertion := authenticator.Get([]byte("challenge"))
+		mockUser := &model.User{UID: uid, Email: "bob@bob.com"}
+		mockPasskeyService.On("FinishLogin", mock.Anything, "session", assertion).Return(mockUser, nil)
+		mockUserService.On("RecordSignin", mock.Anything, mockUser, "passkey").Return()
+
+		mockTokenPair := &model.Token{
+			AccessToken:  model.AccessToken{SignedStringToken: "idToken"},
+			RefreshToken: model.RefreshToken{SignedStringToken: "refreshToken"},
+		}
+		mockTokenService.On("NewPairFromUser", mock.Anything, mockUser, "").Return(mockTokenPair, nil)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:         router,
+			UserService:    mockUserService,
+			TokenService:   mockTokenService,
+			PasskeyService: mockPasskeyService,
+		})
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"session_id": "session",
+			"credential": assertion,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/passkey/finish", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"tokens": mockTokenPair,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockPasskeyService.AssertExpectations(t)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid assertion", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+		mockPasskeyService := new(mocks.MockPasskeyService)
+
+		assertion := authenticator.Get([]byte("challenge"))
+		mockPasskeyService.On("FinishLogin", mock.Anything, "session", assertion).Return(nil, apperrors.NewAuthorization("Invalid passkey"))
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:         router,
+			TokenService:   mockTokenService,
+			PasskeyService: mockPasskeyService,
+		})
+
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"session_id": "session",
+			"credential": assertion,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/passkey/finish", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+}
+
+func TestPasskeysNotConfigured(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	// without a PasskeyService, as with drivers lacking its repository,
+	// the routes are not there rather than failing
+	router := gin.Default()
+	NewHandler(&Config{
+		Engine:      router,
+		UserService: new(mocks.MockUserService),
+	})
+
+	for _, path := range []string{"/signin/passkey/begin", "/passkeys/register/begin"} {
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodPost, path, bytes.NewBufferString("{}"))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code, path)
+	}
+}
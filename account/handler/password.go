@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+)
+
+type changePasswordReq struct {
+	CurrentPassword string `json:"current_password"` // accounts without password may omit it
+	Password        string `json:"password" binding:"required"`
+}
+
+type passwordResetReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type confirmPasswordResetReq struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ChangePassword handler replaces the password of the signed in user
+// Other sessions are signed out and their access tokens denied, the current
+// one gets a new token pair
+func (h *Handler) ChangePassword(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req changePasswordReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.UserService.ChangePassword(ctx, authUser.UID, req.CurrentPassword, req.Password); err != nil {
+		log.Printf("Failed to change password: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), errorResponse(err))
+		return
+	}
+
+	if err := h.TokenService.Revoke(ctx, authUser.UID); err != nil {
+		log.Printf("Failed to sign out sessions after password change: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, authUser, "")
+	if err != nil {
+		log.Printf("Failed to create tokens for user: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+	})
+}
+
+// RequestPasswordReset handler emails a password reset link
+// It succeeds for unknown emails too, so it does not reveal which accounts exist
+func (h *Handler) RequestPasswordReset(c *gin.Context) {
+	var req passwordResetReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.UserService.RequestPasswordReset(ctx, req.Email); err != nil {
+		log.Printf("Failed to send password reset link: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "success",
+	})
+}
+
+// ConfirmPasswordReset handler sets a new password with the token of a reset link,
+// signs out all sessions denying their access tokens and lifts a lockout,
+// then signs the user in
+func (h *Handler) ConfirmPasswordReset(c *gin.Context) {
+	var req confirmPasswordResetReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.UserService.ResetPassword(ctx, req.Token, req.Password)
+	if err != nil {
+		log.Printf("Failed to reset password: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), errorResponse(err))
+		return
+	}
+
+	if err := h.TokenService.Revoke(ctx, user.UID); err != nil {
+		log.Printf("Failed to sign out sessions after password reset: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if h.LockoutService != nil {
+		if err := h.LockoutService.UnlockAccount(ctx, user.Email); err != nil {
+			log.Printf("Failed to unlock account after password reset: %v\n", err.Error())
+		}
+	}
+
+	h.completeSignin(c, user, "password reset")
+}
@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChangePassword(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	authUser := &model.User{UID: uid, Email: "bob@bob.com"}
+
+	newRouter := func(mockUserService *mocks.MockUserService, mockTokenService *mocks.MockTokenService) *gin.Engine {
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", authUser)
+		})
+
+		NewHandler(&Config{
+			Engine:       router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+		})
+
+		return router
+	}
+
+	changePassword := func(router *gin.Engine, password string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"current_password": "howdyhoneighbor!",
+			"password":         password,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPut, "/password", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		mockTokenPair := &model.Token{
+			AccessToken:  model.AccessToken{SignedStringToken: "idToken"},
+			RefreshToken: model.RefreshToken{SignedStringToken: "refreshToken"},
+		}
+
+		mockUserService.On("ChangePassword", mock.Anything, uid, "howdyhoneighbor!", "correct horse battery staple").Return(nil)
+		mockTokenService.On("Revoke", mock.Anything, uid).Return(nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, authUser, "").Return(mockTokenPair, nil)
+
+		rr := changePassword(newRouter(mockUserService, mockTokenService), "correct horse battery staple")
+
+		respBody, err := json.Marshal(gin.H{
+			"tokens": mockTokenPair,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Password policy violation", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		invalidArgs := []apperrors.InvalidArgument{
+			{Field: "Password", Tag: "breached"},
+		}
+		mockUserService.On("ChangePassword", mock.Anything, uid, "howdyhoneighbor!", "password123").Return(apperrors.NewInvalidArgs(invalidArgs))
+
+		rr := changePassword(newRouter(mockUserService, mockTokenService), "password123")
+
+		respBody, err := json.Marshal(gin.H{
+			"error":       apperrors.NewInvalidArgs(nil),
+			"invalidArgs": invalidArgs,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertNotCalled(t, "Revoke")
+	})
+}
+
+func TestPasswordReset(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	post := func(router *gin.Engine, path string, body gin.H) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(body)
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, path, bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Request", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("RequestPasswordReset", mock.Anything, "bob@bob.com").Return(nil)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:      router,
+			UserService: mockUserService,
+		})
+
+		rr := post(router, "/password/reset", gin.H{"email": "bob@bob.com"})
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Confirm", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockLockoutService := new(mocks.MockLockoutService)
+
+		mockUser := &model.User{UID: uid, Email: "bob@bob.com"}
+		mockTokenPair := &model.Token{
+			AccessToken:  model.AccessToken{SignedStringToken: "idToken"},
+			RefreshToken: model.RefreshToken{SignedStringToken: "refreshToken"},
+		}
+
+		mockUserService.On("ResetPassword", mock.Anything, "token", "correct horse battery staple").Return(mockUser, nil)
+		mockTokenService.On("Revoke", mock.Anything, uid).Return(nil)
+		mockLockoutService.On("UnlockAccount", mock.Anything, "bob@bob.com").Return(nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, mockUser, "").Return(mockTokenPair, nil)
+		mockUserService.On("RecordSignin", mock.Anything, mockUser, "password reset").Return()
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:         router,
+			UserService:    mockUserService,
+			TokenService:   mockTokenService,
+			LockoutService: mockLockoutService,
+		})
+
+		rr := post(router, "/password/reset/confirm", gin.H{
+			"token":    "token",
+			"password": "correct horse battery staple",
+		})
+
+		respBody, err := json.Marshal(gin.H{
+			"tokens": mockTokenPair,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenService.AssertExpectations(t)
+		mockLockoutService.AssertExpectations(t)
+	})
+
+	t.Run("Confirm with invalid token", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		mockUserService.On("ResetPassword", mock.Anything, "expired", "correct horse battery staple").Return(nil, apperrors.NewAuthorization("Invalid or expired password reset link"))
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:       router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+		})
+
+		rr := post(router, "/password/reset/confirm", gin.H{
+			"token":    "expired",
+			"password": "correct horse battery staple",
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockTokenService.AssertNotCalled(t, "Revoke")
+	})
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/utils"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// ProfileImage handler serves a profile image, for image repositories
+// which do not serve their images themselves
+func (h *Handler) ProfileImage(c *gin.Context) {
+	objName := c.Param("name")
+
+	ctx := c.Request.Context()
+	rc, err := h.UserService.ProfileImage(ctx, objName)
+	if err != nil {
+		log.Printf("Failed to get profile image: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+	defer rc.Close()
+
+	image, err := io.ReadAll(rc)
+	if err != nil {
+		log.Printf("Failed to read profile image: %v. Error: %v\n", objName, err)
+		err := apperrors.NewInternal()
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	// profile images keep their name when replaced, so browsers must revalidate,
+	// which the ETag answers with 304 Not Modified until the image changes
+	sum := sha256.Sum256(image)
+	c.Header("ETag", fmt.Sprintf(`"%x"`, sum[:16]))
+	c.Header("Cache-Control", "no-cache, max-age=0")
+	// only image types are served as such, anything else is downloaded,
+	// and is sandboxed should a browser render it anyway
+	c.Header("Content-Type", utils.ImageContentType(image))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Security-Policy", "sandbox")
+
+	http.ServeContent(c.Writer, c.Request, objName, time.Time{}, bytes.NewReader(image))
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"bytes"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProfileImage(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+
+	newRouter := func(mockUserService *mocks.MockUserService, serveImages bool) *gin.Engine {
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Engine:      router,
+			UserService: mockUserService,
+			ServeImages: serveImages,
+		})
+
+		return router
+	}
+
+	get := func(router *gin.Engine, url string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodGet, url, nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Serves image", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("ProfileImage", mock.Anything, "abc").Return(io.NopCloser(bytes.NewReader(png)), nil)
+
+		rr := get(newRouter(mockUserService, true), "/images/abc")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+		assert.Equal(t, "no-cache, max-age=0", rr.Header().Get("Cache-Control"))
+		assert.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+		assert.Equal(t, "sandbox", rr.Header().Get("Content-Security-Policy"))
+		assert.NotEmpty(t, rr.Header().Get("ETag"))
+		assert.Equal(t, png, rr.Body.Bytes())
+	})
+
+	t.Run("Serves other content as a download", func(t *testing.T) {
+		html := []byte("<html><script>alert(document.cookie)</script></html>")
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("ProfileImage", mock.Anything, "abc").Return(io.NopCloser(bytes.NewReader(html)), nil)
+
+		rr := get(newRouter(mockUserService, true), "/images/abc")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/octet-stream", rr.Header().Get("Content-Type"))
+		assert.Equal(t, "sandbox", rr.Header().Get("Content-Security-Policy"))
+	})
+
+	t.Run("Unchanged image is not modified", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("ProfileImage", mock.Anything, "abc").Return(io.NopCloser(bytes.NewReader(png)), nil).Once()
+		mockUserService.On("ProfileImage", mock.Anything, "abc").Return(io.NopCloser(bytes.NewReader(png)), nil).Once()
+		router := newRouter(mockUserService, true)
+
+		etag := get(router, "/images/abc").Header().Get("ETag")
+
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/images/abc", nil)
+		assert.NoError(t, err)
+		request.Header.Set("If-None-Match", etag)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Equal(t, etag, rr.Header().Get("ETag"))
+		assert.Empty(t, rr.Body.Bytes())
+	})
+
+	t.Run("Changed image has another ETag", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("ProfileImage", mock.Anything, "abc").Return(io.NopCloser(bytes.NewReader(png)), nil).Once()
+		mockUserService.On("ProfileImage", mock.Anything, "abc").Return(io.NopCloser(bytes.NewReader(append(png, 0))), nil).Once()
+		router := newRouter(mockUserService, true)
+
+		first := get(router, "/images/abc").Header().Get("ETag")
+		second := get(router, "/images/abc").Header().Get("ETag")
+
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("Unknown image", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("ProfileImage", mock.Anything, "abc").Return(nil, apperrors.NewNotFound("image", "abc"))
+
+		rr := get(newRouter(mockUserService, true), "/images/abc")
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Not served", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		rr := get(newRouter(mockUserService, false), "/images/abc")
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		mockUserService.AssertNotCalled(t, "ProfileImage")
+	})
+}
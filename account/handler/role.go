@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"log"
+	"net/http"
+)
+
+// UserRoles handler lists the roles of a user
+func (h *Handler) UserRoles(c *gin.Context) {
+	uid, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	roles, err := h.RoleService.List(ctx, uid)
+	if err != nil {
+		log.Printf("Failed to list roles: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"roles": roles,
+	})
+}
+
+// GrantRole handler gives a role to a user
+func (h *Handler) GrantRole(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	uid, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.RoleService.Grant(ctx, authUser.UID, uid, c.Param("role")); err != nil {
+		log.Printf("Failed to grant role: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
+
+// RevokeRole handler takes a role from a user
+func (h *Handler) RevokeRole(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	uid, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.RoleService.Revoke(ctx, authUser.UID, uid, c.Param("role")); err != nil {
+		log.Printf("Failed to revoke role: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
+
+// userIDParam parses the user id of an admin route, responding
+// with a bad request if it is not a uuid
+func userIDParam(c *gin.Context) (uuid.UUID, bool) {
+	uid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		err := apperrors.NewBadRequest("Invalid user id")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return uuid.Nil, false
+	}
+
+	return uid, true
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoles(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	adminUID, _ := uuid.NewRandom()
+	authUser := &model.User{UID: adminUID, Email: "admin@bob.com", Roles: []string{model.RoleAdmin}}
+	uid, _ := uuid.NewRandom()
+
+	newRouter := func(mockRoleService *mocks.MockRoleService) *gin.Engine {
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", authUser)
+		})
+
+		NewHandler(&Config{
+			Engine:      router,
+			RoleService: mockRoleService,
+		})
+
+		return router
+	}
+
+	serve := func(router *gin.Engine, method string, url string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(method, url, nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("List", func(t *testing.T) {
+		mockRoleService := new(mocks.MockRoleService)
+
+		mockRoleService.On("List", mock.Anything, uid).Return([]string{model.RoleAdmin}, nil)
+
+		rr := serve(newRouter(mockRoleService), http.MethodGet, "/admin/users/"+uid.String()+"/roles")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"roles":["admin"]}`, rr.Body.String())
+	})
+
+	t.Run("Grant", func(t *testing.T) {
+		mockRoleService := new(mocks.MockRoleService)
+
+		mockRoleService.On("Grant", mock.Anything, adminUID, uid, model.RoleAdmin).Return(nil)
+
+		rr := serve(newRouter(mockRoleService), http.MethodPut, "/admin/users/"+uid.String()+"/roles/admin")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockRoleService.AssertExpectations(t)
+	})
+
+	t.Run("Revoke last admin", func(t *testing.T) {
+		mockRoleService := new(mocks.MockRoleService)
+
+		mockRoleService.On("Revoke", mock.Anything, adminUID, uid, model.RoleAdmin).Return(apperrors.NewBadRequest("The last admin cannot be revoked"))
+
+		rr := serve(newRouter(mockRoleService), http.MethodDelete, "/admin/users/"+uid.String()+"/roles/admin")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Invalid user id", func(t *testing.T) {
+		mockRoleService := new(mocks.MockRoleService)
+
+		rr := serve(newRouter(mockRoleService), http.MethodPut, "/admin/users/abc/roles/admin")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockRoleService.AssertNotCalled(t, "Grant", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
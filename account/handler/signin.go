@@ -6,12 +6,16 @@ import (
 	"github.com/gin-gonic/gin"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // signinReq is not exported
+// users sign in with either their email or their handle
 type signinReq struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,gte=6,lte=30"`
+	Email    string `json:"email" binding:"required_without=Handle,omitempty,email"`
+	Handle   string `json:"handle" binding:"required_without=Email"`
+	Password string `json:"password" binding:"required"`
 }
 
 // Signin used to authenticate extant user
@@ -24,29 +28,153 @@ func (h *Handler) Signin(c *gin.Context) {
 
 	user := &model.User{
 		Email:    req.Email,
+		Handle:   req.Handle,
 		Password: req.Password,
 	}
 
 	ctx := c.Request.Context()
+	account := h.signinAccount(c, req)
+
+	// attempts are throttled per account and per IP before
+	// spending a password hash on them
+	if h.LockoutService != nil {
+		if err := h.LockoutService.Check(ctx, account, c.ClientIP()); err != nil {
+			log.Printf("Rejected sign in attempt: %v\n", err.Error())
+			respondWithRetryAfter(c, err)
+			return
+		}
+	}
+
 	err := h.UserService.Signin(ctx, user)
 	if err != nil {
 		log.Printf("Failed to sign in user: %v\n", err.Error())
+
+		if h.LockoutService != nil && apperrors.Status(err) == http.StatusUnauthorized {
+			if err := h.LockoutService.RecordFailure(ctx, account, c.ClientIP()); err != nil {
+				log.Printf("Failed to record failed sign in attempt: %v\n", err.Error())
+			}
+		}
+
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err,
 		})
 		return
 	}
 
+	// with a second factor to complete, failures are only forgotten
+	// once it is, so password and code guesses add up
+	if signedIn := h.completeSignin(c, user, "password"); signedIn && h.LockoutService != nil {
+		if err := h.LockoutService.RecordSuccess(ctx, account); err != nil {
+			log.Printf("Failed to reset failed sign in attempts: %v\n", err.Error())
+		}
+	}
+}
+
+// signinAccount returns the email failed sign-ins are counted against
+// Handles are resolved to the email of their user, so switching between
+// both does not get an attacker more attempts
+func (h *Handler) signinAccount(c *gin.Context, req signinReq) string {
+	if req.Email != "" || h.LockoutService == nil {
+		return req.Email
+	}
+
+	user, err := h.UserService.GetByHandle(c.Request.Context(), req.Handle)
+	if err != nil {
+		return req.Handle
+	}
+
+	return user.Email
+}
+
+// respondWithRetryAfter responds with an error, telling the client
+// when to retry if the error is a rate limit
+func respondWithRetryAfter(c *gin.Context, err error) {
+	if retryAfter := apperrors.RetryAfter(err); retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+	}
+
+	c.JSON(apperrors.Status(err), gin.H{
+		"error": err,
+	})
+}
+
+// rejectSuspended responds with an error if the user is suspended,
+// returning true if it did
+func rejectSuspended(c *gin.Context, user *model.User) bool {
+	if !user.Suspended(time.Now()) {
+		return false
+	}
+
+	err := apperrors.NewSuspended(user.StatusReason, user.StatusExpiresAt)
+	c.JSON(err.Status(), gin.H{
+		"error": err,
+	})
+	return true
+}
+
+// completeSignin responds with a token pair for a user authenticated with
+// method, or with a second factor challenge if the user enrolled one
+// It returns true if the user got a token pair
+func (h *Handler) completeSignin(c *gin.Context, user *model.User, method string) bool {
+	ctx := c.Request.Context()
+
+	// sign-ins without a password reach here without userService.Signin
+	if rejectSuspended(c, user) {
+		return false
+	}
+
+	// two-factor authentication is optional, users who enrolled an
+	// authenticator get a challenge to complete at /signin/mfa instead
+	if h.MFAService != nil {
+		enrolled, err := h.MFAService.IsEnrolled(ctx, user.UID)
+		if err != nil {
+			log.Printf("Failed to check two-factor enrollment: %v\n", err.Error())
+			c.JSON(apperrors.Status(err), gin.H{
+				"error": err,
+			})
+			return false
+		}
+
+		if enrolled {
+			mfaToken, err := h.MFAService.NewChallenge(ctx, user.UID)
+			if err != nil {
+				log.Printf("Failed to create mfa challenge for user: %v\n", err.Error())
+				c.JSON(apperrors.Status(err), gin.H{
+					"error": err,
+				})
+				return false
+			}
+
+			c.JSON(http.StatusOK, gin.H{
+				"mfa_required": true,
+				"mfa_token":    mfaToken,
+			})
+			return false
+		}
+	}
+
+	return h.issueSigninTokens(c, user, method)
+}
+
+// issueSigninTokens responds with a token pair for a user who completed
+// sign-in with method, which is only then recorded as signed in
+// It returns true if the user got a token pair
+func (h *Handler) issueSigninTokens(c *gin.Context, user *model.User, method string) bool {
+	ctx := c.Request.Context()
+
 	tokens, err := h.TokenService.NewPairFromUser(ctx, user, "")
 	if err != nil {
 		log.Printf("Failed to create tokens for user: %v\n", err.Error())
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err,
 		})
-		return
+		return false
 	}
 
+	h.UserService.RecordSignin(ctx, user, method)
+
 	c.JSON(http.StatusOK, gin.H{
 		"tokens": tokens,
 	})
+	return true
 }
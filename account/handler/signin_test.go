@@ -8,11 +8,13 @@ import (
 	"github.com/dolong2110/memorization-apps/account/model/mocks"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestSignin(t *testing.T) {
@@ -184,3 +186,214 @@ func TestSignin(t *testing.T) {
 		mockTokenService.AssertCalled(t, "NewPairFromUser", mockTSArgs...)
 	})
 }
+
+func TestSigninLockout(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	email := "bob@bob.com"
+	password := "pwdoesnotmatch123"
+
+	signin := func(router *gin.Engine) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"email":    email,
+			"password": password,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Blocked", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockLockoutService := new(mocks.MockLockoutService)
+
+		mockLockoutService.On("Check", mock.Anything, email, mock.AnythingOfType("string")).Return(apperrors.NewTooManyRequests("Too many failed sign-in attempts. Try again later", 90*time.Second))
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:         router,
+			UserService:    mockUserService,
+			LockoutService: mockLockoutService,
+		})
+
+		rr := signin(router)
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "90", rr.Header().Get("Retry-After"))
+		mockUserService.AssertNotCalled(t, "Signin")
+	})
+
+	t.Run("Records failure", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockLockoutService := new(mocks.MockLockoutService)
+
+		mockLockoutService.On("Check", mock.Anything, email, mock.AnythingOfType("string")).Return(nil)
+		mockUserService.On("Signin", mock.Anything, &model.User{Email: email, Password: password}).Return(apperrors.NewAuthorization("Invalid email and password combination"))
+		mockLockoutService.On("RecordFailure", mock.Anything, email, mock.AnythingOfType("string")).Return(nil)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:         router,
+			UserService:    mockUserService,
+			LockoutService: mockLockoutService,
+		})
+
+		rr := signin(router)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockLockoutService.AssertExpectations(t)
+		mockLockoutService.AssertNotCalled(t, "RecordSuccess")
+	})
+
+	t.Run("Records success", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockLockoutService := new(mocks.MockLockoutService)
+
+		mockLockoutService.On("Check", mock.Anything, email, mock.AnythingOfType("string")).Return(nil)
+		mockUserService.On("Signin", mock.Anything, &model.User{Email: email, Password: password}).Return(nil)
+		mockLockoutService.On("RecordSuccess", mock.Anything, email).Return(nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, &model.User{Email: email, Password: password}, "").Return(&model.Token{}, nil)
+		mockUserService.On("RecordSignin", mock.Anything, &model.User{Email: email, Password: password}, "password").Return()
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:         router,
+			UserService:    mockUserService,
+			TokenService:   mockTokenService,
+			LockoutService: mockLockoutService,
+		})
+
+		rr := signin(router)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockLockoutService.AssertExpectations(t)
+		mockLockoutService.AssertNotCalled(t, "RecordFailure")
+	})
+
+	t.Run("Keeps failures until the second factor", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockMFAService := new(mocks.MockMFAService)
+		mockLockoutService := new(mocks.MockLockoutService)
+
+		mockLockoutService.On("Check", mock.Anything, email, mock.AnythingOfType("string")).Return(nil)
+		mockUserService.On("Signin", mock.Anything, &model.User{Email: email, Password: password}).Return(nil)
+		mockMFAService.On("IsEnrolled", mock.Anything, uuid.Nil).Return(true, nil)
+		mockMFAService.On("NewChallenge", mock.Anything, uuid.Nil).Return("challengetoken", nil)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:         router,
+			UserService:    mockUserService,
+			MFAService:     mockMFAService,
+			LockoutService: mockLockoutService,
+		})
+
+		rr := signin(router)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockMFAService.AssertExpectations(t)
+		mockLockoutService.AssertNotCalled(t, "RecordSuccess")
+	})
+}
+
+func TestSigninWithHandle(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	password := "pwdoesnotmatch123"
+
+	signin := func(router *gin.Engine, body gin.H) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(body)
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokens := &model.Token{
+			AccessToken:  model.AccessToken{SignedStringToken: "idToken"},
+			RefreshToken: model.RefreshToken{SignedStringToken: "refreshToken"},
+		}
+
+		mockUserService.On("Signin", mock.Anything, &model.User{Handle: "bob", Password: password}).Return(nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, mock.AnythingOfType("*model.User"), "").Return(mockTokens, nil)
+		mockUserService.On("RecordSignin", mock.Anything, mock.AnythingOfType("*model.User"), "password").Return()
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:       router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+		})
+
+		rr := signin(router, gin.H{
+			"handle":   "bob",
+			"password": password,
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Failures count against the email", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockLockoutService := new(mocks.MockLockoutService)
+
+		mockUserService.On("GetByHandle", mock.Anything, "bob").Return(&model.User{Email: "bob@bob.com", Handle: "bob"}, nil)
+		mockLockoutService.On("Check", mock.Anything, "bob@bob.com", mock.AnythingOfType("string")).Return(nil)
+		mockUserService.On("Signin", mock.Anything, &model.User{Handle: "bob", Password: password}).Return(apperrors.NewAuthorization("Invalid email and password combination"))
+		mockLockoutService.On("RecordFailure", mock.Anything, "bob@bob.com", mock.AnythingOfType("string")).Return(nil)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:         router,
+			UserService:    mockUserService,
+			LockoutService: mockLockoutService,
+		})
+
+		rr := signin(router, gin.H{
+			"handle":   "bob",
+			"password": password,
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockLockoutService.AssertExpectations(t)
+	})
+
+	t.Run("Neither email nor handle", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:      router,
+			UserService: mockUserService,
+		})
+
+		rr := signin(router, gin.H{
+			"password": password,
+		})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "Signin", mock.Anything, mock.Anything)
+	})
+}
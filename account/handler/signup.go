@@ -11,8 +11,11 @@ import (
 // signupReq is not exported, hence the lowercase name
 // it is used for validation and json marshalling
 type signupReq struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,gte=6,lte=30"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required"` // the length is checked by the password policy
+	InviteCode string `json:"invite_code"`                 // required by invite-only sign-up
+	// TermsVersion of the terms of service shown to the user, required while terms are published
+	TermsVersion string `json:"terms_version"`
 }
 
 // Signup handler
@@ -27,18 +30,23 @@ func (h *Handler) Signup(c *gin.Context) {
 	}
 
 	user := &model.User{
-		Email:    req.Email,
-		Password: req.Password,
+		Email:         req.Email,
+		Password:      req.Password,
+		InviteCode:    req.InviteCode,
+		AcceptedTerms: req.TermsVersion,
 	}
 
 	ctx := c.Request.Context()
 	err := h.UserService.Signup(ctx, user)
 
+	if h.EnumerationSafeSignup {
+		h.enumerationSafeSignup(c, user, err)
+		return
+	}
+
 	if err != nil {
 		log.Printf("Failed to sign up user: %v\n", err.Error())
-		c.JSON(apperrors.Status(err), gin.H{
-			"error": err,
-		})
+		c.JSON(apperrors.Status(err), errorResponse(err))
 		return
 	}
 
@@ -61,3 +69,40 @@ func (h *Handler) Signup(c *gin.Context) {
 		"tokens": tokens,
 	})
 }
+
+// enumerationSafeSignup answers a sign-up the same way whether the email
+// was registered before or not. The owner of a registered email is notified
+// by email instead, and new users sign in to get their tokens
+func (h *Handler) enumerationSafeSignup(c *gin.Context, user *model.User, err error) {
+	if apperrors.Status(err) == http.StatusConflict {
+		err = h.UserService.NotifySignupAttempt(c.Request.Context(), user.Email)
+	}
+
+	if err != nil {
+		log.Printf("Failed to sign up user: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), errorResponse(err))
+		return
+	}
+
+	signupAccepted(c)
+}
+
+// enumerationSafeConflict answers the conflict of a registered email with
+// the response of an enumeration-safe sign-up, once the owner of the email
+// was notified. It reports whether it answered
+func (h *Handler) enumerationSafeConflict(c *gin.Context, err error) bool {
+	if !h.EnumerationSafeSignup || apperrors.Status(err) != http.StatusConflict {
+		return false
+	}
+
+	log.Printf("Answered sign-up with registered email: %v\n", err.Error())
+	signupAccepted(c)
+	return true
+}
+
+// signupAccepted is the response of an enumeration-safe sign-up
+func signupAccepted(c *gin.Context) {
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "success",
+	})
+}
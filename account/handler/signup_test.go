@@ -8,6 +8,7 @@ import (
 	"github.com/dolong2110/memorization-apps/account/model/mocks"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"net/http"
@@ -87,9 +88,11 @@ func TestSignup(t *testing.T) {
 		mockUserService.AssertNotCalled(t, "Signup")
 	})
 	t.Run("Password too short", func(t *testing.T) {
-		// We just want this to show that it's not called in this case
+		// the password policy of UserService rejects it
 		mockUserService := new(mocks.MockUserService)
-		mockUserService.On("Signup", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.User")).Return(nil)
+		mockUserService.On("Signup", mock.Anything, mock.AnythingOfType("*model.User")).Return(apperrors.NewInvalidArgs([]apperrors.InvalidArgument{
+			{Field: "Password", Tag: "min", Param: "8"},
+		}))
 
 		// a response recorder for getting written http response
 		rr := httptest.NewRecorder()
@@ -102,7 +105,7 @@ func TestSignup(t *testing.T) {
 			UserService: mockUserService,
 		})
 
-		// create a request body with empty email and password
+		// create a request body with a short password
 		reqBody, err := json.Marshal(gin.H{
 			"email":    "long@do.com",
 			"password": "long",
@@ -117,13 +120,21 @@ func TestSignup(t *testing.T) {
 
 		router.ServeHTTP(rr, request)
 
+		respBody, err := json.Marshal(gin.H{
+			"error": apperrors.NewInvalidArgs(nil),
+			"invalidArgs": []apperrors.InvalidArgument{
+				{Field: "Password", Tag: "min", Param: "8"},
+			},
+		})
+		assert.NoError(t, err)
+
 		assert.Equal(t, http.StatusBadRequest, rr.Code)
-		mockUserService.AssertNotCalled(t, "Signup")
+		assert.Equal(t, respBody, rr.Body.Bytes())
 	})
-	t.Run("Password too long", func(t *testing.T) {
-		// We just want this to show that it's not called in this case
+	t.Run("Long passphrase", func(t *testing.T) {
+		// passphrases are no longer capped at 30 characters by request validation
 		mockUserService := new(mocks.MockUserService)
-		mockUserService.On("Signup", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.User")).Return(nil)
+		mockUserService.On("Signup", mock.Anything, mock.AnythingOfType("*model.User")).Return(apperrors.NewConflict("email", "long@do.com"))
 
 		// a response recorder for getting written http response
 		rr := httptest.NewRecorder()
@@ -136,7 +147,7 @@ func TestSignup(t *testing.T) {
 			UserService: mockUserService,
 		})
 
-		// create a request body with empty email and password
+		// create a request body with a long password
 		reqBody, err := json.Marshal(gin.H{
 			"email":    "long@do.com",
 			"password": "long123456789101112131415161718192021222324252627282930",
@@ -151,8 +162,11 @@ func TestSignup(t *testing.T) {
 
 		router.ServeHTTP(rr, request)
 
-		assert.Equal(t, http.StatusBadRequest, rr.Code)
-		mockUserService.AssertNotCalled(t, "Signup")
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		mockUserService.AssertCalled(t, "Signup", mock.Anything, &model.User{
+			Email:    "long@do.com",
+			Password: "long123456789101112131415161718192021222324252627282930",
+		})
 	})
 	t.Run("Error calling UserService", func(t *testing.T) {
 		user := &model.User{
@@ -308,3 +322,161 @@ func TestSignup(t *testing.T) {
 		mockTokenService.AssertExpectations(t)
 	})
 }
+
+func TestEnumerationSafeSignup(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	signup := func(router *gin.Engine, email string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"email":    email,
+			"password": "correct horse battery staple",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signup", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+
+	router := gin.Default()
+	NewHandler(&Config{
+		Engine:                router,
+		UserService:           mockUserService,
+		TokenService:          mockTokenService,
+		EnumerationSafeSignup: true,
+	})
+
+	mockUserService.On("Signup", mock.Anything, &model.User{Email: "new@do.com", Password: "correct horse battery staple"}).Return(nil)
+	mockUserService.On("Signup", mock.Anything, &model.User{Email: "long@do.com", Password: "correct horse battery staple"}).Return(apperrors.NewConflict("email", "long@do.com"))
+	mockUserService.On("NotifySignupAttempt", mock.Anything, "long@do.com").Return(nil)
+
+	newUser := signup(router, "new@do.com")
+	existingUser := signup(router, "long@do.com")
+
+	// both get the same answer, and no tokens
+	assert.Equal(t, http.StatusAccepted, newUser.Code)
+	assert.Equal(t, newUser.Code, existingUser.Code)
+	assert.Equal(t, newUser.Body.Bytes(), existingUser.Body.Bytes())
+	mockUserService.AssertNumberOfCalls(t, "NotifySignupAttempt", 1)
+	mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+}
+
+func TestEnumerationSafeSignupOtherwise(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+
+	post := func(router *gin.Engine, url string, body gin.H) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(body)
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Provider account with a registered email", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockOAuthService := new(mocks.MockOAuthService)
+
+		router := gin.Default()
+		NewHandler(&Config{
+			Engine:                router,
+			UserService:           mockUserService,
+			TokenService:          mockTokenService,
+			OAuthService:          mockOAuthService,
+			EnumerationSafeSignup: true,
+		})
+
+		mockOAuthService.On("Signin", mock.Anything, "github", "code", "state", "nonce").Return(nil, apperrors.NewConflict("email", "long@do.com"))
+
+		rr := post(router, "/oauth/github/callback", gin.H{"code": "code", "state": "state", "nonce": "nonce"})
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.JSONEq(t, `{"message":"success"}`, rr.Body.String())
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("Guest upgrade", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &model.User{UID: uid, Guest: true})
+		})
+		NewHandler(&Config{
+			Engine:                router,
+			UserService:           mockUserService,
+			TokenService:          mockTokenService,
+			EnumerationSafeSignup: true,
+		})
+
+		mockUserService.On("UpgradeGuest", mock.Anything, &model.User{UID: uid, Email: "new@do.com", Password: "correct horse battery staple"}).Return(nil)
+		mockUserService.On("UpgradeGuest", mock.Anything, &model.User{UID: uid, Email: "long@do.com", Password: "correct horse battery staple"}).Return(apperrors.NewConflict("email", "long@do.com"))
+		mockUserService.On("NotifySignupAttempt", mock.Anything, "long@do.com").Return(nil)
+
+		newUser := post(router, "/guest/upgrade", gin.H{"email": "new@do.com", "password": "correct horse battery staple"})
+		existingUser := post(router, "/guest/upgrade", gin.H{"email": "long@do.com", "password": "correct horse battery staple"})
+
+		assert.Equal(t, http.StatusAccepted, newUser.Code)
+		assert.Equal(t, newUser.Body.Bytes(), existingUser.Body.Bytes())
+		mockUserService.AssertNumberOfCalls(t, "NotifySignupAttempt", 1)
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+}
+
+func TestSignupTerms(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := new(mocks.MockUserService)
+	mockTokenService := new(mocks.MockTokenService)
+
+	router := gin.Default()
+	NewHandler(&Config{
+		Engine:       router,
+		UserService:  mockUserService,
+		TokenService: mockTokenService,
+	})
+
+	mockUserService.On("Signup", mock.Anything, &model.User{Email: "new@do.com", Password: "correct horse battery staple", AcceptedTerms: "2024-01"}).Return(apperrors.NewTermsNotAccepted("2024-06"))
+
+	rr := httptest.NewRecorder()
+
+	reqBody, err := json.Marshal(gin.H{
+		"email":         "new@do.com",
+		"password":      "correct horse battery staple",
+		"terms_version": "2024-01",
+	})
+	assert.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/signup", bytes.NewBuffer(reqBody))
+	assert.NoError(t, err)
+
+	request.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(rr, request)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"terms":"2024-06"`)
+	mockUserService.AssertExpectations(t)
+	mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+)
+
+// acceptTermsReq takes the version the client showed to the user
+// A refresh token, if given, is replaced by the new pair
+type acceptTermsReq struct {
+	Version      string `json:"version" binding:"required"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Terms handler returns the current version of the terms of service
+func (h *Handler) Terms(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"version": h.TermsVersion,
+		"url":     h.TermsURL,
+	})
+}
+
+// AcceptTerms handler records the user accepting the current terms of service
+// and returns tokens carrying the accepted version
+func (h *Handler) AcceptTerms(c *gin.Context) {
+	authUser := c.MustGet("user").(*model.User)
+
+	var req acceptTermsReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	prevRefreshTokenID, ok := h.prevRefreshTokenID(c, authUser.UID, req.RefreshToken)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.UserService.AcceptTerms(ctx, authUser.UID, req.Version)
+	if err != nil {
+		log.Printf("Failed to accept terms: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, user, prevRefreshTokenID)
+	if err != nil {
+		log.Printf("Failed to create tokens for uid: %v. Error: %v\n", user.UID, err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":   user,
+		"tokens": tokens,
+	})
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	uid, _ := uuid.NewRandom()
+	authUser := &model.User{UID: uid, TermsVersion: "2024-01"}
+	tokens := &model.Token{
+		AccessToken:  model.AccessToken{SignedStringToken: "idToken"},
+		RefreshToken: model.RefreshToken{SignedStringToken: "refreshToken"},
+	}
+
+	newRouter := func(mockUserService *mocks.MockUserService, mockTokenService *mocks.MockTokenService) *gin.Engine {
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", authUser)
+		})
+
+		NewHandler(&Config{
+			Engine:       router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+			TermsVersion: "2024-06",
+			TermsURL:     "https://example.com/terms",
+		})
+
+		return router
+	}
+
+	accept := func(router *gin.Engine, body gin.H) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(body)
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/terms/accept", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Current version", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodGet, "/terms", nil)
+		assert.NoError(t, err)
+
+		newRouter(nil, nil).ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"url":     "https://example.com/terms",
+			"version": "2024-06",
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Accept returns new tokens", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		accepted := &model.User{UID: uid, TermsVersion: "2024-06"}
+		refreshID, _ := uuid.NewRandom()
+		mockTokenService.On("ValidateRefreshToken", "refreshToken").Return(&model.RefreshToken{ID: refreshID, UID: uid}, nil)
+		mockUserService.On("AcceptTerms", mock.Anything, uid, "2024-06").Return(accepted, nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, accepted, refreshID.String()).Return(tokens, nil)
+
+		rr := accept(newRouter(mockUserService, mockTokenService), gin.H{
+			"version":       "2024-06",
+			"refresh_token": "refreshToken",
+		})
+
+		respBody, err := json.Marshal(gin.H{
+			"tokens": tokens,
+			"user":   accepted,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Accept outdated version", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		mockUserService.On("AcceptTerms", mock.Anything, uid, "2024-01").Return(nil, apperrors.NewBadRequest("outdated"))
+
+		rr := accept(newRouter(mockUserService, mockTokenService), gin.H{
+			"version": "2024-01",
+		})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("Accept without version", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		rr := accept(newRouter(mockUserService, nil), gin.H{})
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserService.AssertNotCalled(t, "AcceptTerms")
+	})
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"github.com/dolong2110/memorization-apps/account/model/apperrors"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"log"
 	"net/http"
 )
@@ -40,10 +41,14 @@ func (h *Handler) Tokens(c *gin.Context) {
 		return
 	}
 
+	if rejectSuspended(c, user) {
+		return
+	}
+
 	// create fresh pair of tokens
 	tokens, err := h.TokenService.NewPairFromUser(ctx, user, refreshToken.ID.String())
 	if err != nil {
-		log.Printf("Failed to create tokens for user: %+v. Error: %v\n", user, err.Error())
+		log.Printf("Failed to create tokens for uid: %v. Error: %v\n", user.UID, err.Error())
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err,
 		})
@@ -54,3 +59,22 @@ func (h *Handler) Tokens(c *gin.Context) {
 		"tokens": tokens,
 	})
 }
+
+// prevRefreshTokenID validates an optional refresh token of the user, which
+// is rotated when new tokens are issued, responding with the error if it is invalid
+func (h *Handler) prevRefreshTokenID(c *gin.Context, uid uuid.UUID, refreshTokenString string) (string, bool) {
+	if refreshTokenString == "" {
+		return "", true
+	}
+
+	refreshToken, err := h.TokenService.ValidateRefreshToken(refreshTokenString)
+	if err != nil || refreshToken.UID != uid {
+		err := apperrors.NewAuthorization("Invalid refresh token")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return "", false
+	}
+
+	return refreshToken.ID.String(), true
+}
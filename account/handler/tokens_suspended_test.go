@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokensSuspended(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockTokenService := new(mocks.MockTokenService)
+	mockUserService := new(mocks.MockUserService)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		Engine:       router,
+		TokenService: mockTokenService,
+		UserService:  mockUserService,
+	})
+
+	tokenID, _ := uuid.NewRandom()
+	uid, _ := uuid.NewRandom()
+	until := time.Now().Add(time.Hour).UTC()
+
+	mockTokenService.
+		On("ValidateRefreshToken", "valid").
+		Return(&model.RefreshToken{SignedStringToken: "valid", ID: tokenID, UID: uid}, nil)
+	mockUserService.
+		On("Get", mock.Anything, uid).
+		Return(&model.User{UID: uid, Status: model.UserStatusSuspended, StatusReason: "spam", StatusExpiresAt: &until}, nil)
+
+	rr := httptest.NewRecorder()
+
+	reqBody, _ := json.Marshal(gin.H{
+		"refresh_token": "valid",
+	})
+
+	request, _ := http.NewRequest(http.MethodPost, "/tokens", bytes.NewBuffer(reqBody))
+	request.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(rr, request)
+
+	respBody, _ := json.Marshal(gin.H{
+		"error": apperrors.NewSuspended("spam", &until),
+	})
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Equal(t, respBody, rr.Body.Bytes())
+	mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+}
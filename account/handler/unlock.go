@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+)
+
+type unlockReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// Unlock handler lifts the lockout of an account with the token
+// of the unlock link emailed when it was locked
+func (h *Handler) Unlock(c *gin.Context) {
+	var req unlockReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	// without lockouts, no unlock links are sent
+	if h.LockoutService == nil {
+		err := apperrors.NewAuthorization("Invalid or expired unlock link")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.LockoutService.Unlock(ctx, req.Token); err != nil {
+		log.Printf("Failed to unlock account: %v\n", err.Error())
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUnlock(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	mockLockoutService := new(mocks.MockLockoutService)
+
+	router := gin.Default()
+	NewHandler(&Config{
+		Engine:         router,
+		LockoutService: mockLockoutService,
+	})
+
+	unlock := func(token string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+
+		reqBody, err := json.Marshal(gin.H{
+			"token": token,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/unlock", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockLockoutService.On("Unlock", mock.Anything, "token").Return(nil)
+
+		rr := unlock("token")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockLockoutService.AssertCalled(t, "Unlock", mock.Anything, "token")
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		mockLockoutService.On("Unlock", mock.Anything, "expired").Return(apperrors.NewAuthorization("Invalid or expired unlock link"))
+
+		rr := unlock("expired")
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		rr := unlock("")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
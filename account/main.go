@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"github.com/dolong2110/memorization-apps/account/router"
 	"log"
 	"net/http"
@@ -11,6 +12,9 @@ import (
 )
 
 func main() {
+	memory := flag.Bool("memory", false, "keep users, tokens and profile images in memory, without Postgres, Redis or Cloud Storage")
+	flag.Parse()
+
 	// you could insert your favorite logger here for structured or leveled logging
 	log.Println("Starting server...")
 
@@ -20,6 +24,11 @@ func main() {
 		log.Fatalf("Failed to get config: %v\n", err)
 	}
 
+	if *memory {
+		config.DataSource.Driver = router.DriverMemory
+		config.DataSource.ImageStore = router.ImageStoreMemory
+	}
+
 	ds, err := router.InitDS(config)
 	if err != nil {
 		log.Fatalf("Unable to initialize data sources: %v\n", err)
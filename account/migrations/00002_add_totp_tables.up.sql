@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS totp_credentials (
+    uid uuid PRIMARY KEY REFERENCES users (uid) ON DELETE CASCADE,
+    secret VARCHAR NOT NULL,
+    confirmed BOOLEAN NOT NULL DEFAULT FALSE,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    uid uuid NOT NULL REFERENCES users (uid) ON DELETE CASCADE,
+    code_hash VARCHAR NOT NULL,
+    used_at TIMESTAMPTZ,
+    PRIMARY KEY (uid, code_hash)
+    );
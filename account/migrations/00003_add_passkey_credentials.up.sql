@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS passkey_credentials (
+    credential_id BYTEA PRIMARY KEY,
+    uid uuid NOT NULL REFERENCES users (uid) ON DELETE CASCADE,
+    name VARCHAR NOT NULL DEFAULT '',
+    public_key BYTEA NOT NULL,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    transports TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ
+    );
+
+CREATE INDEX IF NOT EXISTS passkey_credentials_uid_idx ON passkey_credentials (uid);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS identities (
+    provider VARCHAR NOT NULL,
+    subject VARCHAR NOT NULL,
+    uid uuid NOT NULL REFERENCES users (uid) ON DELETE CASCADE,
+    email VARCHAR NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (provider, subject),
+    UNIQUE (uid, provider)
+    );
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS invites (
+    id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    code_hash VARCHAR NOT NULL UNIQUE,
+    max_uses INTEGER NOT NULL DEFAULT 1,
+    uses INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ,
+    revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by uuid REFERENCES users (uid) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS invite_id uuid REFERENCES invites (id) ON DELETE SET NULL;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS handle_redirects;
+
+DROP INDEX IF EXISTS users_handle_key;
+
+ALTER TABLE users DROP COLUMN IF EXISTS handle_changed_at;
+ALTER TABLE users DROP COLUMN IF EXISTS handle;
@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS handle VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS handle_changed_at TIMESTAMPTZ;
+
+-- handles are unique regardless of case, users without one have an empty handle
+CREATE UNIQUE INDEX IF NOT EXISTS users_handle_key ON users (lower(handle)) WHERE handle <> '';
+
+-- previous handles of users, stored in lowercase, keep pointing to them
+CREATE TABLE IF NOT EXISTS handle_redirects (
+    handle VARCHAR PRIMARY KEY,
+    uid uuid NOT NULL REFERENCES users (uid) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
@@ -0,0 +1,25 @@
+-- emails which only differ in case, surrounding spaces or unicode normalization
+-- belong to separate accounts that must be merged or changed by hand first
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(normalized_email, ', ') INTO duplicates
+    FROM (
+        SELECT lower(normalize(trim(email), NFC)) AS normalized_email
+        FROM users
+        GROUP BY normalized_email
+        HAVING COUNT(*) > 1
+    ) AS duplicate_emails;
+
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'accounts share these emails regardless of case: %', duplicates;
+    END IF;
+END $$;
+
+-- store emails trimmed, NFC normalized and with a lowercase domain
+UPDATE users
+SET email = substring(normalize(trim(email), NFC) FROM '^(.*@)') || lower(substring(normalize(trim(email), NFC) FROM '@([^@]*)$'))
+WHERE position('@' IN email) > 0;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON users (lower(email));
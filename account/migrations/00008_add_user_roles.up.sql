@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS user_roles (
+    uid uuid NOT NULL REFERENCES users (uid) ON DELETE CASCADE,
+    role VARCHAR NOT NULL,
+    granted_by uuid REFERENCES users (uid) ON DELETE SET NULL,
+    granted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (uid, role)
+    );
+
+CREATE INDEX IF NOT EXISTS user_roles_role_idx ON user_roles (role);
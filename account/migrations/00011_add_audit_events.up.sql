@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+    id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    uid uuid,
+    actor_id uuid,
+    type VARCHAR NOT NULL,
+    outcome VARCHAR NOT NULL,
+    detail VARCHAR NOT NULL DEFAULT '',
+    ip VARCHAR NOT NULL DEFAULT '',
+    user_agent VARCHAR NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+CREATE INDEX IF NOT EXISTS audit_events_uid_created_at_idx ON audit_events (uid, created_at DESC);
+
+-- events are never changed or removed, not even along with their user
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE PROCEDURE audit_events_append_only();
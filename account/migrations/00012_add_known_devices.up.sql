@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS known_devices (
+    uid uuid NOT NULL REFERENCES users (uid) ON DELETE CASCADE,
+    fingerprint VARCHAR NOT NULL,
+    user_agent VARCHAR NOT NULL DEFAULT '',
+    ip_prefix VARCHAR NOT NULL DEFAULT '',
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (uid, fingerprint)
+    );
@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN IF EXISTS active_org_id;
+
+DROP TABLE IF EXISTS org_invites;
+DROP TABLE IF EXISTS org_members;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS organizations (
+    id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    name VARCHAR NOT NULL,
+    created_by uuid REFERENCES users (uid) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+CREATE TABLE IF NOT EXISTS org_members (
+    org_id uuid NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    uid uuid NOT NULL REFERENCES users (uid) ON DELETE CASCADE,
+    role VARCHAR NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, uid)
+    );
+
+CREATE INDEX IF NOT EXISTS org_members_uid_idx ON org_members (uid);
+
+-- inviting an email again replaces its pending invite
+CREATE TABLE IF NOT EXISTS org_invites (
+    id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id uuid NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    email VARCHAR NOT NULL,
+    role VARCHAR NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+    token_hash VARCHAR NOT NULL UNIQUE,
+    invited_by uuid REFERENCES users (uid) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+CREATE UNIQUE INDEX IF NOT EXISTS org_invites_org_id_email_idx ON org_invites (org_id, lower(email));
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS active_org_id uuid REFERENCES organizations (id) ON DELETE SET NULL;
@@ -0,0 +1,8 @@
+DELETE FROM users WHERE guest;
+
+DROP INDEX IF EXISTS users_guest_created_at_idx;
+DROP INDEX IF EXISTS users_email_lower_key;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON users (lower(email));
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+
+ALTER TABLE users DROP COLUMN IF EXISTS guest;
@@ -0,0 +1,9 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS guest BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- guests have neither email nor password, so emails are only unique when set
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+DROP INDEX IF EXISTS users_email_lower_key;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON users (lower(email)) WHERE email <> '';
+
+-- unused guests are purged by age
+CREATE INDEX IF NOT EXISTS users_guest_created_at_idx ON users (created_at) WHERE guest;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS terms_acceptances;
+
+ALTER TABLE users DROP COLUMN IF EXISTS terms_accepted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS terms_version;
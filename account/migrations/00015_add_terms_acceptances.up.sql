@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS terms_version VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS terms_accepted_at TIMESTAMPTZ;
+
+-- every acceptance is kept as proof of which version a user accepted, when and from where
+CREATE TABLE IF NOT EXISTS terms_acceptances (
+    uid uuid NOT NULL REFERENCES users (uid) ON DELETE CASCADE,
+    version VARCHAR NOT NULL,
+    ip VARCHAR NOT NULL DEFAULT '',
+    user_agent VARCHAR NOT NULL DEFAULT '',
+    accepted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (uid, version)
+    );
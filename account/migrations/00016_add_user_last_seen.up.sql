@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ;
+
+-- unused guests are purged by when they were last seen, or created if never
+DROP INDEX IF EXISTS users_guest_created_at_idx;
+CREATE INDEX IF NOT EXISTS users_guest_last_seen_at_idx ON users ((COALESCE(last_seen_at, created_at))) WHERE guest;
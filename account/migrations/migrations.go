@@ -0,0 +1,308 @@
+// Package migrations embeds the schema migrations of the database drivers
+// and applies them, keeping the version of the schema in the
+// schema_migrations table of the migrate CLI, so databases migrated
+// by either one are known to the other
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"embed"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"io/fs"
+	"log"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.up.sql
+var postgresMigrations embed.FS
+
+//go:embed sqlite/*.up.sql
+var sqliteMigrations embed.FS
+
+// postgresLockID is the key of the advisory lock held while migrating Postgres,
+// so replicas starting together apply each migration once
+const postgresLockID int64 = 7424110952
+
+// Migration is an up migration, whose version is the number its file starts with
+type Migration struct {
+	Version uint
+	Name    string
+	SQL     string
+}
+
+// Migrator applies the embedded migrations of a driver to its database
+type Migrator struct {
+	DB         *sqlx.DB
+	Migrations []Migration
+	// lock serializes migrating across processes, nil if the database needs no lock
+	lock func(ctx context.Context, conn *sqlx.Conn) (unlock func() error, err error)
+}
+
+// NewPostgresMigrator is a factory for initializing the Migrator of the postgres driver
+func NewPostgresMigrator(db *sqlx.DB) (*Migrator, error) {
+	migrations, err := load(postgresMigrations, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		DB:         db,
+		Migrations: migrations,
+		lock:       postgresLock,
+	}, nil
+}
+
+// NewSQLiteMigrator is a factory for initializing the Migrator of the sqlite driver
+// SQLite allows a single writer at a time, so it needs no lock
+func NewSQLiteMigrator(db *sqlx.DB) (*Migrator, error) {
+	migrations, err := load(sqliteMigrations, "sqlite")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		DB:         db,
+		Migrations: migrations,
+	}, nil
+}
+
+// Latest is the version of the schema the binary understands
+func (m *Migrator) Latest() uint {
+	if len(m.Migrations) == 0 {
+		return 0
+	}
+
+	return m.Migrations[len(m.Migrations)-1].Version
+}
+
+// Version returns the version of the schema, 0 for a database without
+// migrations, and whether a migration failed halfway through
+func (m *Migrator) Version(ctx context.Context) (uint, bool, error) {
+	conn, err := m.DB.Connx(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer conn.Close()
+
+	return version(ctx, conn)
+}
+
+// Check returns an error if the schema cannot be used by the binary,
+// as it is newer than the binary or dirty, and logs pending migrations
+func (m *Migrator) Check(ctx context.Context) error {
+	current, dirty, err := m.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting schema version: %w", err)
+	}
+
+	if err := m.check(current, dirty); err != nil {
+		return err
+	}
+
+	if current < m.Latest() {
+		log.Printf("Schema is at version %d of %d, run the pending migrations or set MIGRATE_ON_START\n", current, m.Latest())
+	}
+
+	return nil
+}
+
+// Up applies the migrations newer than the schema, each in a transaction
+// along with the version it brings the schema to
+func (m *Migrator) Up(ctx context.Context) error {
+	// the lock is held by a session, so every statement must use the same connection
+	conn, err := m.DB.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("error connecting to migrate: %w", err)
+	}
+	defer conn.Close()
+
+	if m.lock != nil {
+		unlock, err := m.lock(ctx, conn)
+		if err != nil {
+			return fmt.Errorf("error locking schema: %w", err)
+		}
+		defer func() {
+			if err := unlock(); err != nil {
+				log.Printf("Unable to unlock schema: %v\n", err)
+			}
+		}()
+	}
+
+	if err := createVersionTable(ctx, conn); err != nil {
+		return fmt.Errorf("error creating schema version table: %w", err)
+	}
+
+	// read after locking, another replica may have migrated while waiting
+	current, dirty, err := version(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("error getting schema version: %w", err)
+	}
+
+	if err := m.check(current, dirty); err != nil {
+		return err
+	}
+
+	for _, migration := range m.Migrations {
+		if migration.Version <= current {
+			continue
+		}
+
+		log.Printf("Applying migration %s\n", migration.Name)
+		if err := apply(ctx, conn, migration); err != nil {
+			return fmt.Errorf("error applying migration %s: %w", migration.Name, err)
+		}
+	}
+
+	if current < m.Latest() {
+		log.Printf("Migrated schema from version %d to %d\n", current, m.Latest())
+	}
+
+	return nil
+}
+
+// check returns an error for a schema the binary cannot use
+func (m *Migrator) check(current uint, dirty bool) error {
+	if dirty {
+		return fmt.Errorf("schema version %d is dirty, fix the failed migration and force its version", current)
+	}
+
+	if current > m.Latest() {
+		return fmt.Errorf("schema version %d is newer than version %d of this binary", current, m.Latest())
+	}
+
+	return nil
+}
+
+// load reads the up migrations in a directory of fsys, sorted by version
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.up.sql"))
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(files))
+	for _, file := range files {
+		name := path.Base(file)
+		number, _, ok := strings.Cut(name, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s has no version", name)
+		}
+
+		v, err := strconv.ParseUint(number, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has no version: %w", name, err)
+		}
+
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, Migration{
+			Version: uint(v),
+			Name:    strings.TrimSuffix(name, ".up.sql"),
+			SQL:     string(data),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", migrations[i-1].Name, migrations[i].Name)
+		}
+	}
+
+	return migrations, nil
+}
+
+// createVersionTable creates the table of the migrate CLI, which has the
+// version of the schema in its single row
+func createVersionTable(ctx context.Context, conn *sqlx.Conn) error {
+	query := "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)"
+
+	_, err := conn.ExecContext(ctx, query)
+	return err
+}
+
+// version reads the version of the schema, which is 0 without a version table
+func version(ctx context.Context, conn *sqlx.Conn) (uint, bool, error) {
+	var row struct {
+		Version int64 `db:"version"`
+		Dirty   bool  `db:"dirty"`
+	}
+
+	err := conn.GetContext(ctx, &row, "SELECT version, dirty FROM schema_migrations LIMIT 1")
+	if errors.Is(err, sql.ErrNoRows) || isUndefinedTable(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return uint(row.Version), row.Dirty, nil
+}
+
+// apply runs a migration and sets the version of the schema in one transaction,
+// so a failed migration leaves the schema as it was
+func apply(ctx context.Context, conn *sqlx.Conn, migration Migration) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migration.SQL); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return err
+	}
+
+	query := tx.Rebind("INSERT INTO schema_migrations (version, dirty) VALUES (?, FALSE)")
+	if _, err := tx.ExecContext(ctx, query, migration.Version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// isUndefinedTable checks if an error of Postgres or SQLite is a missing table
+func isUndefinedTable(err error) bool {
+	if err, ok := err.(*pq.Error); ok {
+		return err.Code.Name() == "undefined_table"
+	}
+
+	if err, ok := err.(*sqlite.Error); ok {
+		return err.Code() == sqlite3.SQLITE_ERROR && strings.Contains(err.Error(), "no such table")
+	}
+
+	return false
+}
+
+// postgresLock takes the advisory lock of migrations, waiting
+// for other sessions holding it
+func postgresLock(ctx context.Context, conn *sqlx.Conn) (func() error, error) {
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", postgresLockID); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		// the context may be done by now, and the lock must still be released
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", postgresLockID)
+		return err
+	}, nil
+}
@@ -0,0 +1,151 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func newSQLiteMigrator(t *testing.T) *Migrator {
+	db, err := sqlx.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "account.db")+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := NewSQLiteMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return migrator
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("Embedded migrations", func(t *testing.T) {
+		for _, newMigrator := range []func(db *sqlx.DB) (*Migrator, error){NewPostgresMigrator, NewSQLiteMigrator} {
+			migrator, err := newMigrator(nil)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, migrator.Migrations)
+
+			// versions have no gaps, which would hide a missing file
+			for i, migration := range migrator.Migrations {
+				assert.Equal(t, uint(i+1), migration.Version, migration.Name)
+				assert.NotEmpty(t, migration.SQL, migration.Name)
+			}
+		}
+	})
+
+	t.Run("Sorted by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"00010_ten.up.sql":   {Data: []byte("SELECT 10")},
+			"00002_two.up.sql":   {Data: []byte("SELECT 2")},
+			"00002_two.down.sql": {Data: []byte("SELECT -2")},
+		}
+
+		migrations, err := load(fsys, ".")
+		assert.NoError(t, err)
+		assert.Equal(t, []Migration{
+			{Version: 2, Name: "00002_two", SQL: "SELECT 2"},
+			{Version: 10, Name: "00010_ten", SQL: "SELECT 10"},
+		}, migrations)
+	})
+
+	t.Run("Invalid versions", func(t *testing.T) {
+		for _, fsys := range []fstest.MapFS{
+			{"two.up.sql": {}},
+			{"two_two.up.sql": {}},
+			{"00002_two.up.sql": {}, "2_other_two.up.sql": {}},
+		} {
+			_, err := load(fsys, ".")
+			assert.Error(t, err)
+		}
+	})
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("Up migrates a new database once", func(t *testing.T) {
+		migrator := newSQLiteMigrator(t)
+
+		current, dirty, err := migrator.Version(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(0), current)
+		assert.False(t, dirty)
+		assert.NoError(t, migrator.Check(ctx))
+
+		assert.NoError(t, migrator.Up(ctx))
+		assert.NoError(t, migrator.Up(ctx))
+
+		current, _, err = migrator.Version(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, migrator.Latest(), current)
+
+		var users int
+		assert.NoError(t, migrator.DB.Get(&users, "SELECT COUNT(*) FROM users"))
+		assert.NoError(t, migrator.Check(ctx))
+	})
+
+	t.Run("Up applies only pending migrations", func(t *testing.T) {
+		migrator := newSQLiteMigrator(t)
+		migrator.Migrations = []Migration{
+			{Version: 1, Name: "00001_one", SQL: "CREATE TABLE one (id INTEGER)"},
+		}
+		assert.NoError(t, migrator.Up(ctx))
+
+		migrator.Migrations = append(migrator.Migrations, Migration{Version: 2, Name: "00002_two", SQL: "CREATE TABLE two (id INTEGER)"})
+		assert.NoError(t, migrator.Up(ctx))
+
+		current, _, err := migrator.Version(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(2), current)
+	})
+
+	t.Run("Failed migration leaves the schema as it was", func(t *testing.T) {
+		migrator := newSQLiteMigrator(t)
+		migrator.Migrations = []Migration{
+			{Version: 1, Name: "00001_one", SQL: "CREATE TABLE one (id INTEGER)"},
+			{Version: 2, Name: "00002_two", SQL: "CREATE TABLE two (id INTEGER); INSERT INTO missing VALUES (1)"},
+		}
+
+		assert.Error(t, migrator.Up(ctx))
+
+		current, dirty, err := migrator.Version(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(1), current)
+		assert.False(t, dirty)
+
+		var tables int
+		assert.NoError(t, migrator.DB.Get(&tables, "SELECT COUNT(*) FROM sqlite_master WHERE name='two'"))
+		assert.Equal(t, 0, tables)
+	})
+
+	t.Run("Newer schema is refused", func(t *testing.T) {
+		migrator := newSQLiteMigrator(t)
+		assert.NoError(t, migrator.Up(ctx))
+
+		_, err := migrator.DB.Exec("UPDATE schema_migrations SET version=?", migrator.Latest()+1)
+		assert.NoError(t, err)
+
+		assert.Error(t, migrator.Check(ctx))
+		assert.Error(t, migrator.Up(ctx))
+	})
+
+	t.Run("Dirty schema is refused", func(t *testing.T) {
+		migrator := newSQLiteMigrator(t)
+		assert.NoError(t, migrator.Up(ctx))
+
+		// as left by the migrate CLI after a failed migration
+		_, err := migrator.DB.Exec("UPDATE schema_migrations SET dirty=TRUE")
+		assert.NoError(t, err)
+
+		assert.Error(t, migrator.Check(ctx))
+		assert.Error(t, migrator.Up(ctx))
+	})
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS handle_redirects;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,37 @@
+-- SQLite has no uuid type or generator, uids are random version 4 UUIDs in text
+CREATE TABLE IF NOT EXISTS users (
+    uid TEXT PRIMARY KEY NOT NULL DEFAULT (lower(
+        hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+        substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))
+    )),
+    name TEXT NOT NULL DEFAULT '',
+    email TEXT NOT NULL,
+    password TEXT NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    website TEXT NOT NULL DEFAULT '',
+    handle TEXT NOT NULL DEFAULT '',
+    handle_changed_at DATETIME,
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'suspended', 'pending_deletion')),
+    status_reason TEXT NOT NULL DEFAULT '',
+    status_expires_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    guest BOOLEAN NOT NULL DEFAULT FALSE,
+    invite_id TEXT,
+    active_org_id TEXT,
+    terms_version TEXT NOT NULL DEFAULT '',
+    terms_accepted_at DATETIME
+    );
+
+-- emails and handles are unique regardless of case, guests have no email
+-- and users without a handle have an empty one
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON users (lower(email)) WHERE email <> '';
+CREATE UNIQUE INDEX IF NOT EXISTS users_handle_key ON users (lower(handle)) WHERE handle <> '';
+CREATE INDEX IF NOT EXISTS users_status_idx ON users (status);
+CREATE INDEX IF NOT EXISTS users_guest_created_at_idx ON users (created_at) WHERE guest;
+
+-- previous handles of users, stored in lowercase, keep pointing to them
+CREATE TABLE IF NOT EXISTS handle_redirects (
+    handle TEXT PRIMARY KEY,
+    uid TEXT NOT NULL REFERENCES users (uid) ON DELETE CASCADE,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+    );
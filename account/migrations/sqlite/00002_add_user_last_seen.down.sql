@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS users_guest_last_seen_at_idx;
+CREATE INDEX IF NOT EXISTS users_guest_created_at_idx ON users (created_at) WHERE guest;
+
+ALTER TABLE users DROP COLUMN last_seen_at;
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // Type holds a type string and integer code for the error
@@ -14,10 +15,14 @@ const (
 	Authorization        Type = "AUTHORIZATION"          // Authentication Failures -
 	BadRequest           Type = "BAD_REQUEST"            // Validation errors / BadInput
 	Conflict             Type = "CONFLICT"               // Already exists (eg, create account with existent email) - 409
+	Forbidden            Type = "FORBIDDEN"              // Authenticated, but not allowed to do this - 403
 	Internal             Type = "INTERNAL"               // Server (500) and fallback errors
 	NotFound             Type = "NOT_FOUND"              // For not finding resource
 	PayloadTooLarge      Type = "PAYLOAD_TOO_LARGE"      // For uploading tons of JSON, or an image over the limit - 413
 	ServiceUnavailable   Type = "SERVICE_UNAVAILABLE"    // For long run handlers
+	Suspended            Type = "SUSPENDED"              // Account suspended by an admin - 403
+	TermsNotAccepted     Type = "TERMS_NOT_ACCEPTED"     // Current terms of service not accepted yet - 403
+	TooManyRequests      Type = "TOO_MANY_REQUESTS"      // For throttled clients, sent with a Retry-After header - 429
 	UnsupportedMediaType Type = "UNSUPPORTED_MEDIA_TYPE" // for http 415
 )
 
@@ -25,9 +30,21 @@ const (
 // which is helpful in returning a consistent
 // error type/message from API endpoints
 type Error struct {
-	Type    Type   `json:"type"`
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Type        Type              `json:"type"`
+	Code        int               `json:"code"`
+	Message     string            `json:"message"`
+	RetryAfter  int               `json:"retry_after,omitempty"` // seconds, only for TooManyRequests
+	Until       *time.Time        `json:"until,omitempty"`       // end of a Suspended error, if any
+	Terms       string            `json:"terms,omitempty"`       // version of a TermsNotAccepted error
+	InvalidArgs []InvalidArgument `json:"-"`                     // sent beside the error as "invalidArgs"
+}
+
+// InvalidArgument describes why a field of a request is invalid
+type InvalidArgument struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+	Tag   string `json:"tag"`
+	Param string `json:"param"`
 }
 
 // Response return response for error
@@ -54,6 +71,8 @@ func (e *Error) Status() int {
 		return http.StatusBadRequest
 	case Conflict:
 		return http.StatusConflict
+	case Forbidden:
+		return http.StatusForbidden
 	case Internal:
 		return http.StatusInternalServerError
 	case NotFound:
@@ -62,6 +81,12 @@ func (e *Error) Status() int {
 		return http.StatusRequestEntityTooLarge
 	case ServiceUnavailable:
 		return http.StatusServiceUnavailable
+	case Suspended:
+		return http.StatusForbidden
+	case TermsNotAccepted:
+		return http.StatusForbidden
+	case TooManyRequests:
+		return http.StatusTooManyRequests
 	case UnsupportedMediaType:
 		return http.StatusUnsupportedMediaType
 	default:
@@ -80,6 +105,26 @@ func Status(err error) int {
 	return http.StatusInternalServerError
 }
 
+// RetryAfter returns the seconds a client should wait
+// before retrying, or 0 if the error is not TooManyRequests
+func RetryAfter(err error) int {
+	var e *Error
+	if errors.As(err, &e) && e.Type == TooManyRequests {
+		return e.RetryAfter
+	}
+	return 0
+}
+
+// InvalidArgs returns the invalid fields of a request,
+// or nil if the error does not describe any
+func InvalidArgs(err error) []InvalidArgument {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.InvalidArgs
+	}
+	return nil
+}
+
 /*
 * Error "Factories"
  */
@@ -102,6 +147,16 @@ func NewBadRequest(reason string) *Error {
 	}
 }
 
+// NewInvalidArgs to create a 400 error for a request with invalid fields
+func NewInvalidArgs(invalidArgs []InvalidArgument) *Error {
+	return &Error{
+		Type:        BadRequest,
+		Code:        http.StatusBadRequest,
+		Message:     "Invalid request parameters. See invalidArgs",
+		InvalidArgs: invalidArgs,
+	}
+}
+
 // NewConflict to create an error for 409
 func NewConflict(name string, value string) *Error {
 	return &Error{
@@ -111,6 +166,15 @@ func NewConflict(name string, value string) *Error {
 	}
 }
 
+// NewForbidden to create an error for 403
+func NewForbidden(reason string) *Error {
+	return &Error{
+		Type:    Forbidden,
+		Code:    http.StatusForbidden,
+		Message: reason,
+	}
+}
+
 // NewInternal for 500 errors and unknown errors
 func NewInternal() *Error {
 	return &Error{
@@ -147,6 +211,44 @@ func NewServiceUnavailable() *Error {
 	}
 }
 
+// NewSuspended to create an error for a suspended account, a 403
+// until is nil for suspensions without an end
+func NewSuspended(reason string, until *time.Time) *Error {
+	message := "Account suspended"
+	if reason != "" {
+		message = fmt.Sprintf("Account suspended: %v", reason)
+	}
+
+	return &Error{
+		Type:    Suspended,
+		Code:    http.StatusForbidden,
+		Message: message,
+		Until:   until,
+	}
+}
+
+// NewTermsNotAccepted to create an error for a user who has not accepted
+// the current version of the terms of service, a 403
+func NewTermsNotAccepted(version string) *Error {
+	return &Error{
+		Type:    TermsNotAccepted,
+		Code:    http.StatusForbidden,
+		Message: fmt.Sprintf("Terms of service version %v must be accepted", version),
+		Terms:   version,
+	}
+}
+
+// NewTooManyRequests to create an error for 429
+// retryAfter is rounded up to whole seconds, as sent in the Retry-After header
+func NewTooManyRequests(reason string, retryAfter time.Duration) *Error {
+	return &Error{
+		Type:       TooManyRequests,
+		Code:       http.StatusTooManyRequests,
+		Message:    reason,
+		RetryAfter: int((retryAfter + time.Second - 1) / time.Second),
+	}
+}
+
 // NewUnsupportedMediaType to create an error for 415
 func NewUnsupportedMediaType(reason string) *Error {
 	return &Error{
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEvent is a security-relevant event of an account, kept in an append-only log
+// UID is nil for events of unknown accounts, ActorID for anonymous requests
+type AuditEvent struct {
+	ID        uuid.UUID  `db:"id" json:"id"`
+	UID       *uuid.UUID `db:"uid" json:"uid,omitempty"`
+	ActorID   *uuid.UUID `db:"actor_id" json:"actor_id,omitempty"`
+	Type      string     `db:"type" json:"type"`
+	Outcome   string     `db:"outcome" json:"outcome"`
+	Detail    string     `db:"detail" json:"detail,omitempty"`
+	IP        string     `db:"ip" json:"ip"`
+	UserAgent string     `db:"user_agent" json:"user_agent"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+}
+
+// Types of audit events
+const (
+	AuditSignup         = "signup"
+	AuditSignin         = "signin"
+	AuditTokenRefresh   = "token_refresh"
+	AuditSignout        = "signout"
+	AuditProfileUpdate  = "profile_update"
+	AuditEmailChange    = "email_change"
+	AuditImageChange    = "image_change"
+	AuditPasswordChange = "password_change"
+	AuditHandleChange   = "handle_change"
+	AuditStatusChange   = "status_change"
+	AuditRoleGrant      = "role_grant"
+	AuditRoleRevoke     = "role_revoke"
+	AuditNewDevice      = "new_device"
+	AuditSigninDisowned = "signin_disowned"
+	AuditOrgJoin        = "org_join"
+	AuditOrgLeave       = "org_leave"
+	AuditOrgRoleChange  = "org_role_change"
+	AuditGuestUpgrade   = "guest_upgrade"
+	AuditTermsAccept    = "terms_accept"
+	AuditMFAEnable      = "mfa_enable"
+	AuditMFADisable     = "mfa_disable"
+	AuditPasskeyAdd     = "passkey_add"
+	AuditPasskeyRemove  = "passkey_remove"
+	AuditIdentityLink   = "identity_link"
+	AuditIdentityUnlink = "identity_unlink"
+)
+
+// Outcomes of audit events
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// ClientInfo describes the client of a request for audit events
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+type clientInfoKey struct{}
+
+type actorKey struct{}
+
+// WithClientInfo returns a copy of ctx carrying the client of the request
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFrom returns the client of the request, empty outside of requests
+func ClientInfoFrom(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
+
+// WithActor returns a copy of ctx carrying the authenticated user of the request
+func WithActor(ctx context.Context, uid uuid.UUID) context.Context {
+	return context.WithValue(ctx, actorKey{}, uid)
+}
+
+// ActorFrom returns the authenticated user of the request, or nil if there is none
+func ActorFrom(ctx context.Context) *uuid.UUID {
+	uid, ok := ctx.Value(actorKey{}).(uuid.UUID)
+	if !ok {
+		return nil
+	}
+	return &uid
+}
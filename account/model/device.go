@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// KnownDevice is a device a user signed in from before
+// Fingerprint combines a hash of the user agent with the network of the IP,
+// so a browser update or another IP of the same network is the same device
+type KnownDevice struct {
+	UID         uuid.UUID `db:"uid" json:"-"`
+	Fingerprint string    `db:"fingerprint" json:"-"`
+	UserAgent   string    `db:"user_agent" json:"user_agent"`
+	IPPrefix    string    `db:"ip_prefix" json:"ip_prefix"`
+	FirstSeenAt time.Time `db:"first_seen_at" json:"first_seen_at"`
+	LastSeenAt  time.Time `db:"last_seen_at" json:"last_seen_at"`
+}
+
+// NewDeviceNotice tells a user about a sign-in from a new device
+// RevokeURL signs out all sessions and forces a password reset if it was not them
+type NewDeviceNotice struct {
+	User      *User
+	Device    *KnownDevice
+	IP        string
+	RevokeURL string
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// ExportStatus holds the state of a personal data export
+type ExportStatus string
+
+// "Set" of valid export statuses
+const (
+	ExportPending ExportStatus = "PENDING" // archive is still being assembled
+	ExportReady   ExportStatus = "READY"   // archive can be downloaded
+	ExportFailed  ExportStatus = "FAILED"  // archive could not be assembled
+)
+
+// Export holds the metadata of a user's personal data export.
+// DownloadToken is the secret part of the time-limited download link
+type Export struct {
+	ID            uuid.UUID    `json:"id"`
+	UID           uuid.UUID    `json:"uid"`
+	Status        ExportStatus `json:"status"`
+	DownloadToken string       `json:"-"`
+	CreatedAt     time.Time    `json:"created_at"`
+	ExpiresAt     time.Time    `json:"expires_at"`
+}
+
+// Session describes a valid refresh token of a user,
+// it never exposes the signed token itself
+type Session struct {
+	TokenID   string    `json:"token_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
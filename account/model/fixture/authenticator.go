@@ -0,0 +1,157 @@
+package fixture
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// ctap2 encodes maps with sorted keys, as authenticators do
+var ctap2, _ = cbor.CTAP2EncOptions().EncMode()
+
+// Authenticator is a software WebAuthn authenticator holding a single
+// ES256 credential, used to drive passkey ceremonies in tests
+type Authenticator struct {
+	RPID         string
+	Origin       string
+	CredentialID []byte
+	UserHandle   []byte
+	SignCount    uint32
+	// WithoutUV leaves out the user verified flag, as for a security
+	// key without a PIN
+	WithoutUV  bool
+	privateKey *ecdsa.PrivateKey
+}
+
+// NewAuthenticator creates an authenticator with a fresh key pair for rpID
+func NewAuthenticator(rpID string, origin string) *Authenticator {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		panic(err)
+	}
+
+	return &Authenticator{
+		RPID:         rpID,
+		Origin:       origin,
+		CredentialID: credentialID,
+		privateKey:   privateKey,
+	}
+}
+
+// PublicKey returns the COSE encoding of the credential public key
+func (a *Authenticator) PublicKey() []byte {
+	key, err := ctap2.Marshal(map[int]interface{}{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: a.privateKey.X.FillBytes(make([]byte, 32)),
+		-3: a.privateKey.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+}
+
+// Create answers navigator.credentials.create for the given challenge
+func (a *Authenticator) Create(challenge []byte, userHandle []byte) *model.PasskeyAttestation {
+	a.UserHandle = userHandle
+
+	// aaguid (zeroes) | credentialIdLength | credentialId | credentialPublicKey
+	attested := make([]byte, 18)
+	binary.BigEndian.PutUint16(attested[16:], uint16(len(a.CredentialID)))
+	attested = append(attested, a.CredentialID...)
+	attested = append(attested, a.PublicKey()...)
+
+	authData := append(a.authData(0x01|0x40), attested...)
+
+	attestationObject, err := ctap2.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return &model.PasskeyAttestation{
+		ID:    base64.RawURLEncoding.EncodeToString(a.CredentialID),
+		RawID: a.CredentialID,
+		Type:  "public-key",
+		Response: model.AttestationResponse{
+			ClientDataJSON:    a.clientData(model.WebAuthnCreate, challenge),
+			AttestationObject: attestationObject,
+			Transports:        []string{"internal"},
+		},
+	}
+}
+
+// Get answers navigator.credentials.get for the given challenge,
+// increasing the signature counter
+func (a *Authenticator) Get(challenge []byte) *model.PasskeyAssertion {
+	a.SignCount++
+
+	authData := a.authData(0x01)
+	clientDataJSON := a.clientData(model.WebAuthnGet, challenge)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, a.privateKey, digest[:])
+	if err != nil {
+		panic(err)
+	}
+
+	return &model.PasskeyAssertion{
+		ID:    base64.RawURLEncoding.EncodeToString(a.CredentialID),
+		RawID: a.CredentialID,
+		Type:  "public-key",
+		Response: model.AssertionResponse{
+			ClientDataJSON:    clientDataJSON,
+			AuthenticatorData: authData,
+			Signature:         signature,
+			UserHandle:        a.UserHandle,
+		},
+	}
+}
+
+// authData builds rpIdHash | flags | signCount, with the user
+// verified flag unless WithoutUV
+func (a *Authenticator) authData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+
+	if !a.WithoutUV {
+		flags |= 0x04
+	}
+
+	authData := append(rpIDHash[:], flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(authData[33:], a.SignCount)
+
+	return authData
+}
+
+func (a *Authenticator) clientData(ceremony string, challenge []byte) []byte {
+	clientDataJSON, err := json.Marshal(map[string]interface{}{
+		"type":      ceremony,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    a.Origin,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return clientDataJSON
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Identity links an account of an external OAuth2/OIDC provider to a user
+type Identity struct {
+	Provider  string    `db:"provider" json:"provider"`
+	Subject   string    `db:"subject" json:"-"`
+	UID       uuid.UUID `db:"uid" json:"-"`
+	Email     string    `db:"email" json:"email"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// OAuthState holds a pending authorization request between the redirect
+// to a provider and its callback. NonceHash binds it to the browser which
+// started it, UID is only set when linking an identity
+type OAuthState struct {
+	Provider     string    `json:"provider"`
+	CodeVerifier string    `json:"code_verifier"`
+	NonceHash    string    `json:"nonce_hash"`
+	UID          uuid.UUID `json:"uid"`
+}
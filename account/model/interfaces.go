@@ -3,6 +3,7 @@ package model
 import (
 	"context"
 	"github.com/google/uuid"
+	"io"
 	"mime/multipart"
 	"time"
 )
@@ -13,9 +14,27 @@ type UserService interface {
 	Get(ctx context.Context, uid uuid.UUID) (*User, error)
 	Signup(ctx context.Context, user *User) error
 	Signin(ctx context.Context, user *User) error
+	RecordSignin(ctx context.Context, user *User, method string)
 	UpdateDetails(ctx context.Context, user *User) error
 	SetProfileImage(ctx context.Context, uid uuid.UUID, imageFileHeader *multipart.FileHeader) (*User, error)
 	DeleteProfileImage(ctx context.Context, uid uuid.UUID) error
+	ProfileImage(ctx context.Context, objName string) (io.ReadCloser, error)
+	ChangePassword(ctx context.Context, uid uuid.UUID, currentPassword string, newPassword string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token string, password string) (*User, error)
+	NotifySignupAttempt(ctx context.Context, email string) error
+	HandleAvailable(ctx context.Context, handle string) (bool, error)
+	GetByHandle(ctx context.Context, handle string) (*User, error)
+	SetHandle(ctx context.Context, uid uuid.UUID, handle string) (*User, error)
+	List(ctx context.Context, filter *UserFilter) ([]*User, int64, error)
+	Search(ctx context.Context, query string, limit int) ([]*User, error)
+	SetStatus(ctx context.Context, uid uuid.UUID, status string, reason string, expiresAt *time.Time) (*User, error)
+	Activity(ctx context.Context, uid uuid.UUID, limit int, offset int) ([]*AuditEvent, error)
+	DisownSignin(ctx context.Context, token string) (*User, error)
+	CreateGuest(ctx context.Context) (*User, error)
+	UpgradeGuest(ctx context.Context, user *User) error
+	PurgeGuests(ctx context.Context, maxAge time.Duration) (int64, error)
+	AcceptTerms(ctx context.Context, uid uuid.UUID, version string) (*User, error)
 }
 
 // TokenService defines methods the handler layer expects to interact
@@ -23,8 +42,99 @@ type UserService interface {
 type TokenService interface {
 	NewPairFromUser(ctx context.Context, user *User, prevRefreshTokenID string) (*Token, error)
 	Signout(ctx context.Context, uid uuid.UUID) error
-	ValidateIDToken(idTokenString string) (*User, error)                   // jwt not require context, and we not do anything in repository or db that cancel or modify context
-	ValidateRefreshToken(refreshTokenString string) (*RefreshToken, error) // not need context because not reach DB or other layer.
+	Revoke(ctx context.Context, uid uuid.UUID) error
+	ValidateIDToken(ctx context.Context, idTokenString string) (*User, error) // checks the denylist of revoked access tokens
+	ValidateRefreshToken(refreshTokenString string) (*RefreshToken, error)    // not need context because not reach DB or other layer.
+}
+
+// MFAService defines methods the handler layer expects to interact
+// with in regards to two-factor authentication
+type MFAService interface {
+	EnrollTOTP(ctx context.Context, uid uuid.UUID) (*TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, uid uuid.UUID, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, uid uuid.UUID, password string) error
+	IsEnrolled(ctx context.Context, uid uuid.UUID) (bool, error)
+	NewChallenge(ctx context.Context, uid uuid.UUID) (string, error)
+	ChallengeUser(ctx context.Context, challengeToken string) (*User, error)
+	VerifyChallenge(ctx context.Context, challengeToken string, code string) (*User, error)
+}
+
+// PasskeyService defines methods the handler layer expects to interact
+// with in regards to WebAuthn registration and sign-in
+type PasskeyService interface {
+	BeginRegistration(ctx context.Context, uid uuid.UUID) (string, *PasskeyCreationOptions, error)
+	FinishRegistration(ctx context.Context, uid uuid.UUID, sessionID string, name string, attestation *PasskeyAttestation) (*PasskeyCredential, error)
+	BeginLogin(ctx context.Context, email string) (string, *PasskeyRequestOptions, error)
+	FinishLogin(ctx context.Context, sessionID string, assertion *PasskeyAssertion) (*User, error)
+	ListCredentials(ctx context.Context, uid uuid.UUID) ([]*PasskeyCredential, error)
+	DeleteCredential(ctx context.Context, uid uuid.UUID, credentialID []byte) error
+}
+
+// MagicLinkService defines methods the handler layer expects to interact
+// with in regards to passwordless sign-in by email
+type MagicLinkService interface {
+	Send(ctx context.Context, email string) (string, error)
+	Verify(ctx context.Context, token string, nonce string) (*User, error)
+}
+
+// OAuthService defines methods the handler layer expects to interact
+// with in regards to signing in with external identity providers
+type OAuthService interface {
+	Providers() []string
+	AuthCodeURL(ctx context.Context, provider string, uid uuid.UUID) (string, string, error)
+	Signin(ctx context.Context, provider string, code string, state string, nonce string) (*User, error)
+	Link(ctx context.Context, uid uuid.UUID, provider string, code string, state string, nonce string) (*Identity, error)
+	ListIdentities(ctx context.Context, uid uuid.UUID) ([]*Identity, error)
+	Unlink(ctx context.Context, uid uuid.UUID, provider string) error
+}
+
+// LockoutService defines methods the handler layer expects to interact
+// with in regards to throttling failed sign-in attempts
+type LockoutService interface {
+	Check(ctx context.Context, email string, ip string) error
+	RecordFailure(ctx context.Context, email string, ip string) error
+	RecordSuccess(ctx context.Context, email string) error
+	Unlock(ctx context.Context, token string) error
+	UnlockAccount(ctx context.Context, email string) error
+	CheckGuest(ctx context.Context, ip string) error
+}
+
+// ExportService defines methods the handler layer expects to interact
+// with in regards to personal data exports
+type ExportService interface {
+	Request(ctx context.Context, uid uuid.UUID) (*Export, error)
+	Get(ctx context.Context, uid uuid.UUID, exportID uuid.UUID) (*Export, error)
+	Download(ctx context.Context, downloadToken string) (*Export, []byte, error)
+}
+
+// InviteService defines methods the handler layer expects to interact
+// with in regards to managing sign-up invites
+type InviteService interface {
+	Create(ctx context.Context, createdBy uuid.UUID, maxUses int, expiresIn time.Duration) (*Invite, error)
+	List(ctx context.Context) ([]*Invite, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+}
+
+// RoleService defines methods the handler layer expects to interact
+// with in regards to granting roles to users
+type RoleService interface {
+	List(ctx context.Context, uid uuid.UUID) ([]string, error)
+	Grant(ctx context.Context, actor uuid.UUID, uid uuid.UUID, role string) error
+	Revoke(ctx context.Context, actor uuid.UUID, uid uuid.UUID, role string) error
+	Bootstrap(ctx context.Context, adminEmails []string) error
+}
+
+// OrgService defines methods the handler layer expects to interact
+// with in regards to organizations and their members
+type OrgService interface {
+	Create(ctx context.Context, uid uuid.UUID, name string) (*Organization, error)
+	List(ctx context.Context, uid uuid.UUID) ([]*Organization, error)
+	Members(ctx context.Context, uid uuid.UUID, orgID uuid.UUID) ([]*OrgMember, error)
+	Invite(ctx context.Context, actor uuid.UUID, orgID uuid.UUID, email string, role string) (*OrgInvite, error)
+	AcceptInvite(ctx context.Context, uid uuid.UUID, token string) (*Organization, error)
+	RemoveMember(ctx context.Context, actor uuid.UUID, orgID uuid.UUID, uid uuid.UUID) error
+	SetMemberRole(ctx context.Context, actor uuid.UUID, orgID uuid.UUID, uid uuid.UUID, role string) error
+	Switch(ctx context.Context, uid uuid.UUID, orgID *uuid.UUID) (*User, error)
 }
 
 // UserRepository defines methods the service layer expects
@@ -32,9 +142,21 @@ type TokenService interface {
 type UserRepository interface {
 	FindByID(ctx context.Context, uid uuid.UUID) (*User, error)
 	FindByEmail(ctx context.Context, email string) (*User, error)
+	FindByHandle(ctx context.Context, handle string) (*User, error)
 	Create(ctx context.Context, user *User) error
 	Update(ctx context.Context, user *User) error
 	UpdateImage(ctx context.Context, uid uuid.UUID, imageURL string) (*User, error)
+	UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error
+	UpdateHandle(ctx context.Context, uid uuid.UUID, handle string) (*User, error)
+	UpdateStatus(ctx context.Context, user *User) error
+	UpdateActiveOrg(ctx context.Context, uid uuid.UUID, orgID *uuid.UUID) (*User, error)
+	UpdateLastSeen(ctx context.Context, uid uuid.UUID, seenAt time.Time) error
+	UpgradeGuest(ctx context.Context, user *User) error
+	Delete(ctx context.Context, uid uuid.UUID) error
+	DeleteGuests(ctx context.Context, seenBefore time.Time) ([]*User, error)
+	List(ctx context.Context, filter *UserFilter) ([]*User, error)
+	Count(ctx context.Context, filter *UserFilter) (int64, error)
+	Search(ctx context.Context, query string, limit int) ([]*User, error)
 }
 
 // TokenRepository defines methods it expects a repository
@@ -43,6 +165,36 @@ type TokenRepository interface {
 	SetRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error
 	DeleteRefreshToken(ctx context.Context, userID string, prevTokenID string) error
 	DeleteUserRefreshToken(ctx context.Context, userID string) error
+	GetUserSessions(ctx context.Context, userID string) ([]*Session, error)
+	SetMFAChallenge(ctx context.Context, challengeID string, userID string, expiresIn time.Duration) error
+	GetMFAChallenge(ctx context.Context, challengeID string) (string, error)
+	IncrementMFAChallengeAttempts(ctx context.Context, challengeID string) (int64, error)
+	DeleteMFAChallenge(ctx context.Context, challengeID string) error
+	SetWebAuthnSession(ctx context.Context, sessionID string, session *WebAuthnSession, expiresIn time.Duration) error
+	GetWebAuthnSession(ctx context.Context, sessionID string) (*WebAuthnSession, error)
+	SetMagicLink(ctx context.Context, token string, link *MagicLink, expiresIn time.Duration) error
+	GetMagicLink(ctx context.Context, token string) (*MagicLink, error)
+	IncrementMagicLinkRequests(ctx context.Context, email string, window time.Duration) (int64, error)
+	SetOAuthState(ctx context.Context, state string, oauthState *OAuthState, expiresIn time.Duration) error
+	GetOAuthState(ctx context.Context, state string) (*OAuthState, error)
+	SetPasswordResetToken(ctx context.Context, token string, userID string, expiresIn time.Duration) error
+	PeekPasswordResetToken(ctx context.Context, token string) (string, error)
+	GetPasswordResetToken(ctx context.Context, token string) (string, error)
+	SetAccessTokensRevokedAt(ctx context.Context, userID string, revokedAt time.Time, expiresIn time.Duration) error
+	GetAccessTokensRevokedAt(ctx context.Context, userID string) (time.Time, error)
+	SetDeviceRevokeToken(ctx context.Context, token string, userID string, expiresIn time.Duration) error
+	GetDeviceRevokeToken(ctx context.Context, token string) (string, error)
+}
+
+// MFARepository defines methods it expects a repository
+// it interacts with to implement
+type MFARepository interface {
+	SetTOTP(ctx context.Context, totp *TOTP) error
+	FindTOTP(ctx context.Context, uid uuid.UUID) (*TOTP, error)
+	ConfirmTOTP(ctx context.Context, uid uuid.UUID, step int64, recoveryCodeHashes []string) error
+	UpdateTOTPStep(ctx context.Context, uid uuid.UUID, step int64) (bool, error)
+	DeleteTOTP(ctx context.Context, uid uuid.UUID) error
+	UseRecoveryCode(ctx context.Context, uid uuid.UUID, codeHash string) (bool, error)
 }
 
 // ImageRepository defines methods it expects a repository
@@ -50,4 +202,147 @@ type TokenRepository interface {
 type ImageRepository interface {
 	UpdateProfile(ctx context.Context, objName string, imageFile multipart.File) (string, error)
 	DeleteProfile(ctx context.Context, objName string) error
+	GetProfile(ctx context.Context, objName string) (io.ReadCloser, error)
+}
+
+// PasskeyRepository defines methods it expects a repository
+// it interacts with to implement
+type PasskeyRepository interface {
+	Create(ctx context.Context, credential *PasskeyCredential) error
+	FindByID(ctx context.Context, credentialID []byte) (*PasskeyCredential, error)
+	FindByUID(ctx context.Context, uid uuid.UUID) ([]*PasskeyCredential, error)
+	UpdateSignCount(ctx context.Context, credentialID []byte, signCount uint32) error
+	Delete(ctx context.Context, uid uuid.UUID, credentialID []byte) error
+}
+
+// IdentityRepository defines methods it expects a repository
+// it interacts with to implement
+type IdentityRepository interface {
+	Create(ctx context.Context, identity *Identity) error
+	Find(ctx context.Context, provider string, subject string) (*Identity, error)
+	FindByUID(ctx context.Context, uid uuid.UUID) ([]*Identity, error)
+	Delete(ctx context.Context, uid uuid.UUID, provider string) error
+}
+
+// LoginAttemptRepository defines methods it expects a repository
+// it interacts with to implement
+type LoginAttemptRepository interface {
+	IncrementFailures(ctx context.Context, key string, window time.Duration) (int64, error)
+	IncrementCount(ctx context.Context, key string, window time.Duration) (int64, error)
+	ResetFailures(ctx context.Context, key string) error
+	Block(ctx context.Context, key string, duration time.Duration) error
+	BlockedFor(ctx context.Context, key string) (time.Duration, error)
+	SetUnlockToken(ctx context.Context, token string, email string, expiresIn time.Duration) error
+	GetUnlockToken(ctx context.Context, token string) (string, error)
+}
+
+// BreachedPasswordRepository defines methods it expects a repository
+// it interacts with to implement
+type BreachedPasswordRepository interface {
+	IsBreached(ctx context.Context, password string) (bool, error)
+}
+
+// InviteRepository defines methods it expects a repository
+// it interacts with to implement
+type InviteRepository interface {
+	Create(ctx context.Context, invite *Invite) error
+	FindAll(ctx context.Context) ([]*Invite, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+	Redeem(ctx context.Context, codeHash string) (*Invite, error)
+	Release(ctx context.Context, id uuid.UUID) error
+}
+
+// RoleRepository defines methods it expects a repository
+// it interacts with to implement
+type RoleRepository interface {
+	FindByUID(ctx context.Context, uid uuid.UUID) ([]string, error)
+	CountUsers(ctx context.Context, role string) (int64, error)
+	Grant(ctx context.Context, uid uuid.UUID, role string, grantedBy *uuid.UUID) error
+	Revoke(ctx context.Context, uid uuid.UUID, role string) error
+}
+
+// OrgRepository defines methods it expects a repository
+// it interacts with to implement
+// AcceptInvite deletes the invite and adds its member at once, so invites are single-use
+type OrgRepository interface {
+	Create(ctx context.Context, org *Organization, owner uuid.UUID) error
+	FindByID(ctx context.Context, id uuid.UUID) (*Organization, error)
+	FindByUID(ctx context.Context, uid uuid.UUID) ([]*Organization, error)
+	FindMember(ctx context.Context, orgID uuid.UUID, uid uuid.UUID) (*OrgMember, error)
+	FindMembers(ctx context.Context, orgID uuid.UUID) ([]*OrgMember, error)
+	UpdateMemberRole(ctx context.Context, orgID uuid.UUID, uid uuid.UUID, role string) error
+	RemoveMember(ctx context.Context, orgID uuid.UUID, uid uuid.UUID) error
+	CountOwners(ctx context.Context, orgID uuid.UUID) (int64, error)
+	CreateInvite(ctx context.Context, invite *OrgInvite) error
+	FindInvite(ctx context.Context, tokenHash string) (*OrgInvite, error)
+	AcceptInvite(ctx context.Context, inviteID uuid.UUID, uid uuid.UUID) (*OrgMember, error)
+}
+
+// ExportRepository defines methods it expects a repository
+// it interacts with to implement
+// SetPendingExport returns the export which is already being assembled
+// for the user, or uuid.Nil once exportID is
+type ExportRepository interface {
+	SetExport(ctx context.Context, export *Export, expiresIn time.Duration) error
+	GetExport(ctx context.Context, exportID uuid.UUID) (*Export, error)
+	GetExportByToken(ctx context.Context, downloadToken string) (*Export, error)
+	SetArchive(ctx context.Context, exportID uuid.UUID, archive []byte, expiresIn time.Duration) error
+	GetArchive(ctx context.Context, exportID uuid.UUID) ([]byte, error)
+	SetPendingExport(ctx context.Context, uid uuid.UUID, exportID uuid.UUID, expiresIn time.Duration) (uuid.UUID, error)
+	DeletePendingExport(ctx context.Context, uid uuid.UUID) error
+}
+
+// AuditRepository defines methods the service layer expects
+// the append-only audit log to implement
+type AuditRepository interface {
+	Create(ctx context.Context, event *AuditEvent) error
+	FindByUID(ctx context.Context, uid uuid.UUID, limit int, offset int) ([]*AuditEvent, error)
+}
+
+// DeviceRepository defines methods the service layer expects
+// any repository of known sign-in devices to implement
+// Upsert reports whether the device was not known before
+type DeviceRepository interface {
+	Upsert(ctx context.Context, device *KnownDevice) (bool, error)
+	Count(ctx context.Context, uid uuid.UUID) (int64, error)
+	FindByUID(ctx context.Context, uid uuid.UUID) ([]*KnownDevice, error)
+	DeleteByUID(ctx context.Context, uid uuid.UUID) error
+}
+
+// TermsRepository defines methods the service layer expects
+// any repository of terms of service acceptances to implement
+// Accept keeps the acceptance and returns the user with the accepted version
+type TermsRepository interface {
+	Accept(ctx context.Context, acceptance *TermsAcceptance) (*User, error)
+	FindByUID(ctx context.Context, uid uuid.UUID) ([]*TermsAcceptance, error)
+}
+
+// PasswordPolicy defines methods the service layer expects
+// any password policy it interacts with to implement
+// Validate checks a new password of the user and returns the rules it breaks
+// as apperrors.InvalidArgs
+type PasswordPolicy interface {
+	Validate(ctx context.Context, password string, user *User) error
+}
+
+// SignupPolicy defines methods the service layer expects
+// any sign-up policy it interacts with to implement
+// Admit decides whether an email may create an account and redeems the invite
+// code if one is required. A redeemed invite is released again with Release
+// when the account is not created after all
+type SignupPolicy interface {
+	Admit(ctx context.Context, email string, inviteCode string) (*Invite, error)
+	Release(ctx context.Context, invite *Invite) error
+}
+
+// Mailer defines methods the service layer expects
+// any mail transport it interacts with to implement
+type Mailer interface {
+	Send(ctx context.Context, email *Email) error
+}
+
+// Notifier defines methods the service layer expects
+// any way of telling users about security events to implement
+type Notifier interface {
+	NotifyNewDevice(ctx context.Context, notice *NewDeviceNotice) error
 }
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Invite allows signing up while sign-up is invite-only
+// Only the hash of its code is stored, the code is returned once when the invite is created
+type Invite struct {
+	ID        uuid.UUID  `db:"id" json:"id"`
+	Code      string     `db:"-" json:"code,omitempty"`
+	CodeHash  string     `db:"code_hash" json:"-"`
+	MaxUses   int        `db:"max_uses" json:"max_uses"`
+	Uses      int        `db:"uses" json:"uses"`
+	ExpiresAt *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	Revoked   bool       `db:"revoked" json:"revoked"`
+	CreatedBy *uuid.UUID `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+}
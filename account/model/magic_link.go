@@ -0,0 +1,8 @@
+package model
+
+// MagicLink is a pending passwordless sign-in for an email address
+// NonceHash binds the link to the browser which requested it
+type MagicLink struct {
+	Email     string `json:"email"`
+	NonceHash string `json:"nonce_hash"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// TOTP holds a user's RFC 6238 authenticator enrollment
+// It only protects sign-in once Confirmed
+type TOTP struct {
+	UID          uuid.UUID `db:"uid" json:"-"`
+	Secret       string    `db:"secret" json:"-"`
+	Confirmed    bool      `db:"confirmed" json:"confirmed"`
+	LastUsedStep int64     `db:"last_used_step" json:"-"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// TOTPEnrollment is returned once when a user starts enrolling an authenticator
+type TOTPEnrollment struct {
+	Secret    string `json:"secret"`
+	URI       string `json:"uri"`
+	QRCodePNG []byte `json:"qr_code_png"` // base64 encoded by encoding/json
+}
@@ -0,0 +1,43 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAuditRepository is a mock type for model.AuditRepository
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+// Create is a mock of AuditRepository.Create
+func (m *MockAuditRepository) Create(ctx context.Context, event *model.AuditEvent) error {
+	ret := m.Called(ctx, event)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FindByUID is a mock of AuditRepository.FindByUID
+func (m *MockAuditRepository) FindByUID(ctx context.Context, uid uuid.UUID, limit int, offset int) ([]*model.AuditEvent, error) {
+	ret := m.Called(ctx, uid, limit, offset)
+
+	var r0 []*model.AuditEvent
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.AuditEvent)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
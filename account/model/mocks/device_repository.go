@@ -0,0 +1,77 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockDeviceRepository is a mock type for model.DeviceRepository
+type MockDeviceRepository struct {
+	mock.Mock
+}
+
+// Upsert is a mock of DeviceRepository.Upsert
+func (m *MockDeviceRepository) Upsert(ctx context.Context, device *model.KnownDevice) (bool, error) {
+	ret := m.Called(ctx, device)
+
+	var r0 bool
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Count is a mock of DeviceRepository.Count
+func (m *MockDeviceRepository) Count(ctx context.Context, uid uuid.UUID) (int64, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 int64
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// FindByUID is a mock of DeviceRepository.FindByUID
+func (m *MockDeviceRepository) FindByUID(ctx context.Context, uid uuid.UUID) ([]*model.KnownDevice, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.KnownDevice
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.KnownDevice)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// DeleteByUID is a mock of DeviceRepository.DeleteByUID
+func (m *MockDeviceRepository) DeleteByUID(ctx context.Context, uid uuid.UUID) error {
+	ret := m.Called(ctx, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
@@ -0,0 +1,119 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockExportRepository is a mock type for model.ExportRepository
+type MockExportRepository struct {
+	mock.Mock
+}
+
+// SetExport is a mock of ExportRepository.SetExport
+func (m *MockExportRepository) SetExport(ctx context.Context, export *model.Export, expiresIn time.Duration) error {
+	ret := m.Called(ctx, export, expiresIn)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// GetExport is a mock of ExportRepository.GetExport
+func (m *MockExportRepository) GetExport(ctx context.Context, exportID uuid.UUID) (*model.Export, error) {
+	ret := m.Called(ctx, exportID)
+
+	var r0 *model.Export
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Export)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// GetExportByToken is a mock of ExportRepository.GetExportByToken
+func (m *MockExportRepository) GetExportByToken(ctx context.Context, downloadToken string) (*model.Export, error) {
+	ret := m.Called(ctx, downloadToken)
+
+	var r0 *model.Export
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Export)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SetArchive is a mock of ExportRepository.SetArchive
+func (m *MockExportRepository) SetArchive(ctx context.Context, exportID uuid.UUID, archive []byte, expiresIn time.Duration) error {
+	ret := m.Called(ctx, exportID, archive, expiresIn)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// GetArchive is a mock of ExportRepository.GetArchive
+func (m *MockExportRepository) GetArchive(ctx context.Context, exportID uuid.UUID) ([]byte, error) {
+	ret := m.Called(ctx, exportID)
+
+	var r0 []byte
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]byte)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SetPendingExport is a mock of ExportRepository.SetPendingExport
+func (m *MockExportRepository) SetPendingExport(ctx context.Context, uid uuid.UUID, exportID uuid.UUID, expiresIn time.Duration) (uuid.UUID, error) {
+	ret := m.Called(ctx, uid, exportID, expiresIn)
+
+	var r0 uuid.UUID
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(uuid.UUID)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// DeletePendingExport is a mock of ExportRepository.DeletePendingExport
+func (m *MockExportRepository) DeletePendingExport(ctx context.Context, uid uuid.UUID) error {
+	ret := m.Called(ctx, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
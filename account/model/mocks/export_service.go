@@ -0,0 +1,70 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockExportService is a mock type for model.ExportService
+type MockExportService struct {
+	mock.Mock
+}
+
+// Request is a mock of ExportService.Request
+func (m *MockExportService) Request(ctx context.Context, uid uuid.UUID) (*model.Export, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 *model.Export
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Export)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Get is a mock of ExportService.Get
+func (m *MockExportService) Get(ctx context.Context, uid uuid.UUID, exportID uuid.UUID) (*model.Export, error) {
+	ret := m.Called(ctx, uid, exportID)
+
+	var r0 *model.Export
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Export)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Download is a mock of ExportService.Download
+func (m *MockExportService) Download(ctx context.Context, downloadToken string) (*model.Export, []byte, error) {
+	ret := m.Called(ctx, downloadToken)
+
+	var r0 *model.Export
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Export)
+	}
+
+	var r1 []byte
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).([]byte)
+	}
+
+	var r2 error
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+
+	return r0, r1, r2
+}
@@ -0,0 +1,72 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockIdentityRepository is a mock type for model.IdentityRepository
+type MockIdentityRepository struct {
+	mock.Mock
+}
+
+// Create is a mock of IdentityRepository.Create
+func (m *MockIdentityRepository) Create(ctx context.Context, identity *model.Identity) error {
+	ret := m.Called(ctx, identity)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Find is a mock of IdentityRepository.Find
+func (m *MockIdentityRepository) Find(ctx context.Context, provider string, subject string) (*model.Identity, error) {
+	ret := m.Called(ctx, provider, subject)
+
+	var r0 *model.Identity
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Identity)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// FindByUID is a mock of IdentityRepository.FindByUID
+func (m *MockIdentityRepository) FindByUID(ctx context.Context, uid uuid.UUID) ([]*model.Identity, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.Identity
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Identity)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Delete is a mock of IdentityRepository.Delete
+func (m *MockIdentityRepository) Delete(ctx context.Context, uid uuid.UUID, provider string) error {
+	ret := m.Called(ctx, uid, provider)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
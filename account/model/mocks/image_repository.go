@@ -3,6 +3,7 @@ package mocks
 import (
 	"context"
 	"github.com/stretchr/testify/mock"
+	"io"
 	"mime/multipart"
 )
 
@@ -43,3 +44,20 @@ func (m *MockImageRepository) DeleteProfile(ctx context.Context, objName string)
 
 	return r0
 }
+
+// GetProfile is mock of representations of ImageRepository GetProfile
+func (m *MockImageRepository) GetProfile(ctx context.Context, objName string) (io.ReadCloser, error) {
+	ret := m.Called(ctx, objName)
+
+	var r0 io.ReadCloser
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(io.ReadCloser)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,84 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockInviteRepository is a mock type for model.InviteRepository
+type MockInviteRepository struct {
+	mock.Mock
+}
+
+// Create is a mock of InviteRepository.Create
+func (m *MockInviteRepository) Create(ctx context.Context, invite *model.Invite) error {
+	ret := m.Called(ctx, invite)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FindAll is a mock of InviteRepository.FindAll
+func (m *MockInviteRepository) FindAll(ctx context.Context) ([]*model.Invite, error) {
+	ret := m.Called(ctx)
+
+	var r0 []*model.Invite
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Invite)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Revoke is a mock of InviteRepository.Revoke
+func (m *MockInviteRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	ret := m.Called(ctx, id)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Redeem is a mock of InviteRepository.Redeem
+func (m *MockInviteRepository) Redeem(ctx context.Context, codeHash string) (*model.Invite, error) {
+	ret := m.Called(ctx, codeHash)
+
+	var r0 *model.Invite
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Invite)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Release is a mock of InviteRepository.Release
+func (m *MockInviteRepository) Release(ctx context.Context, id uuid.UUID) error {
+	ret := m.Called(ctx, id)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
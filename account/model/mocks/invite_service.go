@@ -0,0 +1,61 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+// MockInviteService is a mock type for model.InviteService
+type MockInviteService struct {
+	mock.Mock
+}
+
+// Create is a mock of InviteService.Create
+func (m *MockInviteService) Create(ctx context.Context, createdBy uuid.UUID, maxUses int, expiresIn time.Duration) (*model.Invite, error) {
+	ret := m.Called(ctx, createdBy, maxUses, expiresIn)
+
+	var r0 *model.Invite
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Invite)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// List is a mock of InviteService.List
+func (m *MockInviteService) List(ctx context.Context) ([]*model.Invite, error) {
+	ret := m.Called(ctx)
+
+	var r0 []*model.Invite
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Invite)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Revoke is a mock of InviteService.Revoke
+func (m *MockInviteService) Revoke(ctx context.Context, id uuid.UUID) error {
+	ret := m.Called(ctx, id)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
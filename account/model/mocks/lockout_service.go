@@ -0,0 +1,84 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockLockoutService is a mock type for model.LockoutService
+type MockLockoutService struct {
+	mock.Mock
+}
+
+// Check is a mock of LockoutService.Check
+func (m *MockLockoutService) Check(ctx context.Context, email string, ip string) error {
+	ret := m.Called(ctx, email, ip)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// CheckGuest is a mock of LockoutService.CheckGuest
+func (m *MockLockoutService) CheckGuest(ctx context.Context, ip string) error {
+	ret := m.Called(ctx, ip)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// RecordFailure is a mock of LockoutService.RecordFailure
+func (m *MockLockoutService) RecordFailure(ctx context.Context, email string, ip string) error {
+	ret := m.Called(ctx, email, ip)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// RecordSuccess is a mock of LockoutService.RecordSuccess
+func (m *MockLockoutService) RecordSuccess(ctx context.Context, email string) error {
+	ret := m.Called(ctx, email)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Unlock is a mock of LockoutService.Unlock
+func (m *MockLockoutService) Unlock(ctx context.Context, token string) error {
+	ret := m.Called(ctx, token)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// UnlockAccount is a mock of LockoutService.UnlockAccount
+func (m *MockLockoutService) UnlockAccount(ctx context.Context, email string) error {
+	ret := m.Called(ctx, email)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
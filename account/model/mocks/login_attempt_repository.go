@@ -0,0 +1,117 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockLoginAttemptRepository is a mock type for model.LoginAttemptRepository
+type MockLoginAttemptRepository struct {
+	mock.Mock
+}
+
+// IncrementFailures is a mock of LoginAttemptRepository.IncrementFailures
+func (m *MockLoginAttemptRepository) IncrementFailures(ctx context.Context, key string, window time.Duration) (int64, error) {
+	ret := m.Called(ctx, key, window)
+
+	var r0 int64
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// IncrementCount is a mock of LoginAttemptRepository.IncrementCount
+func (m *MockLoginAttemptRepository) IncrementCount(ctx context.Context, key string, window time.Duration) (int64, error) {
+	ret := m.Called(ctx, key, window)
+
+	var r0 int64
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ResetFailures is a mock of LoginAttemptRepository.ResetFailures
+func (m *MockLoginAttemptRepository) ResetFailures(ctx context.Context, key string) error {
+	ret := m.Called(ctx, key)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Block is a mock of LoginAttemptRepository.Block
+func (m *MockLoginAttemptRepository) Block(ctx context.Context, key string, duration time.Duration) error {
+	ret := m.Called(ctx, key, duration)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// BlockedFor is a mock of LoginAttemptRepository.BlockedFor
+func (m *MockLoginAttemptRepository) BlockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ret := m.Called(ctx, key)
+
+	var r0 time.Duration
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SetUnlockToken is a mock of LoginAttemptRepository.SetUnlockToken
+func (m *MockLoginAttemptRepository) SetUnlockToken(ctx context.Context, token string, email string, expiresIn time.Duration) error {
+	ret := m.Called(ctx, token, email, expiresIn)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// GetUnlockToken is a mock of LoginAttemptRepository.GetUnlockToken
+func (m *MockLoginAttemptRepository) GetUnlockToken(ctx context.Context, token string) (string, error) {
+	ret := m.Called(ctx, token)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,47 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockMagicLinkService is a mock type for model.MagicLinkService
+type MockMagicLinkService struct {
+	mock.Mock
+}
+
+// Send is a mock of MagicLinkService.Send
+func (m *MockMagicLinkService) Send(ctx context.Context, email string) (string, error) {
+	ret := m.Called(ctx, email)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Verify is a mock of MagicLinkService.Verify
+func (m *MockMagicLinkService) Verify(ctx context.Context, token string, nonce string) (*model.User, error) {
+	ret := m.Called(ctx, token, nonce)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockMailer is a mock type for model.Mailer
+type MockMailer struct {
+	mock.Mock
+}
+
+// Send is a mock of Mailer.Send
+func (m *MockMailer) Send(ctx context.Context, email *model.Email) error {
+	ret := m.Called(ctx, email)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
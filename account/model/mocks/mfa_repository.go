@@ -0,0 +1,101 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockMFARepository is a mock type for model.MFARepository
+type MockMFARepository struct {
+	mock.Mock
+}
+
+// SetTOTP is a mock of MFARepository.SetTOTP
+func (m *MockMFARepository) SetTOTP(ctx context.Context, totp *model.TOTP) error {
+	ret := m.Called(ctx, totp)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FindTOTP is a mock of MFARepository.FindTOTP
+func (m *MockMFARepository) FindTOTP(ctx context.Context, uid uuid.UUID) (*model.TOTP, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 *model.TOTP
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.TOTP)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ConfirmTOTP is a mock of MFARepository.ConfirmTOTP
+func (m *MockMFARepository) ConfirmTOTP(ctx context.Context, uid uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	ret := m.Called(ctx, uid, step, recoveryCodeHashes)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// UpdateTOTPStep is a mock of MFARepository.UpdateTOTPStep
+func (m *MockMFARepository) UpdateTOTPStep(ctx context.Context, uid uuid.UUID, step int64) (bool, error) {
+	ret := m.Called(ctx, uid, step)
+
+	var r0 bool
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// DeleteTOTP is a mock of MFARepository.DeleteTOTP
+func (m *MockMFARepository) DeleteTOTP(ctx context.Context, uid uuid.UUID) error {
+	ret := m.Called(ctx, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// UseRecoveryCode is a mock of MFARepository.UseRecoveryCode
+func (m *MockMFARepository) UseRecoveryCode(ctx context.Context, uid uuid.UUID, codeHash string) (bool, error) {
+	ret := m.Called(ctx, uid, codeHash)
+
+	var r0 bool
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,128 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockMFAService is a mock type for model.MFAService
+type MockMFAService struct {
+	mock.Mock
+}
+
+// EnrollTOTP is a mock of MFAService.EnrollTOTP
+func (m *MockMFAService) EnrollTOTP(ctx context.Context, uid uuid.UUID) (*model.TOTPEnrollment, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 *model.TOTPEnrollment
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.TOTPEnrollment)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ConfirmTOTP is a mock of MFAService.ConfirmTOTP
+func (m *MockMFAService) ConfirmTOTP(ctx context.Context, uid uuid.UUID, code string) ([]string, error) {
+	ret := m.Called(ctx, uid, code)
+
+	var r0 []string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// DisableTOTP is a mock of MFAService.DisableTOTP
+func (m *MockMFAService) DisableTOTP(ctx context.Context, uid uuid.UUID, password string) error {
+	ret := m.Called(ctx, uid, password)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// IsEnrolled is a mock of MFAService.IsEnrolled
+func (m *MockMFAService) IsEnrolled(ctx context.Context, uid uuid.UUID) (bool, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 bool
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// NewChallenge is a mock of MFAService.NewChallenge
+func (m *MockMFAService) NewChallenge(ctx context.Context, uid uuid.UUID) (string, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ChallengeUser is a mock of MFAService.ChallengeUser
+func (m *MockMFAService) ChallengeUser(ctx context.Context, challengeToken string) (*model.User, error) {
+	ret := m.Called(ctx, challengeToken)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// VerifyChallenge is a mock of MFAService.VerifyChallenge
+func (m *MockMFAService) VerifyChallenge(ctx context.Context, challengeToken string, code string) (*model.User, error) {
+	ret := m.Called(ctx, challengeToken, code)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockNotifier is a mock type for model.Notifier
+type MockNotifier struct {
+	mock.Mock
+}
+
+// NotifyNewDevice is a mock of Notifier.NotifyNewDevice
+func (m *MockNotifier) NotifyNewDevice(ctx context.Context, notice *model.NewDeviceNotice) error {
+	ret := m.Called(ctx, notice)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
@@ -0,0 +1,111 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockOAuthService is a mock type for model.OAuthService
+type MockOAuthService struct {
+	mock.Mock
+}
+
+// Providers is a mock of OAuthService.Providers
+func (m *MockOAuthService) Providers() []string {
+	ret := m.Called()
+
+	var r0 []string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]string)
+	}
+
+	return r0
+}
+
+// AuthCodeURL is a mock of OAuthService.AuthCodeURL
+func (m *MockOAuthService) AuthCodeURL(ctx context.Context, provider string, uid uuid.UUID) (string, string, error) {
+	ret := m.Called(ctx, provider, uid)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 string
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(string)
+	}
+
+	var r2 error
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+
+	return r0, r1, r2
+}
+
+// Signin is a mock of OAuthService.Signin
+func (m *MockOAuthService) Signin(ctx context.Context, provider string, code string, state string, nonce string) (*model.User, error) {
+	ret := m.Called(ctx, provider, code, state, nonce)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Link is a mock of OAuthService.Link
+func (m *MockOAuthService) Link(ctx context.Context, uid uuid.UUID, provider string, code string, state string, nonce string) (*model.Identity, error) {
+	ret := m.Called(ctx, uid, provider, code, state, nonce)
+
+	var r0 *model.Identity
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Identity)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ListIdentities is a mock of OAuthService.ListIdentities
+func (m *MockOAuthService) ListIdentities(ctx context.Context, uid uuid.UUID) ([]*model.Identity, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.Identity
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Identity)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Unlink is a mock of OAuthService.Unlink
+func (m *MockOAuthService) Unlink(ctx context.Context, uid uuid.UUID, provider string) error {
+	ret := m.Called(ctx, uid, provider)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
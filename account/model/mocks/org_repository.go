@@ -0,0 +1,181 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockOrgRepository is a mock type for model.OrgRepository
+type MockOrgRepository struct {
+	mock.Mock
+}
+
+// Create is a mock of OrgRepository.Create
+func (m *MockOrgRepository) Create(ctx context.Context, org *model.Organization, owner uuid.UUID) error {
+	ret := m.Called(ctx, org, owner)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FindByID is a mock of OrgRepository.FindByID
+func (m *MockOrgRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.Organization, error) {
+	ret := m.Called(ctx, id)
+
+	var r0 *model.Organization
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Organization)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// FindByUID is a mock of OrgRepository.FindByUID
+func (m *MockOrgRepository) FindByUID(ctx context.Context, uid uuid.UUID) ([]*model.Organization, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.Organization
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Organization)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// FindMember is a mock of OrgRepository.FindMember
+func (m *MockOrgRepository) FindMember(ctx context.Context, orgID uuid.UUID, uid uuid.UUID) (*model.OrgMember, error) {
+	ret := m.Called(ctx, orgID, uid)
+
+	var r0 *model.OrgMember
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OrgMember)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// FindMembers is a mock of OrgRepository.FindMembers
+func (m *MockOrgRepository) FindMembers(ctx context.Context, orgID uuid.UUID) ([]*model.OrgMember, error) {
+	ret := m.Called(ctx, orgID)
+
+	var r0 []*model.OrgMember
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.OrgMember)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// UpdateMemberRole is a mock of OrgRepository.UpdateMemberRole
+func (m *MockOrgRepository) UpdateMemberRole(ctx context.Context, orgID uuid.UUID, uid uuid.UUID, role string) error {
+	ret := m.Called(ctx, orgID, uid, role)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// RemoveMember is a mock of OrgRepository.RemoveMember
+func (m *MockOrgRepository) RemoveMember(ctx context.Context, orgID uuid.UUID, uid uuid.UUID) error {
+	ret := m.Called(ctx, orgID, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// CountOwners is a mock of OrgRepository.CountOwners
+func (m *MockOrgRepository) CountOwners(ctx context.Context, orgID uuid.UUID) (int64, error) {
+	ret := m.Called(ctx, orgID)
+
+	var r0 int64
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// CreateInvite is a mock of OrgRepository.CreateInvite
+func (m *MockOrgRepository) CreateInvite(ctx context.Context, invite *model.OrgInvite) error {
+	ret := m.Called(ctx, invite)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FindInvite is a mock of OrgRepository.FindInvite
+func (m *MockOrgRepository) FindInvite(ctx context.Context, tokenHash string) (*model.OrgInvite, error) {
+	ret := m.Called(ctx, tokenHash)
+
+	var r0 *model.OrgInvite
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OrgInvite)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// AcceptInvite is a mock of OrgRepository.AcceptInvite
+func (m *MockOrgRepository) AcceptInvite(ctx context.Context, inviteID uuid.UUID, uid uuid.UUID) (*model.OrgMember, error) {
+	ret := m.Called(ctx, inviteID, uid)
+
+	var r0 *model.OrgMember
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OrgMember)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
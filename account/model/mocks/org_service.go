@@ -0,0 +1,140 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockOrgService is a mock type for model.OrgService
+type MockOrgService struct {
+	mock.Mock
+}
+
+// Create is a mock of OrgService.Create
+func (m *MockOrgService) Create(ctx context.Context, uid uuid.UUID, name string) (*model.Organization, error) {
+	ret := m.Called(ctx, uid, name)
+
+	var r0 *model.Organization
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Organization)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// List is a mock of OrgService.List
+func (m *MockOrgService) List(ctx context.Context, uid uuid.UUID) ([]*model.Organization, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.Organization
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Organization)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Members is a mock of OrgService.Members
+func (m *MockOrgService) Members(ctx context.Context, uid uuid.UUID, orgID uuid.UUID) ([]*model.OrgMember, error) {
+	ret := m.Called(ctx, uid, orgID)
+
+	var r0 []*model.OrgMember
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.OrgMember)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Invite is a mock of OrgService.Invite
+func (m *MockOrgService) Invite(ctx context.Context, actor uuid.UUID, orgID uuid.UUID, email string, role string) (*model.OrgInvite, error) {
+	ret := m.Called(ctx, actor, orgID, email, role)
+
+	var r0 *model.OrgInvite
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OrgInvite)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// AcceptInvite is a mock of OrgService.AcceptInvite
+func (m *MockOrgService) AcceptInvite(ctx context.Context, uid uuid.UUID, token string) (*model.Organization, error) {
+	ret := m.Called(ctx, uid, token)
+
+	var r0 *model.Organization
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Organization)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// RemoveMember is a mock of OrgService.RemoveMember
+func (m *MockOrgService) RemoveMember(ctx context.Context, actor uuid.UUID, orgID uuid.UUID, uid uuid.UUID) error {
+	ret := m.Called(ctx, actor, orgID, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// SetMemberRole is a mock of OrgService.SetMemberRole
+func (m *MockOrgService) SetMemberRole(ctx context.Context, actor uuid.UUID, orgID uuid.UUID, uid uuid.UUID, role string) error {
+	ret := m.Called(ctx, actor, orgID, uid, role)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Switch is a mock of OrgService.Switch
+func (m *MockOrgService) Switch(ctx context.Context, uid uuid.UUID, orgID *uuid.UUID) (*model.User, error) {
+	ret := m.Called(ctx, uid, orgID)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
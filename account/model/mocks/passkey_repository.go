@@ -0,0 +1,84 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPasskeyRepository is a mock type for model.PasskeyRepository
+type MockPasskeyRepository struct {
+	mock.Mock
+}
+
+// Create is a mock of PasskeyRepository.Create
+func (m *MockPasskeyRepository) Create(ctx context.Context, credential *model.PasskeyCredential) error {
+	ret := m.Called(ctx, credential)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FindByID is a mock of PasskeyRepository.FindByID
+func (m *MockPasskeyRepository) FindByID(ctx context.Context, credentialID []byte) (*model.PasskeyCredential, error) {
+	ret := m.Called(ctx, credentialID)
+
+	var r0 *model.PasskeyCredential
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.PasskeyCredential)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// FindByUID is a mock of PasskeyRepository.FindByUID
+func (m *MockPasskeyRepository) FindByUID(ctx context.Context, uid uuid.UUID) ([]*model.PasskeyCredential, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.PasskeyCredential
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.PasskeyCredential)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// UpdateSignCount is a mock of PasskeyRepository.UpdateSignCount
+func (m *MockPasskeyRepository) UpdateSignCount(ctx context.Context, credentialID []byte, signCount uint32) error {
+	ret := m.Called(ctx, credentialID, signCount)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Delete is a mock of PasskeyRepository.Delete
+func (m *MockPasskeyRepository) Delete(ctx context.Context, uid uuid.UUID, credentialID []byte) error {
+	ret := m.Called(ctx, uid, credentialID)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
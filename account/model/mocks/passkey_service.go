@@ -0,0 +1,121 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPasskeyService is a mock type for model.PasskeyService
+type MockPasskeyService struct {
+	mock.Mock
+}
+
+// BeginRegistration is a mock of PasskeyService.BeginRegistration
+func (m *MockPasskeyService) BeginRegistration(ctx context.Context, uid uuid.UUID) (string, *model.PasskeyCreationOptions, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 *model.PasskeyCreationOptions
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(*model.PasskeyCreationOptions)
+	}
+
+	var r2 error
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+
+	return r0, r1, r2
+}
+
+// FinishRegistration is a mock of PasskeyService.FinishRegistration
+func (m *MockPasskeyService) FinishRegistration(ctx context.Context, uid uuid.UUID, sessionID string, name string, attestation *model.PasskeyAttestation) (*model.PasskeyCredential, error) {
+	ret := m.Called(ctx, uid, sessionID, name, attestation)
+
+	var r0 *model.PasskeyCredential
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.PasskeyCredential)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// BeginLogin is a mock of PasskeyService.BeginLogin
+func (m *MockPasskeyService) BeginLogin(ctx context.Context, email string) (string, *model.PasskeyRequestOptions, error) {
+	ret := m.Called(ctx, email)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 *model.PasskeyRequestOptions
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(*model.PasskeyRequestOptions)
+	}
+
+	var r2 error
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+
+	return r0, r1, r2
+}
+
+// FinishLogin is a mock of PasskeyService.FinishLogin
+func (m *MockPasskeyService) FinishLogin(ctx context.Context, sessionID string, assertion *model.PasskeyAssertion) (*model.User, error) {
+	ret := m.Called(ctx, sessionID, assertion)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ListCredentials is a mock of PasskeyService.ListCredentials
+func (m *MockPasskeyService) ListCredentials(ctx context.Context, uid uuid.UUID) ([]*model.PasskeyCredential, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.PasskeyCredential
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.PasskeyCredential)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// DeleteCredential is a mock of PasskeyService.DeleteCredential
+func (m *MockPasskeyService) DeleteCredential(ctx context.Context, uid uuid.UUID, credentialID []byte) error {
+	ret := m.Called(ctx, uid, credentialID)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPasswordPolicy is a mock type for model.PasswordPolicy
+type MockPasswordPolicy struct {
+	mock.Mock
+}
+
+// Validate is a mock of PasswordPolicy.Validate
+func (m *MockPasswordPolicy) Validate(ctx context.Context, password string, user *model.User) error {
+	ret := m.Called(ctx, password, user)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
@@ -0,0 +1,71 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRoleRepository is a mock type for model.RoleRepository
+type MockRoleRepository struct {
+	mock.Mock
+}
+
+// FindByUID is a mock of RoleRepository.FindByUID
+func (m *MockRoleRepository) FindByUID(ctx context.Context, uid uuid.UUID) ([]string, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// CountUsers is a mock of RoleRepository.CountUsers
+func (m *MockRoleRepository) CountUsers(ctx context.Context, role string) (int64, error) {
+	ret := m.Called(ctx, role)
+
+	var r0 int64
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Grant is a mock of RoleRepository.Grant
+func (m *MockRoleRepository) Grant(ctx context.Context, uid uuid.UUID, role string, grantedBy *uuid.UUID) error {
+	ret := m.Called(ctx, uid, role, grantedBy)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Revoke is a mock of RoleRepository.Revoke
+func (m *MockRoleRepository) Revoke(ctx context.Context, uid uuid.UUID, role string) error {
+	ret := m.Called(ctx, uid, role)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
@@ -0,0 +1,66 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRoleService is a mock type for model.RoleService
+type MockRoleService struct {
+	mock.Mock
+}
+
+// List is a mock of RoleService.List
+func (m *MockRoleService) List(ctx context.Context, uid uuid.UUID) ([]string, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Grant is a mock of RoleService.Grant
+func (m *MockRoleService) Grant(ctx context.Context, actor uuid.UUID, uid uuid.UUID, role string) error {
+	ret := m.Called(ctx, actor, uid, role)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Revoke is a mock of RoleService.Revoke
+func (m *MockRoleService) Revoke(ctx context.Context, actor uuid.UUID, uid uuid.UUID, role string) error {
+	ret := m.Called(ctx, actor, uid, role)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Bootstrap is a mock of RoleService.Bootstrap
+func (m *MockRoleService) Bootstrap(ctx context.Context, adminEmails []string) error {
+	ret := m.Called(ctx, adminEmails)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
@@ -0,0 +1,42 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSignupPolicy is a mock type for model.SignupPolicy
+type MockSignupPolicy struct {
+	mock.Mock
+}
+
+// Admit is a mock of SignupPolicy.Admit
+func (m *MockSignupPolicy) Admit(ctx context.Context, email string, inviteCode string) (*model.Invite, error) {
+	ret := m.Called(ctx, email, inviteCode)
+
+	var r0 *model.Invite
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Invite)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Release is a mock of SignupPolicy.Release
+func (m *MockSignupPolicy) Release(ctx context.Context, invite *model.Invite) error {
+	ret := m.Called(ctx, invite)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
@@ -0,0 +1,48 @@
+package mocks
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockTermsRepository is a mock type for model.TermsRepository
+type MockTermsRepository struct {
+	mock.Mock
+}
+
+// Accept is a mock of TermsRepository.Accept
+func (m *MockTermsRepository) Accept(ctx context.Context, acceptance *model.TermsAcceptance) (*model.User, error) {
+	ret := m.Called(ctx, acceptance)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// FindByUID is a mock of TermsRepository.FindByUID
+func (m *MockTermsRepository) FindByUID(ctx context.Context, uid uuid.UUID) ([]*model.TermsAcceptance, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []*model.TermsAcceptance
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.TermsAcceptance)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
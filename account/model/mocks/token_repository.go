@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
 	"github.com/stretchr/testify/mock"
 	"time"
 )
@@ -50,3 +51,287 @@ func (m *MockTokenRepository) DeleteUserRefreshToken(ctx context.Context, userID
 
 	return r0
 }
+
+// GetUserSessions mocks concrete GetUserSessions
+func (m *MockTokenRepository) GetUserSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	ret := m.Called(ctx, userID)
+
+	var r0 []*model.Session
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Session)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SetMFAChallenge is a mock of model.TokenRepository.SetMFAChallenge
+func (m *MockTokenRepository) SetMFAChallenge(ctx context.Context, challengeID string, userID string, expiresIn time.Duration) error {
+	ret := m.Called(ctx, challengeID, userID, expiresIn)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// GetMFAChallenge is a mock of model.TokenRepository.GetMFAChallenge
+func (m *MockTokenRepository) GetMFAChallenge(ctx context.Context, challengeID string) (string, error) {
+	ret := m.Called(ctx, challengeID)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// IncrementMFAChallengeAttempts is a mock of model.TokenRepository.IncrementMFAChallengeAttempts
+func (m *MockTokenRepository) IncrementMFAChallengeAttempts(ctx context.Context, challengeID string) (int64, error) {
+	ret := m.Called(ctx, challengeID)
+
+	var r0 int64
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// DeleteMFAChallenge is a mock of model.TokenRepository.DeleteMFAChallenge
+func (m *MockTokenRepository) DeleteMFAChallenge(ctx context.Context, challengeID string) error {
+	ret := m.Called(ctx, challengeID)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// SetWebAuthnSession is a mock of model.TokenRepository.SetWebAuthnSession
+func (m *MockTokenRepository) SetWebAuthnSession(ctx context.Context, sessionID string, session *model.WebAuthnSession, expiresIn time.Duration) error {
+	ret := m.Called(ctx, sessionID, session, expiresIn)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// GetWebAuthnSession is a mock of model.TokenRepository.GetWebAuthnSession
+func (m *MockTokenRepository) GetWebAuthnSession(ctx context.Context, sessionID string) (*model.WebAuthnSession, error) {
+	ret := m.Called(ctx, sessionID)
+
+	var r0 *model.WebAuthnSession
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.WebAuthnSession)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SetMagicLink is a mock of model.TokenRepository.SetMagicLink
+func (m *MockTokenRepository) SetMagicLink(ctx context.Context, token string, link *model.MagicLink, expiresIn time.Duration) error {
+	ret := m.Called(ctx, token, link, expiresIn)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// GetMagicLink is a mock of model.TokenRepository.GetMagicLink
+func (m *MockTokenRepository) GetMagicLink(ctx context.Context, token string) (*model.MagicLink, error) {
+	ret := m.Called(ctx, token)
+
+	var r0 *model.MagicLink
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.MagicLink)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// IncrementMagicLinkRequests is a mock of model.TokenRepository.IncrementMagicLinkRequests
+func (m *MockTokenRepository) IncrementMagicLinkRequests(ctx context.Context, email string, window time.Duration) (int64, error) {
+	ret := m.Called(ctx, email, window)
+
+	var r0 int64
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SetOAuthState is a mock of model.TokenRepository.SetOAuthState
+func (m *MockTokenRepository) SetOAuthState(ctx context.Context, state string, oauthState *model.OAuthState, expiresIn time.Duration) error {
+	ret := m.Called(ctx, state, oauthState, expiresIn)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// GetOAuthState is a mock of model.TokenRepository.GetOAuthState
+func (m *MockTokenRepository) GetOAuthState(ctx context.Context, state string) (*model.OAuthState, error) {
+	ret := m.Called(ctx, state)
+
+	var r0 *model.OAuthState
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OAuthState)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SetPasswordResetToken is a mock of model.TokenRepository.SetPasswordResetToken
+func (m *MockTokenRepository) SetPasswordResetToken(ctx context.Context, token string, userID string, expiresIn time.Duration) error {
+	ret := m.Called(ctx, token, userID, expiresIn)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// PeekPasswordResetToken is a mock of model.TokenRepository.PeekPasswordResetToken
+func (m *MockTokenRepository) PeekPasswordResetToken(ctx context.Context, token string) (string, error) {
+	ret := m.Called(ctx, token)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// GetPasswordResetToken is a mock of model.TokenRepository.GetPasswordResetToken
+func (m *MockTokenRepository) GetPasswordResetToken(ctx context.Context, token string) (string, error) {
+	ret := m.Called(ctx, token)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SetAccessTokensRevokedAt is a mock of TokenRepository.SetAccessTokensRevokedAt
+func (m *MockTokenRepository) SetAccessTokensRevokedAt(ctx context.Context, userID string, revokedAt time.Time, expiresIn time.Duration) error {
+	ret := m.Called(ctx, userID, revokedAt, expiresIn)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// GetAccessTokensRevokedAt is a mock of TokenRepository.GetAccessTokensRevokedAt
+func (m *MockTokenRepository) GetAccessTokensRevokedAt(ctx context.Context, userID string) (time.Time, error) {
+	ret := m.Called(ctx, userID)
+
+	var r0 time.Time
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(time.Time)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SetDeviceRevokeToken is a mock of TokenRepository.SetDeviceRevokeToken
+func (m *MockTokenRepository) SetDeviceRevokeToken(ctx context.Context, token string, userID string, expiresIn time.Duration) error {
+	ret := m.Called(ctx, token, userID, expiresIn)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// GetDeviceRevokeToken is a mock of TokenRepository.GetDeviceRevokeToken
+func (m *MockTokenRepository) GetDeviceRevokeToken(ctx context.Context, token string) (string, error) {
+	ret := m.Called(ctx, token)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -45,8 +45,8 @@ func (m *MockTokenService) Signout(ctx context.Context, uid uuid.UUID) error {
 }
 
 // ValidateIDToken mocks concrete ValidateIDToken
-func (m *MockTokenService) ValidateIDToken(tokenString string) (*model.User, error) {
-	ret := m.Called(tokenString)
+func (m *MockTokenService) ValidateIDToken(ctx context.Context, tokenString string) (*model.User, error) {
+	ret := m.Called(ctx, tokenString)
 
 	// first value passed to "Return"
 	var r0 *model.User
@@ -81,3 +81,15 @@ func (m *MockTokenService) ValidateRefreshToken(refreshTokenString string) (*mod
 
 	return r0, r1
 }
+
+// Revoke is a mock of TokenService.Revoke
+func (m *MockTokenService) Revoke(ctx context.Context, uid uuid.UUID) error {
+	ret := m.Called(ctx, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/dolong2110/memorization-apps/account/model"
 	"github.com/google/uuid"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -94,3 +95,182 @@ func (m *MockUserRepository) UpdateImage(
 
 	return r0, r1
 }
+
+// UpdatePassword is a mock of UserRepository.UpdatePassword
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error {
+	ret := m.Called(ctx, uid, password)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FindByHandle is a mock of UserRepository.FindByHandle
+func (m *MockUserRepository) FindByHandle(ctx context.Context, handle string) (*model.User, error) {
+	ret := m.Called(ctx, handle)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// UpdateHandle is a mock of UserRepository.UpdateHandle
+func (m *MockUserRepository) UpdateHandle(ctx context.Context, uid uuid.UUID, handle string) (*model.User, error) {
+	ret := m.Called(ctx, uid, handle)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// List is a mock of UserRepository.List
+func (m *MockUserRepository) List(ctx context.Context, filter *model.UserFilter) ([]*model.User, error) {
+	ret := m.Called(ctx, filter)
+
+	var r0 []*model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Count is a mock of UserRepository.Count
+func (m *MockUserRepository) Count(ctx context.Context, filter *model.UserFilter) (int64, error) {
+	ret := m.Called(ctx, filter)
+
+	var r0 int64
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Search is a mock of UserRepository.Search
+func (m *MockUserRepository) Search(ctx context.Context, query string, limit int) ([]*model.User, error) {
+	ret := m.Called(ctx, query, limit)
+
+	var r0 []*model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// UpdateStatus is a mock of UserRepository.UpdateStatus
+func (m *MockUserRepository) UpdateStatus(ctx context.Context, user *model.User) error {
+	ret := m.Called(ctx, user)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// UpdateActiveOrg is a mock of UserRepository.UpdateActiveOrg
+func (m *MockUserRepository) UpdateActiveOrg(ctx context.Context, uid uuid.UUID, orgID *uuid.UUID) (*model.User, error) {
+	ret := m.Called(ctx, uid, orgID)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// UpdateLastSeen is a mock of UserRepository.UpdateLastSeen
+func (m *MockUserRepository) UpdateLastSeen(ctx context.Context, uid uuid.UUID, seenAt time.Time) error {
+	ret := m.Called(ctx, uid, seenAt)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Delete is a mock of UserRepository.Delete
+func (m *MockUserRepository) Delete(ctx context.Context, uid uuid.UUID) error {
+	ret := m.Called(ctx, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// UpgradeGuest is a mock of UserRepository.UpgradeGuest
+func (m *MockUserRepository) UpgradeGuest(ctx context.Context, user *model.User) error {
+	ret := m.Called(ctx, user)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// DeleteGuests is a mock of UserRepository.DeleteGuests
+func (m *MockUserRepository) DeleteGuests(ctx context.Context, seenBefore time.Time) ([]*model.User, error) {
+	ret := m.Called(ctx, seenBefore)
+
+	var r0 []*model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -3,7 +3,9 @@ package mocks
 import (
 	"context"
 	"github.com/dolong2110/memorization-apps/account/model"
+	"io"
 	"mime/multipart"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
@@ -60,6 +62,11 @@ func (m *MockUserService) Signin(ctx context.Context, u *model.User) error {
 	return r0
 }
 
+// RecordSignin is a mock of UserService.RecordSignin
+func (m *MockUserService) RecordSignin(ctx context.Context, u *model.User, method string) {
+	m.Called(ctx, u, method)
+}
+
 // UpdateDetails is a mock of UserService.UpdateDetails
 func (m *MockUserService) UpdateDetails(ctx context.Context, u *model.User) error {
 	ret := m.Called(ctx, u)
@@ -106,3 +113,277 @@ func (m *MockUserService) DeleteProfileImage(ctx context.Context, uid uuid.UUID)
 
 	return r0
 }
+
+// ProfileImage is a mock of UserService.ProfileImage
+func (m *MockUserService) ProfileImage(ctx context.Context, objName string) (io.ReadCloser, error) {
+	ret := m.Called(ctx, objName)
+
+	var r0 io.ReadCloser
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(io.ReadCloser)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ChangePassword is a mock of UserService.ChangePassword
+func (m *MockUserService) ChangePassword(ctx context.Context, uid uuid.UUID, currentPassword string, newPassword string) error {
+	ret := m.Called(ctx, uid, currentPassword, newPassword)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// RequestPasswordReset is a mock of UserService.RequestPasswordReset
+func (m *MockUserService) RequestPasswordReset(ctx context.Context, email string) error {
+	ret := m.Called(ctx, email)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// ResetPassword is a mock of UserService.ResetPassword
+func (m *MockUserService) ResetPassword(ctx context.Context, token string, password string) (*model.User, error) {
+	ret := m.Called(ctx, token, password)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// NotifySignupAttempt is a mock of UserService.NotifySignupAttempt
+func (m *MockUserService) NotifySignupAttempt(ctx context.Context, email string) error {
+	ret := m.Called(ctx, email)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// HandleAvailable is a mock of UserService.HandleAvailable
+func (m *MockUserService) HandleAvailable(ctx context.Context, handle string) (bool, error) {
+	ret := m.Called(ctx, handle)
+
+	var r0 bool
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// GetByHandle is a mock of UserService.GetByHandle
+func (m *MockUserService) GetByHandle(ctx context.Context, handle string) (*model.User, error) {
+	ret := m.Called(ctx, handle)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SetHandle is a mock of UserService.SetHandle
+func (m *MockUserService) SetHandle(ctx context.Context, uid uuid.UUID, handle string) (*model.User, error) {
+	ret := m.Called(ctx, uid, handle)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// List is a mock of UserService.List
+func (m *MockUserService) List(ctx context.Context, filter *model.UserFilter) ([]*model.User, int64, error) {
+	ret := m.Called(ctx, filter)
+
+	var r0 []*model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.User)
+	}
+
+	var r1 int64
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(int64)
+	}
+
+	var r2 error
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+
+	return r0, r1, r2
+}
+
+// Search is a mock of UserService.Search
+func (m *MockUserService) Search(ctx context.Context, query string, limit int) ([]*model.User, error) {
+	ret := m.Called(ctx, query, limit)
+
+	var r0 []*model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SetStatus is a mock of UserService.SetStatus
+func (m *MockUserService) SetStatus(ctx context.Context, uid uuid.UUID, status string, reason string, expiresAt *time.Time) (*model.User, error) {
+	ret := m.Called(ctx, uid, status, reason, expiresAt)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Activity is a mock of UserService.Activity
+func (m *MockUserService) Activity(ctx context.Context, uid uuid.UUID, limit int, offset int) ([]*model.AuditEvent, error) {
+	ret := m.Called(ctx, uid, limit, offset)
+
+	var r0 []*model.AuditEvent
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.AuditEvent)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// DisownSignin is a mock of UserService.DisownSignin
+func (m *MockUserService) DisownSignin(ctx context.Context, token string) (*model.User, error) {
+	ret := m.Called(ctx, token)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// CreateGuest is a mock of UserService.CreateGuest
+func (m *MockUserService) CreateGuest(ctx context.Context) (*model.User, error) {
+	ret := m.Called(ctx)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// UpgradeGuest is a mock of UserService.UpgradeGuest
+func (m *MockUserService) UpgradeGuest(ctx context.Context, user *model.User) error {
+	ret := m.Called(ctx, user)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// PurgeGuests is a mock of UserService.PurgeGuests
+func (m *MockUserService) PurgeGuests(ctx context.Context, maxAge time.Duration) (int64, error) {
+	ret := m.Called(ctx, maxAge)
+
+	var r0 int64
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// AcceptTerms is a mock of UserService.AcceptTerms
+func (m *MockUserService) AcceptTerms(ctx context.Context, uid uuid.UUID, version string) (*model.User, error) {
+	ret := m.Called(ctx, uid, version)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Organization is a team of users who share ownership
+type Organization struct {
+	ID        uuid.UUID  `db:"id" json:"id"`
+	Name      string     `db:"name" json:"name"`
+	CreatedBy *uuid.UUID `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	// Role is the role of the user the organization was listed for
+	Role string `db:"role" json:"role,omitempty"`
+}
+
+// OrgMember is a user's membership of an organization
+// Email and Name are read from the user for listing members
+type OrgMember struct {
+	OrgID     uuid.UUID `db:"org_id" json:"org_id"`
+	UID       uuid.UUID `db:"uid" json:"uid"`
+	Role      string    `db:"role" json:"role"`
+	Email     string    `db:"email" json:"email"`
+	Name      string    `db:"name" json:"name"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// OrgInvite invites an email to join an organization with a role
+// Only the hash of its token is stored, the token is mailed once when the invite is created
+type OrgInvite struct {
+	ID        uuid.UUID  `db:"id" json:"id"`
+	OrgID     uuid.UUID  `db:"org_id" json:"org_id"`
+	Email     string     `db:"email" json:"email"`
+	Role      string     `db:"role" json:"role"`
+	Token     string     `db:"-" json:"-"`
+	TokenHash string     `db:"token_hash" json:"-"`
+	InvitedBy *uuid.UUID `db:"invited_by" json:"invited_by,omitempty"`
+	ExpiresAt time.Time  `db:"expires_at" json:"expires_at"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+}
+
+// Roles of organization members, from most to least privileged
+// Owners manage everything, admins manage members but not owners
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// orgRoleRanks orders the organization roles by privilege
+var orgRoleRanks = map[string]int{
+	OrgRoleMember: 1,
+	OrgRoleAdmin:  2,
+	OrgRoleOwner:  3,
+}
+
+// ValidOrgRole reports whether role is a role of organization members
+func ValidOrgRole(role string) bool {
+	_, ok := orgRoleRanks[role]
+	return ok
+}
+
+// OrgRoleAtLeast reports whether role is as privileged as min
+func OrgRoleAtLeast(role string, min string) bool {
+	return orgRoleRanks[role] >= orgRoleRanks[min] && ValidOrgRole(role)
+}
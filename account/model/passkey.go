@@ -0,0 +1,145 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebAuthn ceremonies as reported in the client data "type"
+const (
+	WebAuthnCreate = "webauthn.create"
+	WebAuthnGet    = "webauthn.get"
+)
+
+// Base64URL holds binary data which is encoded as unpadded
+// base64url in JSON, the encoding used by the WebAuthn browser API
+type Base64URL []byte
+
+// MarshalJSON encodes the bytes as an unpadded base64url string
+func (b Base64URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+// UnmarshalJSON decodes a base64url string, with or without padding
+func (b *Base64URL) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+
+	*b = decoded
+	return nil
+}
+
+// PasskeyCredential holds a WebAuthn public key credential registered by a user
+type PasskeyCredential struct {
+	ID         Base64URL  `json:"id"`
+	UID        uuid.UUID  `json:"-"`
+	Name       string     `json:"name"`
+	PublicKey  []byte     `json:"-"` // COSE encoded
+	SignCount  uint32     `json:"-"`
+	Transports []string   `json:"transports"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// WebAuthnSession holds the challenge of a ceremony between its begin and finish steps
+// UID is unset for a sign-in with a discoverable credential
+type WebAuthnSession struct {
+	Challenge Base64URL `json:"challenge"`
+	UID       uuid.UUID `json:"uid"`
+	Ceremony  string    `json:"ceremony"`
+}
+
+// RelyingParty identifies this service to authenticators
+type RelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// PasskeyUser identifies the user an authenticator creates a credential for
+type PasskeyUser struct {
+	ID          Base64URL `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"displayName"`
+}
+
+// PubKeyCredParam is a credential type and COSE algorithm the service accepts
+type PubKeyCredParam struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+// CredentialDescriptor refers to an existing credential
+type CredentialDescriptor struct {
+	Type       string    `json:"type"`
+	ID         Base64URL `json:"id"`
+	Transports []string  `json:"transports,omitempty"`
+}
+
+// AuthenticatorSelection restricts which authenticators may be used
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// PasskeyCreationOptions are passed to navigator.credentials.create
+type PasskeyCreationOptions struct {
+	Challenge              Base64URL              `json:"challenge"`
+	RP                     RelyingParty           `json:"rp"`
+	User                   PasskeyUser            `json:"user"`
+	PubKeyCredParams       []PubKeyCredParam      `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// PasskeyRequestOptions are passed to navigator.credentials.get
+type PasskeyRequestOptions struct {
+	Challenge        Base64URL              `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials,omitempty"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// AttestationResponse is the response of navigator.credentials.create
+type AttestationResponse struct {
+	ClientDataJSON    Base64URL `json:"clientDataJSON" binding:"required"`
+	AttestationObject Base64URL `json:"attestationObject" binding:"required"`
+	Transports        []string  `json:"transports"`
+}
+
+// PasskeyAttestation is a new credential as serialized by PublicKeyCredential.toJSON()
+type PasskeyAttestation struct {
+	ID       string              `json:"id"`
+	RawID    Base64URL           `json:"rawId" binding:"required"`
+	Type     string              `json:"type" binding:"required"`
+	Response AttestationResponse `json:"response" binding:"required"`
+}
+
+// AssertionResponse is the response of navigator.credentials.get
+type AssertionResponse struct {
+	ClientDataJSON    Base64URL `json:"clientDataJSON" binding:"required"`
+	AuthenticatorData Base64URL `json:"authenticatorData" binding:"required"`
+	Signature         Base64URL `json:"signature" binding:"required"`
+	UserHandle        Base64URL `json:"userHandle"`
+}
+
+// PasskeyAssertion is a signed challenge as serialized by PublicKeyCredential.toJSON()
+type PasskeyAssertion struct {
+	ID       string            `json:"id"`
+	RawID    Base64URL         `json:"rawId" binding:"required"`
+	Type     string            `json:"type" binding:"required"`
+	Response AssertionResponse `json:"response" binding:"required"`
+}
@@ -0,0 +1,4 @@
+package model
+
+// RoleAdmin may manage users, their roles and sign-up invites
+const RoleAdmin = "admin"
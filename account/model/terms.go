@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TermsAcceptance is the proof of a user accepting a version of the terms of service
+type TermsAcceptance struct {
+	UID        uuid.UUID `db:"uid" json:"uid"`
+	Version    string    `db:"version" json:"version"`
+	IP         string    `db:"ip" json:"ip"`
+	UserAgent  string    `db:"user_agent" json:"user_agent"`
+	AcceptedAt time.Time `db:"accepted_at" json:"accepted_at"`
+}
@@ -41,8 +41,11 @@ type RefreshTokenInfo struct {
 }
 
 // AccessTokenCustomClaims holds structure of jwt claims of idToken
+// IssuedAtMs is the issue time in milliseconds, finer than iat for the
+// denylist of revoked access tokens
 type AccessTokenCustomClaims struct {
-	User *User `json:"user"`
+	User       *User `json:"user"`
+	IssuedAtMs int64 `json:"iat_ms,omitempty"`
 	jwt.StandardClaims
 }
 
@@ -1,15 +1,72 @@
 package model
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
 // User defines domain model and its json and db representations
 type User struct {
-	UID      uuid.UUID `db:"uid" json:"uid"`
-	Email    string    `db:"email" json:"email"`
-	Password string    `db:"password" json:"-"` // "-" to ensure password can not be sent to user via that struct
-	Name     string    `db:"name" json:"name"`
-	ImageURL string    `db:"image_url" json:"image_url"`
-	Website  string    `db:"website" json:"website"`
+	UID       uuid.UUID `db:"uid" json:"uid"`
+	Email     string    `db:"email" json:"email"`
+	Password  string    `db:"password" json:"-"` // "-" to ensure password can not be sent to user via that struct
+	Name      string    `db:"name" json:"name"`
+	ImageURL  string    `db:"image_url" json:"image_url"`
+	Website   string    `db:"website" json:"website"`
+	Handle    string    `db:"handle" json:"handle"` // unique regardless of case, empty until chosen
+	Status    string    `db:"status" json:"status"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	// Guest users have neither email nor password until they upgrade
+	Guest bool `db:"guest" json:"guest"`
+	// StatusReason and StatusExpiresAt explain a status set by an admin,
+	// StatusExpiresAt is nil for statuses without an end
+	StatusReason    string     `db:"status_reason" json:"status_reason,omitempty"`
+	StatusExpiresAt *time.Time `db:"status_expires_at" json:"status_expires_at,omitempty"`
+	// TermsVersion is the version of the terms of service the user accepted last, at TermsAcceptedAt
+	TermsVersion    string     `db:"terms_version" json:"terms_version"`
+	TermsAcceptedAt *time.Time `db:"terms_accepted_at" json:"terms_accepted_at,omitempty"`
+	// HandleChangedAt is when the handle was last changed, for the change cooldown
+	HandleChangedAt *time.Time `db:"handle_changed_at" json:"-"`
+	// LastSeenAt is when tokens were last issued to the user, nil if never since it was tracked
+	LastSeenAt *time.Time `db:"last_seen_at" json:"-"`
+	// Roles are loaded into access tokens, they are not a users column
+	Roles []string `db:"-" json:"roles,omitempty"`
+	// ActiveOrgID is the organization the user acts for, nil for none
+	ActiveOrgID *uuid.UUID `db:"active_org_id" json:"active_org_id,omitempty"`
+	// OrgRole is the role in the active organization, loaded into access tokens
+	OrgRole string `db:"-" json:"org_role,omitempty"`
+	// InviteID is the invite the user signed up with, if any
+	InviteID *uuid.UUID `db:"invite_id" json:"-"`
+	// InviteCode is only supplied on sign-up and never stored
+	InviteCode string `db:"-" json:"-"`
+	// AcceptedTerms is the version of the terms of service shown on sign-up, never stored as is
+	AcceptedTerms string `db:"-" json:"-"`
+}
+
+// Statuses of users, only active users and users pending deletion may sign in
+const (
+	UserStatusActive          = "active"
+	UserStatusSuspended       = "suspended"
+	UserStatusPendingDeletion = "pending_deletion"
+)
+
+// Suspended reports whether the user is suspended at the given time
+// Suspensions end by themselves once they expire
+func (u *User) Suspended(now time.Time) bool {
+	if u.Status != UserStatusSuspended {
+		return false
+	}
+
+	return u.StatusExpiresAt == nil || now.Before(*u.StatusExpiresAt)
+}
+
+// UserFilter selects users to list, empty fields match all users
+// Email and Name match parts of the field regardless of case
+type UserFilter struct {
+	Email  string
+	Name   string
+	Status string
+	Limit  int
+	Offset int
 }
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"io"
+	"sort"
+	"strings"
+)
+
+// fileBreachedPasswordRepository is a model.BreachedPasswordRepository
+// holding a list of breached password hashes in memory, so passwords
+// are never sent to a third party to be checked
+// Each hash takes about 100 bytes, so lists of up to a few million hashes,
+// like the most common passwords, fit. The full Have I Been Pwned download
+// is searched where it is stored by sortedBreachedPasswordRepository instead
+type fileBreachedPasswordRepository struct {
+	Prefixes map[string]struct{}
+	Lengths  []int
+}
+
+// NewBreachedPasswordRepository is a factory for initializing a Breached Password
+// Repository from a list with one hex SHA-1 hash, or prefix of one, per line.
+// A ":count" suffix as in Have I Been Pwned downloads and "#" comments are ignored
+func NewBreachedPasswordRepository(list io.Reader) (model.BreachedPasswordRepository, error) {
+	prefixes := map[string]struct{}{}
+	lengths := map[int]bool{}
+
+	scanner := bufio.NewScanner(list)
+	for line := 1; scanner.Scan(); line++ {
+		prefix := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(prefix, ':'); i >= 0 {
+			prefix = prefix[:i]
+		}
+
+		if prefix == "" || strings.HasPrefix(prefix, "#") {
+			continue
+		}
+
+		if len(prefix) > sha1.Size*2 || strings.Trim(prefix, "0123456789abcdefABCDEF") != "" {
+			return nil, fmt.Errorf("line %d is not a SHA-1 hash or prefix: %q", line, prefix)
+		}
+
+		prefixes[strings.ToUpper(prefix)] = struct{}{}
+		lengths[len(prefix)] = true
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sortedLengths := make([]int, 0, len(lengths))
+	for length := range lengths {
+		sortedLengths = append(sortedLengths, length)
+	}
+	sort.Ints(sortedLengths)
+
+	return &fileBreachedPasswordRepository{
+		Prefixes: prefixes,
+		Lengths:  sortedLengths,
+	}, nil
+}
+
+// IsBreached reports whether the SHA-1 hash of a password starts with a listed prefix
+func (r *fileBreachedPasswordRepository) IsBreached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	for _, length := range r.Lengths {
+		if _, ok := r.Prefixes[hash[:length]]; ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileImageRepository is a filesystem implementation of service layer
+// ImageRepository, which keeps each image in a file of Dir
+// Images are served by the API under BaseURL, see handler.ProfileImage
+type fileImageRepository struct {
+	Dir     string
+	BaseURL string
+}
+
+// NewFileImageRepository is a factory for initializing filesystem Image Repositories
+// dir must exist, and baseURL is the public URL the images are served under
+func NewFileImageRepository(dir string, baseURL string) model.ImageRepository {
+	return &fileImageRepository{
+		Dir:     dir,
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// UpdateProfile writes an image and returns its URL
+// The image is written to a temporary file which replaces the previous one,
+// so readers see either the previous or the new image in full
+func (r *fileImageRepository) UpdateProfile(ctx context.Context, objName string, imageFile multipart.File) (string, error) {
+	path, ok := r.path(objName)
+	if !ok {
+		log.Printf("Invalid image object name: %q\n", objName)
+		return "", apperrors.NewInternal()
+	}
+
+	// temporary files start with a dot, which is never a valid object name
+	tmp, err := os.CreateTemp(r.Dir, "."+objName+".*.tmp")
+	if err != nil {
+		log.Printf("Unable to create image file in: %v. Err: %v\n", r.Dir, err)
+		return "", apperrors.NewInternal()
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeImageFile(tmp, imageFile); err != nil {
+		log.Printf("Unable to write image file: %v. Err: %v\n", tmp.Name(), err)
+		return "", apperrors.NewInternal()
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		log.Printf("Unable to replace image file: %v. Err: %v\n", path, err)
+		return "", apperrors.NewInternal()
+	}
+
+	return fmt.Sprintf("%s/%s", r.BaseURL, objName), nil
+}
+
+// DeleteProfile removes an image
+func (r *fileImageRepository) DeleteProfile(ctx context.Context, objName string) error {
+	path, ok := r.path(objName)
+	if !ok {
+		return apperrors.NewNotFound("image", objName)
+	}
+
+	if err := os.Remove(path); err != nil {
+		log.Printf("Failed to delete image object with ID: %s from %s. Err: %v\n", objName, r.Dir, err)
+		if errors.Is(err, fs.ErrNotExist) {
+			return apperrors.NewNotFound("image", objName)
+		}
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// GetProfile returns a reader of an image
+func (r *fileImageRepository) GetProfile(ctx context.Context, objName string) (io.ReadCloser, error) {
+	path, ok := r.path(objName)
+	if !ok {
+		return nil, apperrors.NewNotFound("image", objName)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, apperrors.NewNotFound("image", objName)
+		}
+
+		log.Printf("Unable to open image file: %v. Err: %v\n", path, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return file, nil
+}
+
+// path returns the file of an object, if its name is a plain file name
+// Names with separators or a leading dot could reach outside of Dir
+// or at temporary files
+func (r *fileImageRepository) path(objName string) (string, bool) {
+	if objName == "" || strings.HasPrefix(objName, ".") || strings.ContainsAny(objName, `/\`) {
+		return "", false
+	}
+
+	return filepath.Join(r.Dir, objName), true
+}
+
+// writeImageFile copies an image to a file and flushes it to disk before closing it
+func writeImageFile(file *os.File, image io.Reader) error {
+	if _, err := io.Copy(file, image); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	// temporary files are only readable by their owner
+	if err := file.Chmod(0o644); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileImageRepository(t *testing.T) {
+	ctx := context.TODO()
+
+	// imageFile opens a file holding data, like an uploaded multipart.File
+	imageFile := func(t *testing.T, data string) *os.File {
+		path := filepath.Join(t.TempDir(), "upload")
+		assert.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+		file, err := os.Open(path)
+		assert.NoError(t, err)
+		t.Cleanup(func() { file.Close() })
+
+		return file
+	}
+
+	read := func(t *testing.T, r io.ReadCloser) string {
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("Writes, replaces and deletes images", func(t *testing.T) {
+		dir := t.TempDir()
+		r := NewFileImageRepository(dir, "http://localhost:8080/api/account/images/")
+
+		imageURL, err := r.UpdateProfile(ctx, "abc", imageFile(t, "first"))
+		assert.NoError(t, err)
+		assert.Equal(t, "http://localhost:8080/api/account/images/abc", imageURL)
+
+		_, err = r.UpdateProfile(ctx, "abc", imageFile(t, "second"))
+		assert.NoError(t, err)
+
+		image, err := r.GetProfile(ctx, "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, "second", read(t, image))
+
+		// no temporary files are left behind
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+
+		info, err := os.Stat(filepath.Join(dir, "abc"))
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+
+		assert.NoError(t, r.DeleteProfile(ctx, "abc"))
+
+		_, err = r.GetProfile(ctx, "abc")
+		assert.Equal(t, apperrors.NotFound, err.(*apperrors.Error).Type)
+
+		err = r.DeleteProfile(ctx, "abc")
+		assert.Equal(t, apperrors.NotFound, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("Names outside of the directory are not found", func(t *testing.T) {
+		parent := t.TempDir()
+		dir := filepath.Join(parent, "images")
+		assert.NoError(t, os.Mkdir(dir, 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(parent, "secret"), []byte("secret"), 0o600))
+		r := NewFileImageRepository(dir, "http://localhost:8080/api/account/images")
+
+		for _, name := range []string{"../secret", `..\secret`, "..", ".", "", ".abc.123.tmp"} {
+			_, err := r.GetProfile(ctx, name)
+			assert.Equal(t, apperrors.NotFound, err.(*apperrors.Error).Type, name)
+
+			_, err = r.UpdateProfile(ctx, name, imageFile(t, "image"))
+			assert.Error(t, err, name)
+		}
+
+		data, err := os.ReadFile(filepath.Join(parent, "secret"))
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", string(data))
+	})
+}
@@ -70,3 +70,20 @@ func (r *gcpImageRepository) DeleteProfile(ctx context.Context, objName string)
 
 	return nil
 }
+
+func (r *gcpImageRepository) GetProfile(ctx context.Context, objName string) (io.ReadCloser, error) {
+	bckt := r.Storage.Bucket(r.BucketName)
+
+	object := bckt.Object(objName)
+
+	rc, err := object.NewReader(ctx)
+	if err != nil {
+		log.Printf("Failed to read image object with ID: %s from GC Storage: %v\n", objName, err)
+		if err == storage.ErrObjectNotExist {
+			return nil, apperrors.NewNotFound("image", objName)
+		}
+		return nil, apperrors.NewInternal()
+	}
+
+	return rc, nil
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"log"
+)
+
+// logMailer is a model.Mailer which writes emails to the log instead of
+// sending them, for development without an SMTP server
+type logMailer struct{}
+
+// NewLogMailer is a factory for initializing a Mailer which only logs
+func NewLogMailer() model.Mailer {
+	return &logMailer{}
+}
+
+// Send logs the email
+func (m *logMailer) Send(ctx context.Context, email *model.Email) error {
+	log.Printf("Email to: %s\nSubject: %s\n\n%s\n", email.To, email.Subject, email.Body)
+	return nil
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"log"
+)
+
+// logNotifier is a model.Notifier which writes notifications to the log,
+// for development and deployments which do not notify users
+type logNotifier struct{}
+
+// NewLogNotifier is a factory for initializing a Notifier which only logs
+func NewLogNotifier() model.Notifier {
+	return &logNotifier{}
+}
+
+// NotifyNewDevice logs the sign-in from a new device
+func (n *logNotifier) NotifyNewDevice(ctx context.Context, notice *model.NewDeviceNotice) error {
+	log.Printf("New device sign-in for uid: %v from: %v (%s)\nNot them: %s\n", notice.User.UID, notice.Device.IPPrefix, notice.Device.UserAgent, notice.RevokeURL)
+	return nil
+}
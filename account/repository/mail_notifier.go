@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+)
+
+// mailNotifier is a model.Notifier which emails users
+type mailNotifier struct {
+	Mailer model.Mailer
+}
+
+// NewMailNotifier is a factory for initializing a Notifier which sends emails through a Mailer
+func NewMailNotifier(mailer model.Mailer) model.Notifier {
+	return &mailNotifier{
+		Mailer: mailer,
+	}
+}
+
+// NotifyNewDevice emails the user about the sign-in from a new device
+func (n *mailNotifier) NotifyNewDevice(ctx context.Context, notice *model.NewDeviceNotice) error {
+	return n.Mailer.Send(ctx, &model.Email{
+		To:      notice.User.Email,
+		Subject: "New sign-in to your account",
+		Body: fmt.Sprintf(
+			"Your account was signed in to from a new device.\n\nDevice: %s\nIP address: %s\nTime: %s\n\nIf this was you, you can ignore this email. If it was not, sign out everywhere and reset your password with this link:\n\n%s",
+			notice.Device.UserAgent,
+			notice.IP,
+			notice.Device.FirstSeenAt.UTC().Format("2006-01-02 15:04 MST"),
+			notice.RevokeURL,
+		),
+	})
+}
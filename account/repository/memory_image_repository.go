@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"io"
+	"log"
+	"mime/multipart"
+	"strings"
+	"sync"
+)
+
+// memoryImageRepository is an in-memory implementation of service layer
+// ImageRepository, for running without Cloud Storage
+// Images are served by the API under BaseURL, see handler.ProfileImage
+type memoryImageRepository struct {
+	mu      sync.RWMutex
+	images  map[string][]byte
+	BaseURL string
+}
+
+// NewMemoryImageRepository is a factory for initializing in-memory Image Repositories
+// baseURL is the public URL the images are served under
+func NewMemoryImageRepository(baseURL string) model.ImageRepository {
+	return &memoryImageRepository{
+		images:  make(map[string][]byte),
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// UpdateProfile stores an image and returns its URL
+func (r *memoryImageRepository) UpdateProfile(ctx context.Context, objName string, imageFile multipart.File) (string, error) {
+	image, err := io.ReadAll(imageFile)
+	if err != nil {
+		log.Printf("Unable to read image file: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+
+	r.mu.Lock()
+	r.images[objName] = image
+	r.mu.Unlock()
+
+	return fmt.Sprintf("%s/%s", r.BaseURL, objName), nil
+}
+
+// DeleteProfile removes an image
+func (r *memoryImageRepository) DeleteProfile(ctx context.Context, objName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.images[objName]; !ok {
+		log.Printf("Failed to delete image object with ID: %s from memory\n", objName)
+		return apperrors.NewNotFound("image", objName)
+	}
+
+	delete(r.images, objName)
+	return nil
+}
+
+// GetProfile returns a reader of an image
+func (r *memoryImageRepository) GetProfile(ctx context.Context, objName string) (io.ReadCloser, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	image, ok := r.images[objName]
+	if !ok {
+		return nil, apperrors.NewNotFound("image", objName)
+	}
+
+	// stored images are replaced, never changed, so the reader can share them
+	return io.NopCloser(bytes.NewReader(image)), nil
+}
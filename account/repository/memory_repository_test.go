@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMemoryUserRepository(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("Email is unique regardless of case", func(t *testing.T) {
+		r := NewMemoryUserRepository()
+
+		user := &model.User{Email: "bob@bob.com", Password: "hash"}
+		assert.NoError(t, r.Create(ctx, user))
+		assert.Equal(t, model.UserStatusActive, user.Status)
+
+		err := r.Create(ctx, &model.User{Email: "BOB@bob.com"})
+		assert.Equal(t, apperrors.Conflict, err.(*apperrors.Error).Type)
+
+		found, err := r.FindByEmail(ctx, "Bob@Bob.com")
+		assert.NoError(t, err)
+		assert.Equal(t, user.UID, found.UID)
+	})
+
+	t.Run("Guests have no email", func(t *testing.T) {
+		r := NewMemoryUserRepository()
+
+		assert.NoError(t, r.Create(ctx, &model.User{Guest: true}))
+		assert.NoError(t, r.Create(ctx, &model.User{Guest: true}))
+
+		_, err := r.FindByEmail(ctx, "")
+		assert.Equal(t, apperrors.NotFound, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("Previous handles redirect", func(t *testing.T) {
+		r := NewMemoryUserRepository()
+
+		bob := &model.User{Email: "bob@bob.com"}
+		alice := &model.User{Email: "alice@alice.com"}
+		assert.NoError(t, r.Create(ctx, bob))
+		assert.NoError(t, r.Create(ctx, alice))
+
+		_, err := r.UpdateHandle(ctx, bob.UID, "bob")
+		assert.NoError(t, err)
+		_, err = r.UpdateHandle(ctx, bob.UID, "robert")
+		assert.NoError(t, err)
+
+		found, err := r.FindByHandle(ctx, "BOB")
+		assert.NoError(t, err)
+		assert.Equal(t, bob.UID, found.UID)
+
+		_, err = r.UpdateHandle(ctx, alice.UID, "bob")
+		assert.Equal(t, apperrors.Conflict, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("Guests seen recently are not purged", func(t *testing.T) {
+		r := NewMemoryUserRepository()
+
+		active := &model.User{Guest: true}
+		unused := &model.User{Guest: true}
+		assert.NoError(t, r.Create(ctx, active))
+		assert.NoError(t, r.Create(ctx, unused))
+		assert.NoError(t, r.UpdateLastSeen(ctx, active.UID, time.Now().Add(time.Hour)))
+
+		deleted, err := r.DeleteGuests(ctx, time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Len(t, deleted, 1)
+		assert.Equal(t, unused.UID, deleted[0].UID)
+
+		_, err = r.FindByID(ctx, active.UID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Returned users are copies", func(t *testing.T) {
+		r := NewMemoryUserRepository()
+
+		user := &model.User{Email: "bob@bob.com"}
+		assert.NoError(t, r.Create(ctx, user))
+
+		found, _ := r.FindByID(ctx, user.UID)
+		found.Name = "Changed"
+
+		found, _ = r.FindByID(ctx, user.UID)
+		assert.Equal(t, "", found.Name)
+	})
+}
+
+func TestMemoryTokenRepository(t *testing.T) {
+	ctx := context.TODO()
+
+	now := time.Now()
+	r := NewMemoryTokenRepository().(*memoryTokenRepository)
+	r.now = func() time.Time { return now }
+
+	t.Run("Refresh tokens expire", func(t *testing.T) {
+		assert.NoError(t, r.SetRefreshToken(ctx, "uid", "a", time.Minute))
+		assert.NoError(t, r.SetRefreshToken(ctx, "uid", "b", time.Hour))
+
+		now = now.Add(2 * time.Minute)
+
+		sessions, err := r.GetUserSessions(ctx, "uid")
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 1)
+		assert.Equal(t, "b", sessions[0].TokenID)
+
+		err = r.DeleteRefreshToken(ctx, "uid", "a")
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		assert.NoError(t, r.DeleteRefreshToken(ctx, "uid", "b"))
+	})
+
+	t.Run("Links can be used once", func(t *testing.T) {
+		assert.NoError(t, r.SetPasswordResetToken(ctx, "token", "uid", time.Hour))
+
+		userID, err := r.PeekPasswordResetToken(ctx, "token")
+		assert.NoError(t, err)
+		assert.Equal(t, "uid", userID)
+
+		userID, err = r.GetPasswordResetToken(ctx, "token")
+		assert.NoError(t, err)
+		assert.Equal(t, "uid", userID)
+
+		_, err = r.GetPasswordResetToken(ctx, "token")
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+
+		_, err = r.PeekPasswordResetToken(ctx, "token")
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("Request windows restart once expired", func(t *testing.T) {
+		count, _ := r.IncrementMagicLinkRequests(ctx, "bob@bob.com", time.Minute)
+		assert.Equal(t, int64(1), count)
+		count, _ = r.IncrementMagicLinkRequests(ctx, "bob@bob.com", time.Minute)
+		assert.Equal(t, int64(2), count)
+
+		now = now.Add(time.Minute)
+
+		count, _ = r.IncrementMagicLinkRequests(ctx, "bob@bob.com", time.Minute)
+		assert.Equal(t, int64(1), count)
+	})
+}
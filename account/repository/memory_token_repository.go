@@ -0,0 +1,333 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryEntry is a value of the in-memory token store, which
+// is gone once expiresAt has passed
+type memoryEntry struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+// memoryTokenRepository is an in-memory implementation of service layer
+// TokenRepository, for running without Redis
+// Entries expire like the keys of the Redis implementation, under the
+// same key names. Expired entries are dropped when they are next read,
+// or by a sweep at most once every memorySweepInterval
+type memoryTokenRepository struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	now       func() time.Time
+	nextSweep time.Time
+}
+
+// memorySweepInterval is the least time between two sweeps of expired entries
+const memorySweepInterval = time.Minute
+
+// NewMemoryTokenRepository is a factory for initializing in-memory Token Repositories
+func NewMemoryTokenRepository() model.TokenRepository {
+	return &memoryTokenRepository{
+		entries: make(map[string]*memoryEntry),
+		now:     time.Now,
+	}
+}
+
+// SetRefreshToken stores a refresh token with an expiry time
+func (r *memoryTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error {
+	r.set(fmt.Sprintf("%s:%s", userID, tokenID), 0, expiresIn)
+	return nil
+}
+
+// DeleteRefreshToken used to delete old refresh tokens
+func (r *memoryTokenRepository) DeleteRefreshToken(ctx context.Context, userID string, tokenID string) error {
+	if _, ok := r.take(fmt.Sprintf("%s:%s", userID, tokenID)); !ok {
+		return apperrors.NewAuthorization("Invalid refresh token")
+	}
+
+	return nil
+}
+
+// DeleteUserRefreshToken deletes all refresh tokens of a user
+func (r *memoryTokenRepository) DeleteUserRefreshToken(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key := range r.entries {
+		if strings.HasPrefix(key, userID+":") {
+			delete(r.entries, key)
+		}
+	}
+
+	return nil
+}
+
+// GetUserSessions returns the token IDs of the refresh tokens
+// of a user along with their expiry
+func (r *memoryTokenRepository) GetUserSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	sessions := []*model.Session{}
+	for key, entry := range r.entries {
+		if !strings.HasPrefix(key, userID+":") || r.expired(key, entry, now) {
+			continue
+		}
+
+		sessions = append(sessions, &model.Session{
+			TokenID:   strings.TrimPrefix(key, userID+":"),
+			ExpiresAt: entry.expiresAt.UTC(),
+		})
+	}
+
+	return sessions, nil
+}
+
+// mfaChallenge is a pending second factor challenge
+type mfaChallenge struct {
+	userID   string
+	attempts int64
+}
+
+// SetMFAChallenge stores a pending second factor challenge for a user
+func (r *memoryTokenRepository) SetMFAChallenge(ctx context.Context, challengeID string, userID string, expiresIn time.Duration) error {
+	r.set(mfaChallengeKey(challengeID), &mfaChallenge{userID: userID}, expiresIn)
+	return nil
+}
+
+// GetMFAChallenge returns the userID a challenge was issued for
+func (r *memoryTokenRepository) GetMFAChallenge(ctx context.Context, challengeID string) (string, error) {
+	value, ok := r.get(mfaChallengeKey(challengeID))
+	if !ok {
+		return "", apperrors.NewAuthorization("Invalid or expired MFA token")
+	}
+
+	return value.(*mfaChallenge).userID, nil
+}
+
+// IncrementMFAChallengeAttempts counts a failed attempt at a challenge
+// and returns the attempts made so far
+func (r *memoryTokenRepository) IncrementMFAChallengeAttempts(ctx context.Context, challengeID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := mfaChallengeKey(challengeID)
+	entry, ok := r.entries[key]
+	if !ok || r.expired(key, entry, r.now()) {
+		// like HINCRBY, which creates a hash without expiry
+		entry = &memoryEntry{value: &mfaChallenge{}}
+		r.entries[key] = entry
+	}
+
+	challenge := entry.value.(*mfaChallenge)
+	challenge.attempts++
+	return challenge.attempts, nil
+}
+
+// DeleteMFAChallenge removes a challenge once used or exhausted
+func (r *memoryTokenRepository) DeleteMFAChallenge(ctx context.Context, challengeID string) error {
+	r.take(mfaChallengeKey(challengeID))
+	return nil
+}
+
+// SetWebAuthnSession stores the challenge of a pending WebAuthn ceremony
+func (r *memoryTokenRepository) SetWebAuthnSession(ctx context.Context, sessionID string, session *model.WebAuthnSession, expiresIn time.Duration) error {
+	stored := *session
+	r.set(webAuthnSessionKey(sessionID), &stored, expiresIn)
+	return nil
+}
+
+// GetWebAuthnSession retrieves and removes a pending WebAuthn ceremony,
+// so each challenge can only be answered once
+func (r *memoryTokenRepository) GetWebAuthnSession(ctx context.Context, sessionID string) (*model.WebAuthnSession, error) {
+	value, ok := r.take(webAuthnSessionKey(sessionID))
+	if !ok {
+		return nil, apperrors.NewAuthorization("Invalid or expired passkey session")
+	}
+
+	return value.(*model.WebAuthnSession), nil
+}
+
+// SetMagicLink stores a pending sign-in link
+func (r *memoryTokenRepository) SetMagicLink(ctx context.Context, token string, link *model.MagicLink, expiresIn time.Duration) error {
+	stored := *link
+	r.set(magicLinkKey(token), &stored, expiresIn)
+	return nil
+}
+
+// GetMagicLink retrieves and removes a sign-in link, so each link can only be used once
+func (r *memoryTokenRepository) GetMagicLink(ctx context.Context, token string) (*model.MagicLink, error) {
+	value, ok := r.take(magicLinkKey(token))
+	if !ok {
+		return nil, apperrors.NewAuthorization("Invalid or expired sign-in link")
+	}
+
+	return value.(*model.MagicLink), nil
+}
+
+// IncrementMagicLinkRequests counts the sign-in links requested for an
+// email address within a fixed window and returns the new count
+func (r *memoryTokenRepository) IncrementMagicLinkRequests(ctx context.Context, email string, window time.Duration) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := fmt.Sprintf("signin_link_requests:%s", email)
+	entry, ok := r.entries[key]
+	if !ok || r.expired(key, entry, r.now()) {
+		// only the first request of a window sets the expiry
+		entry = &memoryEntry{value: int64(0), expiresAt: r.now().Add(window)}
+		r.entries[key] = entry
+	}
+
+	entry.value = entry.value.(int64) + 1
+	return entry.value.(int64), nil
+}
+
+// SetOAuthState stores a pending authorization request to a provider
+func (r *memoryTokenRepository) SetOAuthState(ctx context.Context, state string, oauthState *model.OAuthState, expiresIn time.Duration) error {
+	stored := *oauthState
+	r.set(oauthStateKey(state), &stored, expiresIn)
+	return nil
+}
+
+// GetOAuthState retrieves and removes a pending authorization request,
+// so each state can only be used for one callback
+func (r *memoryTokenRepository) GetOAuthState(ctx context.Context, state string) (*model.OAuthState, error) {
+	value, ok := r.take(oauthStateKey(state))
+	if !ok {
+		return nil, apperrors.NewAuthorization("Invalid or expired authorization request")
+	}
+
+	return value.(*model.OAuthState), nil
+}
+
+// SetPasswordResetToken stores the user a password reset link was sent to
+func (r *memoryTokenRepository) SetPasswordResetToken(ctx context.Context, token string, userID string, expiresIn time.Duration) error {
+	r.set(passwordResetKey(token), userID, expiresIn)
+	return nil
+}
+
+// PeekPasswordResetToken retrieves the user of a password reset link,
+// leaving the link usable
+func (r *memoryTokenRepository) PeekPasswordResetToken(ctx context.Context, token string) (string, error) {
+	value, ok := r.get(passwordResetKey(token))
+	if !ok {
+		return "", apperrors.NewAuthorization("Invalid or expired password reset link")
+	}
+
+	return value.(string), nil
+}
+
+// GetPasswordResetToken retrieves and removes the user of a password reset link,
+// so each link can only be used once
+func (r *memoryTokenRepository) GetPasswordResetToken(ctx context.Context, token string) (string, error) {
+	value, ok := r.take(passwordResetKey(token))
+	if !ok {
+		return "", apperrors.NewAuthorization("Invalid or expired password reset link")
+	}
+
+	return value.(string), nil
+}
+
+// SetAccessTokensRevokedAt denies the access tokens of a user issued up to revokedAt
+// The entry expires with the last of these tokens
+func (r *memoryTokenRepository) SetAccessTokensRevokedAt(ctx context.Context, userID string, revokedAt time.Time, expiresIn time.Duration) error {
+	// kept in milliseconds like the Redis implementation, so both compare the same
+	r.set(accessTokensRevokedKey(userID), time.UnixMilli(revokedAt.UnixMilli()), expiresIn)
+	return nil
+}
+
+// GetAccessTokensRevokedAt returns when the access tokens of a user were last revoked,
+// or the zero time if there are no revoked tokens that are still valid
+func (r *memoryTokenRepository) GetAccessTokensRevokedAt(ctx context.Context, userID string) (time.Time, error) {
+	value, ok := r.get(accessTokensRevokedKey(userID))
+	if !ok {
+		return time.Time{}, nil
+	}
+
+	return value.(time.Time), nil
+}
+
+// SetDeviceRevokeToken stores the user a new device notification was sent to
+func (r *memoryTokenRepository) SetDeviceRevokeToken(ctx context.Context, token string, userID string, expiresIn time.Duration) error {
+	r.set(deviceRevokeKey(token), userID, expiresIn)
+	return nil
+}
+
+// GetDeviceRevokeToken retrieves and removes the user of a "this wasn't me" link,
+// so each link can only be used once
+func (r *memoryTokenRepository) GetDeviceRevokeToken(ctx context.Context, token string) (string, error) {
+	value, ok := r.take(deviceRevokeKey(token))
+	if !ok {
+		return "", apperrors.NewAuthorization("Invalid or expired link")
+	}
+
+	return value.(string), nil
+}
+
+// set stores a value which expires after expiresIn, or never for 0
+func (r *memoryTokenRepository) set(key string, value interface{}, expiresIn time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if now.After(r.nextSweep) {
+		for k, entry := range r.entries {
+			r.expired(k, entry, now)
+		}
+		r.nextSweep = now.Add(memorySweepInterval)
+	}
+
+	entry := &memoryEntry{value: value}
+	if expiresIn > 0 {
+		entry.expiresAt = now.Add(expiresIn)
+	}
+	r.entries[key] = entry
+}
+
+// get returns a value, if it has not expired
+func (r *memoryTokenRepository) get(key string) (interface{}, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[key]
+	if !ok || r.expired(key, entry, r.now()) {
+		return nil, false
+	}
+
+	return entry.value, true
+}
+
+// take returns and removes a value, if it has not expired
+func (r *memoryTokenRepository) take(key string) (interface{}, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[key]
+	if !ok || r.expired(key, entry, r.now()) {
+		return nil, false
+	}
+
+	delete(r.entries, key)
+	return entry.value, true
+}
+
+// expired checks if an entry has expired, dropping it if so
+// The caller must hold the lock
+func (r *memoryTokenRepository) expired(key string, entry *memoryEntry, now time.Time) bool {
+	if entry.expiresAt.IsZero() || now.Before(entry.expiresAt) {
+		return false
+	}
+
+	delete(r.entries, key)
+	return true
+}
@@ -0,0 +1,424 @@
+package repository
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/google/uuid"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryUserRepository is an in-memory implementation of service layer
+// UserRepository, for running without Postgres. Users are lost on restart
+type memoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[uuid.UUID]*model.User
+	// redirects maps previous handles, in lowercase, to their users
+	redirects map[string]uuid.UUID
+}
+
+// NewMemoryUserRepository is a factory for initializing in-memory User Repositories
+func NewMemoryUserRepository() model.UserRepository {
+	return &memoryUserRepository{
+		users:     make(map[uuid.UUID]*model.User),
+		redirects: make(map[string]uuid.UUID),
+	}
+}
+
+// Create stores a new user with the defaults of the users table
+func (r *memoryUserRepository) Create(ctx context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.emailTaken(user.Email, uuid.Nil) {
+		log.Printf("Could not create a user with email: %v. Reason: unique_violation\n", user.Email)
+		return apperrors.NewConflict("email", user.Email)
+	}
+
+	uid, err := uuid.NewRandom()
+	if err != nil {
+		log.Printf("Could not create a user with email: %v. Reason: %v\n", user.Email, err)
+		return apperrors.NewInternal()
+	}
+
+	stored := &model.User{
+		UID:       uid,
+		Email:     user.Email,
+		Password:  user.Password,
+		Status:    model.UserStatusActive,
+		CreatedAt: time.Now().UTC(),
+		Guest:     user.Guest,
+		InviteID:  user.InviteID,
+	}
+	r.users[uid] = stored
+
+	*user = *stored
+	return nil
+}
+
+// FindByEmail retrieves a user by email address, regardless of case
+func (r *memoryUserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// guests have an empty email, which belongs to nobody
+	if email != "" {
+		for _, user := range r.users {
+			if strings.EqualFold(user.Email, email) {
+				return copyUser(user), nil
+			}
+		}
+	}
+
+	return &model.User{}, apperrors.NewNotFound("email", email)
+}
+
+// FindByHandle retrieves the user with a handle, regardless of case,
+// or the user who had it before changing it
+func (r *memoryUserRepository) FindByHandle(ctx context.Context, handle string) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if handle != "" {
+		for _, user := range r.users {
+			if strings.EqualFold(user.Handle, handle) {
+				return copyUser(user), nil
+			}
+		}
+	}
+
+	if uid, ok := r.redirects[strings.ToLower(handle)]; ok {
+		if user, ok := r.users[uid]; ok {
+			return copyUser(user), nil
+		}
+	}
+
+	return nil, apperrors.NewNotFound("handle", handle)
+}
+
+// FindByID fetches user by id
+func (r *memoryUserRepository) FindByID(ctx context.Context, uid uuid.UUID) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[uid]
+	if !ok {
+		return &model.User{}, apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return copyUser(user), nil
+}
+
+// Update updates the name, email and website of a user
+func (r *memoryUserRepository) Update(ctx context.Context, user *model.User) error {
+	return r.update(user.UID, user, func(stored *model.User) error {
+		if r.emailTaken(user.Email, user.UID) {
+			return apperrors.NewConflict("email", user.Email)
+		}
+
+		stored.Name = user.Name
+		stored.Email = user.Email
+		stored.Website = user.Website
+		return nil
+	})
+}
+
+// UpdateImage is used to separately update a user's image separate from
+// other account details
+func (r *memoryUserRepository) UpdateImage(ctx context.Context, uid uuid.UUID, imageURL string) (*model.User, error) {
+	user := &model.User{}
+	err := r.update(uid, user, func(stored *model.User) error {
+		stored.ImageURL = imageURL
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// UpdatePassword replaces the password hash of a user
+func (r *memoryUserRepository) UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error {
+	return r.update(uid, &model.User{}, func(stored *model.User) error {
+		stored.Password = password
+		return nil
+	})
+}
+
+// UpdateHandle changes the handle of a user, which keeps the previous
+// handle pointing to the user
+// Handles of other users, current or previous, are a Conflict
+func (r *memoryUserRepository) UpdateHandle(ctx context.Context, uid uuid.UUID, handle string) (*model.User, error) {
+	user := &model.User{}
+	err := r.update(uid, user, func(stored *model.User) error {
+		lower := strings.ToLower(handle)
+
+		if redirectUID, ok := r.redirects[lower]; ok {
+			if redirectUID != uid {
+				return apperrors.NewConflict("handle", handle)
+			}
+
+			// users may take back one of their previous handles
+			delete(r.redirects, lower)
+		}
+
+		for _, other := range r.users {
+			if other.UID != uid && other.Handle != "" && strings.EqualFold(other.Handle, handle) {
+				return apperrors.NewConflict("handle", handle)
+			}
+		}
+
+		// a change of case keeps the handle
+		if stored.Handle != "" && !strings.EqualFold(stored.Handle, handle) {
+			if _, ok := r.redirects[strings.ToLower(stored.Handle)]; !ok {
+				r.redirects[strings.ToLower(stored.Handle)] = uid
+			}
+		}
+
+		now := time.Now().UTC()
+		stored.Handle = handle
+		stored.HandleChangedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// UpdateStatus sets the status of a user along with its reason and expiry
+func (r *memoryUserRepository) UpdateStatus(ctx context.Context, user *model.User) error {
+	return r.update(user.UID, user, func(stored *model.User) error {
+		stored.Status = user.Status
+		stored.StatusReason = user.StatusReason
+		stored.StatusExpiresAt = user.StatusExpiresAt
+		return nil
+	})
+}
+
+// UpdateActiveOrg sets the organization a user acts for, nil for none
+func (r *memoryUserRepository) UpdateActiveOrg(ctx context.Context, uid uuid.UUID, orgID *uuid.UUID) (*model.User, error) {
+	user := &model.User{}
+	err := r.update(uid, user, func(stored *model.User) error {
+		stored.ActiveOrgID = orgID
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// UpgradeGuest sets the email, password and invite of a guest, who stops being a guest
+// Users who are not guests are NotFound, so an upgrade cannot overwrite an account
+func (r *memoryUserRepository) UpgradeGuest(ctx context.Context, user *model.User) error {
+	err := r.update(user.UID, user, func(stored *model.User) error {
+		if !stored.Guest {
+			return apperrors.NewNotFound("uid", user.UID.String())
+		}
+
+		if r.emailTaken(user.Email, user.UID) {
+			return apperrors.NewConflict("email", user.Email)
+		}
+
+		stored.Email = user.Email
+		stored.Password = user.Password
+		stored.InviteID = user.InviteID
+		stored.Guest = false
+		return nil
+	})
+	// unknown users are not guests either
+	if err, ok := err.(*apperrors.Error); ok && err.Type == apperrors.NotFound {
+		return apperrors.NewNotFound("guest", user.UID.String())
+	}
+
+	return err
+}
+
+// UpdateLastSeen records when tokens were last issued to a user
+func (r *memoryUserRepository) UpdateLastSeen(ctx context.Context, uid uuid.UUID, seenAt time.Time) error {
+	return r.update(uid, &model.User{}, func(stored *model.User) error {
+		seenAt := seenAt.UTC()
+		stored.LastSeenAt = &seenAt
+		return nil
+	})
+}
+
+// Delete deletes a user
+func (r *memoryUserRepository) Delete(ctx context.Context, uid uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[uid]; !ok {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	delete(r.users, uid)
+	return nil
+}
+
+// DeleteGuests deletes the guests last seen before a time, or created
+// before it if they were never seen, and returns them
+func (r *memoryUserRepository) DeleteGuests(ctx context.Context, seenBefore time.Time) ([]*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users := []*model.User{}
+	for uid, user := range r.users {
+		lastSeen := user.CreatedAt
+		if user.LastSeenAt != nil {
+			lastSeen = *user.LastSeenAt
+		}
+
+		if user.Guest && lastSeen.Before(seenBefore) {
+			users = append(users, user)
+			delete(r.users, uid)
+		}
+	}
+
+	return users, nil
+}
+
+// List retrieves a page of the users matching a filter, oldest first
+func (r *memoryUserRepository) List(ctx context.Context, filter *model.UserFilter) ([]*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := r.filter(filter)
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].UID.String() < users[j].UID.String()
+		}
+		return users[i].CreatedAt.Before(users[j].CreatedAt)
+	})
+
+	if filter.Offset >= len(users) {
+		return []*model.User{}, nil
+	}
+
+	users = users[filter.Offset:]
+	if len(users) > filter.Limit {
+		users = users[:filter.Limit]
+	}
+
+	return users, nil
+}
+
+// Count counts the users matching a filter, regardless of its page
+func (r *memoryUserRepository) Count(ctx context.Context, filter *model.UserFilter) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return int64(len(r.filter(filter))), nil
+}
+
+// Search finds users whose email, name or handle contains the query,
+// regardless of case. Exact matches of the email or handle come first
+func (r *memoryUserRepository) Search(ctx context.Context, query string, limit int) ([]*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := []*model.User{}
+	for _, user := range r.users {
+		if containsFold(user.Email, query) || containsFold(user.Name, query) || containsFold(user.Handle, query) {
+			users = append(users, copyUser(user))
+		}
+	}
+
+	exact := func(user *model.User) bool {
+		return strings.EqualFold(user.Email, query) || strings.EqualFold(user.Handle, query)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if exact(users[i]) != exact(users[j]) {
+			return exact(users[i])
+		}
+		return users[i].Email < users[j].Email
+	})
+
+	if len(users) > limit {
+		users = users[:limit]
+	}
+
+	return users, nil
+}
+
+// update applies a change to a stored user under the write lock,
+// copying the result into user on success
+func (r *memoryUserRepository) update(uid uuid.UUID, user *model.User, change func(stored *model.User) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[uid]
+	if !ok {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	// changes are made to a copy, so a failed change leaves the user as it was
+	changed := copyUser(stored)
+	if err := change(changed); err != nil {
+		return err
+	}
+
+	r.users[uid] = changed
+	*user = *copyUser(changed)
+	return nil
+}
+
+// emailTaken checks if a user other than uid has the email, regardless of case
+// The caller must hold the lock
+func (r *memoryUserRepository) emailTaken(email string, uid uuid.UUID) bool {
+	if email == "" {
+		return false
+	}
+
+	for _, user := range r.users {
+		if user.UID != uid && strings.EqualFold(user.Email, email) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filter returns copies of the users matching a filter
+// The caller must hold the lock
+func (r *memoryUserRepository) filter(filter *model.UserFilter) []*model.User {
+	users := []*model.User{}
+	for _, user := range r.users {
+		if filter.Email != "" && !containsFold(user.Email, filter.Email) {
+			continue
+		}
+		if filter.Name != "" && !containsFold(user.Name, filter.Name) {
+			continue
+		}
+		if filter.Status != "" && user.Status != filter.Status {
+			continue
+		}
+
+		users = append(users, copyUser(user))
+	}
+
+	return users
+}
+
+// copyUser returns a copy of a stored user, without the fields
+// which are not stored by the users table
+func copyUser(user *model.User) *model.User {
+	c := *user
+	c.Roles = nil
+	c.OrgRole = ""
+	c.InviteCode = ""
+	return &c
+}
+
+func containsFold(s string, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
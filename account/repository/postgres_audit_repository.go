@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"log"
+)
+
+// pGAuditRepository is data/repository implementation
+// of service layer AuditRepository
+type pGAuditRepository struct {
+	DB *sqlx.DB
+}
+
+// NewAuditRepository is a factory for initializing Audit Repositories
+func NewAuditRepository(db *sqlx.DB) model.AuditRepository {
+	return &pGAuditRepository{
+		DB: db,
+	}
+}
+
+// Create appends an event to the audit log
+func (r *pGAuditRepository) Create(ctx context.Context, event *model.AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (uid, actor_id, type, outcome, detail, ip, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at;
+	`
+
+	row := r.DB.QueryRowxContext(ctx, query, event.UID, event.ActorID, event.Type, event.Outcome, event.Detail, event.IP, event.UserAgent)
+	if err := row.Scan(&event.ID, &event.CreatedAt); err != nil {
+		log.Printf("Could not create audit event: %v. Reason: %v\n", event.Type, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// FindByUID retrieves a page of the events of a user, newest first
+func (r *pGAuditRepository) FindByUID(ctx context.Context, uid uuid.UUID, limit int, offset int) ([]*model.AuditEvent, error) {
+	events := []*model.AuditEvent{}
+
+	query := `
+		SELECT * FROM audit_events
+		WHERE uid=$1
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3;
+	`
+
+	if err := r.DB.SelectContext(ctx, &events, query, uid, limit, offset); err != nil {
+		log.Printf("Unable to get audit events for uid: %v. Err: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return events, nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"log"
+)
+
+// pGDeviceRepository is data/repository implementation
+// of service layer DeviceRepository
+type pGDeviceRepository struct {
+	DB *sqlx.DB
+}
+
+// NewDeviceRepository is a factory for initializing Device Repositories
+func NewDeviceRepository(db *sqlx.DB) model.DeviceRepository {
+	return &pGDeviceRepository{
+		DB: db,
+	}
+}
+
+// Upsert records a sign-in from a device, returning true if the device is new
+// Known devices only have their last sign-in updated
+func (r *pGDeviceRepository) Upsert(ctx context.Context, device *model.KnownDevice) (bool, error) {
+	query := `
+		INSERT INTO known_devices (uid, fingerprint, user_agent, ip_prefix)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (uid, fingerprint) DO UPDATE SET last_seen_at=NOW()
+		RETURNING first_seen_at, last_seen_at, (xmax = 0) AS inserted;
+	`
+
+	var inserted bool
+	row := r.DB.QueryRowxContext(ctx, query, device.UID, device.Fingerprint, device.UserAgent, device.IPPrefix)
+	if err := row.Scan(&device.FirstSeenAt, &device.LastSeenAt, &inserted); err != nil {
+		log.Printf("Could not record device for uid: %v. Reason: %v\n", device.UID, err)
+		return false, apperrors.NewInternal()
+	}
+
+	return inserted, nil
+}
+
+// Count counts the known devices of a user
+func (r *pGDeviceRepository) Count(ctx context.Context, uid uuid.UUID) (int64, error) {
+	var count int64
+
+	query := "SELECT COUNT(*) FROM known_devices WHERE uid=$1"
+
+	if err := r.DB.GetContext(ctx, &count, query, uid); err != nil {
+		log.Printf("Unable to count devices for uid: %v. Err: %v\n", uid, err)
+		return 0, apperrors.NewInternal()
+	}
+
+	return count, nil
+}
+
+// FindByUID retrieves the known devices of a user, last seen first
+func (r *pGDeviceRepository) FindByUID(ctx context.Context, uid uuid.UUID) ([]*model.KnownDevice, error) {
+	devices := []*model.KnownDevice{}
+
+	query := "SELECT * FROM known_devices WHERE uid=$1 ORDER BY last_seen_at DESC"
+
+	if err := r.DB.SelectContext(ctx, &devices, query, uid); err != nil {
+		log.Printf("Unable to get devices for uid: %v. Err: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return devices, nil
+}
+
+// DeleteByUID forgets all devices of a user
+func (r *pGDeviceRepository) DeleteByUID(ctx context.Context, uid uuid.UUID) error {
+	query := "DELETE FROM known_devices WHERE uid=$1"
+
+	if _, err := r.DB.ExecContext(ctx, query, uid); err != nil {
+		log.Printf("Unable to delete devices for uid: %v. Err: %v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
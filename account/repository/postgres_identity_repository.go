@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"log"
+)
+
+// pGIdentityRepository is data/repository implementation
+// of service layer IdentityRepository
+type pGIdentityRepository struct {
+	DB *sqlx.DB
+}
+
+// NewIdentityRepository is a factory for initializing Identity Repositories
+func NewIdentityRepository(db *sqlx.DB) model.IdentityRepository {
+	return &pGIdentityRepository{
+		DB: db,
+	}
+}
+
+// Create links a provider account to a user
+// A provider account can only be linked once, and a user can only link one account per provider
+func (r *pGIdentityRepository) Create(ctx context.Context, identity *model.Identity) error {
+	query := `
+		INSERT INTO identities (provider, subject, uid, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING *;
+	`
+
+	if err := r.DB.GetContext(ctx, identity, query, identity.Provider, identity.Subject, identity.UID, identity.Email); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			log.Printf("Could not link %v identity for uid: %v. Reason: %v\n", identity.Provider, identity.UID, err.Code.Name())
+			return apperrors.NewConflict("identity", identity.Provider)
+		}
+
+		log.Printf("Could not link %v identity for uid: %v. Reason: %v\n", identity.Provider, identity.UID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// Find retrieves the identity of a provider account
+func (r *pGIdentityRepository) Find(ctx context.Context, provider string, subject string) (*model.Identity, error) {
+	identity := &model.Identity{}
+
+	query := "SELECT * FROM identities WHERE provider=$1 AND subject=$2"
+
+	if err := r.DB.GetContext(ctx, identity, query, provider, subject); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NewNotFound("identity", provider)
+		}
+
+		log.Printf("Unable to get %v identity. Err: %v\n", provider, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return identity, nil
+}
+
+// FindByUID retrieves all identities linked to a user
+func (r *pGIdentityRepository) FindByUID(ctx context.Context, uid uuid.UUID) ([]*model.Identity, error) {
+	identities := []*model.Identity{}
+
+	query := "SELECT * FROM identities WHERE uid=$1 ORDER BY created_at"
+
+	if err := r.DB.SelectContext(ctx, &identities, query, uid); err != nil {
+		log.Printf("Unable to get identities for uid: %v. Err: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return identities, nil
+}
+
+// Delete unlinks the identity of a provider from a user
+func (r *pGIdentityRepository) Delete(ctx context.Context, uid uuid.UUID, provider string) error {
+	query := "DELETE FROM identities WHERE uid=$1 AND provider=$2"
+
+	result, err := r.DB.ExecContext(ctx, query, uid, provider)
+	if err != nil {
+		log.Printf("Unable to unlink %v identity for uid: %v. Err: %v\n", provider, uid, err)
+		return apperrors.NewInternal()
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewInternal()
+	}
+
+	if rows == 0 {
+		return apperrors.NewNotFound("identity", provider)
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"log"
+)
+
+// pGInviteRepository is data/repository implementation
+// of service layer InviteRepository
+type pGInviteRepository struct {
+	DB *sqlx.DB
+}
+
+// NewInviteRepository is a factory for initializing Invite Repositories
+func NewInviteRepository(db *sqlx.DB) model.InviteRepository {
+	return &pGInviteRepository{
+		DB: db,
+	}
+}
+
+// Create stores a new invite
+func (r *pGInviteRepository) Create(ctx context.Context, invite *model.Invite) error {
+	query := `
+		INSERT INTO invites (code_hash, max_uses, expires_at, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING *;
+	`
+
+	code := invite.Code
+	if err := r.DB.GetContext(ctx, invite, query, invite.CodeHash, invite.MaxUses, invite.ExpiresAt, invite.CreatedBy); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			log.Printf("Could not create invite. Reason: %v\n", err.Code.Name())
+			return apperrors.NewConflict("invite", "code")
+		}
+
+		log.Printf("Could not create invite. Reason: %v\n", err)
+		return apperrors.NewInternal()
+	}
+
+	// the code is not stored, so it is not returned by the query
+	invite.Code = code
+	return nil
+}
+
+// FindAll retrieves all invites, newest first
+func (r *pGInviteRepository) FindAll(ctx context.Context) ([]*model.Invite, error) {
+	invites := []*model.Invite{}
+
+	query := "SELECT * FROM invites ORDER BY created_at DESC"
+
+	if err := r.DB.SelectContext(ctx, &invites, query); err != nil {
+		log.Printf("Unable to get invites. Err: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return invites, nil
+}
+
+// Revoke makes an invite unusable
+func (r *pGInviteRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	query := "UPDATE invites SET revoked=TRUE WHERE id=$1"
+
+	result, err := r.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		log.Printf("Unable to revoke invite: %v. Err: %v\n", id, err)
+		return apperrors.NewInternal()
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewInternal()
+	}
+
+	if rows == 0 {
+		return apperrors.NewNotFound("invite", id.String())
+	}
+
+	return nil
+}
+
+// Redeem uses up one use of a valid invite in a single statement,
+// so concurrent sign-ups cannot redeem more uses than the invite has
+func (r *pGInviteRepository) Redeem(ctx context.Context, codeHash string) (*model.Invite, error) {
+	invite := &model.Invite{}
+
+	query := `
+		UPDATE invites
+		SET uses=uses+1
+		WHERE code_hash=$1
+			AND NOT revoked
+			AND uses < max_uses
+			AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING *;
+	`
+
+	if err := r.DB.GetContext(ctx, invite, query, codeHash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NewNotFound("invite", "code")
+		}
+
+		log.Printf("Unable to redeem invite. Err: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return invite, nil
+}
+
+// Release gives back a use of an invite
+func (r *pGInviteRepository) Release(ctx context.Context, id uuid.UUID) error {
+	query := "UPDATE invites SET uses=uses-1 WHERE id=$1 AND uses > 0"
+
+	if _, err := r.DB.ExecContext(ctx, query, id); err != nil {
+		log.Printf("Unable to release invite: %v. Err: %v\n", id, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
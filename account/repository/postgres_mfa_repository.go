@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"log"
+)
+
+// pGMFARepository is data/repository implementation
+// of service layer MFARepository
+type pGMFARepository struct {
+	DB *sqlx.DB
+}
+
+// NewMFARepository is a factory for initializing MFA Repositories
+func NewMFARepository(db *sqlx.DB) model.MFARepository {
+	return &pGMFARepository{
+		DB: db,
+	}
+}
+
+// SetTOTP stores a new, unconfirmed enrollment, replacing any previous
+// unconfirmed one. A confirmed enrollment is never overwritten
+func (r *pGMFARepository) SetTOTP(ctx context.Context, totp *model.TOTP) error {
+	query := `
+		INSERT INTO totp_credentials (uid, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (uid) DO UPDATE
+		SET secret=EXCLUDED.secret, last_used_step=0, created_at=NOW()
+		WHERE totp_credentials.confirmed = FALSE
+		RETURNING *;
+	`
+
+	if err := r.DB.GetContext(ctx, totp, query, totp.UID, totp.Secret); err != nil {
+		if err == sql.ErrNoRows {
+			return apperrors.NewConflict("totp", totp.UID.String())
+		}
+
+		log.Printf("Could not store totp for uid: %v. Reason: %v\n", totp.UID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// FindTOTP retrieves a user's enrollment
+func (r *pGMFARepository) FindTOTP(ctx context.Context, uid uuid.UUID) (*model.TOTP, error) {
+	totp := &model.TOTP{}
+
+	query := "SELECT * FROM totp_credentials WHERE uid=$1"
+
+	if err := r.DB.GetContext(ctx, totp, query, uid); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NewNotFound("totp", uid.String())
+		}
+
+		log.Printf("Unable to get totp for uid: %v. Err: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return totp, nil
+}
+
+// ConfirmTOTP marks an enrollment as confirmed and stores
+// the hashes of its recovery codes in a single transaction
+func (r *pGMFARepository) ConfirmTOTP(ctx context.Context, uid uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("Unable to begin transaction: %v\n", err)
+		return apperrors.NewInternal()
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE totp_credentials SET confirmed=TRUE, last_used_step=$2 WHERE uid=$1"
+	if _, err := tx.ExecContext(ctx, query, uid, step); err != nil {
+		log.Printf("Unable to confirm totp for uid: %v. Err: %v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE uid=$1", uid); err != nil {
+		log.Printf("Unable to delete recovery codes for uid: %v. Err: %v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	for _, codeHash := range recoveryCodeHashes {
+		query := "INSERT INTO recovery_codes (uid, code_hash) VALUES ($1, $2)"
+		if _, err := tx.ExecContext(ctx, query, uid, codeHash); err != nil {
+			log.Printf("Unable to store recovery code for uid: %v. Err: %v\n", uid, err)
+			return apperrors.NewInternal()
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Unable to commit totp confirmation for uid: %v. Err: %v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// UpdateTOTPStep records the time step of an accepted code
+// It returns false if that step, or a later one, was already used
+func (r *pGMFARepository) UpdateTOTPStep(ctx context.Context, uid uuid.UUID, step int64) (bool, error) {
+	query := "UPDATE totp_credentials SET last_used_step=$2 WHERE uid=$1 AND last_used_step < $2"
+
+	result, err := r.DB.ExecContext(ctx, query, uid, step)
+	if err != nil {
+		log.Printf("Unable to update totp step for uid: %v. Err: %v\n", uid, err)
+		return false, apperrors.NewInternal()
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, apperrors.NewInternal()
+	}
+
+	return rows == 1, nil
+}
+
+// DeleteTOTP removes an enrollment along with its recovery codes
+func (r *pGMFARepository) DeleteTOTP(ctx context.Context, uid uuid.UUID) error {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("Unable to begin transaction: %v\n", err)
+		return apperrors.NewInternal()
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE uid=$1", uid); err != nil {
+		log.Printf("Unable to delete recovery codes for uid: %v. Err: %v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM totp_credentials WHERE uid=$1", uid); err != nil {
+		log.Printf("Unable to delete totp for uid: %v. Err: %v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Unable to commit totp deletion for uid: %v. Err: %v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used
+// It returns false if there is no such unused code
+func (r *pGMFARepository) UseRecoveryCode(ctx context.Context, uid uuid.UUID, codeHash string) (bool, error) {
+	query := "UPDATE recovery_codes SET used_at=NOW() WHERE uid=$1 AND code_hash=$2 AND used_at IS NULL"
+
+	result, err := r.DB.ExecContext(ctx, query, uid, codeHash)
+	if err != nil {
+		log.Printf("Unable to use recovery code for uid: %v. Err: %v\n", uid, err)
+		return false, apperrors.NewInternal()
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, apperrors.NewInternal()
+	}
+
+	return rows == 1, nil
+}
@@ -0,0 +1,309 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"log"
+)
+
+// pGOrgRepository is data/repository implementation
+// of service layer OrgRepository
+type pGOrgRepository struct {
+	DB *sqlx.DB
+}
+
+// NewOrgRepository is a factory for initializing Organization Repositories
+func NewOrgRepository(db *sqlx.DB) model.OrgRepository {
+	return &pGOrgRepository{
+		DB: db,
+	}
+}
+
+// Create stores a new organization along with its owner in a single transaction
+func (r *pGOrgRepository) Create(ctx context.Context, org *model.Organization, owner uuid.UUID) error {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("Unable to begin transaction: %v\n", err)
+		return apperrors.NewInternal()
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO organizations (name, created_by)
+		VALUES ($1, $2)
+		RETURNING id, name, created_by, created_at;
+	`
+
+	if err := tx.GetContext(ctx, org, query, org.Name, owner); err != nil {
+		log.Printf("Could not create organization: %v. Reason: %v\n", org.Name, err)
+		return apperrors.NewInternal()
+	}
+
+	query = "INSERT INTO org_members (org_id, uid, role) VALUES ($1, $2, $3)"
+	if _, err := tx.ExecContext(ctx, query, org.ID, owner, model.OrgRoleOwner); err != nil {
+		log.Printf("Could not add owner: %v to organization: %v. Reason: %v\n", owner, org.ID, err)
+		return apperrors.NewInternal()
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Unable to commit organization: %v. Err: %v\n", org.ID, err)
+		return apperrors.NewInternal()
+	}
+
+	org.Role = model.OrgRoleOwner
+	return nil
+}
+
+// FindByID retrieves an organization by its id
+func (r *pGOrgRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.Organization, error) {
+	org := &model.Organization{}
+
+	query := "SELECT id, name, created_by, created_at FROM organizations WHERE id=$1"
+
+	if err := r.DB.GetContext(ctx, org, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NewNotFound("organization", id.String())
+		}
+
+		log.Printf("Unable to get organization: %v. Err: %v\n", id, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return org, nil
+}
+
+// FindByUID retrieves the organizations of a user along with the user's role, oldest first
+func (r *pGOrgRepository) FindByUID(ctx context.Context, uid uuid.UUID) ([]*model.Organization, error) {
+	orgs := []*model.Organization{}
+
+	query := `
+		SELECT organizations.id, organizations.name, organizations.created_by, organizations.created_at, org_members.role
+		FROM organizations JOIN org_members ON org_members.org_id=organizations.id
+		WHERE org_members.uid=$1
+		ORDER BY org_members.created_at;
+	`
+
+	if err := r.DB.SelectContext(ctx, &orgs, query, uid); err != nil {
+		log.Printf("Unable to get organizations for uid: %v. Err: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return orgs, nil
+}
+
+// FindMember retrieves the membership of a user in an organization
+func (r *pGOrgRepository) FindMember(ctx context.Context, orgID uuid.UUID, uid uuid.UUID) (*model.OrgMember, error) {
+	member := &model.OrgMember{}
+
+	query := `
+		SELECT org_members.org_id, org_members.uid, org_members.role, org_members.created_at, users.email, users.name
+		FROM org_members JOIN users ON users.uid=org_members.uid
+		WHERE org_members.org_id=$1 AND org_members.uid=$2;
+	`
+
+	if err := r.DB.GetContext(ctx, member, query, orgID, uid); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NewNotFound("member", uid.String())
+		}
+
+		log.Printf("Unable to get member: %v of organization: %v. Err: %v\n", uid, orgID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return member, nil
+}
+
+// FindMembers retrieves the members of an organization, oldest first
+func (r *pGOrgRepository) FindMembers(ctx context.Context, orgID uuid.UUID) ([]*model.OrgMember, error) {
+	members := []*model.OrgMember{}
+
+	query := `
+		SELECT org_members.org_id, org_members.uid, org_members.role, org_members.created_at, users.email, users.name
+		FROM org_members JOIN users ON users.uid=org_members.uid
+		WHERE org_members.org_id=$1
+		ORDER BY org_members.created_at;
+	`
+
+	if err := r.DB.SelectContext(ctx, &members, query, orgID); err != nil {
+		log.Printf("Unable to get members of organization: %v. Err: %v\n", orgID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return members, nil
+}
+
+// UpdateMemberRole changes the role of a member
+func (r *pGOrgRepository) UpdateMemberRole(ctx context.Context, orgID uuid.UUID, uid uuid.UUID, role string) error {
+	query := "UPDATE org_members SET role=$3 WHERE org_id=$1 AND uid=$2"
+
+	result, err := r.DB.ExecContext(ctx, query, orgID, uid, role)
+	if err != nil {
+		log.Printf("Unable to update role of member: %v of organization: %v. Err: %v\n", uid, orgID, err)
+		return apperrors.NewInternal()
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewInternal()
+	}
+
+	if rows == 0 {
+		return apperrors.NewNotFound("member", uid.String())
+	}
+
+	return nil
+}
+
+// RemoveMember removes a user from an organization, which stops
+// being the user's active organization
+func (r *pGOrgRepository) RemoveMember(ctx context.Context, orgID uuid.UUID, uid uuid.UUID) error {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("Unable to begin transaction: %v\n", err)
+		return apperrors.NewInternal()
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM org_members WHERE org_id=$1 AND uid=$2", orgID, uid)
+	if err != nil {
+		log.Printf("Unable to remove member: %v of organization: %v. Err: %v\n", uid, orgID, err)
+		return apperrors.NewInternal()
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewInternal()
+	}
+
+	if rows == 0 {
+		return apperrors.NewNotFound("member", uid.String())
+	}
+
+	query := "UPDATE users SET active_org_id=NULL WHERE uid=$1 AND active_org_id=$2"
+	if _, err := tx.ExecContext(ctx, query, uid, orgID); err != nil {
+		log.Printf("Unable to clear active organization of uid: %v. Err: %v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Unable to commit removal of member: %v. Err: %v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// CountOwners counts the owners of an organization
+func (r *pGOrgRepository) CountOwners(ctx context.Context, orgID uuid.UUID) (int64, error) {
+	var count int64
+
+	query := "SELECT COUNT(*) FROM org_members WHERE org_id=$1 AND role=$2"
+
+	if err := r.DB.GetContext(ctx, &count, query, orgID, model.OrgRoleOwner); err != nil {
+		log.Printf("Unable to count owners of organization: %v. Err: %v\n", orgID, err)
+		return 0, apperrors.NewInternal()
+	}
+
+	return count, nil
+}
+
+// CreateInvite stores an invite, replacing a pending invite of the same email
+func (r *pGOrgRepository) CreateInvite(ctx context.Context, invite *model.OrgInvite) error {
+	query := `
+		INSERT INTO org_invites (org_id, email, role, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (org_id, lower(email)) DO UPDATE
+		SET email=EXCLUDED.email, role=EXCLUDED.role, token_hash=EXCLUDED.token_hash,
+			invited_by=EXCLUDED.invited_by, expires_at=EXCLUDED.expires_at, created_at=NOW()
+		RETURNING *;
+	`
+
+	token := invite.Token
+	err := r.DB.GetContext(ctx, invite, query, invite.OrgID, invite.Email, invite.Role, invite.TokenHash, invite.InvitedBy, invite.ExpiresAt)
+	if err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "foreign_key_violation" {
+			return apperrors.NewNotFound("organization", invite.OrgID.String())
+		}
+
+		log.Printf("Could not create invite to organization: %v. Reason: %v\n", invite.OrgID, err)
+		return apperrors.NewInternal()
+	}
+
+	// the token is not stored, so it is not returned by the query
+	invite.Token = token
+	return nil
+}
+
+// FindInvite retrieves an invite by the hash of its token
+func (r *pGOrgRepository) FindInvite(ctx context.Context, tokenHash string) (*model.OrgInvite, error) {
+	invite := &model.OrgInvite{}
+
+	query := "SELECT * FROM org_invites WHERE token_hash=$1"
+
+	if err := r.DB.GetContext(ctx, invite, query, tokenHash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NewNotFound("invite", "token")
+		}
+
+		log.Printf("Unable to get organization invite. Err: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return invite, nil
+}
+
+// AcceptInvite deletes an invite and adds the user as a member with its role
+// Invites of users who are members already are used up all the same
+func (r *pGOrgRepository) AcceptInvite(ctx context.Context, inviteID uuid.UUID, uid uuid.UUID) (*model.OrgMember, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("Unable to begin transaction: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+	defer tx.Rollback()
+
+	invite := &model.OrgInvite{}
+	if err := tx.GetContext(ctx, invite, "DELETE FROM org_invites WHERE id=$1 RETURNING *", inviteID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NewNotFound("invite", inviteID.String())
+		}
+
+		log.Printf("Unable to delete organization invite: %v. Err: %v\n", inviteID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	query := `
+		INSERT INTO org_members (org_id, uid, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (org_id, uid) DO NOTHING
+		RETURNING org_id, uid, role, created_at;
+	`
+
+	member := &model.OrgMember{}
+	err = tx.GetContext(ctx, member, query, invite.OrgID, uid, invite.Role)
+	if err == sql.ErrNoRows {
+		if err := tx.Commit(); err != nil {
+			log.Printf("Unable to commit organization invite: %v. Err: %v\n", inviteID, err)
+			return nil, apperrors.NewInternal()
+		}
+
+		return nil, apperrors.NewConflict("member", uid.String())
+	}
+	if err != nil {
+		log.Printf("Unable to add member: %v to organization: %v. Err: %v\n", uid, invite.OrgID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Unable to commit organization invite: %v. Err: %v\n", inviteID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return member, nil
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"log"
+)
+
+// pGPasskeyRepository is data/repository implementation
+// of service layer PasskeyRepository
+type pGPasskeyRepository struct {
+	DB *sqlx.DB
+}
+
+// NewPasskeyRepository is a factory for initializing Passkey Repositories
+func NewPasskeyRepository(db *sqlx.DB) model.PasskeyRepository {
+	return &pGPasskeyRepository{
+		DB: db,
+	}
+}
+
+// passkeyRow maps the postgres array of transports
+type passkeyRow struct {
+	CredentialID []byte         `db:"credential_id"`
+	UID          uuid.UUID      `db:"uid"`
+	Name         string         `db:"name"`
+	PublicKey    []byte         `db:"public_key"`
+	SignCount    int64          `db:"sign_count"`
+	Transports   pq.StringArray `db:"transports"`
+	CreatedAt    time.Time      `db:"created_at"`
+	LastUsedAt   *time.Time     `db:"last_used_at"`
+}
+
+func (row *passkeyRow) credential() *model.PasskeyCredential {
+	return &model.PasskeyCredential{
+		ID:         row.CredentialID,
+		UID:        row.UID,
+		Name:       row.Name,
+		PublicKey:  row.PublicKey,
+		SignCount:  uint32(row.SignCount),
+		Transports: row.Transports,
+		CreatedAt:  row.CreatedAt,
+		LastUsedAt: row.LastUsedAt,
+	}
+}
+
+func credentialIDString(credentialID []byte) string {
+	return base64.RawURLEncoding.EncodeToString(credentialID)
+}
+
+// Create stores a newly registered credential
+func (r *pGPasskeyRepository) Create(ctx context.Context, credential *model.PasskeyCredential) error {
+	query := `
+		INSERT INTO passkey_credentials (credential_id, uid, name, public_key, sign_count, transports)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING *;
+	`
+
+	row := &passkeyRow{}
+	err := r.DB.GetContext(ctx, row, query,
+		[]byte(credential.ID),
+		credential.UID,
+		credential.Name,
+		credential.PublicKey,
+		int64(credential.SignCount),
+		pq.StringArray(credential.Transports),
+	)
+	if err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			log.Printf("Could not create a passkey with id: %v. Reason: %v\n", credentialIDString(credential.ID), err.Code.Name())
+			return apperrors.NewConflict("passkey", credentialIDString(credential.ID))
+		}
+
+		log.Printf("Could not create a passkey for uid: %v. Reason: %v\n", credential.UID, err)
+		return apperrors.NewInternal()
+	}
+
+	*credential = *row.credential()
+	return nil
+}
+
+// FindByID retrieves a credential by its WebAuthn credential ID
+func (r *pGPasskeyRepository) FindByID(ctx context.Context, credentialID []byte) (*model.PasskeyCredential, error) {
+	row := &passkeyRow{}
+
+	query := "SELECT * FROM passkey_credentials WHERE credential_id=$1"
+
+	if err := r.DB.GetContext(ctx, row, query, credentialID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NewNotFound("passkey", credentialIDString(credentialID))
+		}
+
+		log.Printf("Unable to get passkey with id: %v. Err: %v\n", credentialIDString(credentialID), err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return row.credential(), nil
+}
+
+// FindByUID retrieves all credentials of a user
+func (r *pGPasskeyRepository) FindByUID(ctx context.Context, uid uuid.UUID) ([]*model.PasskeyCredential, error) {
+	var rows []*passkeyRow
+
+	query := "SELECT * FROM passkey_credentials WHERE uid=$1 ORDER BY created_at"
+
+	if err := r.DB.SelectContext(ctx, &rows, query, uid); err != nil {
+		log.Printf("Unable to get passkeys for uid: %v. Err: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	credentials := make([]*model.PasskeyCredential, len(rows))
+	for i, row := range rows {
+		credentials[i] = row.credential()
+	}
+
+	return credentials, nil
+}
+
+// UpdateSignCount records a successful assertion
+func (r *pGPasskeyRepository) UpdateSignCount(ctx context.Context, credentialID []byte, signCount uint32) error {
+	query := "UPDATE passkey_credentials SET sign_count=$2, last_used_at=NOW() WHERE credential_id=$1"
+
+	if _, err := r.DB.ExecContext(ctx, query, credentialID, int64(signCount)); err != nil {
+		log.Printf("Unable to update sign count of passkey with id: %v. Err: %v\n", credentialIDString(credentialID), err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// Delete removes a credential of a user
+func (r *pGPasskeyRepository) Delete(ctx context.Context, uid uuid.UUID, credentialID []byte) error {
+	query := "DELETE FROM passkey_credentials WHERE uid=$1 AND credential_id=$2"
+
+	result, err := r.DB.ExecContext(ctx, query, uid, credentialID)
+	if err != nil {
+		log.Printf("Unable to delete passkey with id: %v. Err: %v\n", credentialIDString(credentialID), err)
+		return apperrors.NewInternal()
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewInternal()
+	}
+
+	if rows == 0 {
+		return apperrors.NewNotFound("passkey", credentialIDString(credentialID))
+	}
+
+	return nil
+}
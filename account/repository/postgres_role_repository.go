@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"log"
+)
+
+// pGRoleRepository is data/repository implementation
+// of service layer RoleRepository
+type pGRoleRepository struct {
+	DB *sqlx.DB
+}
+
+// NewRoleRepository is a factory for initializing Role Repositories
+func NewRoleRepository(db *sqlx.DB) model.RoleRepository {
+	return &pGRoleRepository{
+		DB: db,
+	}
+}
+
+// FindByUID retrieves the roles of a user
+func (r *pGRoleRepository) FindByUID(ctx context.Context, uid uuid.UUID) ([]string, error) {
+	roles := []string{}
+
+	query := "SELECT role FROM user_roles WHERE uid=$1 ORDER BY role"
+
+	if err := r.DB.SelectContext(ctx, &roles, query, uid); err != nil {
+		log.Printf("Unable to get roles for uid: %v. Err: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return roles, nil
+}
+
+// CountUsers counts the users with a role
+func (r *pGRoleRepository) CountUsers(ctx context.Context, role string) (int64, error) {
+	var count int64
+
+	query := "SELECT COUNT(*) FROM user_roles WHERE role=$1"
+
+	if err := r.DB.GetContext(ctx, &count, query, role); err != nil {
+		log.Printf("Unable to count users with role: %v. Err: %v\n", role, err)
+		return 0, apperrors.NewInternal()
+	}
+
+	return count, nil
+}
+
+// Grant gives a role to a user, granting a role twice keeps the first grant
+func (r *pGRoleRepository) Grant(ctx context.Context, uid uuid.UUID, role string, grantedBy *uuid.UUID) error {
+	query := `
+		INSERT INTO user_roles (uid, role, granted_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (uid, role) DO NOTHING;
+	`
+
+	if _, err := r.DB.ExecContext(ctx, query, uid, role, grantedBy); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "foreign_key_violation" {
+			return apperrors.NewNotFound("uid", uid.String())
+		}
+
+		log.Printf("Unable to grant role: %v to uid: %v. Err: %v\n", role, uid, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// Revoke takes a role from a user
+func (r *pGRoleRepository) Revoke(ctx context.Context, uid uuid.UUID, role string) error {
+	query := "DELETE FROM user_roles WHERE uid=$1 AND role=$2"
+
+	result, err := r.DB.ExecContext(ctx, query, uid, role)
+	if err != nil {
+		log.Printf("Unable to revoke role: %v from uid: %v. Err: %v\n", role, uid, err)
+		return apperrors.NewInternal()
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.NewInternal()
+	}
+
+	if rows == 0 {
+		return apperrors.NewNotFound("role", role)
+	}
+
+	return nil
+}
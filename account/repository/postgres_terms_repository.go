@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"log"
+)
+
+// pGTermsRepository is data/repository implementation
+// of service layer TermsRepository
+type pGTermsRepository struct {
+	DB *sqlx.DB
+}
+
+// NewTermsRepository is a factory for initializing Terms Repositories
+func NewTermsRepository(db *sqlx.DB) model.TermsRepository {
+	return &pGTermsRepository{
+		DB: db,
+	}
+}
+
+// Accept records the acceptance and sets it as the latest one of the user
+// Accepting a version again keeps the first acceptance of it
+func (r *pGTermsRepository) Accept(ctx context.Context, acceptance *model.TermsAcceptance) (*model.User, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("Unable to begin transaction: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE users SET terms_version=$2, terms_accepted_at=$3
+		WHERE uid=$1
+		RETURNING *;
+	`
+
+	user := &model.User{}
+	if err := tx.GetContext(ctx, user, query, acceptance.UID, acceptance.Version, acceptance.AcceptedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NewNotFound("uid", acceptance.UID.String())
+		}
+
+		log.Printf("Could not set terms version of user: %v. Reason: %v\n", acceptance.UID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	query = `
+		INSERT INTO terms_acceptances (uid, version, ip, user_agent, accepted_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (uid, version) DO NOTHING;
+	`
+
+	if _, err := tx.ExecContext(ctx, query, acceptance.UID, acceptance.Version, acceptance.IP, acceptance.UserAgent, acceptance.AcceptedAt); err != nil {
+		log.Printf("Could not record terms acceptance of user: %v. Reason: %v\n", acceptance.UID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Unable to commit terms acceptance of user: %v. Err: %v\n", acceptance.UID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return user, nil
+}
+
+// FindByUID retrieves every acceptance of a user, oldest first
+func (r *pGTermsRepository) FindByUID(ctx context.Context, uid uuid.UUID) ([]*model.TermsAcceptance, error) {
+	acceptances := []*model.TermsAcceptance{}
+
+	query := "SELECT * FROM terms_acceptances WHERE uid=$1 ORDER BY accepted_at"
+
+	if err := r.DB.SelectContext(ctx, &acceptances, query, uid); err != nil {
+		log.Printf("Unable to get terms acceptances for uid: %v. Err: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return acceptances, nil
+}
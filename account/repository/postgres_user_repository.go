@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"github.com/dolong2110/memorization-apps/account/model"
 	"github.com/dolong2110/memorization-apps/account/model/apperrors"
 
@@ -9,6 +11,8 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 	"log"
+	"strings"
+	"time"
 )
 
 // pGUserRepository is data/repository implementation
@@ -26,9 +30,9 @@ func NewUserRepository(db *sqlx.DB) model.UserRepository {
 
 // Create reaches out to database SQLX api
 func (r *pGUserRepository) Create(ctx context.Context, user *model.User) error {
-	query := "INSERT INTO users (email, password) VALUES ($1, $2) RETURNING *"
+	query := "INSERT INTO users (email, password, invite_id, guest) VALUES ($1, $2, $3, $4) RETURNING *"
 
-	if err := r.DB.GetContext(ctx, user, query, user.Email, user.Password); err != nil {
+	if err := r.DB.GetContext(ctx, user, query, user.Email, user.Password, user.InviteID, user.Guest); err != nil {
 		// check unique constraint
 		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
 			log.Printf("Could not create a user with email: %v. Reason: %v\n", user.Email, err.Code.Name())
@@ -41,11 +45,16 @@ func (r *pGUserRepository) Create(ctx context.Context, user *model.User) error {
 	return nil
 }
 
-// FindByEmail retrieves user row by email address
+// FindByEmail retrieves user row by email address, regardless of case
 func (r *pGUserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
 	user := &model.User{}
 
-	query := "SELECT * FROM users WHERE email=$1"
+	// guests have an empty email, which belongs to nobody
+	if email == "" {
+		return user, apperrors.NewNotFound("email", email)
+	}
+
+	query := "SELECT * FROM users WHERE lower(email)=lower($1)"
 
 	if err := r.DB.GetContext(ctx, user, query, email); err != nil {
 		log.Printf("Unable to get user with email address: %v. Err: %v\n", email, err)
@@ -55,6 +64,31 @@ func (r *pGUserRepository) FindByEmail(ctx context.Context, email string) (*mode
 	return user, nil
 }
 
+// FindByHandle retrieves the user with a handle, regardless of case,
+// or the user who had it before changing it
+func (r *pGUserRepository) FindByHandle(ctx context.Context, handle string) (*model.User, error) {
+	user := &model.User{}
+
+	query := `
+		SELECT * FROM users WHERE lower(handle)=lower($1) AND handle <> ''
+		UNION ALL
+		SELECT users.* FROM users JOIN handle_redirects ON handle_redirects.uid=users.uid
+		WHERE handle_redirects.handle=lower($1)
+		LIMIT 1;
+	`
+
+	if err := r.DB.GetContext(ctx, user, query, handle); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NewNotFound("handle", handle)
+		}
+
+		log.Printf("Unable to get user with handle: %v. Err: %v\n", handle, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return user, nil
+}
+
 // FindByID fetches user by id
 func (r *pGUserRepository) FindByID(ctx context.Context, uid uuid.UUID) (*model.User, error) {
 	user := &model.User{}
@@ -85,6 +119,12 @@ func (r *pGUserRepository) Update(ctx context.Context, user *model.User) error {
 	}
 
 	if err := nstmt.GetContext(ctx, user, user); err != nil {
+		// check unique constraint of the email
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			log.Printf("Could not update email of user: %v. Reason: %v\n", user.UID, err.Code.Name())
+			return apperrors.NewConflict("email", user.Email)
+		}
+
 		log.Printf("Failed to update details for user: %v\n", user)
 		return apperrors.NewInternal()
 	}
@@ -113,3 +153,299 @@ func (r *pGUserRepository) UpdateImage(ctx context.Context, uid uuid.UUID, image
 
 	return user, nil
 }
+
+// UpdatePassword replaces the password hash of a user
+func (r *pGUserRepository) UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error {
+	query := "UPDATE users SET password=$2 WHERE uid=$1"
+
+	result, err := r.DB.ExecContext(ctx, query, uid, password)
+	if err != nil {
+		log.Printf("Error updating password in database: %v\n", err)
+		return apperrors.NewInternal()
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+// UpdateHandle changes the handle of a user in a single transaction,
+// which keeps the previous handle pointing to the user
+// Handles of other users, current or previous, are a Conflict
+func (r *pGUserRepository) UpdateHandle(ctx context.Context, uid uuid.UUID, handle string) (*model.User, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("Unable to begin transaction: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+	defer tx.Rollback()
+
+	var oldHandle string
+	if err := tx.GetContext(ctx, &oldHandle, "SELECT handle FROM users WHERE uid=$1 FOR UPDATE", uid); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NewNotFound("uid", uid.String())
+		}
+
+		log.Printf("Unable to get handle of uid: %v. Err: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	var redirectUID uuid.UUID
+	err = tx.GetContext(ctx, &redirectUID, "SELECT uid FROM handle_redirects WHERE handle=lower($1)", handle)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		log.Printf("Unable to get redirect of handle: %v. Err: %v\n", handle, err)
+		return nil, apperrors.NewInternal()
+	case redirectUID != uid:
+		return nil, apperrors.NewConflict("handle", handle)
+	default:
+		// users may take back one of their previous handles
+		if _, err := tx.ExecContext(ctx, "DELETE FROM handle_redirects WHERE handle=lower($1)", handle); err != nil {
+			log.Printf("Unable to delete redirect of handle: %v. Err: %v\n", handle, err)
+			return nil, apperrors.NewInternal()
+		}
+	}
+
+	// a change of case keeps the handle
+	if oldHandle != "" && !strings.EqualFold(oldHandle, handle) {
+		query := "INSERT INTO handle_redirects (handle, uid) VALUES (lower($1), $2) ON CONFLICT (handle) DO NOTHING"
+		if _, err := tx.ExecContext(ctx, query, oldHandle, uid); err != nil {
+			log.Printf("Unable to redirect handle: %v. Err: %v\n", oldHandle, err)
+			return nil, apperrors.NewInternal()
+		}
+	}
+
+	query := `
+		UPDATE users
+		SET handle=$2, handle_changed_at=NOW()
+		WHERE uid=$1
+		RETURNING *;
+	`
+
+	user := &model.User{}
+	if err := tx.GetContext(ctx, user, query, uid, handle); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			return nil, apperrors.NewConflict("handle", handle)
+		}
+
+		log.Printf("Unable to update handle of uid: %v. Err: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Unable to commit handle change for uid: %v. Err: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return user, nil
+}
+
+// UpdateStatus sets the status of a user along with its reason and expiry
+func (r *pGUserRepository) UpdateStatus(ctx context.Context, user *model.User) error {
+	query := `
+		UPDATE users
+		SET status=$2, status_reason=$3, status_expires_at=$4
+		WHERE uid=$1
+		RETURNING *;
+	`
+
+	err := r.DB.GetContext(ctx, user, query, user.UID, user.Status, user.StatusReason, user.StatusExpiresAt)
+	if err == sql.ErrNoRows {
+		return apperrors.NewNotFound("uid", user.UID.String())
+	}
+	if err != nil {
+		log.Printf("Error updating status in database: %v\n", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// UpdateActiveOrg sets the organization a user acts for, nil for none
+func (r *pGUserRepository) UpdateActiveOrg(ctx context.Context, uid uuid.UUID, orgID *uuid.UUID) (*model.User, error) {
+	query := `
+		UPDATE users
+		SET active_org_id=$2
+		WHERE uid=$1
+		RETURNING *;
+	`
+
+	user := &model.User{}
+	err := r.DB.GetContext(ctx, user, query, uid, orgID)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.NewNotFound("uid", uid.String())
+	}
+	if err != nil {
+		log.Printf("Error updating active_org_id in database: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return user, nil
+}
+
+// UpgradeGuest sets the email, password and invite of a guest, who stops being a guest
+// Users who are not guests are NotFound, so an upgrade cannot overwrite an account
+func (r *pGUserRepository) UpgradeGuest(ctx context.Context, user *model.User) error {
+	query := `
+		UPDATE users
+		SET email=$2, password=$3, invite_id=$4, guest=FALSE
+		WHERE uid=$1 AND guest
+		RETURNING *;
+	`
+
+	err := r.DB.GetContext(ctx, user, query, user.UID, user.Email, user.Password, user.InviteID)
+	if err == sql.ErrNoRows {
+		return apperrors.NewNotFound("guest", user.UID.String())
+	}
+	if err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			log.Printf("Could not upgrade guest to email: %v. Reason: %v\n", user.Email, err.Code.Name())
+			return apperrors.NewConflict("email", user.Email)
+		}
+
+		log.Printf("Could not upgrade guest: %v. Reason: %v\n", user.UID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// UpdateLastSeen records when tokens were last issued to a user
+func (r *pGUserRepository) UpdateLastSeen(ctx context.Context, uid uuid.UUID, seenAt time.Time) error {
+	query := "UPDATE users SET last_seen_at=$2 WHERE uid=$1"
+
+	result, err := r.DB.ExecContext(ctx, query, uid, seenAt)
+	if err != nil {
+		log.Printf("Error updating last_seen_at in database: %v\n", err)
+		return apperrors.NewInternal()
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+// Delete deletes a user
+func (r *pGUserRepository) Delete(ctx context.Context, uid uuid.UUID) error {
+	query := "DELETE FROM users WHERE uid=$1"
+
+	result, err := r.DB.ExecContext(ctx, query, uid)
+	if err != nil {
+		log.Printf("Error deleting user: %v from database: %v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+// DeleteGuests deletes the guests last seen before a time, or created
+// before it if they were never seen, and returns them
+func (r *pGUserRepository) DeleteGuests(ctx context.Context, seenBefore time.Time) ([]*model.User, error) {
+	users := []*model.User{}
+
+	query := "DELETE FROM users WHERE guest AND COALESCE(last_seen_at, created_at) < $1 RETURNING *"
+
+	if err := r.DB.SelectContext(ctx, &users, query, seenBefore); err != nil {
+		log.Printf("Unable to delete guests last seen before: %v. Err: %v\n", seenBefore, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return users, nil
+}
+
+// List retrieves a page of the users matching a filter, oldest first
+func (r *pGUserRepository) List(ctx context.Context, filter *model.UserFilter) ([]*model.User, error) {
+	users := []*model.User{}
+
+	where, args := userFilterWhere(filter)
+	query := fmt.Sprintf(
+		"SELECT * FROM users %s ORDER BY created_at, uid LIMIT $%d OFFSET $%d",
+		where, len(args)+1, len(args)+2,
+	)
+	args = append(args, filter.Limit, filter.Offset)
+
+	if err := r.DB.SelectContext(ctx, &users, query, args...); err != nil {
+		log.Printf("Unable to list users. Err: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return users, nil
+}
+
+// Count counts the users matching a filter, regardless of its page
+func (r *pGUserRepository) Count(ctx context.Context, filter *model.UserFilter) (int64, error) {
+	var count int64
+
+	where, args := userFilterWhere(filter)
+	query := "SELECT COUNT(*) FROM users " + where
+
+	if err := r.DB.GetContext(ctx, &count, query, args...); err != nil {
+		log.Printf("Unable to count users. Err: %v\n", err)
+		return 0, apperrors.NewInternal()
+	}
+
+	return count, nil
+}
+
+// Search finds users whose email, name or handle contains the query,
+// regardless of case. Exact matches of the email or handle come first
+func (r *pGUserRepository) Search(ctx context.Context, query string, limit int) ([]*model.User, error) {
+	users := []*model.User{}
+
+	sqlQuery := `
+		SELECT * FROM users
+		WHERE email ILIKE $1 OR name ILIKE $1 OR handle ILIKE $1
+		ORDER BY (lower(email)=lower($2) OR lower(handle)=lower($2)) DESC, email
+		LIMIT $3;
+	`
+
+	if err := r.DB.SelectContext(ctx, &users, sqlQuery, likePattern(query), query, limit); err != nil {
+		log.Printf("Unable to search users. Err: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return users, nil
+}
+
+// userFilterWhere builds the WHERE clause and its arguments for a filter
+func userFilterWhere(filter *model.UserFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Email != "" {
+		args = append(args, likePattern(filter.Email))
+		conditions = append(conditions, fmt.Sprintf("email ILIKE $%d", len(args)))
+	}
+
+	if filter.Name != "" {
+		args = append(args, likePattern(filter.Name))
+		conditions = append(conditions, fmt.Sprintf("name ILIKE $%d", len(args)))
+	}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status=$%d", len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// likePattern matches values containing the text, whose
+// wildcard characters are matched literally
+func likePattern(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return "%" + replacer.Replace(text) + "%"
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"log"
+	"time"
+)
+
+// redisExportRepository is data/repository implementation
+// of service layer ExportRepository
+type redisExportRepository struct {
+	Redis *redis.Client
+}
+
+// NewExportRepository is a factory for initializing Export Repositories
+func NewExportRepository(redisClient *redis.Client) model.ExportRepository {
+	return &redisExportRepository{
+		Redis: redisClient,
+	}
+}
+
+func exportKey(exportID uuid.UUID) string {
+	return fmt.Sprintf("export:%s", exportID)
+}
+
+func exportTokenKey(downloadToken string) string {
+	return fmt.Sprintf("export_token:%s", downloadToken)
+}
+
+func exportArchiveKey(exportID uuid.UUID) string {
+	return fmt.Sprintf("export_archive:%s", exportID)
+}
+
+func exportPendingKey(uid uuid.UUID) string {
+	return fmt.Sprintf("export_pending:%s", uid)
+}
+
+// SetExport stores the export metadata along with a lookup
+// from its download token, both expire with the download link
+func (r *redisExportRepository) SetExport(ctx context.Context, export *model.Export, expiresIn time.Duration) error {
+	data, err := json.Marshal(struct {
+		*model.Export
+		DownloadToken string `json:"download_token"`
+	}{export, export.DownloadToken})
+	if err != nil {
+		log.Printf("Could not marshal export: %s: %v\n", export.ID, err)
+		return apperrors.NewInternal()
+	}
+
+	pipe := r.Redis.TxPipeline()
+	pipe.Set(ctx, exportKey(export.ID), data, expiresIn)
+	pipe.Set(ctx, exportTokenKey(export.DownloadToken), export.ID.String(), expiresIn)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Could not SET export to redis for exportID: %s: %v\n", export.ID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// GetExport retrieves the export metadata by its ID
+func (r *redisExportRepository) GetExport(ctx context.Context, exportID uuid.UUID) (*model.Export, error) {
+	data, err := r.Redis.Get(ctx, exportKey(exportID)).Bytes()
+	if err == redis.Nil {
+		return nil, apperrors.NewNotFound("export", exportID.String())
+	}
+	if err != nil {
+		log.Printf("Could not GET export from redis for exportID: %s: %v\n", exportID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	stored := struct {
+		*model.Export
+		DownloadToken string `json:"download_token"`
+	}{Export: &model.Export{}}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		log.Printf("Could not unmarshal export: %s: %v\n", exportID, err)
+		return nil, apperrors.NewInternal()
+	}
+	stored.Export.DownloadToken = stored.DownloadToken
+
+	return stored.Export, nil
+}
+
+// GetExportByToken retrieves the export metadata by its download token
+func (r *redisExportRepository) GetExportByToken(ctx context.Context, downloadToken string) (*model.Export, error) {
+	id, err := r.Redis.Get(ctx, exportTokenKey(downloadToken)).Result()
+	if err == redis.Nil {
+		return nil, apperrors.NewNotFound("export", "download token")
+	}
+	if err != nil {
+		log.Printf("Could not GET export token from redis: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	exportID, err := uuid.Parse(id)
+	if err != nil {
+		log.Printf("Stored export ID could not be parsed as UUID: %s\n%v\n", id, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return r.GetExport(ctx, exportID)
+}
+
+// SetArchive stores the zip archive of an export
+func (r *redisExportRepository) SetArchive(ctx context.Context, exportID uuid.UUID, archive []byte, expiresIn time.Duration) error {
+	if err := r.Redis.Set(ctx, exportArchiveKey(exportID), archive, expiresIn).Err(); err != nil {
+		log.Printf("Could not SET export archive to redis for exportID: %s: %v\n", exportID, err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+// GetArchive retrieves the zip archive of an export
+func (r *redisExportRepository) GetArchive(ctx context.Context, exportID uuid.UUID) ([]byte, error) {
+	archive, err := r.Redis.Get(ctx, exportArchiveKey(exportID)).Bytes()
+	if err == redis.Nil {
+		return nil, apperrors.NewNotFound("export archive", exportID.String())
+	}
+	if err != nil {
+		log.Printf("Could not GET export archive from redis for exportID: %s: %v\n", exportID, err)
+		return nil, apperrors.NewInternal()
+	}
+	return archive, nil
+}
+
+// SetPendingExport marks an export as the one being assembled for a user,
+// unless another one is, whose ID is returned
+func (r *redisExportRepository) SetPendingExport(ctx context.Context, uid uuid.UUID, exportID uuid.UUID, expiresIn time.Duration) (uuid.UUID, error) {
+	for {
+		set, err := r.Redis.SetNX(ctx, exportPendingKey(uid), exportID.String(), expiresIn).Result()
+		if err != nil {
+			log.Printf("Could not SET pending export to redis for uid: %s: %v\n", uid, err)
+			return uuid.Nil, apperrors.NewInternal()
+		}
+
+		if set {
+			return uuid.Nil, nil
+		}
+
+		id, err := r.Redis.Get(ctx, exportPendingKey(uid)).Result()
+		if err == redis.Nil {
+			// the pending export finished in between
+			continue
+		}
+		if err != nil {
+			log.Printf("Could not GET pending export from redis for uid: %s: %v\n", uid, err)
+			return uuid.Nil, apperrors.NewInternal()
+		}
+
+		pendingID, err := uuid.Parse(id)
+		if err != nil {
+			log.Printf("Stored export ID could not be parsed as UUID: %s\n%v\n", id, err)
+			return uuid.Nil, apperrors.NewInternal()
+		}
+
+		return pendingID, nil
+	}
+}
+
+// DeletePendingExport lets the user request another export
+func (r *redisExportRepository) DeletePendingExport(ctx context.Context, uid uuid.UUID) error {
+	if err := r.Redis.Del(ctx, exportPendingKey(uid)).Err(); err != nil {
+		log.Printf("Could not DEL pending export from redis for uid: %s: %v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
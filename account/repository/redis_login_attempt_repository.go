@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/go-redis/redis/v8"
+	"log"
+	"time"
+)
+
+// redisLoginAttemptRepository is data/repository implementation
+// of service layer LoginAttemptRepository
+type redisLoginAttemptRepository struct {
+	Redis *redis.Client
+}
+
+// NewLoginAttemptRepository is a factory for initializing Login Attempt Repositories
+func NewLoginAttemptRepository(redisClient *redis.Client) model.LoginAttemptRepository {
+	return &redisLoginAttemptRepository{
+		Redis: redisClient,
+	}
+}
+
+func loginFailuresKey(key string) string {
+	return fmt.Sprintf("login_failures:%s", key)
+}
+
+func attemptCountKey(key string) string {
+	return fmt.Sprintf("attempt_count:%s", key)
+}
+
+func loginBlockKey(key string) string {
+	return fmt.Sprintf("login_block:%s", key)
+}
+
+func unlockTokenKey(token string) string {
+	return fmt.Sprintf("unlock_token:%s", token)
+}
+
+// IncrementFailures counts a failed sign-in for key within a fixed window
+// and returns the new count
+func (r *redisLoginAttemptRepository) IncrementFailures(ctx context.Context, key string, window time.Duration) (int64, error) {
+	count, err := r.increment(ctx, loginFailuresKey(key), window)
+	if err != nil {
+		log.Printf("Could not INCR login failures in redis for: %s: %v\n", key, err)
+		return 0, apperrors.NewInternal()
+	}
+
+	return count, nil
+}
+
+// IncrementCount counts an attempt other than a sign-in for key, such as a
+// guest account created, within a fixed window and returns the new count
+func (r *redisLoginAttemptRepository) IncrementCount(ctx context.Context, key string, window time.Duration) (int64, error) {
+	count, err := r.increment(ctx, attemptCountKey(key), window)
+	if err != nil {
+		log.Printf("Could not INCR attempt count in redis for: %s: %v\n", key, err)
+		return 0, apperrors.NewInternal()
+	}
+
+	return count, nil
+}
+
+// increment adds one to the counter at redisKey in a transaction, which
+// creates the counter with the expiry of the window if it does not exist,
+// so a counter never outlives its window
+func (r *redisLoginAttemptRepository) increment(ctx context.Context, redisKey string, window time.Duration) (int64, error) {
+	pipe := r.Redis.TxPipeline()
+	pipe.SetNX(ctx, redisKey, 0, window)
+	incr := pipe.Incr(ctx, redisKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	return incr.Val(), nil
+}
+
+// ResetFailures forgets the failures and any block of key
+func (r *redisLoginAttemptRepository) ResetFailures(ctx context.Context, key string) error {
+	if err := r.Redis.Del(ctx, loginFailuresKey(key), loginBlockKey(key)).Err(); err != nil {
+		log.Printf("Could not DEL login failures in redis for: %s: %v\n", key, err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+// Block rejects sign-in attempts for key during duration
+func (r *redisLoginAttemptRepository) Block(ctx context.Context, key string, duration time.Duration) error {
+	if err := r.Redis.Set(ctx, loginBlockKey(key), 0, duration).Err(); err != nil {
+		log.Printf("Could not SET login block to redis for: %s: %v\n", key, err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+// BlockedFor returns how long key remains blocked, 0 if it is not blocked
+func (r *redisLoginAttemptRepository) BlockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.Redis.PTTL(ctx, loginBlockKey(key)).Result()
+	if err != nil {
+		log.Printf("Could not get TTL of login block from redis for: %s: %v\n", key, err)
+		return 0, apperrors.NewInternal()
+	}
+
+	// negative values mean the key does not exist or does not expire
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// SetUnlockToken stores the token of an emailed unlock link
+func (r *redisLoginAttemptRepository) SetUnlockToken(ctx context.Context, token string, email string, expiresIn time.Duration) error {
+	if err := r.Redis.Set(ctx, unlockTokenKey(token), email, expiresIn).Err(); err != nil {
+		log.Printf("Could not SET unlock token to redis for email: %s: %v\n", email, err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+// GetUnlockToken retrieves and removes the email of an unlock link, so each link can only be used once
+func (r *redisLoginAttemptRepository) GetUnlockToken(ctx context.Context, token string) (string, error) {
+	key := unlockTokenKey(token)
+
+	pipe := r.Redis.TxPipeline()
+	get := pipe.Get(ctx, key)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		log.Printf("Could not GET unlock token from redis: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+
+	email, err := get.Result()
+	if err == redis.Nil {
+		return "", apperrors.NewAuthorization("Invalid or expired unlock link")
+	}
+	if err != nil {
+		log.Printf("Could not GET unlock token from redis: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+	return email, nil
+}
@@ -2,12 +2,14 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/dolong2110/memorization-apps/account/model"
 	"github.com/dolong2110/memorization-apps/account/model/apperrors"
 
 	"github.com/go-redis/redis/v8"
 	"log"
+	"strings"
 	"time"
 )
 
@@ -82,3 +84,376 @@ func (r *redisTokenRepository) DeleteUserRefreshToken(ctx context.Context, userI
 
 	return nil
 }
+
+// GetUserSessions looks for all tokens beginning with userID
+// and returns their token IDs along with their expiry
+func (r *redisTokenRepository) GetUserSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	pattern := fmt.Sprintf("%s:*", userID)
+
+	iter := r.Redis.Scan(ctx, 0, pattern, 5).Iterator()
+	sessions := []*model.Session{}
+
+	for iter.Next(ctx) {
+		key := iter.Val()
+		ttl, err := r.Redis.TTL(ctx, key).Result()
+		if err != nil {
+			log.Printf("Could not get TTL of refresh token: %s: %v\n", key, err)
+			return nil, apperrors.NewInternal()
+		}
+
+		// key expired between SCAN and TTL
+		if ttl < 0 {
+			continue
+		}
+
+		sessions = append(sessions, &model.Session{
+			TokenID:   strings.TrimPrefix(key, userID+":"),
+			ExpiresAt: time.Now().Add(ttl).UTC(),
+		})
+	}
+
+	if err := iter.Err(); err != nil {
+		log.Printf("Failed to scan refresh tokens for userID: %s: %v\n", userID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return sessions, nil
+}
+
+func mfaChallengeKey(challengeID string) string {
+	return fmt.Sprintf("mfa_challenge:%s", challengeID)
+}
+
+// SetMFAChallenge stores a pending second factor challenge for a user
+func (r *redisTokenRepository) SetMFAChallenge(ctx context.Context, challengeID string, userID string, expiresIn time.Duration) error {
+	key := mfaChallengeKey(challengeID)
+
+	pipe := r.Redis.TxPipeline()
+	pipe.HSet(ctx, key, "uid", userID, "attempts", 0)
+	pipe.Expire(ctx, key, expiresIn)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Could not SET mfa challenge to redis for userID: %s: %v\n", userID, err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+// GetMFAChallenge returns the userID a challenge was issued for
+func (r *redisTokenRepository) GetMFAChallenge(ctx context.Context, challengeID string) (string, error) {
+	userID, err := r.Redis.HGet(ctx, mfaChallengeKey(challengeID), "uid").Result()
+	if err == redis.Nil {
+		return "", apperrors.NewAuthorization("Invalid or expired MFA token")
+	}
+	if err != nil {
+		log.Printf("Could not GET mfa challenge from redis: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+	return userID, nil
+}
+
+// IncrementMFAChallengeAttempts counts a failed attempt at a challenge
+// and returns the attempts made so far
+func (r *redisTokenRepository) IncrementMFAChallengeAttempts(ctx context.Context, challengeID string) (int64, error) {
+	attempts, err := r.Redis.HIncrBy(ctx, mfaChallengeKey(challengeID), "attempts", 1).Result()
+	if err != nil {
+		log.Printf("Could not increment mfa challenge attempts in redis: %v\n", err)
+		return 0, apperrors.NewInternal()
+	}
+	return attempts, nil
+}
+
+// DeleteMFAChallenge removes a challenge once used or exhausted
+func (r *redisTokenRepository) DeleteMFAChallenge(ctx context.Context, challengeID string) error {
+	if err := r.Redis.Del(ctx, mfaChallengeKey(challengeID)).Err(); err != nil {
+		log.Printf("Could not delete mfa challenge from redis: %v\n", err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+func webAuthnSessionKey(sessionID string) string {
+	return fmt.Sprintf("webauthn_session:%s", sessionID)
+}
+
+// SetWebAuthnSession stores the challenge of a pending WebAuthn ceremony
+func (r *redisTokenRepository) SetWebAuthnSession(ctx context.Context, sessionID string, session *model.WebAuthnSession, expiresIn time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		log.Printf("Could not marshal webauthn session: %v\n", err)
+		return apperrors.NewInternal()
+	}
+
+	if err := r.Redis.Set(ctx, webAuthnSessionKey(sessionID), data, expiresIn).Err(); err != nil {
+		log.Printf("Could not SET webauthn session to redis: %v\n", err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+// GetWebAuthnSession retrieves and removes a pending WebAuthn ceremony,
+// so each challenge can only be answered once
+func (r *redisTokenRepository) GetWebAuthnSession(ctx context.Context, sessionID string) (*model.WebAuthnSession, error) {
+	key := webAuthnSessionKey(sessionID)
+
+	pipe := r.Redis.TxPipeline()
+	get := pipe.Get(ctx, key)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		log.Printf("Could not GET webauthn session from redis: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	data, err := get.Bytes()
+	if err == redis.Nil {
+		return nil, apperrors.NewAuthorization("Invalid or expired passkey session")
+	}
+	if err != nil {
+		log.Printf("Could not GET webauthn session from redis: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	session := &model.WebAuthnSession{}
+	if err := json.Unmarshal(data, session); err != nil {
+		log.Printf("Could not unmarshal webauthn session: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return session, nil
+}
+
+func magicLinkKey(token string) string {
+	return fmt.Sprintf("signin_link:%s", token)
+}
+
+// SetMagicLink stores a pending sign-in link
+func (r *redisTokenRepository) SetMagicLink(ctx context.Context, token string, link *model.MagicLink, expiresIn time.Duration) error {
+	data, err := json.Marshal(link)
+	if err != nil {
+		log.Printf("Could not marshal sign-in link for email: %s: %v\n", link.Email, err)
+		return apperrors.NewInternal()
+	}
+
+	if err := r.Redis.Set(ctx, magicLinkKey(token), data, expiresIn).Err(); err != nil {
+		log.Printf("Could not SET sign-in link to redis for email: %s: %v\n", link.Email, err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+// GetMagicLink retrieves and removes a sign-in link, so each link can only be used once
+func (r *redisTokenRepository) GetMagicLink(ctx context.Context, token string) (*model.MagicLink, error) {
+	key := magicLinkKey(token)
+
+	pipe := r.Redis.TxPipeline()
+	get := pipe.Get(ctx, key)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		log.Printf("Could not GET sign-in link from redis: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	data, err := get.Bytes()
+	if err == redis.Nil {
+		return nil, apperrors.NewAuthorization("Invalid or expired sign-in link")
+	}
+	if err != nil {
+		log.Printf("Could not GET sign-in link from redis: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	link := &model.MagicLink{}
+	if err := json.Unmarshal(data, link); err != nil {
+		log.Printf("Could not unmarshal sign-in link: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return link, nil
+}
+
+// IncrementMagicLinkRequests counts the sign-in links requested for an
+// email address within a fixed window and returns the new count
+func (r *redisTokenRepository) IncrementMagicLinkRequests(ctx context.Context, email string, window time.Duration) (int64, error) {
+	key := fmt.Sprintf("signin_link_requests:%s", email)
+
+	count, err := r.Redis.Incr(ctx, key).Result()
+	if err != nil {
+		log.Printf("Could not INCR sign-in link requests in redis for email: %s: %v\n", email, err)
+		return 0, apperrors.NewInternal()
+	}
+
+	// only the first request of a window sets the expiry
+	if count == 1 {
+		if err := r.Redis.Expire(ctx, key, window).Err(); err != nil {
+			log.Printf("Could not set expiry of sign-in link requests in redis for email: %s: %v\n", email, err)
+			return 0, apperrors.NewInternal()
+		}
+	}
+
+	return count, nil
+}
+
+func oauthStateKey(state string) string {
+	return fmt.Sprintf("oauth_state:%s", state)
+}
+
+// SetOAuthState stores a pending authorization request to a provider
+func (r *redisTokenRepository) SetOAuthState(ctx context.Context, state string, oauthState *model.OAuthState, expiresIn time.Duration) error {
+	data, err := json.Marshal(oauthState)
+	if err != nil {
+		log.Printf("Could not marshal oauth state: %v\n", err)
+		return apperrors.NewInternal()
+	}
+
+	if err := r.Redis.Set(ctx, oauthStateKey(state), data, expiresIn).Err(); err != nil {
+		log.Printf("Could not SET oauth state to redis: %v\n", err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+// GetOAuthState retrieves and removes a pending authorization request,
+// so each state can only be used for one callback
+func (r *redisTokenRepository) GetOAuthState(ctx context.Context, state string) (*model.OAuthState, error) {
+	key := oauthStateKey(state)
+
+	pipe := r.Redis.TxPipeline()
+	get := pipe.Get(ctx, key)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		log.Printf("Could not GET oauth state from redis: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	data, err := get.Bytes()
+	if err == redis.Nil {
+		return nil, apperrors.NewAuthorization("Invalid or expired authorization request")
+	}
+	if err != nil {
+		log.Printf("Could not GET oauth state from redis: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	oauthState := &model.OAuthState{}
+	if err := json.Unmarshal(data, oauthState); err != nil {
+		log.Printf("Could not unmarshal oauth state: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return oauthState, nil
+}
+
+func passwordResetKey(token string) string {
+	return fmt.Sprintf("password_reset:%s", token)
+}
+
+// SetPasswordResetToken stores the user a password reset link was sent to
+func (r *redisTokenRepository) SetPasswordResetToken(ctx context.Context, token string, userID string, expiresIn time.Duration) error {
+	if err := r.Redis.Set(ctx, passwordResetKey(token), userID, expiresIn).Err(); err != nil {
+		log.Printf("Could not SET password reset token to redis for userID: %s: %v\n", userID, err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+// PeekPasswordResetToken retrieves the user of a password reset link,
+// leaving the link usable
+func (r *redisTokenRepository) PeekPasswordResetToken(ctx context.Context, token string) (string, error) {
+	userID, err := r.Redis.Get(ctx, passwordResetKey(token)).Result()
+	if err == redis.Nil {
+		return "", apperrors.NewAuthorization("Invalid or expired password reset link")
+	}
+	if err != nil {
+		log.Printf("Could not GET password reset token from redis: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+	return userID, nil
+}
+
+// GetPasswordResetToken retrieves and removes the user of a password reset link,
+// so each link can only be used once
+func (r *redisTokenRepository) GetPasswordResetToken(ctx context.Context, token string) (string, error) {
+	key := passwordResetKey(token)
+
+	pipe := r.Redis.TxPipeline()
+	get := pipe.Get(ctx, key)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		log.Printf("Could not GET password reset token from redis: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+
+	userID, err := get.Result()
+	if err == redis.Nil {
+		return "", apperrors.NewAuthorization("Invalid or expired password reset link")
+	}
+	if err != nil {
+		log.Printf("Could not GET password reset token from redis: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+	return userID, nil
+}
+
+func accessTokensRevokedKey(userID string) string {
+	return fmt.Sprintf("access_revoked:%s", userID)
+}
+
+// SetAccessTokensRevokedAt denies the access tokens of a user issued up to revokedAt
+// The entry expires with the last of these tokens
+func (r *redisTokenRepository) SetAccessTokensRevokedAt(ctx context.Context, userID string, revokedAt time.Time, expiresIn time.Duration) error {
+	if err := r.Redis.Set(ctx, accessTokensRevokedKey(userID), revokedAt.UnixMilli(), expiresIn).Err(); err != nil {
+		log.Printf("Could not SET access token denylist entry to redis for userID: %s: %v\n", userID, err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+// GetAccessTokensRevokedAt returns when the access tokens of a user were last revoked,
+// or the zero time if there are no revoked tokens that are still valid
+func (r *redisTokenRepository) GetAccessTokensRevokedAt(ctx context.Context, userID string) (time.Time, error) {
+	revokedAt, err := r.Redis.Get(ctx, accessTokensRevokedKey(userID)).Int64()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		log.Printf("Could not GET access token denylist entry from redis for userID: %s: %v\n", userID, err)
+		return time.Time{}, apperrors.NewInternal()
+	}
+	return time.UnixMilli(revokedAt), nil
+}
+
+func deviceRevokeKey(token string) string {
+	return fmt.Sprintf("device_revoke:%s", token)
+}
+
+// SetDeviceRevokeToken stores the user a new device notification was sent to
+func (r *redisTokenRepository) SetDeviceRevokeToken(ctx context.Context, token string, userID string, expiresIn time.Duration) error {
+	if err := r.Redis.Set(ctx, deviceRevokeKey(token), userID, expiresIn).Err(); err != nil {
+		log.Printf("Could not SET device revoke token to redis for userID: %s: %v\n", userID, err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+// GetDeviceRevokeToken retrieves and removes the user of a "this wasn't me" link,
+// so each link can only be used once
+func (r *redisTokenRepository) GetDeviceRevokeToken(ctx context.Context, token string) (string, error) {
+	key := deviceRevokeKey(token)
+
+	pipe := r.Redis.TxPipeline()
+	get := pipe.Get(ctx, key)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		log.Printf("Could not GET device revoke token from redis: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+
+	userID, err := get.Result()
+	if err == redis.Nil {
+		return "", apperrors.NewAuthorization("Invalid or expired link")
+	}
+	if err != nil {
+		log.Printf("Could not GET device revoke token from redis: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+	return userID, nil
+}
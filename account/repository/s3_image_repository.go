@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/utils"
+
+	"github.com/minio/minio-go/v7"
+	"io"
+	"log"
+	"mime/multipart"
+	"strings"
+)
+
+// s3ImageRepository is an implementation of service layer ImageRepository
+// for S3 compatible object storage, like AWS S3, MinIO or Cloudflare R2
+type s3ImageRepository struct {
+	Client     *minio.Client
+	BucketName string
+	// URLTemplate is the public URL of an image, with {bucket} and {key}
+	// replaced by the bucket name and the object name
+	URLTemplate string
+}
+
+// NewS3ImageRepository is a factory for initializing S3 Image Repositories
+// urlTemplate is the public URL of an image, like "https://{bucket}.s3.amazonaws.com/{key}"
+func NewS3ImageRepository(s3Client *minio.Client, bucketName string, urlTemplate string) model.ImageRepository {
+	return &s3ImageRepository{
+		Client:      s3Client,
+		BucketName:  bucketName,
+		URLTemplate: urlTemplate,
+	}
+}
+
+// UpdateProfile uploads an image and returns its URL
+func (r *s3ImageRepository) UpdateProfile(ctx context.Context, objName string, imageFile multipart.File) (string, error) {
+	// the size saves the client from buffering the image for a multipart upload
+	size, err := imageFile.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = imageFile.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		log.Printf("Unable to get size of image file: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+
+	head := make([]byte, 512)
+	n, _ := imageFile.ReadAt(head, 0)
+
+	opts := minio.PutObjectOptions{
+		// only image types are stored as such, so the bucket never serves
+		// uploaded HTML or script with a type browsers render
+		ContentType: utils.ImageContentType(head[:n]),
+		// set cache control so profile image will be served fresh by browsers
+		CacheControl: "no-cache, max-age=0",
+	}
+
+	if _, err := r.Client.PutObject(ctx, r.BucketName, objName, imageFile, size, opts); err != nil {
+		log.Printf("Unable to write file to S3 bucket: %v. Err: %v\n", r.BucketName, err)
+		return "", apperrors.NewInternal()
+	}
+
+	replacer := strings.NewReplacer("{bucket}", r.BucketName, "{key}", objName)
+	return replacer.Replace(r.URLTemplate), nil
+}
+
+// DeleteProfile removes an image
+// Like S3, deleting an image which does not exist succeeds
+func (r *s3ImageRepository) DeleteProfile(ctx context.Context, objName string) error {
+	if err := r.Client.RemoveObject(ctx, r.BucketName, objName, minio.RemoveObjectOptions{}); err != nil {
+		log.Printf("Failed to delete image object with ID: %s from S3 bucket: %v. Err: %v\n", objName, r.BucketName, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// GetProfile returns a reader of an image
+func (r *s3ImageRepository) GetProfile(ctx context.Context, objName string) (io.ReadCloser, error) {
+	object, err := r.Client.GetObject(ctx, r.BucketName, objName, minio.GetObjectOptions{})
+	if err == nil {
+		// objects are fetched on first use, so a missing object is only found out here
+		_, err = object.Stat()
+	}
+	if err != nil {
+		log.Printf("Failed to read image object with ID: %s from S3 bucket: %v. Err: %v\n", objName, r.BucketName, err)
+		if object != nil {
+			object.Close()
+		}
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, apperrors.NewNotFound("image", objName)
+		}
+		return nil, apperrors.NewInternal()
+	}
+
+	return object, nil
+}
@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3Object is an object stored by fakeS3
+type fakeS3Object struct {
+	data         []byte
+	contentType  string
+	cacheControl string
+}
+
+// fakeS3 is a local stand-in for S3, which keeps the objects of a single
+// bucket in memory. Buckets are addressed by path, or by host under
+// the s3.test domain
+type fakeS3 struct {
+	mu          sync.Mutex
+	bucket      string
+	accessKeyID string
+	objects     map[string]*fakeS3Object
+	// paths are the hosts and paths requested, like "127.0.0.1:1234/images/abc"
+	paths []string
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.paths = append(s.paths, r.Host+r.URL.Path)
+
+	// only the scope of the signature is checked, not the signature itself
+	credential := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/", s.accessKeyID)
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, credential) || !strings.Contains(auth, "/us-east-1/s3/aws4_request") {
+		s.error(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	bucket, key := "", ""
+	if host, _, _ := net.SplitHostPort(r.Host); strings.HasSuffix(host, ".s3.test") {
+		bucket, key = strings.TrimSuffix(host, ".s3.test"), strings.TrimPrefix(r.URL.Path, "/")
+	} else {
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+		if len(parts) == 2 {
+			bucket, key = parts[0], parts[1]
+		}
+	}
+	if bucket != s.bucket {
+		s.error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			s.error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+
+		s.objects[key] = &fakeS3Object{
+			data:         data,
+			contentType:  r.Header.Get("Content-Type"),
+			cacheControl: r.Header.Get("Cache-Control"),
+		}
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodGet, http.MethodHead:
+		object, ok := s.objects[key]
+		if !ok {
+			s.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Type", object.contentType)
+		w.Header().Set("Content-Length", fmt.Sprint(len(object.data)))
+		if r.Method == http.MethodGet {
+			w.Write(object.data)
+		}
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (s *fakeS3) error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func TestS3ImageRepository(t *testing.T) {
+	ctx := context.TODO()
+
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"
+
+	// imageFile opens a file holding data, like an uploaded multipart.File
+	imageFile := func(t *testing.T, data string) *os.File {
+		path := filepath.Join(t.TempDir(), "upload")
+		assert.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+		file, err := os.Open(path)
+		assert.NoError(t, err)
+		t.Cleanup(func() { file.Close() })
+
+		return file
+	}
+
+	// newS3 starts a stand-in for S3 over TLS, as plain HTTP has uploads signed
+	// in chunks, and returns a client of it for endpoint
+	// Requests to any host go to the stand-in
+	newS3 := func(t *testing.T, endpoint string, accessKeyID string, lookup minio.BucketLookupType) (*fakeS3, *minio.Client) {
+		s3 := &fakeS3{
+			bucket:      "images",
+			accessKeyID: "key",
+			objects:     make(map[string]*fakeS3Object),
+		}
+
+		server := httptest.NewTLSServer(s3)
+		t.Cleanup(server.Close)
+
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		transport.TLSClientConfig.InsecureSkipVerify = true
+		transport.DialContext = func(ctx context.Context, network string, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		}
+
+		if endpoint == "" {
+			endpoint = server.Listener.Addr().String()
+		}
+
+		client, err := minio.New(endpoint, &minio.Options{
+			Creds:        credentials.NewStaticV4(accessKeyID, "secret", ""),
+			Secure:       true,
+			Region:       "us-east-1",
+			BucketLookup: lookup,
+			Transport:    transport,
+		})
+		assert.NoError(t, err)
+
+		return s3, client
+	}
+
+	t.Run("Path-style addressing", func(t *testing.T) {
+		s3, client := newS3(t, "", "key", minio.BucketLookupPath)
+		r := NewS3ImageRepository(client, "images", "https://cdn.test/{bucket}/{key}")
+
+		imageURL, err := r.UpdateProfile(ctx, "abc", imageFile(t, png))
+		assert.NoError(t, err)
+		assert.Equal(t, "https://cdn.test/images/abc", imageURL)
+
+		object := s3.objects["abc"]
+		assert.Equal(t, png, string(object.data))
+		assert.Equal(t, "image/png", object.contentType)
+		assert.Equal(t, "no-cache, max-age=0", object.cacheControl)
+		assert.Equal(t, client.EndpointURL().Host+"/images/abc", s3.paths[0])
+
+		image, err := r.GetProfile(ctx, "abc")
+		assert.NoError(t, err)
+		data, err := io.ReadAll(image)
+		assert.NoError(t, err)
+		assert.NoError(t, image.Close())
+		assert.Equal(t, png, string(data))
+
+		assert.NoError(t, r.DeleteProfile(ctx, "abc"))
+		assert.Empty(t, s3.objects)
+
+		_, err = r.GetProfile(ctx, "abc")
+		assert.Equal(t, apperrors.NotFound, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("Virtual-hosted-style addressing", func(t *testing.T) {
+		s3, client := newS3(t, "s3.test:9000", "key", minio.BucketLookupDNS)
+		r := NewS3ImageRepository(client, "images", "https://{bucket}.s3.test/{key}")
+
+		imageURL, err := r.UpdateProfile(ctx, "abc", imageFile(t, png))
+		assert.NoError(t, err)
+		assert.Equal(t, "https://images.s3.test/abc", imageURL)
+		assert.Equal(t, []string{"images.s3.test:9000/abc"}, s3.paths)
+
+		image, err := r.GetProfile(ctx, "abc")
+		assert.NoError(t, err)
+		assert.NoError(t, image.Close())
+	})
+
+	t.Run("Other content is stored as a download", func(t *testing.T) {
+		s3, client := newS3(t, "", "key", minio.BucketLookupPath)
+		r := NewS3ImageRepository(client, "images", "https://cdn.test/{bucket}/{key}")
+
+		_, err := r.UpdateProfile(ctx, "abc", imageFile(t, "<html><script>alert(1)</script></html>"))
+		assert.NoError(t, err)
+		assert.Equal(t, "application/octet-stream", s3.objects["abc"].contentType)
+	})
+
+	t.Run("Rejected credentials", func(t *testing.T) {
+		s3, client := newS3(t, "", "other", minio.BucketLookupPath)
+		r := NewS3ImageRepository(client, "images", "https://cdn.test/{bucket}/{key}")
+
+		_, err := r.UpdateProfile(ctx, "abc", imageFile(t, png))
+		assert.Equal(t, apperrors.Internal, err.(*apperrors.Error).Type)
+		assert.Empty(t, s3.objects)
+
+		_, err = r.GetProfile(ctx, "abc")
+		assert.Equal(t, apperrors.Internal, err.(*apperrors.Error).Type)
+	})
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// smtpMailer is a model.Mailer which delivers email through an SMTP server
+type smtpMailer struct {
+	Addr string
+	Auth smtp.Auth
+	From string
+}
+
+// NewSMTPMailer is a factory for initializing a Mailer sending through host:port
+// Authentication is skipped when no username is configured
+func NewSMTPMailer(host string, port string, username string, password string, from string) model.Mailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &smtpMailer{
+		Addr: net.JoinHostPort(host, port),
+		Auth: auth,
+		From: from,
+	}
+}
+
+// Send delivers a plain text email
+func (m *smtpMailer) Send(ctx context.Context, email *model.Email) error {
+	// header values must not break out of their line
+	if strings.ContainsAny(email.To+email.Subject, "\r\n") {
+		log.Printf("Refusing to send email with line breaks in its headers to: %s\n", email.To)
+		return apperrors.NewBadRequest("Invalid email address")
+	}
+
+	msg := fmt.Sprintf(
+		"From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=\"utf-8\"\r\n\r\n%s\r\n",
+		m.From, email.To, email.Subject, email.Body,
+	)
+
+	if err := smtp.SendMail(m.Addr, m.Auth, m.From, []string{email.To}, []byte(msg)); err != nil {
+		log.Printf("Could not send email to: %s: %v\n", email.To, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
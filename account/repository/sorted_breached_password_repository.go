@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"io"
+	"log"
+	"strings"
+)
+
+// sortedBreachedLineMax is the longest line of a sorted list, a hash with
+// a count as in Have I Been Pwned downloads takes about 50 bytes
+const sortedBreachedLineMax = 256
+
+// sortedBreachedPasswordRepository is a model.BreachedPasswordRepository
+// searching a sorted list of breached password hashes where it is stored,
+// so lists too large to be held in memory can be used
+type sortedBreachedPasswordRepository struct {
+	List io.ReaderAt
+	Size int64
+}
+
+// NewSortedBreachedPasswordRepository is a factory for initializing a Breached
+// Password Repository from a list of size bytes with one hex SHA-1 hash per line,
+// sorted by hash, like the Have I Been Pwned download ordered by hash.
+// A ":count" suffix is ignored, and "#" comments may only come first
+// Each check reads about 30 lines of the list, the list is never loaded
+func NewSortedBreachedPasswordRepository(list io.ReaderAt, size int64) model.BreachedPasswordRepository {
+	return &sortedBreachedPasswordRepository{
+		List: list,
+		Size: size,
+	}
+}
+
+// IsBreached reports whether the SHA-1 hash of a password is listed,
+// with a binary search of the lines of the list
+func (r *sortedBreachedPasswordRepository) IsBreached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	// lo is always the start of a line, the lines starting before it
+	// are listed before the hash, those starting from hi after it
+	lo, hi := int64(0), r.Size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		start, line, err := r.lineFrom(mid)
+		if err != nil {
+			log.Printf("Could not read sorted breached passwords: %v\n", err)
+			return false, apperrors.NewInternal()
+		}
+
+		if start >= hi {
+			hi = mid
+			continue
+		}
+
+		switch listed := sortedBreachedHash(line); {
+		case listed == hash:
+			return true, nil
+		case listed < hash:
+			lo = start + int64(len(line))
+		default:
+			hi = mid
+		}
+	}
+
+	return false, nil
+}
+
+// lineFrom returns the first line starting at or after offset, with its
+// line break, and where it starts. The line is empty at the end of the list
+func (r *sortedBreachedPasswordRepository) lineFrom(offset int64) (int64, []byte, error) {
+	start := offset
+	if offset > 0 {
+		// the line break before offset may be the one of the previous line
+		end, err := r.lineEnd(offset - 1)
+		if err != nil {
+			return 0, nil, err
+		}
+		start = end
+	}
+
+	if start >= r.Size {
+		return start, nil, nil
+	}
+
+	end, err := r.lineEnd(start)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	line := make([]byte, end-start)
+	if _, err := r.List.ReadAt(line, start); err != nil && err != io.EOF {
+		return 0, nil, err
+	}
+
+	return start, line, nil
+}
+
+// lineEnd returns where the line containing offset ends, past its line break
+func (r *sortedBreachedPasswordRepository) lineEnd(offset int64) (int64, error) {
+	buf := make([]byte, sortedBreachedLineMax)
+	n, err := r.List.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+
+	if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+		return offset + int64(i) + 1, nil
+	}
+
+	if offset+int64(n) >= r.Size {
+		return r.Size, nil
+	}
+
+	return 0, fmt.Errorf("line at %d is longer than %d bytes", offset, sortedBreachedLineMax)
+}
+
+// sortedBreachedHash returns the hash of a line in upper case, without its count
+func sortedBreachedHash(line []byte) string {
+	hash := string(line)
+	if i := strings.IndexByte(hash, ':'); i >= 0 {
+		hash = hash[:i]
+	}
+
+	return strings.ToUpper(strings.TrimSpace(hash))
+}
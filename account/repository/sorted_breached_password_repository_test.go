@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSortedBreachedPasswordRepository(t *testing.T) {
+	ctx := context.TODO()
+
+	hash := func(password string) string {
+		sum := sha1.Sum([]byte(password))
+		return strings.ToUpper(hex.EncodeToString(sum[:]))
+	}
+
+	// newList lists the hashes of passwords sorted by hash, with counts
+	// and line breaks as in Have I Been Pwned downloads
+	newList := func(passwords []string) *strings.Reader {
+		hashes := make([]string, 0, len(passwords))
+		for i, password := range passwords {
+			hashes = append(hashes, fmt.Sprintf("%s:%d", hash(password), i+1))
+		}
+		sort.Strings(hashes)
+
+		return strings.NewReader(strings.Join(hashes, "\r\n"))
+	}
+
+	t.Run("Finds every listed password", func(t *testing.T) {
+		passwords := make([]string, 1000)
+		for i := range passwords {
+			passwords[i] = fmt.Sprintf("password%d", i)
+		}
+
+		list := newList(passwords)
+		r := NewSortedBreachedPasswordRepository(list, list.Size())
+
+		for _, password := range passwords {
+			breached, err := r.IsBreached(ctx, password)
+			assert.NoError(t, err)
+			assert.True(t, breached, password)
+		}
+
+		for _, password := range []string{"", "correct horse battery staple", "password1000"} {
+			breached, err := r.IsBreached(ctx, password)
+			assert.NoError(t, err)
+			assert.False(t, breached, password)
+		}
+	})
+
+	t.Run("Small lists", func(t *testing.T) {
+		for _, passwords := range [][]string{{}, {"password"}, {"password", "123456"}} {
+			list := newList(passwords)
+			r := NewSortedBreachedPasswordRepository(list, list.Size())
+
+			for _, password := range passwords {
+				breached, err := r.IsBreached(ctx, password)
+				assert.NoError(t, err)
+				assert.True(t, breached)
+			}
+
+			breached, err := r.IsBreached(ctx, "qwerty")
+			assert.NoError(t, err)
+			assert.False(t, breached)
+		}
+	})
+
+	t.Run("Comments come first", func(t *testing.T) {
+		list := strings.NewReader("# common passwords\n" + strings.ToLower(hash("password")) + "\n")
+		r := NewSortedBreachedPasswordRepository(list, list.Size())
+
+		breached, err := r.IsBreached(ctx, "password")
+		assert.NoError(t, err)
+		assert.True(t, breached)
+	})
+
+	t.Run("Not a list of hashes", func(t *testing.T) {
+		list := strings.NewReader(strings.Repeat("x", 1000))
+		r := NewSortedBreachedPasswordRepository(list, list.Size())
+
+		_, err := r.IsBreached(ctx, "password")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,491 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"log"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"strings"
+	"time"
+)
+
+// sqliteUserRepository is an SQLite implementation of service layer
+// UserRepository, for small deployments which run on a single file
+// The schema is created by the migrations in migrations/sqlite
+// Writes read the changed row back within the same transaction instead
+// of using RETURNING, which SQLite only has since 3.35
+type sqliteUserRepository struct {
+	DB *sqlx.DB
+}
+
+// NewSQLiteUserRepository is a factory for initializing SQLite User Repositories
+func NewSQLiteUserRepository(db *sqlx.DB) model.UserRepository {
+	return &sqliteUserRepository{
+		DB: db,
+	}
+}
+
+// sqliteTimeFormat is how times are stored, which sorts and compares
+// like the defaults of the tables as long as times are in UTC
+const sqliteTimeFormat = "2006-01-02 15:04:05.000000"
+
+// Create inserts a user and reads it back with the defaults of the users table
+func (r *sqliteUserRepository) Create(ctx context.Context, user *model.User) error {
+	err := r.withTx(ctx, func(tx *sqlx.Tx) error {
+		query := "INSERT INTO users (email, password, invite_id, guest) VALUES (?, ?, ?, ?)"
+
+		result, err := tx.ExecContext(ctx, query, user.Email, user.Password, user.InviteID, user.Guest)
+		if err != nil {
+			return err
+		}
+
+		rowID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return tx.GetContext(ctx, user, "SELECT * FROM users WHERE rowid=?", rowID)
+	})
+	if err != nil {
+		// check unique constraint
+		if isUniqueViolation(err) {
+			log.Printf("Could not create a user with email: %v. Reason: unique_violation\n", user.Email)
+			return apperrors.NewConflict("email", user.Email)
+		}
+
+		log.Printf("Could not create a user with email: %v. Reason: %v\n", user.Email, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// FindByEmail retrieves user row by email address, regardless of case
+func (r *sqliteUserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	user := &model.User{}
+
+	// guests have an empty email, which belongs to nobody
+	if email == "" {
+		return user, apperrors.NewNotFound("email", email)
+	}
+
+	query := "SELECT * FROM users WHERE lower(email)=lower(?)"
+
+	if err := r.DB.GetContext(ctx, user, query, email); err != nil {
+		log.Printf("Unable to get user with email address: %v. Err: %v\n", email, err)
+		return user, apperrors.NewNotFound("email", email)
+	}
+
+	return user, nil
+}
+
+// FindByHandle retrieves the user with a handle, regardless of case,
+// or the user who had it before changing it
+func (r *sqliteUserRepository) FindByHandle(ctx context.Context, handle string) (*model.User, error) {
+	user := &model.User{}
+
+	query := `
+		SELECT * FROM users WHERE lower(handle)=lower(?1) AND handle <> ''
+		UNION ALL
+		SELECT users.* FROM users JOIN handle_redirects ON handle_redirects.uid=users.uid
+		WHERE handle_redirects.handle=lower(?1)
+		LIMIT 1;
+	`
+
+	if err := r.DB.GetContext(ctx, user, query, handle); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NewNotFound("handle", handle)
+		}
+
+		log.Printf("Unable to get user with handle: %v. Err: %v\n", handle, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return user, nil
+}
+
+// FindByID fetches user by id
+func (r *sqliteUserRepository) FindByID(ctx context.Context, uid uuid.UUID) (*model.User, error) {
+	user := &model.User{}
+
+	query := "SELECT * FROM users WHERE uid=?"
+
+	if err := r.DB.GetContext(ctx, user, query, uid); err != nil {
+		return user, apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return user, nil
+}
+
+// Update updates a user's properties
+func (r *sqliteUserRepository) Update(ctx context.Context, user *model.User) error {
+	query := "UPDATE users SET name=?, email=?, website=? WHERE uid=?"
+
+	err := r.updateReturning(ctx, user, user.UID, query, user.Name, user.Email, user.Website, user.UID)
+	if err != nil {
+		// check unique constraint of the email
+		if isUniqueViolation(err) {
+			log.Printf("Could not update email of user: %v. Reason: unique_violation\n", user.UID)
+			return apperrors.NewConflict("email", user.Email)
+		}
+
+		log.Printf("Failed to update details for user: %v\n", user)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// UpdateImage is used to separately update a user's image separate from
+// other account details
+func (r *sqliteUserRepository) UpdateImage(ctx context.Context, uid uuid.UUID, imageURL string) (*model.User, error) {
+	query := "UPDATE users SET image_url=? WHERE uid=?"
+
+	user := &model.User{}
+	if err := r.updateReturning(ctx, user, uid, query, imageURL, uid); err != nil {
+		log.Printf("Error updating image_url in database: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return user, nil
+}
+
+// UpdatePassword replaces the password hash of a user
+func (r *sqliteUserRepository) UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error {
+	query := "UPDATE users SET password=? WHERE uid=?"
+
+	result, err := r.DB.ExecContext(ctx, query, password, uid)
+	if err != nil {
+		log.Printf("Error updating password in database: %v\n", err)
+		return apperrors.NewInternal()
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+// UpdateHandle changes the handle of a user in a single transaction,
+// which keeps the previous handle pointing to the user
+// Handles of other users, current or previous, are a Conflict
+func (r *sqliteUserRepository) UpdateHandle(ctx context.Context, uid uuid.UUID, handle string) (*model.User, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("Unable to begin transaction: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+	defer tx.Rollback()
+
+	// transactions of SQLite lock the whole database, there is no FOR UPDATE
+	var oldHandle string
+	if err := tx.GetContext(ctx, &oldHandle, "SELECT handle FROM users WHERE uid=?", uid); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NewNotFound("uid", uid.String())
+		}
+
+		log.Printf("Unable to get handle of uid: %v. Err: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	var redirectUID uuid.UUID
+	err = tx.GetContext(ctx, &redirectUID, "SELECT uid FROM handle_redirects WHERE handle=lower(?)", handle)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		log.Printf("Unable to get redirect of handle: %v. Err: %v\n", handle, err)
+		return nil, apperrors.NewInternal()
+	case redirectUID != uid:
+		return nil, apperrors.NewConflict("handle", handle)
+	default:
+		// users may take back one of their previous handles
+		if _, err := tx.ExecContext(ctx, "DELETE FROM handle_redirects WHERE handle=lower(?)", handle); err != nil {
+			log.Printf("Unable to delete redirect of handle: %v. Err: %v\n", handle, err)
+			return nil, apperrors.NewInternal()
+		}
+	}
+
+	// a change of case keeps the handle
+	if oldHandle != "" && !strings.EqualFold(oldHandle, handle) {
+		query := "INSERT INTO handle_redirects (handle, uid) VALUES (lower(?), ?) ON CONFLICT (handle) DO NOTHING"
+		if _, err := tx.ExecContext(ctx, query, oldHandle, uid); err != nil {
+			log.Printf("Unable to redirect handle: %v. Err: %v\n", oldHandle, err)
+			return nil, apperrors.NewInternal()
+		}
+	}
+
+	query := "UPDATE users SET handle=?, handle_changed_at=? WHERE uid=?"
+
+	user := &model.User{}
+	if err := txUpdateReturning(ctx, tx, user, uid, query, handle, sqliteTime(time.Now()), uid); err != nil {
+		if isUniqueViolation(err) {
+			return nil, apperrors.NewConflict("handle", handle)
+		}
+
+		log.Printf("Unable to update handle of uid: %v. Err: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Unable to commit handle change for uid: %v. Err: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return user, nil
+}
+
+// UpdateStatus sets the status of a user along with its reason and expiry
+func (r *sqliteUserRepository) UpdateStatus(ctx context.Context, user *model.User) error {
+	query := "UPDATE users SET status=?, status_reason=?, status_expires_at=? WHERE uid=?"
+
+	var expiresAt interface{}
+	if user.StatusExpiresAt != nil {
+		expiresAt = sqliteTime(*user.StatusExpiresAt)
+	}
+
+	err := r.updateReturning(ctx, user, user.UID, query, user.Status, user.StatusReason, expiresAt, user.UID)
+	if err == sql.ErrNoRows {
+		return apperrors.NewNotFound("uid", user.UID.String())
+	}
+	if err != nil {
+		log.Printf("Error updating status in database: %v\n", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// UpdateActiveOrg sets the organization a user acts for, nil for none
+func (r *sqliteUserRepository) UpdateActiveOrg(ctx context.Context, uid uuid.UUID, orgID *uuid.UUID) (*model.User, error) {
+	query := "UPDATE users SET active_org_id=? WHERE uid=?"
+
+	user := &model.User{}
+	err := r.updateReturning(ctx, user, uid, query, orgID, uid)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.NewNotFound("uid", uid.String())
+	}
+	if err != nil {
+		log.Printf("Error updating active_org_id in database: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return user, nil
+}
+
+// UpgradeGuest sets the email, password and invite of a guest, who stops being a guest
+// Users who are not guests are NotFound, so an upgrade cannot overwrite an account
+func (r *sqliteUserRepository) UpgradeGuest(ctx context.Context, user *model.User) error {
+	query := "UPDATE users SET email=?, password=?, invite_id=?, guest=FALSE WHERE uid=? AND guest"
+
+	err := r.updateReturning(ctx, user, user.UID, query, user.Email, user.Password, user.InviteID, user.UID)
+	if err == sql.ErrNoRows {
+		return apperrors.NewNotFound("guest", user.UID.String())
+	}
+	if err != nil {
+		if isUniqueViolation(err) {
+			log.Printf("Could not upgrade guest to email: %v. Reason: unique_violation\n", user.Email)
+			return apperrors.NewConflict("email", user.Email)
+		}
+
+		log.Printf("Could not upgrade guest: %v. Reason: %v\n", user.UID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// UpdateLastSeen records when tokens were last issued to a user
+func (r *sqliteUserRepository) UpdateLastSeen(ctx context.Context, uid uuid.UUID, seenAt time.Time) error {
+	query := "UPDATE users SET last_seen_at=? WHERE uid=?"
+
+	result, err := r.DB.ExecContext(ctx, query, sqliteTime(seenAt), uid)
+	if err != nil {
+		log.Printf("Error updating last_seen_at in database: %v\n", err)
+		return apperrors.NewInternal()
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+// Delete deletes a user
+func (r *sqliteUserRepository) Delete(ctx context.Context, uid uuid.UUID) error {
+	query := "DELETE FROM users WHERE uid=?"
+
+	result, err := r.DB.ExecContext(ctx, query, uid)
+	if err != nil {
+		log.Printf("Error deleting user: %v from database: %v\n", uid, err)
+		return apperrors.NewInternal()
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return apperrors.NewNotFound("uid", uid.String())
+	}
+
+	return nil
+}
+
+// DeleteGuests deletes the guests last seen before a time, or created
+// before it if they were never seen, and returns them
+func (r *sqliteUserRepository) DeleteGuests(ctx context.Context, seenBefore time.Time) ([]*model.User, error) {
+	users := []*model.User{}
+
+	err := r.withTx(ctx, func(tx *sqlx.Tx) error {
+		query := "SELECT * FROM users WHERE guest AND COALESCE(last_seen_at, created_at) < ?"
+		if err := tx.SelectContext(ctx, &users, query, sqliteTime(seenBefore)); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, "DELETE FROM users WHERE guest AND COALESCE(last_seen_at, created_at) < ?", sqliteTime(seenBefore))
+		return err
+	})
+	if err != nil {
+		log.Printf("Unable to delete guests last seen before: %v. Err: %v\n", seenBefore, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return users, nil
+}
+
+// List retrieves a page of the users matching a filter, oldest first
+func (r *sqliteUserRepository) List(ctx context.Context, filter *model.UserFilter) ([]*model.User, error) {
+	users := []*model.User{}
+
+	where, args := sqliteUserFilterWhere(filter)
+	query := fmt.Sprintf("SELECT * FROM users %s ORDER BY created_at, uid LIMIT ? OFFSET ?", where)
+	args = append(args, filter.Limit, filter.Offset)
+
+	if err := r.DB.SelectContext(ctx, &users, query, args...); err != nil {
+		log.Printf("Unable to list users. Err: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return users, nil
+}
+
+// Count counts the users matching a filter, regardless of its page
+func (r *sqliteUserRepository) Count(ctx context.Context, filter *model.UserFilter) (int64, error) {
+	var count int64
+
+	where, args := sqliteUserFilterWhere(filter)
+	query := "SELECT COUNT(*) FROM users " + where
+
+	if err := r.DB.GetContext(ctx, &count, query, args...); err != nil {
+		log.Printf("Unable to count users. Err: %v\n", err)
+		return 0, apperrors.NewInternal()
+	}
+
+	return count, nil
+}
+
+// Search finds users whose email, name or handle contains the query,
+// regardless of case. Exact matches of the email or handle come first
+func (r *sqliteUserRepository) Search(ctx context.Context, query string, limit int) ([]*model.User, error) {
+	users := []*model.User{}
+
+	// LIKE of SQLite ignores the case of ASCII letters, like ILIKE
+	sqlQuery := `
+		SELECT * FROM users
+		WHERE email LIKE ?1 ESCAPE '\' OR name LIKE ?1 ESCAPE '\' OR handle LIKE ?1 ESCAPE '\'
+		ORDER BY (lower(email)=lower(?2) OR lower(handle)=lower(?2)) DESC, email
+		LIMIT ?3;
+	`
+
+	if err := r.DB.SelectContext(ctx, &users, sqlQuery, likePattern(query), query, limit); err != nil {
+		log.Printf("Unable to search users. Err: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return users, nil
+}
+
+// withTx runs fn in a transaction, which is committed if fn succeeds
+func (r *sqliteUserRepository) withTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateReturning runs an UPDATE of the user with uid and scans the updated
+// row into user, in place of UPDATE ... RETURNING *
+func (r *sqliteUserRepository) updateReturning(ctx context.Context, user *model.User, uid uuid.UUID, query string, args ...interface{}) error {
+	return r.withTx(ctx, func(tx *sqlx.Tx) error {
+		return txUpdateReturning(ctx, tx, user, uid, query, args...)
+	})
+}
+
+// txUpdateReturning is updateReturning within a transaction
+// An UPDATE which matches no rows is sql.ErrNoRows, like RETURNING
+func txUpdateReturning(ctx context.Context, tx *sqlx.Tx, user *model.User, uid uuid.UUID, query string, args ...interface{}) error {
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.GetContext(ctx, user, "SELECT * FROM users WHERE uid=?", uid)
+}
+
+// sqliteUserFilterWhere builds the WHERE clause and its arguments for a filter
+func sqliteUserFilterWhere(filter *model.UserFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Email != "" {
+		args = append(args, likePattern(filter.Email))
+		conditions = append(conditions, `email LIKE ? ESCAPE '\'`)
+	}
+
+	if filter.Name != "" {
+		args = append(args, likePattern(filter.Name))
+		conditions = append(conditions, `name LIKE ? ESCAPE '\'`)
+	}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, "status=?")
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// isUniqueViolation checks if an error of SQLite is a unique constraint failing
+func isUniqueViolation(err error) bool {
+	sqliteErr, ok := err.(*sqlite.Error)
+	return ok && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+// sqliteTime formats a time the way it is stored
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeFormat)
+}
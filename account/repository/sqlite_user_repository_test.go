@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/migrations"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newSQLiteTestDB opens a database in a temporary file with the
+// migrations of the sqlite driver applied
+func newSQLiteTestDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "account.db")+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrations.NewSQLiteMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestSQLiteUserRepository(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("Create returns the defaults", func(t *testing.T) {
+		r := NewSQLiteUserRepository(newSQLiteTestDB(t))
+
+		user := &model.User{Email: "bob@bob.com", Password: "hash"}
+		assert.NoError(t, r.Create(ctx, user))
+		assert.NotEqual(t, uuid.Nil, user.UID)
+		assert.Equal(t, uuid.Version(4), user.UID.Version())
+		assert.Equal(t, uuid.RFC4122, user.UID.Variant())
+		assert.Equal(t, model.UserStatusActive, user.Status)
+		assert.WithinDuration(t, time.Now(), user.CreatedAt, time.Minute)
+		assert.Equal(t, "hash", user.Password)
+		assert.False(t, user.Guest)
+
+		found, err := r.FindByID(ctx, user.UID)
+		assert.NoError(t, err)
+		assert.Equal(t, user, found)
+	})
+
+	t.Run("Email is unique regardless of case", func(t *testing.T) {
+		r := NewSQLiteUserRepository(newSQLiteTestDB(t))
+
+		user := &model.User{Email: "bob@bob.com"}
+		assert.NoError(t, r.Create(ctx, user))
+
+		err := r.Create(ctx, &model.User{Email: "BOB@bob.com"})
+		assert.Equal(t, apperrors.Conflict, err.(*apperrors.Error).Type)
+
+		alice := &model.User{Email: "alice@alice.com"}
+		assert.NoError(t, r.Create(ctx, alice))
+		alice.Email = "Bob@Bob.com"
+		err = r.Update(ctx, alice)
+		assert.Equal(t, apperrors.Conflict, err.(*apperrors.Error).Type)
+
+		found, err := r.FindByEmail(ctx, "Bob@Bob.com")
+		assert.NoError(t, err)
+		assert.Equal(t, user.UID, found.UID)
+	})
+
+	t.Run("Deleted users free their email", func(t *testing.T) {
+		r := NewSQLiteUserRepository(newSQLiteTestDB(t))
+
+		user := &model.User{Email: "bob@bob.com"}
+		assert.NoError(t, r.Create(ctx, user))
+		assert.NoError(t, r.Delete(ctx, user.UID))
+
+		_, err := r.FindByID(ctx, user.UID)
+		assert.Equal(t, apperrors.NotFound, err.(*apperrors.Error).Type)
+		assert.NoError(t, r.Create(ctx, &model.User{Email: "bob@bob.com"}))
+
+		err = r.Delete(ctx, user.UID)
+		assert.Equal(t, apperrors.NotFound, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("Updates return the updated user", func(t *testing.T) {
+		r := NewSQLiteUserRepository(newSQLiteTestDB(t))
+
+		user := &model.User{Email: "bob@bob.com"}
+		assert.NoError(t, r.Create(ctx, user))
+
+		user.Name = "Bob"
+		user.Website = "https://bob.com"
+		assert.NoError(t, r.Update(ctx, user))
+		assert.Equal(t, "Bob", user.Name)
+
+		updated, err := r.UpdateImage(ctx, user.UID, "https://images/bob.png")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://images/bob.png", updated.ImageURL)
+		assert.Equal(t, "https://bob.com", updated.Website)
+
+		expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Microsecond)
+		user.Status = model.UserStatusSuspended
+		user.StatusReason = "spam"
+		user.StatusExpiresAt = &expiresAt
+		assert.NoError(t, r.UpdateStatus(ctx, user))
+		assert.Equal(t, model.UserStatusSuspended, user.Status)
+		assert.True(t, expiresAt.Equal(*user.StatusExpiresAt))
+
+		orgID := uuid.New()
+		updated, err = r.UpdateActiveOrg(ctx, user.UID, &orgID)
+		assert.NoError(t, err)
+		assert.Equal(t, orgID, *updated.ActiveOrgID)
+
+		_, err = r.UpdateImage(ctx, uuid.New(), "https://images/nobody.png")
+		assert.Error(t, err)
+
+		_, err = r.UpdateActiveOrg(ctx, uuid.New(), nil)
+		assert.Equal(t, apperrors.NotFound, err.(*apperrors.Error).Type)
+
+		err = r.UpdatePassword(ctx, uuid.New(), "hash")
+		assert.Equal(t, apperrors.NotFound, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("Previous handles redirect", func(t *testing.T) {
+		r := NewSQLiteUserRepository(newSQLiteTestDB(t))
+
+		bob := &model.User{Email: "bob@bob.com"}
+		alice := &model.User{Email: "alice@alice.com"}
+		assert.NoError(t, r.Create(ctx, bob))
+		assert.NoError(t, r.Create(ctx, alice))
+
+		_, err := r.UpdateHandle(ctx, bob.UID, "bob")
+		assert.NoError(t, err)
+		updated, err := r.UpdateHandle(ctx, bob.UID, "robert")
+		assert.NoError(t, err)
+		assert.Equal(t, "robert", updated.Handle)
+		assert.NotNil(t, updated.HandleChangedAt)
+
+		found, err := r.FindByHandle(ctx, "BOB")
+		assert.NoError(t, err)
+		assert.Equal(t, bob.UID, found.UID)
+
+		_, err = r.UpdateHandle(ctx, alice.UID, "bob")
+		assert.Equal(t, apperrors.Conflict, err.(*apperrors.Error).Type)
+
+		_, err = r.UpdateHandle(ctx, alice.UID, "Robert")
+		assert.Equal(t, apperrors.Conflict, err.(*apperrors.Error).Type)
+
+		_, err = r.UpdateHandle(ctx, bob.UID, "bob")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Guests upgrade and are purged", func(t *testing.T) {
+		r := NewSQLiteUserRepository(newSQLiteTestDB(t))
+
+		guest := &model.User{Guest: true}
+		other := &model.User{Guest: true}
+		assert.NoError(t, r.Create(ctx, guest))
+		assert.NoError(t, r.Create(ctx, other))
+		assert.True(t, guest.Guest)
+
+		guest.Email = "bob@bob.com"
+		guest.Password = "hash"
+		assert.NoError(t, r.UpgradeGuest(ctx, guest))
+		assert.False(t, guest.Guest)
+
+		err := r.UpgradeGuest(ctx, guest)
+		assert.Equal(t, apperrors.NotFound, err.(*apperrors.Error).Type)
+
+		deleted, err := r.DeleteGuests(ctx, time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Len(t, deleted, 1)
+		assert.Equal(t, other.UID, deleted[0].UID)
+
+		_, err = r.FindByID(ctx, other.UID)
+		assert.Error(t, err)
+		_, err = r.FindByID(ctx, guest.UID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Old guests seen recently are not purged", func(t *testing.T) {
+		db := newSQLiteTestDB(t)
+		r := NewSQLiteUserRepository(db)
+
+		active := &model.User{Guest: true}
+		unused := &model.User{Guest: true}
+		assert.NoError(t, r.Create(ctx, active))
+		assert.NoError(t, r.Create(ctx, unused))
+
+		_, err := db.ExecContext(ctx, "UPDATE users SET created_at=?", sqliteTime(time.Now().AddDate(0, -2, 0)))
+		assert.NoError(t, err)
+		assert.NoError(t, r.UpdateLastSeen(ctx, active.UID, time.Now()))
+
+		deleted, err := r.DeleteGuests(ctx, time.Now().AddDate(0, -1, 0))
+		assert.NoError(t, err)
+		assert.Len(t, deleted, 1)
+		assert.Equal(t, unused.UID, deleted[0].UID)
+
+		found, err := r.FindByID(ctx, active.UID)
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), *found.LastSeenAt, time.Minute)
+	})
+
+	t.Run("List, count and search", func(t *testing.T) {
+		r := NewSQLiteUserRepository(newSQLiteTestDB(t))
+
+		for _, email := range []string{"bob@bob.com", "bobby@bob.com", "alice@alice.com", "100%@bob.com"} {
+			assert.NoError(t, r.Create(ctx, &model.User{Email: email}))
+		}
+
+		filter := &model.UserFilter{Email: "BOB", Limit: 2}
+		users, err := r.List(ctx, filter)
+		assert.NoError(t, err)
+		assert.Len(t, users, 2)
+
+		count, err := r.Count(ctx, filter)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+
+		count, err = r.Count(ctx, &model.UserFilter{Email: "%"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+
+		users, err = r.Search(ctx, "bobby@bob.com", 10)
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+
+		alice, err := r.FindByEmail(ctx, "alice@alice.com")
+		assert.NoError(t, err)
+		_, err = r.UpdateHandle(ctx, alice.UID, "Bob")
+		assert.NoError(t, err)
+
+		users, err = r.Search(ctx, "bob", 10)
+		assert.NoError(t, err)
+		assert.Len(t, users, 4)
+		assert.Equal(t, alice.UID, users[0].UID)
+	})
+}
@@ -0,0 +1,17 @@
+package router
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"time"
+)
+
+// bootstrapTimeout bounds making the first admins on start
+const bootstrapTimeout = 10 * time.Second
+
+func bootstrapAdmins(roleService model.RoleService, adminEmails []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), bootstrapTimeout)
+	defer cancel()
+
+	return roleService.Bootstrap(ctx, adminEmails)
+}
@@ -2,6 +2,8 @@ package router
 
 import (
 	"github.com/spf13/viper"
+	"reflect"
+	"strings"
 )
 
 // Config is the struct works as template to parse env variables
@@ -12,13 +14,46 @@ type Config struct {
 	HandlerTimeout int64      `mapstructure:"HANDLER_TIMEOUT" default:"5"`
 	DataSource     DataSource `mapstructure:"DATA_SOURCE,omitempty"`
 	Token          Token      `mapstructure:"TOKEN,omitempty"`
+	Export         Export     `mapstructure:"EXPORT,omitempty"`
+	MFA            MFA        `mapstructure:"MFA,omitempty"`
+	WebAuthn       WebAuthn   `mapstructure:"WEBAUTHN,omitempty"`
+	MagicLink      MagicLink  `mapstructure:"MAGIC_LINK,omitempty"`
+	Mail           Mail       `mapstructure:"MAIL,omitempty"`
+	OAuth          OAuth      `mapstructure:"OAUTH,omitempty"`
+	Lockout        Lockout    `mapstructure:"LOCKOUT,omitempty"`
+	Password       Password   `mapstructure:"PASSWORD,omitempty"`
+	Signup         Signup     `mapstructure:"SIGNUP,omitempty"`
+	Admin          Admin      `mapstructure:"ADMIN,omitempty"`
+	Handle         Handle     `mapstructure:"HANDLE,omitempty"`
+	Device         Device     `mapstructure:"DEVICE,omitempty"`
+	Org            Org        `mapstructure:"ORG,omitempty"`
+	Guest          Guest      `mapstructure:"GUEST,omitempty"`
+	Terms          Terms      `mapstructure:"TERMS,omitempty"`
 }
 
 // DataSource is the struct that contains env variables to connect data sources
+// DRIVER keeps users and tokens in "postgres", along with Redis, or in "memory"
+// The "sqlite" driver keeps users in the SQLite file at SQLITE_PATH, and tokens and
+// sign-in attempts in Redis, or tokens in memory and no lockouts when Redis is down
+// Drivers other than "postgres" turn off audit events, MFA, passkeys, OAuth, roles,
+// organizations, invites, exports and new device notifications, with a warning on start
+// IMAGE_STORE keeps profile images in "gcs", in S3 compatible storage with "s3",
+// in "memory" or in files of IMAGE_DIR with "file"
+// Images in memory and in files are served by the API under IMAGE_BASE_URL
+// MIGRATE_ON_START applies the migrations embedded in the binary to the database
+// of the postgres and sqlite drivers before serving. Either way, a schema newer
+// than the binary stops it from starting
 type DataSource struct {
-	PostGreSQL PostGreSQL `mapstructure:"POST_GRESQL,omitempty"`
-	Redis      Redis      `mapstructure:"REDIS,omitempty"`
-	GCP        GCP        `mapstructure:"GCP,omitempty"`
+	Driver         string     `mapstructure:"DRIVER" default:"postgres"`
+	MigrateOnStart bool       `mapstructure:"MIGRATE_ON_START" default:"false"`
+	ImageStore     string     `mapstructure:"IMAGE_STORE" default:"gcs"`
+	ImageBaseURL   string     `mapstructure:"IMAGE_BASE_URL" default:"http://localhost:8080/api/account/images"`
+	ImageDir       string     `mapstructure:"IMAGE_DIR" default:"images"`
+	PostGreSQL     PostGreSQL `mapstructure:"POST_GRESQL,omitempty"`
+	SQLite         SQLite     `mapstructure:"SQLITE,omitempty"`
+	Redis          Redis      `mapstructure:"REDIS,omitempty"`
+	GCP            GCP        `mapstructure:"GCP,omitempty"`
+	S3             S3         `mapstructure:"S3,omitempty"`
 }
 
 // PostGreSQL is the struct contains env variables which is needed to connect to PostGreSQL Client
@@ -32,6 +67,12 @@ type PostGreSQL struct {
 	PostGresConnectionTimeOut int64  `mapstructure:"POSTGRES_CONNECTION_TIMEOUT" default:"10"`
 }
 
+// SQLite is the struct contains env variables which is needed to open the SQLite database
+// The schema is created with the migrations in migrations/sqlite
+type SQLite struct {
+	SQLitePath string `mapstructure:"SQLITE_PATH" default:"account.db"`
+}
+
 // Redis is the struct contains env variables which is needed to connect to Redis Client
 type Redis struct {
 	RedisHost string `mapstructure:"REDIS_HOST" default:"redis-account"`
@@ -45,6 +86,23 @@ type GCP struct {
 	CloudConnectionTimeout       int64  `mapstructure:"CLOUD_CONNECTION_TIMEOUT" default:"5"`
 }
 
+// S3 is the struct contains env variables which is needed to connect to S3 compatible
+// object storage, like AWS S3, MinIO or Cloudflare R2
+// Without S3_ACCESS_KEY_ID, credentials are taken from the AWS_* environment variables
+// or the IAM role of the instance. S3_PATH_STYLE addresses buckets as endpoint/bucket
+// instead of bucket.endpoint. S3_URL_TEMPLATE is the public URL of images, with {bucket}
+// and {key} replaced by the bucket and object names, and defaults to their URL at S3_ENDPOINT
+type S3 struct {
+	S3Endpoint        string `mapstructure:"S3_ENDPOINT" default:"s3.amazonaws.com"`
+	S3Region          string `mapstructure:"S3_REGION" default:"us-east-1"`
+	S3AccessKeyID     string `mapstructure:"S3_ACCESS_KEY_ID"`
+	S3SecretAccessKey string `mapstructure:"S3_SECRET_ACCESS_KEY"`
+	S3ImageBucket     string `mapstructure:"S3_IMAGE_BUCKET" required:"true"`
+	S3UseSSL          bool   `mapstructure:"S3_USE_SSL" default:"true"`
+	S3PathStyle       bool   `mapstructure:"S3_PATH_STYLE" default:"false"`
+	S3URLTemplate     string `mapstructure:"S3_URL_TEMPLATE"`
+}
+
 // Token is the struct of env variables for token which contains access and refresh tokens
 type Token struct {
 	AccessToken  AccessToken  `mapstructure:"ACCESS_TOKEN,omitempty"`
@@ -64,6 +122,162 @@ type RefreshToken struct {
 	RefreshTokenSecret string `mapstructure:"REFRESH_TOKEN_SECRET" required:"true"`
 }
 
+// Export is the struct of env variables for personal data exports
+type Export struct {
+	ExportLinkExpire int64 `mapstructure:"EXPORT_LINK_EXPIRE" default:"86400"` // 1 day in secs
+}
+
+// MFA is the struct of env variables for two-factor authentication
+type MFA struct {
+	TOTPIssuer           string `mapstructure:"TOTP_ISSUER" default:"memorization-apps"`
+	MFAChallengeExpire   int64  `mapstructure:"MFA_CHALLENGE_EXPIRE" default:"300"` // 5 min in secs
+	MFAChallengeAttempts int64  `mapstructure:"MFA_CHALLENGE_ATTEMPTS" default:"5"`
+}
+
+// WebAuthn is the struct of env variables for passkeys, RP_ID is the domain
+// passkeys are bound to and RP_ORIGINS the origins allowed to use them
+type WebAuthn struct {
+	RPID            string   `mapstructure:"RP_ID" default:"localhost"`
+	RPName          string   `mapstructure:"RP_NAME" default:"memorization-apps"`
+	RPOrigins       []string `mapstructure:"RP_ORIGINS"`
+	WebAuthnTimeout int64    `mapstructure:"WEBAUTHN_TIMEOUT" default:"300"` // 5 min in secs
+}
+
+// MagicLink is the struct of env variables for passwordless sign-in links
+// MAGIC_LINK_URL is the client page which verifies the token of a link
+type MagicLink struct {
+	MagicLinkURL         string `mapstructure:"MAGIC_LINK_URL" default:"http://localhost:3000/signin/link"`
+	MagicLinkExpire      int64  `mapstructure:"MAGIC_LINK_EXPIRE" default:"900"` // 15 min in secs
+	MagicLinkMaxRequests int64  `mapstructure:"MAGIC_LINK_MAX_REQUESTS" default:"3"`
+	MagicLinkWindow      int64  `mapstructure:"MAGIC_LINK_WINDOW" default:"3600"` // 1 hour in secs
+	MagicLinkSignup      bool   `mapstructure:"MAGIC_LINK_SIGNUP" default:"true"`
+}
+
+// Mail is the struct of env variables for sending email
+// Emails are only logged when no SMTP_HOST is configured
+type Mail struct {
+	SMTPHost     string `mapstructure:"SMTP_HOST"`
+	SMTPPort     string `mapstructure:"SMTP_PORT" default:"587"`
+	SMTPUsername string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword string `mapstructure:"SMTP_PASSWORD"`
+	MailFrom     string `mapstructure:"MAIL_FROM" default:"no-reply@memorization-apps.local"`
+}
+
+// OAuth is the struct of env variables for external identity providers
+type OAuth struct {
+	OAuthStateExpire int64           `mapstructure:"OAUTH_STATE_EXPIRE" default:"600"` // 10 min in secs
+	OAuthProviders   []OAuthProvider `mapstructure:"OAUTH_PROVIDERS"`
+}
+
+// OAuthProvider is the struct of env variables for one OAuth2/OIDC provider
+// REDIRECT_URL is the client page which posts the code, state and nonce back to the API
+// TRUST_EMAIL treats emails as verified when the provider does not report email_verified
+// GitHub never reports it, but only shows verified emails on profiles, so it must be trusted
+type OAuthProvider struct {
+	Name         string   `mapstructure:"NAME"`
+	ClientID     string   `mapstructure:"CLIENT_ID"`
+	ClientSecret string   `mapstructure:"CLIENT_SECRET"`
+	AuthURL      string   `mapstructure:"AUTH_URL"`
+	TokenURL     string   `mapstructure:"TOKEN_URL"`
+	UserInfoURL  string   `mapstructure:"USERINFO_URL"`
+	RedirectURL  string   `mapstructure:"REDIRECT_URL"`
+	Scopes       []string `mapstructure:"SCOPES"`
+	SubjectClaim string   `mapstructure:"SUBJECT_CLAIM" default:"sub"`
+	EmailClaim   string   `mapstructure:"EMAIL_CLAIM" default:"email"`
+	TrustEmail   bool     `mapstructure:"TRUST_EMAIL" default:"false"`
+}
+
+// Lockout is the struct of env variables for throttling failed sign-in attempts
+// Past the free attempts each failure blocks sign-in for a doubling delay, and
+// accounts are locked at LOCKOUT_THRESHOLD failures within LOGIN_FAILURE_WINDOW
+// UNLOCK_URL is the client page which posts the token of an unlock link to the API
+type Lockout struct {
+	LoginFreeAttempts   int64  `mapstructure:"LOGIN_FREE_ATTEMPTS" default:"3"`
+	LoginIPFreeAttempts int64  `mapstructure:"LOGIN_IP_FREE_ATTEMPTS" default:"20"`
+	LoginFailureWindow  int64  `mapstructure:"LOGIN_FAILURE_WINDOW" default:"3600"` // 1 hour in secs
+	LoginBackoffBase    int64  `mapstructure:"LOGIN_BACKOFF_BASE" default:"1"`      // secs
+	LoginBackoffMax     int64  `mapstructure:"LOGIN_BACKOFF_MAX" default:"300"`     // 5 min in secs
+	LockoutThreshold    int64  `mapstructure:"LOCKOUT_THRESHOLD" default:"10"`
+	LockoutDuration     int64  `mapstructure:"LOCKOUT_DURATION" default:"1800"` // 30 min in secs
+	UnlockURL           string `mapstructure:"UNLOCK_URL" default:"http://localhost:3000/signin/unlock"`
+	UnlockExpire        int64  `mapstructure:"UNLOCK_EXPIRE" default:"86400"` // 1 day in secs
+}
+
+// Password is the struct of env variables for the password policy and resets
+// PASSWORD_MIN_STRENGTH is a zxcvbn score from 0 to 4, BREACHED_PASSWORDS_FILE
+// lists SHA-1 hashes or hash prefixes, one per line, and may be left empty
+// It is loaded into memory, which fits up to a few million hashes, unless
+// BREACHED_PASSWORDS_SORTED, for a list of full hashes sorted by hash like the
+// Have I Been Pwned download, which is searched in the file
+// PASSWORD_RESET_URL is the client page which posts the token of a reset link to the API
+type Password struct {
+	PasswordMinLength       int    `mapstructure:"PASSWORD_MIN_LENGTH" default:"8"`
+	PasswordMaxLength       int    `mapstructure:"PASSWORD_MAX_LENGTH" default:"128"`
+	PasswordMinStrength     int    `mapstructure:"PASSWORD_MIN_STRENGTH" default:"2"`
+	BreachedPasswordsFile   string `mapstructure:"BREACHED_PASSWORDS_FILE"`
+	BreachedPasswordsSorted bool   `mapstructure:"BREACHED_PASSWORDS_SORTED" default:"false"`
+	PasswordResetURL        string `mapstructure:"PASSWORD_RESET_URL" default:"http://localhost:3000/password/reset"`
+	PasswordResetExpire     int64  `mapstructure:"PASSWORD_RESET_EXPIRE" default:"3600"` // 1 hour in secs
+}
+
+// Signup is the struct of env variables for signing up
+// With SIGNUP_ENUMERATION_SAFE, sign-ups, guest upgrades and provider sign-ups
+// are answered the same way for registered emails, whose owners get an email
+// instead, and new users sign in afterwards
+// SIGNUP_MODE is "open", "domains" for emails of SIGNUP_ALLOWED_DOMAINS or with
+// an invite, or "invite" for invites only. INVITE_EXPIRE of 0 keeps invites valid
+type Signup struct {
+	SignupEnumerationSafe bool     `mapstructure:"SIGNUP_ENUMERATION_SAFE" default:"false"`
+	SignupMode            string   `mapstructure:"SIGNUP_MODE" default:"open"`
+	SignupAllowedDomains  []string `mapstructure:"SIGNUP_ALLOWED_DOMAINS"`
+	InviteExpire          int64    `mapstructure:"INVITE_EXPIRE" default:"604800"` // 7 days in secs
+}
+
+// Admin is the struct of env variables for the admin routes
+// The users of ADMIN_EMAILS are made admins on start while there is no admin,
+// after which admins grant the role to others
+type Admin struct {
+	AdminEmails []string `mapstructure:"ADMIN_EMAILS"`
+}
+
+// Handle is the struct of env variables for user handles
+type Handle struct {
+	HandleChangeCooldown int64 `mapstructure:"HANDLE_CHANGE_COOLDOWN" default:"2592000"` // 30 days in secs
+}
+
+// Device is the struct of env variables for new device notifications
+// NOTIFIER is "email" to email users, "log" to only log notifications or "none"
+// DEVICE_REVOKE_URL is the client page which posts the token of a "this wasn't me" link to the API
+type Device struct {
+	Notifier           string `mapstructure:"NOTIFIER" default:"email"`
+	DeviceRevokeURL    string `mapstructure:"DEVICE_REVOKE_URL" default:"http://localhost:3000/signin/disown"`
+	DeviceRevokeExpire int64  `mapstructure:"DEVICE_REVOKE_EXPIRE" default:"604800"` // 7 days in secs
+}
+
+// Org is the struct of env variables for organizations
+// ORG_INVITE_URL is the client page which posts the token of an invite link to the API
+type Org struct {
+	OrgInviteURL    string `mapstructure:"ORG_INVITE_URL" default:"http://localhost:3000/orgs/join"`
+	OrgInviteExpire int64  `mapstructure:"ORG_INVITE_EXPIRE" default:"604800"` // 7 days in secs
+}
+
+// Guest is the struct of env variables for guest accounts
+// Guests which are not upgraded and not seen within GUEST_MAX_AGE are purged, 0 keeps them
+// GUEST_MAX_PER_IP guests may be created per IP within GUEST_IP_WINDOW, 0 does not limit them
+type Guest struct {
+	GuestMaxAge        int64 `mapstructure:"GUEST_MAX_AGE" default:"2592000"`     // 30 days in secs
+	GuestPurgeInterval int64 `mapstructure:"GUEST_PURGE_INTERVAL" default:"3600"` // 1 hour in secs
+	GuestMaxPerIP      int64 `mapstructure:"GUEST_MAX_PER_IP" default:"10"`
+	GuestIPWindow      int64 `mapstructure:"GUEST_IP_WINDOW" default:"3600"` // 1 hour in secs
+}
+
+// Terms is the struct of env variables for the terms of service
+// Publishing a new TERMS_VERSION asks every user to accept it, an empty one asks nothing
+type Terms struct {
+	TermsVersion string `mapstructure:"TERMS_VERSION"`
+	TermsURL     string `mapstructure:"TERMS_URL" default:"http://localhost:3000/terms"`
+}
+
 // GetConfig parse configs file from local into defined Config struct - nested struct
 func GetConfig(path string, name string, fileType string) (*Config, error) {
 	var config *Config
@@ -72,6 +286,7 @@ func GetConfig(path string, name string, fileType string) (*Config, error) {
 	viper.SetConfigType(fileType)
 
 	viper.AutomaticEnv()
+	setDefaults("", reflect.TypeOf(Config{}))
 
 	err := viper.ReadInConfig()
 	if err != nil {
@@ -84,3 +299,22 @@ func GetConfig(path string, name string, fileType string) (*Config, error) {
 	}
 	return config, nil
 }
+
+// setDefaults registers the default tags of a config struct with viper,
+// which otherwise leaves settings missing from the file empty
+// Defaults of structs in lists, like OAUTH_PROVIDERS, are applied where they are used
+func setDefaults(prefix string, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + strings.Split(field.Tag.Get("mapstructure"), ",")[0]
+
+		if field.Type.Kind() == reflect.Struct {
+			setDefaults(key+".", field.Type)
+			continue
+		}
+
+		if value, ok := field.Tag.Lookup("default"); ok {
+			viper.SetDefault(key, value)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package router
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// baselineConfig is a config written before data source drivers, second
+// factors and sign-in lockouts were configurable
+const baselineConfig = `{
+  "ACCOUNT_API_URL": "/api/account",
+  "PORT": "8080",
+  "DATA_SOURCE": {
+    "POST_GRESQL": {
+      "POSTGRES_HOST": "postgres-account",
+      "POSTGRES_PASSWORD": "password"
+    },
+    "GCP": {
+      "GCP_IMAGE_BUCKET": "memorization_apps_profile_images",
+      "GOOGLE_APPLICATION_CREDENTIALS": "/go/src/app/serviceAccount.json"
+    }
+  },
+  "TOKEN": {
+    "REFRESH_TOKEN": {
+      "REFRESH_TOKEN_SECRET": "areallynotsuperg00ds33cret"
+    }
+  }
+}`
+
+func TestGetConfig(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "baseline.json"), []byte(baselineConfig), 0600))
+
+	config, err := GetConfig(dir, "baseline", "json")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres", config.DataSource.Driver)
+	assert.Equal(t, "gcs", config.DataSource.ImageStore)
+	assert.Equal(t, "5432", config.DataSource.PostGreSQL.PostGresPort)
+	assert.Equal(t, "password", config.DataSource.PostGreSQL.PostGresPassword)
+	assert.Equal(t, int64(900), config.Token.AccessToken.AccessTokenExpire)
+	assert.Equal(t, int64(5), config.MFA.MFAChallengeAttempts)
+	assert.Equal(t, int64(3), config.Lockout.LoginFreeAttempts)
+	assert.Equal(t, int64(20), config.Lockout.LoginIPFreeAttempts)
+	assert.Equal(t, int64(10), config.Lockout.LockoutThreshold)
+	assert.True(t, config.MagicLink.MagicLinkSignup)
+	assert.Equal(t, "open", config.Signup.SignupMode)
+}
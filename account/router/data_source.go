@@ -4,23 +4,115 @@ import (
 	"cloud.google.com/go/storage"
 	"context"
 	"fmt"
+	"github.com/dolong2110/memorization-apps/account/migrations"
 	"github.com/go-redis/redis/v8"
 	"github.com/jmoiron/sqlx"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
 	"log"
+	"os"
 	"time"
 )
 
+// Drivers of DataSource, which keep users and tokens
+const (
+	DriverPostgres = "postgres"
+	DriverMemory   = "memory"
+	DriverSQLite   = "sqlite"
+)
+
+// Image stores of DataSource, which keep profile images
+const (
+	ImageStoreGCS    = "gcs"
+	ImageStoreMemory = "memory"
+	ImageStoreFile   = "file"
+	ImageStoreS3     = "s3"
+)
+
 // DataSources is the struct which contains variables representing data sources
+// Data sources which are not used by the configured driver and image store are nil
 type DataSources struct {
 	PostgreSQLDB       *sqlx.DB
+	SQLiteDB           *sqlx.DB
 	RedisClient        *redis.Client
 	CloudStorageClient *storage.Client
+	S3Client           *minio.Client
 }
 
 // InitDS establishes connections to fields in DataSources
 func InitDS(config *Config) (*DataSources, error) {
 	log.Printf("Initializing data sources\n")
-	pg := config.DataSource.PostGreSQL
+	ds := &DataSources{}
+
+	switch config.DataSource.Driver {
+	case DriverPostgres:
+		db, err := initPostgreSQL(config.DataSource.PostGreSQL)
+		if err != nil {
+			return nil, err
+		}
+		ds.PostgreSQLDB = db
+
+		if err := migrateSchema(db, migrations.NewPostgresMigrator, config.DataSource.MigrateOnStart); err != nil {
+			return nil, err
+		}
+
+		rdb, err := initRedis(config.DataSource.Redis)
+		if err != nil {
+			return nil, err
+		}
+		ds.RedisClient = rdb
+	case DriverMemory:
+		log.Printf("Keeping users and tokens in memory, they are lost on restart\n")
+	case DriverSQLite:
+		db, err := initSQLite(config.DataSource.SQLite)
+		if err != nil {
+			return nil, err
+		}
+		ds.SQLiteDB = db
+
+		if err := migrateSchema(db, migrations.NewSQLiteMigrator, config.DataSource.MigrateOnStart); err != nil {
+			return nil, err
+		}
+
+		// Redis is optional, but without it sign-in attempts are not throttled
+		rdb, err := initRedis(config.DataSource.Redis)
+		if err != nil {
+			log.Printf("WARNING: %v. Sign-in lockouts are off and tokens are kept in memory, they are lost on restart\n", err)
+		} else {
+			ds.RedisClient = rdb
+		}
+	default:
+		return nil, fmt.Errorf("unknown data source driver: %v", config.DataSource.Driver)
+	}
+
+	switch config.DataSource.ImageStore {
+	case ImageStoreGCS:
+		cloudStorage, err := initCloudStorage(config.DataSource.GCP)
+		if err != nil {
+			return nil, err
+		}
+		ds.CloudStorageClient = cloudStorage
+	case ImageStoreS3:
+		s3Client, err := initS3(config.DataSource.S3)
+		if err != nil {
+			return nil, err
+		}
+		ds.S3Client = s3Client
+	case ImageStoreMemory:
+		log.Printf("Keeping profile images in memory, they are lost on restart\n")
+	case ImageStoreFile:
+		log.Printf("Keeping profile images in %s\n", config.DataSource.ImageDir)
+		if err := os.MkdirAll(config.DataSource.ImageDir, 0o755); err != nil {
+			return nil, fmt.Errorf("error creating image directory: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown image store: %v", config.DataSource.ImageStore)
+	}
+
+	return ds, nil
+}
+
+func initPostgreSQL(pg PostGreSQL) (*sqlx.DB, error) {
 	pgConnString := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s", pg.PostGresHost, pg.PostGresPort, pg.PostGresUser, pg.PostGresPassword, pg.PostGresDB, pg.PostGresSSL)
 
 	log.Printf("Connecting to Postgresql\n")
@@ -36,7 +128,46 @@ func InitDS(config *Config) (*DataSources, error) {
 
 	db.SetConnMaxLifetime(time.Duration(pg.PostGresConnectionTimeOut) * time.Minute)
 
-	rd := config.DataSource.Redis
+	return db, nil
+}
+
+func initSQLite(sl SQLite) (*sqlx.DB, error) {
+	log.Printf("Opening SQLite database %s\n", sl.SQLitePath)
+	// foreign keys are off by default, and writers wait for each other instead of failing
+	db, err := sqlx.Open("sqlite", fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)", sl.SQLitePath))
+	if err != nil {
+		return nil, fmt.Errorf("error opening sqlite db: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("error connecting to sqlite db: %w", err)
+	}
+
+	// SQLite allows a single writer, so sharing one connection saves
+	// transactions from failing with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	return db, nil
+}
+
+// migrateSchema applies the pending migrations to a database if migrateOnStart is set,
+// and fails for a schema which is newer than the binary
+func migrateSchema(db *sqlx.DB, newMigrator func(db *sqlx.DB) (*migrations.Migrator, error), migrateOnStart bool) error {
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return fmt.Errorf("error loading migrations: %w", err)
+	}
+
+	ctx := context.Background()
+	if !migrateOnStart {
+		return migrator.Check(ctx)
+	}
+
+	log.Printf("Migrating schema to version %d\n", migrator.Latest())
+	return migrator.Up(ctx)
+}
+
+func initRedis(rd Redis) (*redis.Client, error) {
 	log.Printf("Connecting to Redis\n")
 	rdb := redis.NewClient(&redis.Options{
 		Addr:     fmt.Sprintf("%s:%s", rd.RedisHost, rd.RedisPort),
@@ -45,41 +176,101 @@ func InitDS(config *Config) (*DataSources, error) {
 	})
 
 	// verify redis connection
-	_, err = rdb.Ping(context.Background()).Result()
+	_, err := rdb.Ping(context.Background()).Result()
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to redis: %w", err)
 	}
 
+	return rdb, nil
+}
+
+func initCloudStorage(gcp GCP) (*storage.Client, error) {
 	// Initialize google storage client
 	log.Printf("Connecting to Cloud Storage\n")
 	ctx := context.Background()
 
-	ctx, cancel := context.WithTimeout(ctx, time.Duration(config.DataSource.GCP.CloudConnectionTimeout)*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(gcp.CloudConnectionTimeout)*time.Second)
 	defer cancel() // releases resources if slowOperation completes before timeout elapses
 	cloudStorage, err := storage.NewClient(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error creating cloud storage client: %w", err)
 	}
 
-	return &DataSources{
-		PostgreSQLDB:       db,
-		RedisClient:        rdb,
-		CloudStorageClient: cloudStorage,
-	}, nil
+	return cloudStorage, nil
+}
+
+func initS3(s3 S3) (*minio.Client, error) {
+	log.Printf("Connecting to S3 at %s\n", s3.S3Endpoint)
+
+	creds := credentials.NewStaticV4(s3.S3AccessKeyID, s3.S3SecretAccessKey, "")
+	if s3.S3AccessKeyID == "" {
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.IAM{},
+		})
+	}
+
+	bucketLookup := minio.BucketLookupDNS
+	if s3.S3PathStyle {
+		bucketLookup = minio.BucketLookupPath
+	}
+
+	s3Client, err := minio.New(s3.S3Endpoint, &minio.Options{
+		Creds:        creds,
+		Secure:       s3.S3UseSSL,
+		Region:       s3.S3Region,
+		BucketLookup: bucketLookup,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating s3 client: %w", err)
+	}
+
+	return s3Client, nil
+}
+
+// s3URLTemplate is the public URL of images in S3, which is the URL
+// of their objects unless S3_URL_TEMPLATE is set
+func s3URLTemplate(s3 S3) string {
+	if s3.S3URLTemplate != "" {
+		return s3.S3URLTemplate
+	}
+
+	scheme := "http"
+	if s3.S3UseSSL {
+		scheme = "https"
+	}
+
+	if s3.S3PathStyle {
+		return fmt.Sprintf("%s://%s/{bucket}/{key}", scheme, s3.S3Endpoint)
+	}
+
+	return fmt.Sprintf("%s://{bucket}.%s/{key}", scheme, s3.S3Endpoint)
 }
 
 // Close to be used in graceful server shutdown
 func (d *DataSources) Close() error {
-	if err := d.PostgreSQLDB.Close(); err != nil {
-		return fmt.Errorf("error closing Postgresql: %w", err)
+	if d.PostgreSQLDB != nil {
+		if err := d.PostgreSQLDB.Close(); err != nil {
+			return fmt.Errorf("error closing Postgresql: %w", err)
+		}
+	}
+
+	if d.SQLiteDB != nil {
+		if err := d.SQLiteDB.Close(); err != nil {
+			return fmt.Errorf("error closing SQLite: %w", err)
+		}
 	}
 
-	if err := d.RedisClient.Close(); err != nil {
-		return fmt.Errorf("error closing Redis Client: %w", err)
+	if d.RedisClient != nil {
+		if err := d.RedisClient.Close(); err != nil {
+			return fmt.Errorf("error closing Redis Client: %w", err)
+		}
 	}
 
-	if err := d.CloudStorageClient.Close(); err != nil {
-		return fmt.Errorf("error closing Cloud Storage client: %w", err)
+	if d.CloudStorageClient != nil {
+		if err := d.CloudStorageClient.Close(); err != nil {
+			return fmt.Errorf("error closing Cloud Storage client: %w", err)
+		}
 	}
 
 	return nil
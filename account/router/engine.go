@@ -2,6 +2,7 @@ package router
 
 import (
 	"github.com/dolong2110/memorization-apps/account/handler"
+	"github.com/dolong2110/memorization-apps/account/model"
 	"github.com/dolong2110/memorization-apps/account/repository"
 	"github.com/dolong2110/memorization-apps/account/service"
 	"github.com/gin-gonic/gin"
@@ -32,20 +33,150 @@ func (r *Router) InitGin() (*gin.Engine, error) {
 	/*
 	 * repository layer
 	 */
-	userRepository := repository.NewUserRepository(r.dataSource.PostgreSQLDB)
-	tokenRepository := repository.NewTokenRepository(r.dataSource.RedisClient)
-	imageRepository := repository.NewImageRepository(r.dataSource.CloudStorageClient, r.config.DataSource.GCP.GCPImageBucket)
+	dsConfig := r.config.DataSource
+
+	var imageRepository model.ImageRepository
+	switch dsConfig.ImageStore {
+	case ImageStoreMemory:
+		imageRepository = repository.NewMemoryImageRepository(dsConfig.ImageBaseURL)
+	case ImageStoreFile:
+		imageRepository = repository.NewFileImageRepository(dsConfig.ImageDir, dsConfig.ImageBaseURL)
+	case ImageStoreS3:
+		imageRepository = repository.NewS3ImageRepository(r.dataSource.S3Client, dsConfig.S3.S3ImageBucket, s3URLTemplate(dsConfig.S3))
+	default:
+		imageRepository = repository.NewImageRepository(r.dataSource.CloudStorageClient, dsConfig.GCP.GCPImageBucket)
+	}
+
+	var userRepository model.UserRepository
+	var tokenRepository model.TokenRepository
+	var loginAttemptRepository model.LoginAttemptRepository
+	var auditRepository model.AuditRepository
+	var deviceRepository model.DeviceRepository
+	var roleRepository model.RoleRepository
+	var orgRepository model.OrgRepository
+	var termsRepository model.TermsRepository
+	var exportRepository model.ExportRepository
+	var mfaRepository model.MFARepository
+	var passkeyRepository model.PasskeyRepository
+	var identityRepository model.IdentityRepository
+	var inviteRepository model.InviteRepository
+	switch dsConfig.Driver {
+	case DriverMemory:
+		// the other repositories are left out, which turns off the features
+		// that need them, like audit events, lockouts, MFA, passkeys, OAuth,
+		// organizations, invites, roles and exports, along with their routes
+		userRepository = repository.NewMemoryUserRepository()
+		tokenRepository = repository.NewMemoryTokenRepository()
+	case DriverSQLite:
+		// like the memory driver, with users kept in the SQLite file, and
+		// tokens and sign-in attempts in Redis when it is available
+		userRepository = repository.NewSQLiteUserRepository(r.dataSource.SQLiteDB)
+		tokenRepository = repository.NewMemoryTokenRepository()
+		if r.dataSource.RedisClient != nil {
+			tokenRepository = repository.NewTokenRepository(r.dataSource.RedisClient)
+			loginAttemptRepository = repository.NewLoginAttemptRepository(r.dataSource.RedisClient)
+		}
+	default:
+		userRepository = repository.NewUserRepository(r.dataSource.PostgreSQLDB)
+		tokenRepository = repository.NewTokenRepository(r.dataSource.RedisClient)
+		loginAttemptRepository = repository.NewLoginAttemptRepository(r.dataSource.RedisClient)
+		auditRepository = repository.NewAuditRepository(r.dataSource.PostgreSQLDB)
+		deviceRepository = repository.NewDeviceRepository(r.dataSource.PostgreSQLDB)
+		roleRepository = repository.NewRoleRepository(r.dataSource.PostgreSQLDB)
+		orgRepository = repository.NewOrgRepository(r.dataSource.PostgreSQLDB)
+		termsRepository = repository.NewTermsRepository(r.dataSource.PostgreSQLDB)
+		exportRepository = repository.NewExportRepository(r.dataSource.RedisClient)
+		mfaRepository = repository.NewMFARepository(r.dataSource.PostgreSQLDB)
+		passkeyRepository = repository.NewPasskeyRepository(r.dataSource.PostgreSQLDB)
+		identityRepository = repository.NewIdentityRepository(r.dataSource.PostgreSQLDB)
+		inviteRepository = repository.NewInviteRepository(r.dataSource.PostgreSQLDB)
+	}
+
+	if dsConfig.Driver != DriverPostgres {
+		log.Printf("WARNING: the %s driver turns off audit events, MFA, passkeys, OAuth, roles, organizations, invites, exports and new device notifications\n", dsConfig.Driver)
+	}
+
+	if loginAttemptRepository == nil {
+		log.Printf("WARNING: sign-in lockouts are off without Redis, failed sign-ins are not throttled\n")
+	}
+
+	// users could not accept terms of service which cannot be recorded
+	termsVersion := r.config.Terms.TermsVersion
+	if termsRepository == nil {
+		termsVersion = ""
+	}
+
+	passwordConfig := r.config.Password
+	var breachedPasswordRepository model.BreachedPasswordRepository
+	if passwordConfig.BreachedPasswordsFile != "" {
+		var err error
+		breachedPasswordRepository, err = initBreachedPasswords(passwordConfig.BreachedPasswordsFile, passwordConfig.BreachedPasswordsSorted)
+		if err != nil {
+			log.Fatalf("could not load breached passwords: %v\n", err)
+		}
+	}
+
+	mailConfig := r.config.Mail
+	mailer := repository.NewLogMailer()
+	if mailConfig.SMTPHost != "" {
+		mailer = repository.NewSMTPMailer(mailConfig.SMTPHost, mailConfig.SMTPPort, mailConfig.SMTPUsername, mailConfig.SMTPPassword, mailConfig.MailFrom)
+	}
+
+	deviceConfig := r.config.Device
+	var notifier model.Notifier
+	switch deviceConfig.Notifier {
+	case "email":
+		notifier = repository.NewMailNotifier(mailer)
+	case "log":
+		notifier = repository.NewLogNotifier()
+	case "none":
+	default:
+		log.Fatalf("unknown notifier: %v\n", deviceConfig.Notifier)
+	}
 
 	/*
 	 * service layer
 	 */
-	userService := service.NewUserService(&service.USConfig{
-		UserRepository:  userRepository,
-		ImageRepository: imageRepository,
+	passwordPolicy := service.NewPasswordPolicy(&service.PasswordPolicyConfig{
+		BreachedPasswordRepository: breachedPasswordRepository,
+		MinLength:                  passwordConfig.PasswordMinLength,
+		MaxLength:                  passwordConfig.PasswordMaxLength,
+		MinStrength:                passwordConfig.PasswordMinStrength,
+	})
+
+	signupConfig := r.config.Signup
+	switch signupConfig.SignupMode {
+	case service.SignupModeOpen, service.SignupModeDomains, service.SignupModeInvite:
+	default:
+		log.Fatalf("unknown sign-up mode: %v\n", signupConfig.SignupMode)
+	}
+
+	// no one could ever sign up without invites to redeem
+	if signupConfig.SignupMode == service.SignupModeInvite && inviteRepository == nil {
+		log.Fatalf("sign-up mode %v needs the %v driver\n", service.SignupModeInvite, DriverPostgres)
+	}
+
+	signupPolicy := service.NewSignupPolicy(&service.SignupPolicyConfig{
+		InviteRepository: inviteRepository,
+		Mode:             signupConfig.SignupMode,
+		AllowedDomains:   signupConfig.SignupAllowedDomains,
 	})
 
+	var inviteService model.InviteService
+	if inviteRepository != nil {
+		inviteService = service.NewInviteService(&service.InviteServiceConfig{
+			InviteRepository: inviteRepository,
+			DefaultExpires:   time.Duration(signupConfig.InviteExpire) * time.Second,
+		})
+	}
+
 	tokenConfig := r.config.Token
-	accessTokenInfo, err := initAccessToken(tokenConfig.AccessToken)
+	initAccessTokenInfo := initAccessToken
+	if dsConfig.Driver == DriverMemory {
+		initAccessTokenInfo = initEphemeralAccessToken
+	}
+
+	accessTokenInfo, err := initAccessTokenInfo(tokenConfig.AccessToken)
 	if err != nil {
 		log.Fatalf("could not get access token information: %v\n", err)
 	}
@@ -55,18 +186,213 @@ func (r *Router) InitGin() (*gin.Engine, error) {
 		AccessTokenInfo:  *accessTokenInfo,
 		RefreshTokenInfo: *refreshTokenInfo,
 		TokenRepository:  tokenRepository,
+		RoleRepository:   roleRepository,
+		OrgRepository:    orgRepository,
+		AuditRepository:  auditRepository,
+		UserRepository:   userRepository,
 	})
 
+	userService := service.NewUserService(&service.USConfig{
+		UserRepository:       userRepository,
+		ImageRepository:      imageRepository,
+		TokenRepository:      tokenRepository,
+		PasswordPolicy:       passwordPolicy,
+		SignupPolicy:         signupPolicy,
+		Mailer:               mailer,
+		AuditRepository:      auditRepository,
+		DeviceRepository:     deviceRepository,
+		Notifier:             notifier,
+		TermsRepository:      termsRepository,
+		PasskeyRepository:    passkeyRepository,
+		IdentityRepository:   identityRepository,
+		MFARepository:        mfaRepository,
+		TokenService:         tokenService,
+		PasswordResetURL:     passwordConfig.PasswordResetURL,
+		PasswordResetExpires: time.Duration(passwordConfig.PasswordResetExpire) * time.Second,
+		HandleChangeCooldown: time.Duration(r.config.Handle.HandleChangeCooldown) * time.Second,
+		DeviceRevokeURL:      deviceConfig.DeviceRevokeURL,
+		DeviceRevokeExpires:  time.Duration(deviceConfig.DeviceRevokeExpire) * time.Second,
+		TermsVersion:         termsVersion,
+	})
+
+	guestConfig := r.config.Guest
+	if guestConfig.GuestMaxAge > 0 {
+		if guestConfig.GuestPurgeInterval <= 0 {
+			log.Fatalf("invalid guest purge interval: %v\n", guestConfig.GuestPurgeInterval)
+		}
+
+		go purgeGuests(userService, time.Duration(guestConfig.GuestMaxAge)*time.Second, time.Duration(guestConfig.GuestPurgeInterval)*time.Second)
+	}
+
+	var roleService model.RoleService
+	if roleRepository != nil {
+		roleService = service.NewRoleService(&service.RoleServiceConfig{
+			RoleRepository:  roleRepository,
+			UserRepository:  userRepository,
+			AuditRepository: auditRepository,
+		})
+	}
+
+	var orgService model.OrgService
+	if orgRepository != nil {
+		orgService = service.NewOrgService(&service.OrgServiceConfig{
+			OrgRepository:   orgRepository,
+			UserRepository:  userRepository,
+			AuditRepository: auditRepository,
+			Mailer:          mailer,
+			InviteURL:       r.config.Org.OrgInviteURL,
+			InviteExpires:   time.Duration(r.config.Org.OrgInviteExpire) * time.Second,
+		})
+	}
+
+	if roleService != nil {
+		if err := bootstrapAdmins(roleService, r.config.Admin.AdminEmails); err != nil {
+			log.Fatalf("could not bootstrap admins: %v\n", err)
+		}
+	}
+
+	var mfaService model.MFAService
+	if mfaRepository != nil {
+		mfaService = service.NewMFAService(&service.MFAServiceConfig{
+			UserRepository:       userRepository,
+			MFARepository:        mfaRepository,
+			TokenRepository:      tokenRepository,
+			AuditRepository:      auditRepository,
+			Issuer:               r.config.MFA.TOTPIssuer,
+			ChallengeExpires:     time.Duration(r.config.MFA.MFAChallengeExpire) * time.Second,
+			MaxChallengeAttempts: r.config.MFA.MFAChallengeAttempts,
+		})
+	}
+
+	var passkeyService model.PasskeyService
+	if passkeyRepository != nil {
+		passkeyService = service.NewPasskeyService(&service.PasskeyServiceConfig{
+			UserRepository:    userRepository,
+			PasskeyRepository: passkeyRepository,
+			TokenRepository:   tokenRepository,
+			AuditRepository:   auditRepository,
+			RPID:              r.config.WebAuthn.RPID,
+			RPName:            r.config.WebAuthn.RPName,
+			RPOrigins:         r.config.WebAuthn.RPOrigins,
+			Timeout:           time.Duration(r.config.WebAuthn.WebAuthnTimeout) * time.Second,
+		})
+	}
+
+	magicLinkConfig := r.config.MagicLink
+	magicLinkService := service.NewMagicLinkService(&service.MagicLinkServiceConfig{
+		UserRepository:  userRepository,
+		TokenRepository: tokenRepository,
+		AuditRepository: auditRepository,
+		Mailer:          mailer,
+		SignupPolicy:    signupPolicy,
+		LinkURL:         magicLinkConfig.MagicLinkURL,
+		LinkExpires:     time.Duration(magicLinkConfig.MagicLinkExpire) * time.Second,
+		MaxRequests:     magicLinkConfig.MagicLinkMaxRequests,
+		RequestWindow:   time.Duration(magicLinkConfig.MagicLinkWindow) * time.Second,
+		AllowSignup:     magicLinkConfig.MagicLinkSignup,
+	})
+
+	var oauthProviders []service.OAuthProvider
+	for _, p := range r.config.OAuth.OAuthProviders {
+		// providers without credentials are listed in dev.json as examples only
+		if p.ClientID == "" {
+			continue
+		}
+
+		oauthProviders = append(oauthProviders, service.OAuthProvider{
+			Name:         p.Name,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			AuthURL:      p.AuthURL,
+			TokenURL:     p.TokenURL,
+			UserInfoURL:  p.UserInfoURL,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+			SubjectClaim: p.SubjectClaim,
+			EmailClaim:   p.EmailClaim,
+			TrustEmail:   p.TrustEmail,
+		})
+	}
+
+	var oauthService model.OAuthService
+	if identityRepository != nil {
+		oauthService = service.NewOAuthService(&service.OAuthServiceConfig{
+			UserRepository:     userRepository,
+			IdentityRepository: identityRepository,
+			PasskeyRepository:  passkeyRepository,
+			TokenRepository:    tokenRepository,
+			AuditRepository:    auditRepository,
+			SignupPolicy:       signupPolicy,
+			UserService:        userService,
+			Providers:          oauthProviders,
+			StateExpires:       time.Duration(r.config.OAuth.OAuthStateExpire) * time.Second,
+			EnumerationSafe:    signupConfig.SignupEnumerationSafe,
+		})
+	}
+
+	lockoutConfig := r.config.Lockout
+	var lockoutService model.LockoutService
+	if loginAttemptRepository != nil {
+		lockoutService = service.NewLockoutService(&service.LockoutServiceConfig{
+			LoginAttemptRepository: loginAttemptRepository,
+			UserRepository:         userRepository,
+			Mailer:                 mailer,
+			FreeAttempts:           lockoutConfig.LoginFreeAttempts,
+			IPFreeAttempts:         lockoutConfig.LoginIPFreeAttempts,
+			FailureWindow:          time.Duration(lockoutConfig.LoginFailureWindow) * time.Second,
+			BackoffBase:            time.Duration(lockoutConfig.LoginBackoffBase) * time.Second,
+			BackoffMax:             time.Duration(lockoutConfig.LoginBackoffMax) * time.Second,
+			LockoutThreshold:       lockoutConfig.LockoutThreshold,
+			LockoutDuration:        time.Duration(lockoutConfig.LockoutDuration) * time.Second,
+			UnlockURL:              lockoutConfig.UnlockURL,
+			UnlockExpires:          time.Duration(lockoutConfig.UnlockExpire) * time.Second,
+			GuestMaxPerIP:          r.config.Guest.GuestMaxPerIP,
+			GuestWindow:            time.Duration(r.config.Guest.GuestIPWindow) * time.Second,
+		})
+	}
+
+	var exportService model.ExportService
+	if exportRepository != nil {
+		exportService = service.NewExportService(&service.ExportServiceConfig{
+			UserRepository:     userRepository,
+			TokenRepository:    tokenRepository,
+			ImageRepository:    imageRepository,
+			ExportRepository:   exportRepository,
+			AuditRepository:    auditRepository,
+			MFARepository:      mfaRepository,
+			PasskeyRepository:  passkeyRepository,
+			IdentityRepository: identityRepository,
+			OrgRepository:      orgRepository,
+			TermsRepository:    termsRepository,
+			DeviceRepository:   deviceRepository,
+			LinkExpires:        time.Duration(r.config.Export.ExportLinkExpire) * time.Second,
+		})
+	}
+
 	// initialize gin.Engine
 	router := gin.Default()
 
 	handler.NewHandler(&handler.Config{
-		Engine:          router,
-		UserService:     userService,
-		TokenService:    tokenService,
-		BaseURL:         r.config.AccountAPIURL,
-		TimeoutDuration: time.Duration(r.config.HandlerTimeout) * time.Second,
-		MaxBodyBytes:    r.config.MaxBodyBytes,
+		Engine:           router,
+		UserService:      userService,
+		TokenService:     tokenService,
+		MFAService:       mfaService,
+		PasskeyService:   passkeyService,
+		MagicLinkService: magicLinkService,
+		OAuthService:     oauthService,
+		LockoutService:   lockoutService,
+		ExportService:    exportService,
+		InviteService:    inviteService,
+		RoleService:      roleService,
+		OrgService:       orgService,
+		BaseURL:          r.config.AccountAPIURL,
+		TimeoutDuration:  time.Duration(r.config.HandlerTimeout) * time.Second,
+		MaxBodyBytes:     r.config.MaxBodyBytes,
+		TermsVersion:     termsVersion,
+		TermsURL:         r.config.Terms.TermsURL,
+		ServeImages:      dsConfig.ImageStore == ImageStoreMemory || dsConfig.ImageStore == ImageStoreFile,
+
+		EnumerationSafeSignup: signupConfig.SignupEnumerationSafe,
 	})
 
 	return router, nil
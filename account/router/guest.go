@@ -0,0 +1,33 @@
+package router
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"log"
+	"time"
+)
+
+// guestPurgeTimeout bounds a single purge of unused guests
+const guestPurgeTimeout = time.Minute
+
+// purgeGuests deletes the guests not seen within maxAge every interval,
+// for as long as the server runs
+func purgeGuests(userService model.UserService, maxAge time.Duration, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), guestPurgeTimeout)
+		purged, err := userService.PurgeGuests(ctx, maxAge)
+		cancel()
+
+		if err != nil {
+			log.Printf("Failed to purge guests: %v\n", err)
+			continue
+		}
+
+		if purged > 0 {
+			log.Printf("Purged %d unused guests\n", purged)
+		}
+	}
+}
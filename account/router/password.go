@@ -0,0 +1,35 @@
+package router
+
+import (
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/repository"
+	"os"
+)
+
+// initBreachedPasswords loads a list of breached passwords, or opens a sorted
+// one to be searched, which stays open for as long as the service runs
+func initBreachedPasswords(breachedPasswordsFile string, sorted bool) (model.BreachedPasswordRepository, error) {
+	file, err := os.Open(breachedPasswordsFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not open breached passwords file: %w", err)
+	}
+
+	if sorted {
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("could not read breached passwords file: %w", err)
+		}
+
+		return repository.NewSortedBreachedPasswordRepository(file, info.Size()), nil
+	}
+	defer file.Close()
+
+	breachedPasswordRepository, err := repository.NewBreachedPasswordRepository(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read breached passwords file: %w", err)
+	}
+
+	return breachedPasswordRepository, nil
+}
@@ -1,10 +1,16 @@
 package router
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/dolong2110/memorization-apps/account/model"
+	"io/fs"
 	"io/ioutil"
+	"log"
+	"os"
 )
 
 func initAccessToken(accessTokenConfig AccessToken) (*model.AccessTokenInfo, error) {
@@ -36,6 +42,28 @@ func initAccessToken(accessTokenConfig AccessToken) (*model.AccessTokenInfo, err
 	}, nil
 }
 
+// initEphemeralAccessToken generates the keys of access tokens when the key files
+// are missing, for running without users or keys that outlive the process
+func initEphemeralAccessToken(accessTokenConfig AccessToken) (*model.AccessTokenInfo, error) {
+	for _, file := range []string{accessTokenConfig.PublicKeyFile, accessTokenConfig.PrivateKeyFile} {
+		if _, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Key file %v not found, generating access token keys which are lost on restart\n", file)
+			privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				return nil, fmt.Errorf("could not generate private key: %w", err)
+			}
+
+			return &model.AccessTokenInfo{
+				PublicKey:  &privateKey.PublicKey,
+				PrivateKey: privateKey,
+				Expires:    accessTokenConfig.AccessTokenExpire,
+			}, nil
+		}
+	}
+
+	return initAccessToken(accessTokenConfig)
+}
+
 func initRefreshToken(refreshTokenConfig RefreshToken) *model.RefreshTokenInfo {
 	return &model.RefreshTokenInfo{
 		Secret:  refreshTokenConfig.RefreshTokenSecret,
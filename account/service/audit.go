@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+
+	"github.com/google/uuid"
+	"log"
+)
+
+// audit appends an event to the audit log along with the client of the request
+// Without an actor in the context, users act on their own account when the
+// event succeeds and anonymously when it fails. Failing to record an event
+// does not fail the request, it is only logged
+func audit(ctx context.Context, repo model.AuditRepository, uid *uuid.UUID, eventType string, outcome string, detail string) {
+	actor := model.ActorFrom(ctx)
+	if actor == nil && outcome == model.AuditSuccess {
+		actor = uid
+	}
+
+	auditAs(ctx, repo, actor, uid, eventType, outcome, detail)
+}
+
+// auditAs appends an event of an explicit actor to the audit log,
+// a nil actor stands for the service itself
+func auditAs(ctx context.Context, repo model.AuditRepository, actor *uuid.UUID, uid *uuid.UUID, eventType string, outcome string, detail string) {
+	if repo == nil {
+		return
+	}
+
+	info := model.ClientInfoFrom(ctx)
+
+	event := &model.AuditEvent{
+		UID:       uid,
+		ActorID:   actor,
+		Type:      eventType,
+		Outcome:   outcome,
+		Detail:    detail,
+		IP:        info.IP,
+		UserAgent: info.UserAgent,
+	}
+
+	if err := repo.Create(ctx, event); err != nil {
+		log.Printf("Failed to record audit event: %v of uid: %v: %v\n", eventType, uid, err)
+	}
+}
+
+// outcomeOf is the outcome of an audit event ending with err
+func outcomeOf(err error) string {
+	if err != nil {
+		return model.AuditFailure
+	}
+	return model.AuditSuccess
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+	"github.com/dolong2110/memorization-apps/account/utils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	adminUID, _ := uuid.NewRandom()
+
+	ctx := model.WithClientInfo(context.Background(), model.ClientInfo{IP: "203.0.113.7", UserAgent: "curl/8.0"})
+
+	record := func(ctx context.Context, outcome string) *model.AuditEvent {
+		mockAuditRepository := new(mocks.MockAuditRepository)
+
+		var event *model.AuditEvent
+		mockAuditRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.AuditEvent")).
+			Run(func(args mock.Arguments) {
+				event = args.Get(1).(*model.AuditEvent)
+			}).
+			Return(nil)
+
+		audit(ctx, mockAuditRepository, &uid, model.AuditSignin, outcome, "")
+		return event
+	}
+
+	t.Run("Client info", func(t *testing.T) {
+		event := record(ctx, model.AuditSuccess)
+
+		assert.Equal(t, "203.0.113.7", event.IP)
+		assert.Equal(t, "curl/8.0", event.UserAgent)
+		assert.Equal(t, &uid, event.UID)
+	})
+
+	t.Run("Users act on their own account", func(t *testing.T) {
+		event := record(ctx, model.AuditSuccess)
+
+		assert.Equal(t, &uid, event.ActorID)
+	})
+
+	t.Run("Failures are anonymous", func(t *testing.T) {
+		event := record(ctx, model.AuditFailure)
+
+		assert.Nil(t, event.ActorID)
+	})
+
+	t.Run("Authenticated actor", func(t *testing.T) {
+		event := record(model.WithActor(ctx, adminUID), model.AuditSuccess)
+
+		assert.Equal(t, &adminUID, event.ActorID)
+	})
+
+	t.Run("Without repository", func(t *testing.T) {
+		assert.NotPanics(t, func() {
+			audit(ctx, nil, &uid, model.AuditSignin, model.AuditSuccess, "")
+		})
+	})
+}
+
+func TestSigninAudit(t *testing.T) {
+	password := "howdyhoneighbor!"
+	hashedPassword, _ := utils.HashPassword(password)
+	uid, _ := uuid.NewRandom()
+
+	newService := func(mockAuditRepository *mocks.MockAuditRepository) model.UserService {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockUserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(&model.User{UID: uid, Email: "bob@bob.com", Password: hashedPassword}, nil)
+		mockUserRepository.On("FindByEmail", mock.Anything, "nobody@bob.com").Return(nil, apperrors.NewNotFound("email", "nobody@bob.com"))
+
+		return NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			AuditRepository: mockAuditRepository,
+		})
+	}
+
+	eventMatching := func(uid *uuid.UUID, outcome string, detail string) interface{} {
+		return mock.MatchedBy(func(e *model.AuditEvent) bool {
+			return e.Type == model.AuditSignin && assert.ObjectsAreEqual(uid, e.UID) && e.Outcome == outcome && e.Detail == detail
+		})
+	}
+
+	t.Run("Success is not recorded before tokens are issued", func(t *testing.T) {
+		mockAuditRepository := new(mocks.MockAuditRepository)
+
+		err := newService(mockAuditRepository).Signin(context.TODO(), &model.User{Email: "bob@bob.com", Password: password})
+
+		assert.NoError(t, err)
+		mockAuditRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Recorded sign-in", func(t *testing.T) {
+		mockAuditRepository := new(mocks.MockAuditRepository)
+		mockAuditRepository.On("Create", mock.Anything, eventMatching(&uid, model.AuditSuccess, "password")).Return(nil)
+
+		newService(mockAuditRepository).RecordSignin(context.TODO(), &model.User{UID: uid, Email: "bob@bob.com"}, "password")
+
+		mockAuditRepository.AssertExpectations(t)
+	})
+
+	t.Run("Invalid password", func(t *testing.T) {
+		mockAuditRepository := new(mocks.MockAuditRepository)
+		mockAuditRepository.On("Create", mock.Anything, eventMatching(&uid, model.AuditFailure, "invalid password")).Return(nil)
+
+		err := newService(mockAuditRepository).Signin(context.TODO(), &model.User{Email: "bob@bob.com", Password: "wrong password"})
+
+		assert.Error(t, err)
+		mockAuditRepository.AssertExpectations(t)
+	})
+
+	t.Run("Unknown account", func(t *testing.T) {
+		mockAuditRepository := new(mocks.MockAuditRepository)
+		mockAuditRepository.On("Create", mock.Anything, eventMatching(nil, model.AuditFailure, "unknown account")).Return(nil)
+
+		err := newService(mockAuditRepository).Signin(context.TODO(), &model.User{Email: "nobody@bob.com", Password: password})
+
+		assert.Error(t, err)
+		mockAuditRepository.AssertExpectations(t)
+	})
+
+	t.Run("Recording failure does not fail sign-in", func(t *testing.T) {
+		mockAuditRepository := new(mocks.MockAuditRepository)
+		mockAuditRepository.On("Create", mock.Anything, mock.Anything).Return(apperrors.NewInternal())
+
+		assert.NotPanics(t, func() {
+			newService(mockAuditRepository).RecordSignin(context.TODO(), &model.User{UID: uid, Email: "bob@bob.com"}, "password")
+		})
+		mockAuditRepository.AssertExpectations(t)
+	})
+}
+
+func TestUpdateDetailsAudit(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	mockUserRepository := new(mocks.MockUserRepository)
+	mockAuditRepository := new(mocks.MockAuditRepository)
+	us := NewUserService(&USConfig{
+		UserRepository:  mockUserRepository,
+		AuditRepository: mockAuditRepository,
+	})
+
+	user := &model.User{UID: uid, Email: "new@bob.com", Name: "Bob"}
+
+	mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Email: "old@bob.com"}, nil)
+	mockUserRepository.On("Update", mock.Anything, user).Return(nil)
+	mockAuditRepository.On("Create", mock.Anything, mock.MatchedBy(func(e *model.AuditEvent) bool {
+		return e.Type == model.AuditProfileUpdate
+	})).Return(nil)
+	mockAuditRepository.On("Create", mock.Anything, mock.MatchedBy(func(e *model.AuditEvent) bool {
+		return e.Type == model.AuditEmailChange && e.Detail == "previous email: old@bob.com"
+	})).Return(nil)
+
+	err := us.UpdateDetails(context.TODO(), user)
+
+	assert.NoError(t, err)
+	mockAuditRepository.AssertExpectations(t)
+}
+
+func TestSecurityEventAudit(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	hashedPassword, _ := utils.HashPassword("howdyhoneighbor!")
+
+	eventMatching := func(eventType string, outcome string, detail string) interface{} {
+		return mock.MatchedBy(func(e *model.AuditEvent) bool {
+			return e.Type == eventType && assert.ObjectsAreEqual(&uid, e.UID) && e.Outcome == outcome && e.Detail == detail
+		})
+	}
+
+	t.Run("Second factor disabled", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockMFARepository := new(mocks.MockMFARepository)
+		mockAuditRepository := new(mocks.MockAuditRepository)
+		ms := NewMFAService(&MFAServiceConfig{
+			UserRepository:  mockUserRepository,
+			MFARepository:   mockMFARepository,
+			AuditRepository: mockAuditRepository,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Password: hashedPassword}, nil)
+		mockMFARepository.On("FindTOTP", mock.Anything, uid).Return(&model.TOTP{UID: uid, Confirmed: true}, nil)
+		mockMFARepository.On("DeleteTOTP", mock.Anything, uid).Return(nil)
+		mockAuditRepository.On("Create", mock.Anything, eventMatching(model.AuditMFADisable, model.AuditFailure, "invalid password")).Return(nil)
+		mockAuditRepository.On("Create", mock.Anything, eventMatching(model.AuditMFADisable, model.AuditSuccess, "totp")).Return(nil)
+
+		assert.Error(t, ms.DisableTOTP(context.TODO(), uid, "wrongpassword"))
+		assert.NoError(t, ms.DisableTOTP(context.TODO(), uid, "howdyhoneighbor!"))
+		mockAuditRepository.AssertExpectations(t)
+	})
+
+	t.Run("Invalid second factor", func(t *testing.T) {
+		mockMFARepository := new(mocks.MockMFARepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockAuditRepository := new(mocks.MockAuditRepository)
+		ms := NewMFAService(&MFAServiceConfig{
+			MFARepository:        mockMFARepository,
+			TokenRepository:      mockTokenRepository,
+			AuditRepository:      mockAuditRepository,
+			MaxChallengeAttempts: 3,
+		})
+
+		mockTokenRepository.On("GetMFAChallenge", mock.Anything, "challenge").Return(uid.String(), nil)
+		mockTokenRepository.On("IncrementMFAChallengeAttempts", mock.Anything, "challenge").Return(int64(1), nil)
+		mockMFARepository.On("FindTOTP", mock.Anything, uid).Return(&model.TOTP{UID: uid, Confirmed: true}, nil)
+		mockMFARepository.On("UseRecoveryCode", mock.Anything, uid, mock.AnythingOfType("string")).Return(false, nil)
+		mockAuditRepository.On("Create", mock.Anything, eventMatching(model.AuditSignin, model.AuditFailure, "invalid second factor")).Return(nil)
+
+		_, err := ms.VerifyChallenge(context.TODO(), "challenge", "notacode")
+
+		assert.Error(t, err)
+		mockAuditRepository.AssertExpectations(t)
+	})
+
+	t.Run("Passkey removed", func(t *testing.T) {
+		mockPasskeyRepository := new(mocks.MockPasskeyRepository)
+		mockAuditRepository := new(mocks.MockAuditRepository)
+		ps := NewPasskeyService(&PasskeyServiceConfig{
+			PasskeyRepository: mockPasskeyRepository,
+			AuditRepository:   mockAuditRepository,
+		})
+
+		mockPasskeyRepository.On("Delete", mock.Anything, uid, []byte{1, 2, 3}).Return(nil)
+		mockAuditRepository.On("Create", mock.Anything, eventMatching(model.AuditPasskeyRemove, model.AuditSuccess, "AQID")).Return(nil)
+
+		assert.NoError(t, ps.DeleteCredential(context.TODO(), uid, []byte{1, 2, 3}))
+		mockAuditRepository.AssertExpectations(t)
+	})
+
+	t.Run("Identity unlinked", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockIdentityRepository := new(mocks.MockIdentityRepository)
+		mockAuditRepository := new(mocks.MockAuditRepository)
+		oas := NewOAuthService(&OAuthServiceConfig{
+			UserRepository:     mockUserRepository,
+			IdentityRepository: mockIdentityRepository,
+			AuditRepository:    mockAuditRepository,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Password: hashedPassword}, nil)
+		mockIdentityRepository.On("Delete", mock.Anything, uid, "github").Return(nil)
+		mockAuditRepository.On("Create", mock.Anything, eventMatching(model.AuditIdentityUnlink, model.AuditSuccess, "github")).Return(nil)
+
+		assert.NoError(t, oas.Unlink(context.TODO(), uid, "github"))
+		mockAuditRepository.AssertExpectations(t)
+	})
+}
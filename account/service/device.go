@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/utils"
+
+	"github.com/google/uuid"
+	"log"
+	"net/url"
+)
+
+// rememberDevice records the device of the request for a user,
+// returning it and whether it is new
+func (s *userService) rememberDevice(ctx context.Context, uid uuid.UUID) (*model.KnownDevice, bool) {
+	if s.DeviceRepository == nil {
+		return nil, false
+	}
+
+	info := model.ClientInfoFrom(ctx)
+	if info.IP == "" && info.UserAgent == "" {
+		return nil, false
+	}
+
+	ipPrefix := utils.IPPrefix(info.IP)
+	device := &model.KnownDevice{
+		UID:         uid,
+		Fingerprint: utils.DeviceFingerprint(info.UserAgent, ipPrefix),
+		UserAgent:   info.UserAgent,
+		IPPrefix:    ipPrefix,
+	}
+
+	isNew, err := s.DeviceRepository.Upsert(ctx, device)
+	if err != nil {
+		return nil, false
+	}
+
+	return device, isNew
+}
+
+// checkDevice records the device of a sign-in and notifies the user if it
+// is new. The first device of a user is not notified, it is the one they
+// signed up or first signed in with. Failures are only logged, they do not
+// fail the sign-in
+func (s *userService) checkDevice(ctx context.Context, user *model.User) {
+	if s.Notifier == nil {
+		return
+	}
+
+	device, isNew := s.rememberDevice(ctx, user.UID)
+	if !isNew {
+		return
+	}
+
+	count, err := s.DeviceRepository.Count(ctx, user.UID)
+	if err != nil || count <= 1 {
+		return
+	}
+
+	revokeURL, err := s.deviceRevokeURL(ctx, user.UID)
+	if err != nil {
+		log.Printf("Failed to create device revoke link for uid: %v: %v\n", user.UID, err)
+		return
+	}
+
+	audit(ctx, s.AuditRepository, &user.UID, model.AuditNewDevice, model.AuditSuccess, fmt.Sprintf("%v (%v)", device.IPPrefix, device.UserAgent))
+
+	notice := &model.NewDeviceNotice{
+		User:      user,
+		Device:    device,
+		IP:        model.ClientInfoFrom(ctx).IP,
+		RevokeURL: revokeURL,
+	}
+
+	// notified in the background, so the sign-in is not slower for it
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+
+		if err := s.Notifier.NotifyNewDevice(ctx, notice); err != nil {
+			log.Printf("Failed to notify of new device for uid: %v: %v\n", user.UID, err)
+		}
+	}()
+}
+
+// deviceRevokeURL creates a single-use "this wasn't me" link for a user
+func (s *userService) deviceRevokeURL(ctx context.Context, uid uuid.UUID) (string, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.TokenRepository.SetDeviceRevokeToken(ctx, token, uid.String(), s.DeviceRevokeExpires); err != nil {
+		return "", err
+	}
+
+	revokeURL, err := url.Parse(s.DeviceRevokeURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := revokeURL.Query()
+	query.Set("token", token)
+	revokeURL.RawQuery = query.Encode()
+
+	return revokeURL.String(), nil
+}
+
+// DisownSignin handles the "this wasn't me" link of a new device notification
+// It ends every session of the user first, then clears the password and
+// forgets the known devices, passkeys, linked identities and second factor,
+// any of which may have been added by whoever signed in. Signing in again
+// takes the password reset link, which is mailed in the background
+func (s *userService) DisownSignin(ctx context.Context, token string) (*model.User, error) {
+	userID, err := s.TokenRepository.GetDeviceRevokeToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		log.Printf("Invalid uid of device revoke token: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	user, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.TokenService.Revoke(ctx, uid); err != nil {
+		return nil, err
+	}
+
+	// without a password, signing in takes a password reset
+	if err := s.UserRepository.UpdatePassword(ctx, uid, ""); err != nil {
+		return nil, err
+	}
+	user.Password = ""
+
+	if s.DeviceRepository != nil {
+		if err := s.DeviceRepository.DeleteByUID(ctx, uid); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.removeSigninMethods(ctx, uid); err != nil {
+		return nil, err
+	}
+
+	// a second factor enrolled by whoever signed in would lock the user out,
+	// its recovery codes go along with it
+	if s.MFARepository != nil {
+		if err := s.MFARepository.DeleteTOTP(ctx, uid); err != nil {
+			return nil, err
+		}
+	}
+
+	audit(ctx, s.AuditRepository, &uid, model.AuditSigninDisowned, model.AuditSuccess, "")
+
+	// the account is secured by now, a failed mail can be retried with
+	// the forgotten password form
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+
+		if err := s.sendPasswordReset(ctx, user); err != nil {
+			log.Printf("Failed to send password reset of disowned sign-in for uid: %v: %v\n", uid, err)
+		}
+	}()
+
+	return user, nil
+}
+
+// removeSigninMethods deletes the passkeys and linked identities of a user,
+// which the user can add again once signed in with a new password
+func (s *userService) removeSigninMethods(ctx context.Context, uid uuid.UUID) error {
+	if s.PasskeyRepository != nil {
+		credentials, err := s.PasskeyRepository.FindByUID(ctx, uid)
+		if err != nil {
+			return err
+		}
+
+		for _, credential := range credentials {
+			if err := s.PasskeyRepository.Delete(ctx, uid, credential.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if s.IdentityRepository != nil {
+		identities, err := s.IdentityRepository.FindByUID(ctx, uid)
+		if err != nil {
+			return err
+		}
+
+		for _, identity := range identities {
+			if err := s.IdentityRepository.Delete(ctx, uid, identity.Provider); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckDevice(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	user := &model.User{UID: uid, Email: "bob@bob.com"}
+
+	ctx := model.WithClientInfo(context.Background(), model.ClientInfo{IP: "203.0.113.7", UserAgent: "curl/8.0"})
+
+	newService := func(mockDeviceRepository *mocks.MockDeviceRepository, mockNotifier *mocks.MockNotifier, mockTokenRepository *mocks.MockTokenRepository) *userService {
+		return NewUserService(&USConfig{
+			TokenRepository:     mockTokenRepository,
+			DeviceRepository:    mockDeviceRepository,
+			Notifier:            mockNotifier,
+			DeviceRevokeURL:     "http://localhost:3000/signin/disown",
+			DeviceRevokeExpires: time.Hour,
+		}).(*userService)
+	}
+
+	t.Run("New device", func(t *testing.T) {
+		mockDeviceRepository := new(mocks.MockDeviceRepository)
+		mockNotifier := new(mocks.MockNotifier)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+
+		mockDeviceRepository.On("Upsert", mock.Anything, mock.MatchedBy(func(d *model.KnownDevice) bool {
+			return d.UID == uid && d.IPPrefix == "203.0.113.0/24" && d.UserAgent == "curl/8.0" && d.Fingerprint != ""
+		})).Return(true, nil)
+		mockDeviceRepository.On("Count", mock.Anything, uid).Return(int64(2), nil)
+		mockTokenRepository.On("SetDeviceRevokeToken", mock.Anything, mock.AnythingOfType("string"), uid.String(), time.Hour).Return(nil)
+
+		notified := make(chan *model.NewDeviceNotice, 1)
+		mockNotifier.On("NotifyNewDevice", mock.Anything, mock.AnythingOfType("*model.NewDeviceNotice")).
+			Run(func(args mock.Arguments) {
+				notified <- args.Get(1).(*model.NewDeviceNotice)
+			}).
+			Return(nil)
+
+		newService(mockDeviceRepository, mockNotifier, mockTokenRepository).checkDevice(ctx, user)
+
+		select {
+		case notice := <-notified:
+			assert.Equal(t, user, notice.User)
+			assert.Equal(t, "203.0.113.7", notice.IP)
+			assert.True(t, strings.HasPrefix(notice.RevokeURL, "http://localhost:3000/signin/disown?token="))
+		case <-time.After(time.Second):
+			t.Fatal("new device was not notified")
+		}
+
+		mockDeviceRepository.AssertExpectations(t)
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("First device", func(t *testing.T) {
+		mockDeviceRepository := new(mocks.MockDeviceRepository)
+		mockNotifier := new(mocks.MockNotifier)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+
+		mockDeviceRepository.On("Upsert", mock.Anything, mock.AnythingOfType("*model.KnownDevice")).Return(true, nil)
+		mockDeviceRepository.On("Count", mock.Anything, uid).Return(int64(1), nil)
+
+		newService(mockDeviceRepository, mockNotifier, mockTokenRepository).checkDevice(ctx, user)
+
+		mockTokenRepository.AssertNotCalled(t, "SetDeviceRevokeToken")
+		mockNotifier.AssertNotCalled(t, "NotifyNewDevice")
+	})
+
+	t.Run("Known device", func(t *testing.T) {
+		mockDeviceRepository := new(mocks.MockDeviceRepository)
+		mockNotifier := new(mocks.MockNotifier)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+
+		mockDeviceRepository.On("Upsert", mock.Anything, mock.AnythingOfType("*model.KnownDevice")).Return(false, nil)
+
+		newService(mockDeviceRepository, mockNotifier, mockTokenRepository).checkDevice(ctx, user)
+
+		mockDeviceRepository.AssertNotCalled(t, "Count")
+		mockNotifier.AssertNotCalled(t, "NotifyNewDevice")
+	})
+
+	t.Run("Recorded sign-ins of any method", func(t *testing.T) {
+		mockDeviceRepository := new(mocks.MockDeviceRepository)
+		mockNotifier := new(mocks.MockNotifier)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+
+		mockDeviceRepository.On("Upsert", mock.Anything, mock.AnythingOfType("*model.KnownDevice")).Return(true, nil)
+		mockDeviceRepository.On("Count", mock.Anything, uid).Return(int64(2), nil)
+		mockTokenRepository.On("SetDeviceRevokeToken", mock.Anything, mock.AnythingOfType("string"), uid.String(), time.Hour).Return(nil)
+
+		notified := make(chan *model.NewDeviceNotice, 1)
+		mockNotifier.On("NotifyNewDevice", mock.Anything, mock.AnythingOfType("*model.NewDeviceNotice")).
+			Run(func(args mock.Arguments) {
+				notified <- args.Get(1).(*model.NewDeviceNotice)
+			}).
+			Return(nil)
+
+		newService(mockDeviceRepository, mockNotifier, mockTokenRepository).RecordSignin(ctx, user, "passkey")
+
+		select {
+		case notice := <-notified:
+			assert.Equal(t, user, notice.User)
+		case <-time.After(time.Second):
+			t.Fatal("new device was not notified")
+		}
+	})
+}
+
+func TestDisownSignin(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockDeviceRepository := new(mocks.MockDeviceRepository)
+		mockPasskeyRepository := new(mocks.MockPasskeyRepository)
+		mockIdentityRepository := new(mocks.MockIdentityRepository)
+		mockMFARepository := new(mocks.MockMFARepository)
+		mockTokenService := new(mocks.MockTokenService)
+		mockMailer := new(mocks.MockMailer)
+
+		user := &model.User{UID: uid, Email: "bob@bob.com", Password: "hashed"}
+		mockTokenRepository.On("GetDeviceRevokeToken", mock.Anything, "token").Return(uid.String(), nil)
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(user, nil)
+		mockTokenService.On("Revoke", mock.Anything, uid).Return(nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, uid, "").Return(nil)
+		mockDeviceRepository.On("DeleteByUID", mock.Anything, uid).Return(nil)
+		mockPasskeyRepository.On("FindByUID", mock.Anything, uid).Return([]*model.PasskeyCredential{{ID: []byte("key"), UID: uid}}, nil)
+		mockPasskeyRepository.On("Delete", mock.Anything, uid, []byte("key")).Return(nil)
+		mockIdentityRepository.On("FindByUID", mock.Anything, uid).Return([]*model.Identity{{Provider: "github", UID: uid}}, nil)
+		mockIdentityRepository.On("Delete", mock.Anything, uid, "github").Return(nil)
+		mockMFARepository.On("DeleteTOTP", mock.Anything, uid).Return(nil)
+		mockTokenRepository.On("SetPasswordResetToken", mock.Anything, mock.AnythingOfType("string"), uid.String(), time.Hour).Return(nil)
+
+		sent := make(chan *model.Email, 1)
+		mockMailer.On("Send", mock.Anything, mock.AnythingOfType("*model.Email")).
+			Run(func(args mock.Arguments) {
+				sent <- args.Get(1).(*model.Email)
+			}).
+			Return(nil)
+
+		us := NewUserService(&USConfig{
+			UserRepository:       mockUserRepository,
+			TokenRepository:      mockTokenRepository,
+			DeviceRepository:     mockDeviceRepository,
+			PasskeyRepository:    mockPasskeyRepository,
+			IdentityRepository:   mockIdentityRepository,
+			MFARepository:        mockMFARepository,
+			TokenService:         mockTokenService,
+			Mailer:               mockMailer,
+			PasswordResetURL:     "http://localhost:3000/reset-password",
+			PasswordResetExpires: time.Hour,
+		})
+
+		disowned, err := us.DisownSignin(context.TODO(), "token")
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, disowned.UID)
+		assert.Equal(t, "", disowned.Password)
+
+		select {
+		case email := <-sent:
+			assert.Equal(t, "bob@bob.com", email.To)
+		case <-time.After(time.Second):
+			t.Fatal("password reset was not mailed")
+		}
+
+		mockUserRepository.AssertExpectations(t)
+		mockTokenRepository.AssertExpectations(t)
+		mockDeviceRepository.AssertExpectations(t)
+		mockPasskeyRepository.AssertExpectations(t)
+		mockIdentityRepository.AssertExpectations(t)
+		mockMFARepository.AssertExpectations(t)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Sessions end before anything else", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenService := new(mocks.MockTokenService)
+
+		user := &model.User{UID: uid, Email: "bob@bob.com", Password: "hashed"}
+		mockTokenRepository.On("GetDeviceRevokeToken", mock.Anything, "token").Return(uid.String(), nil)
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(user, nil)
+		mockTokenService.On("Revoke", mock.Anything, uid).Return(apperrors.NewInternal())
+
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			TokenService:    mockTokenService,
+		})
+
+		disowned, err := us.DisownSignin(context.TODO(), "token")
+
+		assert.Nil(t, disowned)
+		assert.Equal(t, http.StatusInternalServerError, apperrors.Status(err))
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword")
+	})
+
+	t.Run("Failed mail still disowns", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenService := new(mocks.MockTokenService)
+
+		user := &model.User{UID: uid, Email: "bob@bob.com", Password: "hashed"}
+		mockTokenRepository.On("GetDeviceRevokeToken", mock.Anything, "token").Return(uid.String(), nil)
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(user, nil)
+		mockTokenService.On("Revoke", mock.Anything, uid).Return(nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, uid, "").Return(nil)
+
+		attempted := make(chan struct{})
+		mockTokenRepository.On("SetPasswordResetToken", mock.Anything, mock.AnythingOfType("string"), uid.String(), time.Hour).
+			Run(func(args mock.Arguments) { close(attempted) }).
+			Return(apperrors.NewInternal())
+
+		us := NewUserService(&USConfig{
+			UserRepository:       mockUserRepository,
+			TokenRepository:      mockTokenRepository,
+			TokenService:         mockTokenService,
+			PasswordResetURL:     "http://localhost:3000/reset-password",
+			PasswordResetExpires: time.Hour,
+		})
+
+		disowned, err := us.DisownSignin(context.TODO(), "token")
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, disowned.UID)
+
+		select {
+		case <-attempted:
+		case <-time.After(time.Second):
+			t.Fatal("password reset was not attempted")
+		}
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+
+		mockTokenRepository.On("GetDeviceRevokeToken", mock.Anything, "token").Return("", apperrors.NewBadRequest("Invalid or expired link"))
+
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+		})
+
+		disowned, err := us.DisownSignin(context.TODO(), "token")
+
+		assert.Nil(t, disowned)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword")
+	})
+}
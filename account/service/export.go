@@ -0,0 +1,393 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/utils"
+
+	"github.com/google/uuid"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// activityPageSize is the number of audit events read at once for an export
+const activityPageSize = 500
+
+// exportBuildTimeout bounds assembling an archive in the background,
+// users may request another export once it is over
+const exportBuildTimeout = 5 * time.Minute
+
+// exportService is used to assemble personal data exports
+// from the user, token and image repositories
+type exportService struct {
+	UserRepository     model.UserRepository
+	TokenRepository    model.TokenRepository
+	ImageRepository    model.ImageRepository
+	ExportRepository   model.ExportRepository
+	AuditRepository    model.AuditRepository
+	MFARepository      model.MFARepository
+	PasskeyRepository  model.PasskeyRepository
+	IdentityRepository model.IdentityRepository
+	OrgRepository      model.OrgRepository
+	TermsRepository    model.TermsRepository
+	DeviceRepository   model.DeviceRepository
+	LinkExpires        time.Duration
+}
+
+// ExportServiceConfig will hold repositories that will eventually be injected into
+// this service layer
+// With an AuditRepository, exports include the user's audit events, and
+// likewise the authenticator enrollment, passkeys, linked identities,
+// organizations, terms acceptances and known devices of the other repositories
+type ExportServiceConfig struct {
+	UserRepository     model.UserRepository
+	TokenRepository    model.TokenRepository
+	ImageRepository    model.ImageRepository
+	ExportRepository   model.ExportRepository
+	AuditRepository    model.AuditRepository
+	MFARepository      model.MFARepository
+	PasskeyRepository  model.PasskeyRepository
+	IdentityRepository model.IdentityRepository
+	OrgRepository      model.OrgRepository
+	TermsRepository    model.TermsRepository
+	DeviceRepository   model.DeviceRepository
+	LinkExpires        time.Duration
+}
+
+// NewExportService is a factory function for
+// initializing an ExportService with its repository layer dependencies
+func NewExportService(c *ExportServiceConfig) model.ExportService {
+	return &exportService{
+		UserRepository:     c.UserRepository,
+		TokenRepository:    c.TokenRepository,
+		ImageRepository:    c.ImageRepository,
+		ExportRepository:   c.ExportRepository,
+		AuditRepository:    c.AuditRepository,
+		MFARepository:      c.MFARepository,
+		PasskeyRepository:  c.PasskeyRepository,
+		IdentityRepository: c.IdentityRepository,
+		OrgRepository:      c.OrgRepository,
+		TermsRepository:    c.TermsRepository,
+		DeviceRepository:   c.DeviceRepository,
+		LinkExpires:        c.LinkExpires,
+	}
+}
+
+// Request registers a pending export for the user and assembles
+// the archive in the background. Users have one pending export at a time
+func (s *exportService) Request(ctx context.Context, uid uuid.UUID) (*model.Export, error) {
+	if _, err := s.UserRepository.FindByID(ctx, uid); err != nil {
+		return nil, err
+	}
+
+	exportID, err := uuid.NewRandom()
+	if err != nil {
+		log.Printf("Failed to generate export ID for uid: %v. Error: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	downloadToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		log.Printf("Failed to generate export download token for uid: %v. Error: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	now := time.Now().UTC()
+	export := &model.Export{
+		ID:            exportID,
+		UID:           uid,
+		Status:        model.ExportPending,
+		DownloadToken: downloadToken,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(s.LinkExpires),
+	}
+
+	pendingID, err := s.ExportRepository.SetPendingExport(ctx, uid, exportID, exportBuildTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if pendingID != uuid.Nil {
+		return nil, apperrors.NewConflict("pending export", pendingID.String())
+	}
+
+	if err := s.ExportRepository.SetExport(ctx, export, s.LinkExpires); err != nil {
+		s.releasePending(context.Background(), uid)
+		return nil, err
+	}
+
+	// the request context is cancelled as soon as the handler responds
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), exportBuildTimeout)
+		defer cancel()
+
+		s.build(ctx, *export)
+	}()
+
+	return export, nil
+}
+
+// Get retrieves an export of the user
+func (s *exportService) Get(ctx context.Context, uid uuid.UUID, exportID uuid.UUID) (*model.Export, error) {
+	export, err := s.ExportRepository.GetExport(ctx, exportID)
+	if err != nil {
+		return nil, err
+	}
+
+	// do not leak the existence of other users' exports
+	if export.UID != uid {
+		return nil, apperrors.NewNotFound("export", exportID.String())
+	}
+
+	return export, nil
+}
+
+// Download retrieves a ready export and its archive by the download token
+func (s *exportService) Download(ctx context.Context, downloadToken string) (*model.Export, []byte, error) {
+	export, err := s.ExportRepository.GetExportByToken(ctx, downloadToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if export.Status != model.ExportReady {
+		return nil, nil, apperrors.NewBadRequest("Export is not ready to be downloaded")
+	}
+
+	archive, err := s.ExportRepository.GetArchive(ctx, export.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return export, archive, nil
+}
+
+// build assembles the archive and marks the export as ready or failed,
+// which it does even when ctx ran out
+func (s *exportService) build(ctx context.Context, export model.Export) {
+	defer s.releasePending(context.Background(), export.UID)
+
+	// the archive must not outlive the link
+	expiresIn := time.Until(export.ExpiresAt)
+
+	archive, err := s.assemble(ctx, export.UID)
+	if err == nil {
+		err = s.ExportRepository.SetArchive(ctx, export.ID, archive, expiresIn)
+	}
+
+	export.Status = model.ExportReady
+	if err != nil {
+		log.Printf("Failed to assemble export: %v for uid: %v. Error: %v\n", export.ID, export.UID, err)
+		export.Status = model.ExportFailed
+	}
+
+	if err := s.ExportRepository.SetExport(context.Background(), &export, expiresIn); err != nil {
+		log.Printf("Failed to update export: %v for uid: %v. Error: %v\n", export.ID, export.UID, err)
+	}
+}
+
+// releasePending lets the user request another export
+func (s *exportService) releasePending(ctx context.Context, uid uuid.UUID) {
+	if err := s.ExportRepository.DeletePendingExport(ctx, uid); err != nil {
+		log.Printf("Failed to release pending export of uid: %v. Error: %v\n", uid, err)
+	}
+}
+
+// assemble writes the user's data into a zip archive of JSON files
+// along with the profile image, if any. An image which is gone is left out
+func (s *exportService) assemble(ctx context.Context, uid uuid.UUID) ([]byte, error) {
+	user, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.TokenRepository.GetUserSessions(ctx, uid.String())
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+
+	if err := writeJSONFile(zw, "user.json", user); err != nil {
+		return nil, err
+	}
+
+	if err := writeJSONFile(zw, "sessions.json", sessions); err != nil {
+		return nil, err
+	}
+
+	if s.AuditRepository != nil {
+		activity, err := s.activity(ctx, uid)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := writeJSONFile(zw, "activity.json", activity); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.writeAccountFiles(ctx, zw, uid); err != nil {
+		return nil, err
+	}
+
+	if user.ImageURL != "" {
+		err := s.writeProfileImage(ctx, zw, user.ImageURL)
+		if isNotFound(err) {
+			log.Printf("Leaving out missing profile image of uid: %v from export\n", uid)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeAccountFiles writes a file for each repository of the account's
+// sign-in methods, memberships and devices the service has
+func (s *exportService) writeAccountFiles(ctx context.Context, zw *zip.Writer, uid uuid.UUID) error {
+	if s.MFARepository != nil {
+		totp, err := s.MFARepository.FindTOTP(ctx, uid)
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+
+		// the secret and recovery codes are never exported
+		if err == nil {
+			if err := writeJSONFile(zw, "totp.json", totp); err != nil {
+				return err
+			}
+		}
+	}
+
+	if s.PasskeyRepository != nil {
+		credentials, err := s.PasskeyRepository.FindByUID(ctx, uid)
+		if err != nil {
+			return err
+		}
+
+		if err := writeJSONFile(zw, "passkeys.json", credentials); err != nil {
+			return err
+		}
+	}
+
+	if s.IdentityRepository != nil {
+		identities, err := s.IdentityRepository.FindByUID(ctx, uid)
+		if err != nil {
+			return err
+		}
+
+		if err := writeJSONFile(zw, "identities.json", identities); err != nil {
+			return err
+		}
+	}
+
+	if s.OrgRepository != nil {
+		orgs, err := s.OrgRepository.FindByUID(ctx, uid)
+		if err != nil {
+			return err
+		}
+
+		if err := writeJSONFile(zw, "organizations.json", orgs); err != nil {
+			return err
+		}
+	}
+
+	if s.TermsRepository != nil {
+		acceptances, err := s.TermsRepository.FindByUID(ctx, uid)
+		if err != nil {
+			return err
+		}
+
+		if err := writeJSONFile(zw, "terms_acceptances.json", acceptances); err != nil {
+			return err
+		}
+	}
+
+	if s.DeviceRepository != nil {
+		devices, err := s.DeviceRepository.FindByUID(ctx, uid)
+		if err != nil {
+			return err
+		}
+
+		if err := writeJSONFile(zw, "devices.json", devices); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// activity reads all audit events of a user, page by page
+func (s *exportService) activity(ctx context.Context, uid uuid.UUID) ([]*model.AuditEvent, error) {
+	activity := []*model.AuditEvent{}
+
+	for {
+		events, err := s.AuditRepository.FindByUID(ctx, uid, activityPageSize, len(activity))
+		if err != nil {
+			return nil, err
+		}
+
+		activity = append(activity, events...)
+		if len(events) < activityPageSize {
+			return activity, nil
+		}
+	}
+}
+
+// writeProfileImage copies the profile image from the image repository
+// into the archive, naming it after its detected content type
+func (s *exportService) writeProfileImage(ctx context.Context, zw *zip.Writer, imageURL string) error {
+	objName, err := utils.ObjNameFromURL(imageURL)
+	if err != nil {
+		return err
+	}
+
+	rc, err := s.ImageRepository.GetProfile(ctx, objName)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	image, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+
+	name := "profile_image"
+	switch http.DetectContentType(image) {
+	case "image/png":
+		name += ".png"
+	case "image/jpeg":
+		name += ".jpg"
+	}
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(image)
+	return err
+}
+
+func writeJSONFile(zw *zip.Writer, name string, v interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
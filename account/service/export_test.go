@@ -0,0 +1,341 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"image"
+	"image/png"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestExportRequest(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockExportRepository := new(mocks.MockExportRepository)
+		es := NewExportService(&ExportServiceConfig{
+			UserRepository:   mockUserRepository,
+			TokenRepository:  mockTokenRepository,
+			ExportRepository: mockExportRepository,
+			LinkExpires:      time.Hour,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid}, nil)
+		mockExportRepository.On("SetPendingExport", mock.Anything, uid, mock.AnythingOfType("uuid.UUID"), exportBuildTimeout).Return(uuid.Nil, nil)
+		mockExportRepository.On("SetExport", mock.Anything, mock.AnythingOfType("*model.Export"), time.Hour).Return(nil)
+
+		// used by the background build
+		built := make(chan struct{})
+		mockTokenRepository.On("GetUserSessions", mock.Anything, uid.String()).Return([]*model.Session{}, nil)
+		mockExportRepository.On("SetArchive", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockExportRepository.On("SetExport", mock.Anything, mock.MatchedBy(func(e *model.Export) bool { return e.Status == model.ExportReady }), mock.Anything).Return(nil)
+		mockExportRepository.
+			On("DeletePendingExport", mock.Anything, uid).
+			Run(func(args mock.Arguments) { close(built) }).
+			Return(nil)
+
+		export, err := es.Request(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, export.UID)
+		assert.Equal(t, model.ExportPending, export.Status)
+		assert.Len(t, export.DownloadToken, 64)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), export.ExpiresAt, time.Minute)
+
+		select {
+		case <-built:
+		case <-time.After(time.Second):
+			t.Fatal("export was not marked ready")
+		}
+		mockExportRepository.AssertExpectations(t)
+	})
+
+	t.Run("Export already pending", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		pendingID, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockExportRepository := new(mocks.MockExportRepository)
+		es := NewExportService(&ExportServiceConfig{
+			UserRepository:   mockUserRepository,
+			ExportRepository: mockExportRepository,
+			LinkExpires:      time.Hour,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid}, nil)
+		mockExportRepository.On("SetPendingExport", mock.Anything, uid, mock.AnythingOfType("uuid.UUID"), exportBuildTimeout).Return(pendingID, nil)
+
+		export, err := es.Request(context.TODO(), uid)
+
+		assert.Nil(t, export)
+		assert.Equal(t, apperrors.Conflict, err.(*apperrors.Error).Type)
+		assert.Contains(t, err.Error(), pendingID.String())
+		mockExportRepository.AssertNotCalled(t, "SetExport")
+		mockExportRepository.AssertNotCalled(t, "DeletePendingExport")
+	})
+
+	t.Run("User not found", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockExportRepository := new(mocks.MockExportRepository)
+		es := NewExportService(&ExportServiceConfig{
+			UserRepository:   mockUserRepository,
+			ExportRepository: mockExportRepository,
+		})
+
+		mockErr := apperrors.NewNotFound("uid", uid.String())
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(nil, mockErr)
+
+		export, err := es.Request(context.TODO(), uid)
+
+		assert.Nil(t, export)
+		assert.EqualError(t, err, mockErr.Error())
+		mockExportRepository.AssertNotCalled(t, "SetExport")
+	})
+}
+
+func TestExportGet(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	otherUID, _ := uuid.NewRandom()
+	exportID, _ := uuid.NewRandom()
+
+	mockExportRepository := new(mocks.MockExportRepository)
+	es := NewExportService(&ExportServiceConfig{
+		ExportRepository: mockExportRepository,
+	})
+
+	mockExport := &model.Export{ID: exportID, UID: uid, Status: model.ExportReady}
+	mockExportRepository.On("GetExport", mock.Anything, exportID).Return(mockExport, nil)
+
+	t.Run("Owner", func(t *testing.T) {
+		export, err := es.Get(context.TODO(), uid, exportID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockExport, export)
+	})
+
+	t.Run("Other user", func(t *testing.T) {
+		export, err := es.Get(context.TODO(), otherUID, exportID)
+
+		assert.Nil(t, export)
+		assert.Equal(t, apperrors.NotFound, err.(*apperrors.Error).Type)
+	})
+}
+
+func TestExportDownload(t *testing.T) {
+	exportID, _ := uuid.NewRandom()
+
+	t.Run("Pending", func(t *testing.T) {
+		mockExportRepository := new(mocks.MockExportRepository)
+		es := NewExportService(&ExportServiceConfig{
+			ExportRepository: mockExportRepository,
+		})
+
+		mockExportRepository.
+			On("GetExportByToken", mock.Anything, "token").
+			Return(&model.Export{ID: exportID, Status: model.ExportPending}, nil)
+
+		export, archive, err := es.Download(context.TODO(), "token")
+
+		assert.Nil(t, export)
+		assert.Nil(t, archive)
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockExportRepository.AssertNotCalled(t, "GetArchive")
+	})
+
+	t.Run("Ready", func(t *testing.T) {
+		mockExportRepository := new(mocks.MockExportRepository)
+		es := NewExportService(&ExportServiceConfig{
+			ExportRepository: mockExportRepository,
+		})
+
+		mockExport := &model.Export{ID: exportID, Status: model.ExportReady}
+		mockExportRepository.On("GetExportByToken", mock.Anything, "token").Return(mockExport, nil)
+		mockExportRepository.On("GetArchive", mock.Anything, exportID).Return([]byte("archive"), nil)
+
+		export, archive, err := es.Download(context.TODO(), "token")
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockExport, export)
+		assert.Equal(t, []byte("archive"), archive)
+	})
+}
+
+func TestExportAssemble(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	mockUser := &model.User{
+		UID:      uid,
+		Email:    "long@do.com",
+		Password: "hashedpassword",
+		Name:     "Long Do",
+		ImageURL: "https://storage.googleapis.com/bucket/imageobj",
+	}
+
+	mockSessions := []*model.Session{
+		{TokenID: "tokenid", ExpiresAt: time.Now().UTC()},
+	}
+
+	imageBuf := &bytes.Buffer{}
+	png.Encode(imageBuf, image.NewRGBA(image.Rect(0, 0, 1, 1)))
+
+	mockUserRepository := new(mocks.MockUserRepository)
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	mockImageRepository := new(mocks.MockImageRepository)
+	es := &exportService{
+		UserRepository:  mockUserRepository,
+		TokenRepository: mockTokenRepository,
+		ImageRepository: mockImageRepository,
+	}
+
+	mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+	mockTokenRepository.On("GetUserSessions", mock.Anything, uid.String()).Return(mockSessions, nil)
+	mockImageRepository.On("GetProfile", mock.Anything, "imageobj").Return(io.NopCloser(bytes.NewReader(imageBuf.Bytes())), nil)
+
+	archive, err := es.assemble(context.TODO(), uid)
+	assert.NoError(t, err)
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	assert.NoError(t, err)
+
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		assert.NoError(t, err)
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+
+	assert.Contains(t, files, "user.json")
+	assert.Contains(t, files, "sessions.json")
+	assert.Equal(t, imageBuf.Bytes(), files["profile_image.png"])
+
+	// the password hash never leaves the service
+	assert.NotContains(t, string(files["user.json"]), "hashedpassword")
+
+	var exportedUser model.User
+	assert.NoError(t, json.Unmarshal(files["user.json"], &exportedUser))
+	assert.Equal(t, mockUser.Email, exportedUser.Email)
+}
+
+func TestExportAssembleActivity(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	mockUserRepository := new(mocks.MockUserRepository)
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	mockAuditRepository := new(mocks.MockAuditRepository)
+	es := &exportService{
+		UserRepository:  mockUserRepository,
+		TokenRepository: mockTokenRepository,
+		AuditRepository: mockAuditRepository,
+	}
+
+	firstPage := make([]*model.AuditEvent, activityPageSize)
+	for i := range firstPage {
+		firstPage[i] = &model.AuditEvent{UID: &uid, Type: model.AuditSignin, Outcome: model.AuditSuccess}
+	}
+	lastPage := []*model.AuditEvent{{UID: &uid, Type: model.AuditSignup, Outcome: model.AuditSuccess}}
+
+	mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid}, nil)
+	mockTokenRepository.On("GetUserSessions", mock.Anything, uid.String()).Return([]*model.Session{}, nil)
+	mockAuditRepository.On("FindByUID", mock.Anything, uid, activityPageSize, 0).Return(firstPage, nil)
+	mockAuditRepository.On("FindByUID", mock.Anything, uid, activityPageSize, activityPageSize).Return(lastPage, nil)
+
+	archive, err := es.assemble(context.TODO(), uid)
+	assert.NoError(t, err)
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	assert.NoError(t, err)
+
+	var activity []*model.AuditEvent
+	for _, f := range zr.File {
+		if f.Name != "activity.json" {
+			continue
+		}
+
+		rc, err := f.Open()
+		assert.NoError(t, err)
+		assert.NoError(t, json.NewDecoder(rc).Decode(&activity))
+		rc.Close()
+	}
+
+	assert.Len(t, activity, activityPageSize+1)
+	mockAuditRepository.AssertExpectations(t)
+}
+
+func TestExportAssembleAccount(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	orgID, _ := uuid.NewRandom()
+
+	mockUserRepository := new(mocks.MockUserRepository)
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	mockImageRepository := new(mocks.MockImageRepository)
+	mockMFARepository := new(mocks.MockMFARepository)
+	mockPasskeyRepository := new(mocks.MockPasskeyRepository)
+	mockIdentityRepository := new(mocks.MockIdentityRepository)
+	mockOrgRepository := new(mocks.MockOrgRepository)
+	mockTermsRepository := new(mocks.MockTermsRepository)
+	mockDeviceRepository := new(mocks.MockDeviceRepository)
+	es := &exportService{
+		UserRepository:     mockUserRepository,
+		TokenRepository:    mockTokenRepository,
+		ImageRepository:    mockImageRepository,
+		MFARepository:      mockMFARepository,
+		PasskeyRepository:  mockPasskeyRepository,
+		IdentityRepository: mockIdentityRepository,
+		OrgRepository:      mockOrgRepository,
+		TermsRepository:    mockTermsRepository,
+		DeviceRepository:   mockDeviceRepository,
+	}
+
+	mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, ImageURL: "https://storage.googleapis.com/bucket/imageobj"}, nil)
+	mockTokenRepository.On("GetUserSessions", mock.Anything, uid.String()).Return([]*model.Session{}, nil)
+	mockImageRepository.On("GetProfile", mock.Anything, "imageobj").Return(nil, apperrors.NewNotFound("image", "imageobj"))
+	mockMFARepository.On("FindTOTP", mock.Anything, uid).Return(&model.TOTP{UID: uid, Secret: "totpsecret", Confirmed: true}, nil)
+	mockPasskeyRepository.On("FindByUID", mock.Anything, uid).Return([]*model.PasskeyCredential{{UID: uid, Name: "laptop"}}, nil)
+	mockIdentityRepository.On("FindByUID", mock.Anything, uid).Return([]*model.Identity{{Provider: "github", UID: uid}}, nil)
+	mockOrgRepository.On("FindByUID", mock.Anything, uid).Return([]*model.Organization{{ID: orgID, Name: "Acme"}}, nil)
+	mockTermsRepository.On("FindByUID", mock.Anything, uid).Return([]*model.TermsAcceptance{{UID: uid, Version: "2024-06"}}, nil)
+	mockDeviceRepository.On("FindByUID", mock.Anything, uid).Return([]*model.KnownDevice{{UID: uid, UserAgent: "curl/8.0"}}, nil)
+
+	archive, err := es.assemble(context.TODO(), uid)
+	assert.NoError(t, err)
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	assert.NoError(t, err)
+
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		assert.NoError(t, err)
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+
+	assert.Contains(t, string(files["totp.json"]), `"confirmed": true`)
+	assert.NotContains(t, string(files["totp.json"]), "totpsecret")
+	assert.Contains(t, string(files["passkeys.json"]), "laptop")
+	assert.Contains(t, string(files["identities.json"]), "github")
+	assert.Contains(t, string(files["organizations.json"]), "Acme")
+	assert.Contains(t, string(files["terms_acceptances.json"]), "2024-06")
+	assert.Contains(t, string(files["devices.json"]), "curl/8.0")
+
+	// the missing profile image is left out instead of failing the export
+	for name := range files {
+		assert.NotContains(t, name, "profile_image")
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/utils"
+
+	"log"
+	"time"
+)
+
+// CreateGuest creates a user without email or password for trying the app
+// Guests keep their uid when they upgrade, so nothing tied to it is lost, and
+// accept the terms of service when they upgrade
+func (s *userService) CreateGuest(ctx context.Context) (*model.User, error) {
+	user := &model.User{Guest: true}
+	if err := s.UserRepository.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	audit(ctx, s.AuditRepository, &user.UID, model.AuditSignup, model.AuditSuccess, "guest")
+	return user, nil
+}
+
+// UpgradeGuest attaches an email and password to a guest, under the same
+// rules as a sign-up. On success, user holds all the fields of the upgraded user
+func (s *userService) UpgradeGuest(ctx context.Context, user *model.User) error {
+	guest, err := s.UserRepository.FindByID(ctx, user.UID)
+	if err != nil {
+		return err
+	}
+
+	if !guest.Guest {
+		return apperrors.NewBadRequest("The account is not a guest account")
+	}
+
+	user.Email = utils.NormalizeEmail(user.Email)
+
+	if err := s.validatePassword(ctx, user.Password, user); err != nil {
+		return err
+	}
+
+	pwd, err := utils.HashPassword(user.Password)
+	if err != nil {
+		log.Printf("failed to hash password; email: %v\n", user.Email)
+		return apperrors.NewInternal()
+	}
+
+	if err := s.checkSignupTerms(user); err != nil {
+		return err
+	}
+
+	invite, err := admit(ctx, s.SignupPolicy, user)
+	if err != nil {
+		return err
+	}
+
+	user.Password = pwd
+	if err := s.UserRepository.UpgradeGuest(ctx, user); err != nil {
+		release(s.SignupPolicy, invite)
+		return err
+	}
+
+	audit(ctx, s.AuditRepository, &user.UID, model.AuditGuestUpgrade, model.AuditSuccess, "password")
+	s.rememberDevice(ctx, user.UID)
+	s.acceptSignupTerms(ctx, user)
+
+	return nil
+}
+
+// PurgeGuests deletes the guests which were not upgraded and not seen
+// within maxAge, along with their profile images, and returns how many were deleted
+func (s *userService) PurgeGuests(ctx context.Context, maxAge time.Duration) (int64, error) {
+	guests, err := s.UserRepository.DeleteGuests(ctx, time.Now().Add(-maxAge))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, guest := range guests {
+		if guest.ImageURL == "" {
+			continue
+		}
+
+		objName, err := utils.ObjNameFromURL(guest.ImageURL)
+		if err == nil {
+			err = s.ImageRepository.DeleteProfile(ctx, objName)
+		}
+		if err != nil {
+			log.Printf("Failed to delete profile image of purged guest: %v: %v\n", guest.UID, err)
+		}
+	}
+
+	return int64(len(guests)), nil
+}
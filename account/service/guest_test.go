@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestCreateGuest(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	mockUserRepository := new(mocks.MockUserRepository)
+	us := NewUserService(&USConfig{
+		UserRepository: mockUserRepository,
+	})
+
+	mockUserRepository.On("Create", mock.Anything, mock.MatchedBy(func(u *model.User) bool {
+		return u.Guest && u.Email == "" && u.Password == ""
+	})).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(*model.User).UID = uid
+		}).
+		Return(nil)
+
+	user, err := us.CreateGuest(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Equal(t, uid, user.UID)
+	mockUserRepository.AssertExpectations(t)
+}
+
+func TestUpgradeGuest(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Guest: true}, nil)
+		mockUserRepository.On("UpgradeGuest", mock.Anything, mock.MatchedBy(func(u *model.User) bool {
+			return u.UID == uid && u.Email == "bob@bob.com" && u.Password != "avalidpassword123"
+		})).Return(nil)
+
+		user := &model.User{UID: uid, Email: " bob@BOB.com", Password: "avalidpassword123"}
+		err := us.UpgradeGuest(context.TODO(), user)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Not a guest", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Email: "bob@bob.com"}, nil)
+
+		err := us.UpgradeGuest(context.TODO(), &model.User{UID: uid, Email: "eve@bob.com", Password: "avalidpassword123"})
+
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "UpgradeGuest", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Email taken", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Guest: true}, nil)
+		mockUserRepository.On("UpgradeGuest", mock.Anything, mock.AnythingOfType("*model.User")).Return(apperrors.NewConflict("email", "bob@bob.com"))
+
+		err := us.UpgradeGuest(context.TODO(), &model.User{UID: uid, Email: "bob@bob.com", Password: "avalidpassword123"})
+
+		assert.Equal(t, apperrors.Conflict, err.(*apperrors.Error).Type)
+	})
+}
+
+func TestPurgeGuests(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	mockUserRepository := new(mocks.MockUserRepository)
+	mockImageRepository := new(mocks.MockImageRepository)
+	us := NewUserService(&USConfig{
+		UserRepository:  mockUserRepository,
+		ImageRepository: mockImageRepository,
+	})
+
+	guests := []*model.User{
+		{UID: uid, Guest: true, ImageURL: "https://storage.googleapis.com/images/guest_image.jpg"},
+		{Guest: true},
+	}
+	mockUserRepository.On("DeleteGuests", mock.Anything, mock.MatchedBy(func(createdBefore time.Time) bool {
+		return time.Since(createdBefore) >= 24*time.Hour && time.Since(createdBefore) < 25*time.Hour
+	})).Return(guests, nil)
+	mockImageRepository.On("DeleteProfile", mock.Anything, "guest_image.jpg").Return(nil)
+
+	purged, err := us.PurgeGuests(context.TODO(), 24*time.Hour)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), purged)
+	mockUserRepository.AssertExpectations(t)
+	mockImageRepository.AssertExpectations(t)
+}
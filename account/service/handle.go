@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/google/uuid"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// handles are 3 to 30 letters, digits and underscores, not starting with an underscore
+var handlePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_]{2,29}$`)
+
+// reservedHandles could be mistaken for the service or its pages
+var reservedHandles = map[string]bool{
+	"about":         true,
+	"account":       true,
+	"accounts":      true,
+	"admin":         true,
+	"administrator": true,
+	"api":           true,
+	"app":           true,
+	"auth":          true,
+	"billing":       true,
+	"help":          true,
+	"home":          true,
+	"info":          true,
+	"login":         true,
+	"logout":        true,
+	"me":            true,
+	"memorization":  true,
+	"moderator":     true,
+	"no_reply":      true,
+	"noreply":       true,
+	"null":          true,
+	"official":      true,
+	"root":          true,
+	"security":      true,
+	"settings":      true,
+	"signin":        true,
+	"signout":       true,
+	"signup":        true,
+	"staff":         true,
+	"support":       true,
+	"system":        true,
+	"undefined":     true,
+	"user":          true,
+	"users":         true,
+	"www":           true,
+}
+
+// HandleAvailable tells whether a handle may be chosen, it is not
+// when reserved or the current or previous handle of a user
+// Malformed handles are returned as apperrors.InvalidArgs
+func (s *userService) HandleAvailable(ctx context.Context, handle string) (bool, error) {
+	if err := validateHandle(handle); err != nil {
+		return false, err
+	}
+
+	if reservedHandles[strings.ToLower(handle)] {
+		return false, nil
+	}
+
+	if _, err := s.UserRepository.FindByHandle(ctx, handle); err != nil {
+		if isNotFound(err) {
+			return true, nil
+		}
+
+		return false, err
+	}
+
+	return false, nil
+}
+
+// GetByHandle retrieves the user with a handle, or with a previous handle,
+// in which case the handle of the returned user differs
+func (s *userService) GetByHandle(ctx context.Context, handle string) (*model.User, error) {
+	return s.UserRepository.FindByHandle(ctx, handle)
+}
+
+// SetHandle chooses or changes the handle of a user
+// Changes are limited to one per HandleChangeCooldown, choosing the first
+// handle and changing its case are not
+func (s *userService) SetHandle(ctx context.Context, uid uuid.UUID, handle string) (*model.User, error) {
+	if err := validateHandle(handle); err != nil {
+		return nil, err
+	}
+
+	if reservedHandles[strings.ToLower(handle)] {
+		return nil, apperrors.NewInvalidArgs([]apperrors.InvalidArgument{{
+			Field: "Handle",
+			Value: handle,
+			Tag:   "reserved",
+		}})
+	}
+
+	user, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Handle == handle {
+		return user, nil
+	}
+
+	if user.Handle != "" && !strings.EqualFold(user.Handle, handle) && user.HandleChangedAt != nil {
+		if wait := time.Until(user.HandleChangedAt.Add(s.HandleChangeCooldown)); wait > 0 {
+			return nil, apperrors.NewTooManyRequests("Handle was changed recently", wait)
+		}
+	}
+
+	updatedUser, err := s.UserRepository.UpdateHandle(ctx, uid, handle)
+	if err != nil {
+		return nil, err
+	}
+
+	audit(ctx, s.AuditRepository, &uid, model.AuditHandleChange, model.AuditSuccess, handle)
+	return updatedUser, nil
+}
+
+func validateHandle(handle string) error {
+	if handlePattern.MatchString(handle) {
+		return nil
+	}
+
+	return apperrors.NewInvalidArgs([]apperrors.InvalidArgument{{
+		Field: "Handle",
+		Value: handle,
+		Tag:   "handle",
+	}})
+}
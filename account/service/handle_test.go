@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestHandleAvailable(t *testing.T) {
+	mockUserRepository := new(mocks.MockUserRepository)
+	us := NewUserService(&USConfig{
+		UserRepository: mockUserRepository,
+	})
+
+	mockUserRepository.On("FindByHandle", mock.Anything, "Taken").Return(&model.User{Handle: "taken"}, nil)
+	mockUserRepository.On("FindByHandle", mock.Anything, "free_handle").Return(nil, apperrors.NewNotFound("handle", "free_handle"))
+
+	t.Run("Available", func(t *testing.T) {
+		available, err := us.HandleAvailable(context.TODO(), "free_handle")
+
+		assert.NoError(t, err)
+		assert.True(t, available)
+	})
+
+	t.Run("Taken regardless of case", func(t *testing.T) {
+		available, err := us.HandleAvailable(context.TODO(), "Taken")
+
+		assert.NoError(t, err)
+		assert.False(t, available)
+	})
+
+	t.Run("Reserved", func(t *testing.T) {
+		available, err := us.HandleAvailable(context.TODO(), "Admin")
+
+		assert.NoError(t, err)
+		assert.False(t, available)
+		mockUserRepository.AssertNotCalled(t, "FindByHandle", mock.Anything, "Admin")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, handle := range []string{"ab", "_underscore", "with space", "dash-ed", "ünicode", "a234567890123456789012345678901"} {
+			_, err := us.HandleAvailable(context.TODO(), handle)
+
+			assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type, handle)
+			assert.Equal(t, "handle", apperrors.InvalidArgs(err)[0].Tag, handle)
+		}
+	})
+}
+
+func TestSetHandle(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	newService := func(user *model.User) (model.UserService, *mocks.MockUserRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:       mockUserRepository,
+			HandleChangeCooldown: 24 * time.Hour,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(user, nil)
+
+		return us, mockUserRepository
+	}
+
+	t.Run("First handle", func(t *testing.T) {
+		us, mockUserRepository := newService(&model.User{UID: uid})
+		updated := &model.User{UID: uid, Handle: "long_do"}
+
+		mockUserRepository.On("UpdateHandle", mock.Anything, uid, "long_do").Return(updated, nil)
+
+		user, err := us.SetHandle(context.TODO(), uid, "long_do")
+
+		assert.NoError(t, err)
+		assert.Equal(t, updated, user)
+	})
+
+	t.Run("Change within cooldown", func(t *testing.T) {
+		changedAt := time.Now().Add(-time.Hour)
+		us, mockUserRepository := newService(&model.User{UID: uid, Handle: "long_do", HandleChangedAt: &changedAt})
+
+		_, err := us.SetHandle(context.TODO(), uid, "other")
+
+		assert.Equal(t, apperrors.TooManyRequests, err.(*apperrors.Error).Type)
+		assert.InDelta(t, 23*60*60, apperrors.RetryAfter(err), 5)
+		mockUserRepository.AssertNotCalled(t, "UpdateHandle", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Change of case within cooldown", func(t *testing.T) {
+		changedAt := time.Now().Add(-time.Hour)
+		us, mockUserRepository := newService(&model.User{UID: uid, Handle: "long_do", HandleChangedAt: &changedAt})
+
+		mockUserRepository.On("UpdateHandle", mock.Anything, uid, "Long_Do").Return(&model.User{UID: uid, Handle: "Long_Do"}, nil)
+
+		_, err := us.SetHandle(context.TODO(), uid, "Long_Do")
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Change after cooldown", func(t *testing.T) {
+		changedAt := time.Now().Add(-25 * time.Hour)
+		us, mockUserRepository := newService(&model.User{UID: uid, Handle: "long_do", HandleChangedAt: &changedAt})
+
+		mockUserRepository.On("UpdateHandle", mock.Anything, uid, "other").Return(&model.User{UID: uid, Handle: "other"}, nil)
+
+		_, err := us.SetHandle(context.TODO(), uid, "other")
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Reserved", func(t *testing.T) {
+		us, mockUserRepository := newService(&model.User{UID: uid})
+
+		_, err := us.SetHandle(context.TODO(), uid, "support")
+
+		assert.Equal(t, "reserved", apperrors.InvalidArgs(err)[0].Tag)
+		mockUserRepository.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Taken", func(t *testing.T) {
+		us, mockUserRepository := newService(&model.User{UID: uid})
+		mockErr := apperrors.NewConflict("handle", "taken")
+
+		mockUserRepository.On("UpdateHandle", mock.Anything, uid, "taken").Return(nil, mockErr)
+
+		_, err := us.SetHandle(context.TODO(), uid, "taken")
+
+		assert.Equal(t, mockErr, err)
+	})
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/utils"
+
+	"github.com/google/uuid"
+	"log"
+	"strings"
+	"time"
+)
+
+// inviteService manages the invites of invite-only sign-up
+type inviteService struct {
+	InviteRepository model.InviteRepository
+	DefaultExpires   time.Duration
+}
+
+// InviteServiceConfig will hold repositories that will eventually be injected into
+// this service layer
+// DefaultExpires applies to invites created without an expiry, 0 means never
+type InviteServiceConfig struct {
+	InviteRepository model.InviteRepository
+	DefaultExpires   time.Duration
+}
+
+// NewInviteService is a factory function for
+// initializing an InviteService with its repository layer dependencies
+func NewInviteService(c *InviteServiceConfig) model.InviteService {
+	return &inviteService{
+		InviteRepository: c.InviteRepository,
+		DefaultExpires:   c.DefaultExpires,
+	}
+}
+
+// Create generates an invite for maxUses sign-ups. The returned invite
+// holds the code, which cannot be retrieved again
+func (s *inviteService) Create(ctx context.Context, createdBy uuid.UUID, maxUses int, expiresIn time.Duration) (*model.Invite, error) {
+	if maxUses < 1 {
+		return nil, apperrors.NewBadRequest("An invite must allow at least one use")
+	}
+
+	if expiresIn == 0 {
+		expiresIn = s.DefaultExpires
+	}
+
+	code, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		log.Printf("Failed to generate invite code: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	invite := &model.Invite{
+		Code:      code,
+		CodeHash:  hashInviteCode(code),
+		MaxUses:   maxUses,
+		CreatedBy: &createdBy,
+	}
+
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(expiresIn)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	if err := s.InviteRepository.Create(ctx, invite); err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+// List returns all invites, without their codes
+func (s *inviteService) List(ctx context.Context) ([]*model.Invite, error) {
+	return s.InviteRepository.FindAll(ctx)
+}
+
+// Revoke makes an invite unusable for further sign-ups
+func (s *inviteService) Revoke(ctx context.Context, id uuid.UUID) error {
+	return s.InviteRepository.Revoke(ctx, id)
+}
+
+// hashInviteCode hashes an invite code for storage
+// The codes carry enough entropy that a fast hash is sufficient
+func hashInviteCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
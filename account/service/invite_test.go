@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestCreateInvite(t *testing.T) {
+	adminUID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		mockInviteRepository := new(mocks.MockInviteRepository)
+		is := NewInviteService(&InviteServiceConfig{
+			InviteRepository: mockInviteRepository,
+			DefaultExpires:   time.Hour,
+		})
+
+		var stored *model.Invite
+		mockInviteRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.Invite")).
+			Run(func(args mock.Arguments) {
+				stored = args.Get(1).(*model.Invite)
+			}).Return(nil)
+
+		invite, err := is.Create(context.TODO(), adminUID, 5, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, stored, invite)
+		assert.NotEmpty(t, invite.Code)
+		assert.Equal(t, hashInviteCode(invite.Code), invite.CodeHash)
+		assert.Equal(t, 5, invite.MaxUses)
+		assert.Equal(t, adminUID, *invite.CreatedBy)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), *invite.ExpiresAt, time.Minute)
+	})
+
+	t.Run("Never expires", func(t *testing.T) {
+		mockInviteRepository := new(mocks.MockInviteRepository)
+		is := NewInviteService(&InviteServiceConfig{
+			InviteRepository: mockInviteRepository,
+		})
+
+		mockInviteRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.Invite")).Return(nil)
+
+		invite, err := is.Create(context.TODO(), adminUID, 1, 0)
+
+		assert.NoError(t, err)
+		assert.Nil(t, invite.ExpiresAt)
+	})
+
+	t.Run("No uses", func(t *testing.T) {
+		mockInviteRepository := new(mocks.MockInviteRepository)
+		is := NewInviteService(&InviteServiceConfig{
+			InviteRepository: mockInviteRepository,
+		})
+
+		_, err := is.Create(context.TODO(), adminUID, 0, time.Hour)
+
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockInviteRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/utils"
+
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// lockoutService throttles sign-in per account and per IP. Past a number of
+// free attempts, each failure blocks further attempts for an exponentially
+// growing delay, and an account is locked once it reaches the lockout threshold.
+// It also limits the guest accounts created per IP
+type lockoutService struct {
+	LoginAttemptRepository model.LoginAttemptRepository
+	UserRepository         model.UserRepository
+	Mailer                 model.Mailer
+	FreeAttempts           int64
+	IPFreeAttempts         int64
+	FailureWindow          time.Duration
+	BackoffBase            time.Duration
+	BackoffMax             time.Duration
+	LockoutThreshold       int64
+	LockoutDuration        time.Duration
+	UnlockURL              string
+	UnlockExpires          time.Duration
+	GuestMaxPerIP          int64
+	GuestWindow            time.Duration
+}
+
+// LockoutServiceConfig will hold repositories that will eventually be injected into
+// this service layer
+// UnlockURL is the client page which posts the token of an unlock link to /signin/unlock
+// GuestMaxPerIP guests may be created per IP within GuestWindow, 0 does not limit them
+type LockoutServiceConfig struct {
+	LoginAttemptRepository model.LoginAttemptRepository
+	UserRepository         model.UserRepository
+	Mailer                 model.Mailer
+	FreeAttempts           int64
+	IPFreeAttempts         int64
+	FailureWindow          time.Duration
+	BackoffBase            time.Duration
+	BackoffMax             time.Duration
+	LockoutThreshold       int64
+	LockoutDuration        time.Duration
+	UnlockURL              string
+	UnlockExpires          time.Duration
+	GuestMaxPerIP          int64
+	GuestWindow            time.Duration
+}
+
+// NewLockoutService is a factory function for
+// initializing a LockoutService with its repository layer dependencies
+func NewLockoutService(c *LockoutServiceConfig) model.LockoutService {
+	return &lockoutService{
+		LoginAttemptRepository: c.LoginAttemptRepository,
+		UserRepository:         c.UserRepository,
+		Mailer:                 c.Mailer,
+		FreeAttempts:           c.FreeAttempts,
+		IPFreeAttempts:         c.IPFreeAttempts,
+		FailureWindow:          c.FailureWindow,
+		BackoffBase:            c.BackoffBase,
+		BackoffMax:             c.BackoffMax,
+		LockoutThreshold:       c.LockoutThreshold,
+		LockoutDuration:        c.LockoutDuration,
+		UnlockURL:              c.UnlockURL,
+		UnlockExpires:          c.UnlockExpires,
+		GuestMaxPerIP:          c.GuestMaxPerIP,
+		GuestWindow:            c.GuestWindow,
+	}
+}
+
+// Check rejects a sign-in attempt while the account or the IP is blocked
+func (s *lockoutService) Check(ctx context.Context, email string, ip string) error {
+	accountBlock, err := s.LoginAttemptRepository.BlockedFor(ctx, accountKey(email))
+	if err != nil {
+		return err
+	}
+
+	ipBlock, err := s.LoginAttemptRepository.BlockedFor(ctx, ipKey(ip))
+	if err != nil {
+		return err
+	}
+
+	if ipBlock > accountBlock {
+		accountBlock = ipBlock
+	}
+
+	if accountBlock > 0 {
+		return apperrors.NewTooManyRequests("Too many failed sign-in attempts. Try again later", accountBlock)
+	}
+
+	return nil
+}
+
+// RecordFailure counts a failed sign-in and blocks the account or IP when
+// they used up their free attempts. Reaching the lockout threshold emails
+// an unlock link to the account
+func (s *lockoutService) RecordFailure(ctx context.Context, email string, ip string) error {
+	accountFailures, err := s.LoginAttemptRepository.IncrementFailures(ctx, accountKey(email), s.FailureWindow)
+	if err != nil {
+		return err
+	}
+
+	if accountFailures >= s.LockoutThreshold {
+		if err := s.LoginAttemptRepository.Block(ctx, accountKey(email), s.LockoutDuration); err != nil {
+			return err
+		}
+
+		// the link is only sent once per lockout
+		if accountFailures == s.LockoutThreshold {
+			if err := s.sendUnlockLink(ctx, email); err != nil {
+				return err
+			}
+		}
+	} else if delay := s.backoff(accountFailures, s.FreeAttempts); delay > 0 {
+		if err := s.LoginAttemptRepository.Block(ctx, accountKey(email), delay); err != nil {
+			return err
+		}
+	}
+
+	ipFailures, err := s.LoginAttemptRepository.IncrementFailures(ctx, ipKey(ip), s.FailureWindow)
+	if err != nil {
+		return err
+	}
+
+	if delay := s.backoff(ipFailures, s.IPFreeAttempts); delay > 0 {
+		return s.LoginAttemptRepository.Block(ctx, ipKey(ip), delay)
+	}
+
+	return nil
+}
+
+// RecordSuccess forgets the failures of an account after a successful sign-in
+// Failures of the IP are kept, as they may belong to other accounts
+func (s *lockoutService) RecordSuccess(ctx context.Context, email string) error {
+	return s.LoginAttemptRepository.ResetFailures(ctx, accountKey(email))
+}
+
+// Unlock lifts the lockout of the account an unlock link was sent to
+func (s *lockoutService) Unlock(ctx context.Context, token string) error {
+	email, err := s.LoginAttemptRepository.GetUnlockToken(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	return s.UnlockAccount(ctx, email)
+}
+
+// UnlockAccount lifts the lockout of an account
+func (s *lockoutService) UnlockAccount(ctx context.Context, email string) error {
+	return s.LoginAttemptRepository.ResetFailures(ctx, accountKey(email))
+}
+
+// CheckGuest counts a guest account created from an IP, and rejects it
+// once the IP created GuestMaxPerIP guests within the window
+func (s *lockoutService) CheckGuest(ctx context.Context, ip string) error {
+	if s.GuestMaxPerIP <= 0 {
+		return nil
+	}
+
+	count, err := s.LoginAttemptRepository.IncrementCount(ctx, guestKey(ip), s.GuestWindow)
+	if err != nil {
+		return err
+	}
+
+	if count > s.GuestMaxPerIP {
+		return apperrors.NewTooManyRequests("Too many guest accounts created. Try again later", s.GuestWindow)
+	}
+
+	return nil
+}
+
+// backoff returns how long to block after the given number of failures,
+// doubling from BackoffBase for each failure past the free attempts
+func (s *lockoutService) backoff(failures int64, free int64) time.Duration {
+	if failures <= free {
+		return 0
+	}
+
+	delay := s.BackoffBase
+	for i := free + 1; i < failures && delay < s.BackoffMax; i++ {
+		delay *= 2
+	}
+
+	if delay > s.BackoffMax {
+		delay = s.BackoffMax
+	}
+
+	return delay
+}
+
+func (s *lockoutService) sendUnlockLink(ctx context.Context, email string) error {
+	// failures are also counted for unknown emails, so attempts do
+	// not reveal which exist, but those have no one to notify
+	if _, err := s.UserRepository.FindByEmail(ctx, email); err != nil {
+		return nil
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		log.Printf("Failed to generate unlock token: %v\n", err)
+		return apperrors.NewInternal()
+	}
+
+	if err := s.LoginAttemptRepository.SetUnlockToken(ctx, token, email, s.UnlockExpires); err != nil {
+		return err
+	}
+
+	unlockURL, err := url.Parse(s.UnlockURL)
+	if err != nil {
+		log.Printf("Invalid unlock URL: %v\n", err)
+		return apperrors.NewInternal()
+	}
+
+	query := unlockURL.Query()
+	query.Set("token", token)
+	unlockURL.RawQuery = query.Encode()
+
+	return s.Mailer.Send(ctx, &model.Email{
+		To:      email,
+		Subject: "Your account has been locked",
+		Body: fmt.Sprintf(
+			"There were too many failed attempts to sign in to your account, so it is locked for %d minutes.\n\nIf these attempts were yours, you can unlock your account now:\n\n%s\n\nIf they were not, consider changing your password.",
+			int(s.LockoutDuration.Minutes()),
+			unlockURL.String(),
+		),
+	})
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func guestKey(ip string) string {
+	return "guest:" + ip
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestLockoutCheck(t *testing.T) {
+	newService := func() (model.LockoutService, *mocks.MockLoginAttemptRepository) {
+		mockLoginAttemptRepository := new(mocks.MockLoginAttemptRepository)
+		ls := NewLockoutService(&LockoutServiceConfig{
+			LoginAttemptRepository: mockLoginAttemptRepository,
+		})
+
+		return ls, mockLoginAttemptRepository
+	}
+
+	t.Run("Not blocked", func(t *testing.T) {
+		ls, mockLoginAttemptRepository := newService()
+
+		mockLoginAttemptRepository.On("BlockedFor", mock.Anything, "account:long@do.com").Return(time.Duration(0), nil)
+		mockLoginAttemptRepository.On("BlockedFor", mock.Anything, "ip:127.0.0.1").Return(time.Duration(0), nil)
+
+		err := ls.Check(context.TODO(), "Long@Do.com ", "127.0.0.1")
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Blocked", func(t *testing.T) {
+		ls, mockLoginAttemptRepository := newService()
+
+		mockLoginAttemptRepository.On("BlockedFor", mock.Anything, "account:long@do.com").Return(1500*time.Millisecond, nil)
+		mockLoginAttemptRepository.On("BlockedFor", mock.Anything, "ip:127.0.0.1").Return(30*time.Second, nil)
+
+		err := ls.Check(context.TODO(), "long@do.com", "127.0.0.1")
+
+		// the longer of both blocks is reported
+		assert.Equal(t, apperrors.TooManyRequests, err.(*apperrors.Error).Type)
+		assert.Equal(t, 30, apperrors.RetryAfter(err))
+	})
+}
+
+func TestLockoutCheckGuest(t *testing.T) {
+	newService := func(maxPerIP int64) (model.LockoutService, *mocks.MockLoginAttemptRepository) {
+		mockLoginAttemptRepository := new(mocks.MockLoginAttemptRepository)
+		ls := NewLockoutService(&LockoutServiceConfig{
+			LoginAttemptRepository: mockLoginAttemptRepository,
+			GuestMaxPerIP:          maxPerIP,
+			GuestWindow:            time.Hour,
+		})
+
+		return ls, mockLoginAttemptRepository
+	}
+
+	t.Run("Within limit", func(t *testing.T) {
+		ls, mockLoginAttemptRepository := newService(10)
+
+		mockLoginAttemptRepository.On("IncrementCount", mock.Anything, "guest:127.0.0.1", time.Hour).Return(int64(10), nil)
+
+		err := ls.CheckGuest(context.TODO(), "127.0.0.1")
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Over limit", func(t *testing.T) {
+		ls, mockLoginAttemptRepository := newService(10)
+
+		mockLoginAttemptRepository.On("IncrementCount", mock.Anything, "guest:127.0.0.1", time.Hour).Return(int64(11), nil)
+
+		err := ls.CheckGuest(context.TODO(), "127.0.0.1")
+
+		assert.Equal(t, apperrors.TooManyRequests, err.(*apperrors.Error).Type)
+		assert.Equal(t, 3600, apperrors.RetryAfter(err))
+	})
+
+	t.Run("No limit", func(t *testing.T) {
+		ls, mockLoginAttemptRepository := newService(0)
+
+		err := ls.CheckGuest(context.TODO(), "127.0.0.1")
+
+		assert.NoError(t, err)
+		mockLoginAttemptRepository.AssertNotCalled(t, "IncrementFailures", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestLockoutRecordFailure(t *testing.T) {
+	email := "long@do.com"
+	ip := "127.0.0.1"
+
+	newService := func() (model.LockoutService, *mocks.MockLoginAttemptRepository, *mocks.MockUserRepository, *mocks.MockMailer) {
+		mockLoginAttemptRepository := new(mocks.MockLoginAttemptRepository)
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockMailer := new(mocks.MockMailer)
+		ls := NewLockoutService(&LockoutServiceConfig{
+			LoginAttemptRepository: mockLoginAttemptRepository,
+			UserRepository:         mockUserRepository,
+			Mailer:                 mockMailer,
+			FreeAttempts:           3,
+			IPFreeAttempts:         20,
+			FailureWindow:          time.Hour,
+			BackoffBase:            time.Second,
+			BackoffMax:             5 * time.Minute,
+			LockoutThreshold:       10,
+			LockoutDuration:        30 * time.Minute,
+			UnlockURL:              "http://localhost:3000/signin/unlock",
+			UnlockExpires:          24 * time.Hour,
+		})
+
+		mockLoginAttemptRepository.On("IncrementFailures", mock.Anything, "ip:"+ip, time.Hour).Return(int64(1), nil)
+
+		return ls, mockLoginAttemptRepository, mockUserRepository, mockMailer
+	}
+
+	t.Run("Free attempt", func(t *testing.T) {
+		ls, mockLoginAttemptRepository, _, _ := newService()
+
+		mockLoginAttemptRepository.On("IncrementFailures", mock.Anything, "account:"+email, time.Hour).Return(int64(3), nil)
+
+		err := ls.RecordFailure(context.TODO(), email, ip)
+
+		assert.NoError(t, err)
+		mockLoginAttemptRepository.AssertNotCalled(t, "Block")
+	})
+
+	t.Run("Exponential backoff", func(t *testing.T) {
+		ls, mockLoginAttemptRepository, _, mockMailer := newService()
+
+		mockLoginAttemptRepository.On("IncrementFailures", mock.Anything, "account:"+email, time.Hour).Return(int64(6), nil)
+		mockLoginAttemptRepository.On("Block", mock.Anything, "account:"+email, 4*time.Second).Return(nil)
+
+		err := ls.RecordFailure(context.TODO(), email, ip)
+
+		assert.NoError(t, err)
+		mockLoginAttemptRepository.AssertExpectations(t)
+		mockMailer.AssertNotCalled(t, "Send")
+	})
+
+	t.Run("IP backoff", func(t *testing.T) {
+		mockLoginAttemptRepository := new(mocks.MockLoginAttemptRepository)
+		ls := NewLockoutService(&LockoutServiceConfig{
+			LoginAttemptRepository: mockLoginAttemptRepository,
+			FreeAttempts:           3,
+			IPFreeAttempts:         20,
+			FailureWindow:          time.Hour,
+			BackoffBase:            time.Second,
+			BackoffMax:             5 * time.Minute,
+			LockoutThreshold:       10,
+		})
+
+		mockLoginAttemptRepository.On("IncrementFailures", mock.Anything, "account:"+email, time.Hour).Return(int64(1), nil)
+		mockLoginAttemptRepository.On("IncrementFailures", mock.Anything, "ip:"+ip, time.Hour).Return(int64(100), nil)
+		mockLoginAttemptRepository.On("Block", mock.Anything, "ip:"+ip, 5*time.Minute).Return(nil)
+
+		err := ls.RecordFailure(context.TODO(), email, ip)
+
+		assert.NoError(t, err)
+		mockLoginAttemptRepository.AssertExpectations(t)
+	})
+
+	t.Run("Lockout emails unlock link", func(t *testing.T) {
+		ls, mockLoginAttemptRepository, mockUserRepository, mockMailer := newService()
+		uid, _ := uuid.NewRandom()
+
+		mockLoginAttemptRepository.On("IncrementFailures", mock.Anything, "account:"+email, time.Hour).Return(int64(10), nil)
+		mockLoginAttemptRepository.On("Block", mock.Anything, "account:"+email, 30*time.Minute).Return(nil)
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UID: uid, Email: email}, nil)
+		mockLoginAttemptRepository.On("SetUnlockToken", mock.Anything, mock.AnythingOfType("string"), email, 24*time.Hour).Return(nil)
+		mockMailer.On("Send", mock.Anything, mock.AnythingOfType("*model.Email")).Return(nil)
+
+		err := ls.RecordFailure(context.TODO(), email, ip)
+
+		assert.NoError(t, err)
+
+		var token string
+		for _, call := range mockLoginAttemptRepository.Calls {
+			if call.Method == "SetUnlockToken" {
+				token = call.Arguments.String(1)
+			}
+		}
+
+		sent := mockMailer.Calls[0].Arguments.Get(1).(*model.Email)
+		assert.Equal(t, email, sent.To)
+		assert.Contains(t, sent.Body, "http://localhost:3000/signin/unlock?token="+url.QueryEscape(token))
+	})
+
+	t.Run("Locked account is only emailed once", func(t *testing.T) {
+		ls, mockLoginAttemptRepository, mockUserRepository, mockMailer := newService()
+
+		mockLoginAttemptRepository.On("IncrementFailures", mock.Anything, "account:"+email, time.Hour).Return(int64(11), nil)
+		mockLoginAttemptRepository.On("Block", mock.Anything, "account:"+email, 30*time.Minute).Return(nil)
+
+		err := ls.RecordFailure(context.TODO(), email, ip)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertNotCalled(t, "FindByEmail")
+		mockMailer.AssertNotCalled(t, "Send")
+	})
+
+	t.Run("Unknown email is locked without email", func(t *testing.T) {
+		ls, mockLoginAttemptRepository, mockUserRepository, mockMailer := newService()
+
+		mockLoginAttemptRepository.On("IncrementFailures", mock.Anything, "account:"+email, time.Hour).Return(int64(10), nil)
+		mockLoginAttemptRepository.On("Block", mock.Anything, "account:"+email, 30*time.Minute).Return(nil)
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(nil, apperrors.NewNotFound("email", email))
+
+		err := ls.RecordFailure(context.TODO(), email, ip)
+
+		assert.NoError(t, err)
+		mockLoginAttemptRepository.AssertNotCalled(t, "SetUnlockToken")
+		mockMailer.AssertNotCalled(t, "Send")
+	})
+}
+
+func TestLockoutUnlock(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockLoginAttemptRepository := new(mocks.MockLoginAttemptRepository)
+		ls := NewLockoutService(&LockoutServiceConfig{
+			LoginAttemptRepository: mockLoginAttemptRepository,
+		})
+
+		mockLoginAttemptRepository.On("GetUnlockToken", mock.Anything, "token").Return("long@do.com", nil)
+		mockLoginAttemptRepository.On("ResetFailures", mock.Anything, "account:long@do.com").Return(nil)
+
+		err := ls.Unlock(context.TODO(), "token")
+
+		assert.NoError(t, err)
+		mockLoginAttemptRepository.AssertExpectations(t)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		mockLoginAttemptRepository := new(mocks.MockLoginAttemptRepository)
+		ls := NewLockoutService(&LockoutServiceConfig{
+			LoginAttemptRepository: mockLoginAttemptRepository,
+		})
+
+		mockLoginAttemptRepository.On("GetUnlockToken", mock.Anything, "token").Return("", apperrors.NewAuthorization("Invalid or expired unlock link"))
+
+		err := ls.Unlock(context.TODO(), "token")
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockLoginAttemptRepository.AssertNotCalled(t, "ResetFailures")
+	})
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/utils"
+
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// magicLinkService sends single-use sign-in links by email
+type magicLinkService struct {
+	UserRepository  model.UserRepository
+	TokenRepository model.TokenRepository
+	Mailer          model.Mailer
+	SignupPolicy    model.SignupPolicy
+	AuditRepository model.AuditRepository
+	LinkURL         string
+	LinkExpires     time.Duration
+	MaxRequests     int64
+	RequestWindow   time.Duration
+	AllowSignup     bool
+}
+
+// MagicLinkServiceConfig will hold repositories that will eventually be injected into
+// this service layer
+// LinkURL is the client page which posts the token of a link to /signin/link/verify
+type MagicLinkServiceConfig struct {
+	UserRepository  model.UserRepository
+	TokenRepository model.TokenRepository
+	Mailer          model.Mailer
+	SignupPolicy    model.SignupPolicy
+	AuditRepository model.AuditRepository
+	LinkURL         string
+	LinkExpires     time.Duration
+	MaxRequests     int64
+	RequestWindow   time.Duration
+	AllowSignup     bool
+}
+
+// NewMagicLinkService is a factory function for
+// initializing a MagicLinkService with its repository layer dependencies
+func NewMagicLinkService(c *MagicLinkServiceConfig) model.MagicLinkService {
+	return &magicLinkService{
+		UserRepository:  c.UserRepository,
+		TokenRepository: c.TokenRepository,
+		Mailer:          c.Mailer,
+		SignupPolicy:    c.SignupPolicy,
+		AuditRepository: c.AuditRepository,
+		LinkURL:         c.LinkURL,
+		LinkExpires:     c.LinkExpires,
+		MaxRequests:     c.MaxRequests,
+		RequestWindow:   c.RequestWindow,
+		AllowSignup:     c.AllowSignup,
+	}
+}
+
+// Send emails a sign-in link to the address and returns the nonce the
+// requesting browser must present along with the link's token.
+// The result does not tell whether an account exists or a link was sent,
+// the link is mailed in the background
+func (s *magicLinkService) Send(ctx context.Context, email string) (string, error) {
+	email = utils.NormalizeEmail(email)
+
+	nonce, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		log.Printf("Failed to generate sign-in link nonce: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+
+	count, err := s.TokenRepository.IncrementMagicLinkRequests(ctx, strings.ToLower(email), s.RequestWindow)
+	if err != nil {
+		return "", err
+	}
+
+	if count > s.MaxRequests {
+		log.Printf("Too many sign-in links requested for email: %s\n", email)
+		return nonce, nil
+	}
+
+	if !s.AllowSignup {
+		if _, err := s.UserRepository.FindByEmail(ctx, email); err != nil {
+			return nonce, nil
+		}
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		log.Printf("Failed to generate sign-in link token: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+
+	link := &model.MagicLink{
+		Email:     email,
+		NonceHash: hashNonce(nonce),
+	}
+
+	if err := s.TokenRepository.SetMagicLink(ctx, token, link, s.LinkExpires); err != nil {
+		return "", err
+	}
+
+	linkURL, err := url.Parse(s.LinkURL)
+	if err != nil {
+		log.Printf("Invalid sign-in link URL: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+
+	query := linkURL.Query()
+	query.Set("token", token)
+	linkURL.RawQuery = query.Encode()
+
+	mail := &model.Email{
+		To:      email,
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf(
+			"Use the link below to sign in. It expires in %d minutes and can only be used once, in the browser you requested it from.\n\n%s\n\nIf you did not request this link, you can ignore this email.",
+			int(s.LinkExpires.Minutes()),
+			linkURL.String(),
+		),
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+
+		if err := s.Mailer.Send(ctx, mail); err != nil {
+			log.Printf("Failed to send sign-in link to email: %s: %v\n", mail.To, err)
+		}
+	}()
+
+	return nonce, nil
+}
+
+// Verify exchanges the token of a sign-in link for its user. The link is
+// used up even if the nonce does not match, so a leaked link can not be retried.
+// An account without password is created for unknown addresses when signup is allowed
+func (s *magicLinkService) Verify(ctx context.Context, token string, nonce string) (*model.User, error) {
+	link, err := s.TokenRepository.GetMagicLink(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(link.NonceHash), []byte(hashNonce(nonce))) != 1 {
+		audit(ctx, s.AuditRepository, nil, model.AuditSignin, model.AuditFailure, "sign-in link from another browser")
+		return nil, apperrors.NewAuthorization("Sign-in link must be opened in the browser it was requested from")
+	}
+
+	email := utils.NormalizeEmail(link.Email)
+
+	user, err := s.UserRepository.FindByEmail(ctx, email)
+	if err == nil {
+		return user, nil
+	}
+
+	if !s.AllowSignup || !isNotFound(err) {
+		return nil, err
+	}
+
+	user = &model.User{
+		Email: email,
+	}
+
+	invite, err := admit(ctx, s.SignupPolicy, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.UserRepository.Create(ctx, user); err != nil {
+		release(s.SignupPolicy, invite)
+		return nil, err
+	}
+
+	audit(ctx, s.AuditRepository, &user.UID, model.AuditSignup, model.AuditSuccess, "sign-in link")
+	return user, nil
+}
+
+func hashNonce(nonce string) string {
+	hash := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMagicLinkSend(t *testing.T) {
+	email := "long@do.com"
+	uid, _ := uuid.NewRandom()
+
+	newService := func(allowSignup bool) (model.MagicLinkService, *mocks.MockUserRepository, *mocks.MockTokenRepository, *mocks.MockMailer) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockMailer := new(mocks.MockMailer)
+		ms := NewMagicLinkService(&MagicLinkServiceConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			Mailer:          mockMailer,
+			LinkURL:         "http://localhost:3000/signin/link",
+			LinkExpires:     15 * time.Minute,
+			MaxRequests:     3,
+			RequestWindow:   time.Hour,
+			AllowSignup:     allowSignup,
+		})
+
+		return ms, mockUserRepository, mockTokenRepository, mockMailer
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ms, mockUserRepository, mockTokenRepository, mockMailer := newService(false)
+
+		mockTokenRepository.On("IncrementMagicLinkRequests", mock.Anything, email, time.Hour).Return(int64(1), nil)
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UID: uid, Email: email}, nil)
+		mockTokenRepository.On("SetMagicLink", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*model.MagicLink"), 15*time.Minute).Return(nil)
+
+		mailed := make(chan *model.Email, 1)
+		mockMailer.On("Send", mock.Anything, mock.AnythingOfType("*model.Email")).
+			Run(func(args mock.Arguments) {
+				mailed <- args.Get(1).(*model.Email)
+			}).
+			Return(nil)
+
+		nonce, err := ms.Send(context.TODO(), email)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, nonce)
+
+		// the nonce is stored hashed, and the emailed link carries the stored token
+		token := mockTokenRepository.Calls[1].Arguments.String(1)
+		link := mockTokenRepository.Calls[1].Arguments.Get(2).(*model.MagicLink)
+		assert.Equal(t, email, link.Email)
+		assert.Equal(t, hashNonce(nonce), link.NonceHash)
+
+		select {
+		case sent := <-mailed:
+			assert.Equal(t, email, sent.To)
+			assert.Contains(t, sent.Body, "http://localhost:3000/signin/link?token="+url.QueryEscape(token))
+			assert.False(t, strings.Contains(sent.Body, nonce))
+		case <-time.After(time.Second):
+			t.Fatal("sign-in link was not mailed")
+		}
+	})
+
+	t.Run("Normalized email", func(t *testing.T) {
+		ms, mockUserRepository, mockTokenRepository, mockMailer := newService(false)
+
+		// the domain is normalized, and requests are counted regardless of case
+		mockTokenRepository.On("IncrementMagicLinkRequests", mock.Anything, "long@do.com", time.Hour).Return(int64(1), nil)
+		mockUserRepository.On("FindByEmail", mock.Anything, "Long@do.com").Return(&model.User{UID: uid, Email: "Long@do.com"}, nil)
+		mockTokenRepository.On("SetMagicLink", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*model.MagicLink"), 15*time.Minute).Return(nil)
+
+		mailed := make(chan *model.Email, 1)
+		mockMailer.On("Send", mock.Anything, mock.AnythingOfType("*model.Email")).
+			Run(func(args mock.Arguments) {
+				mailed <- args.Get(1).(*model.Email)
+			}).
+			Return(nil)
+
+		_, err := ms.Send(context.TODO(), " Long@DO.com ")
+
+		assert.NoError(t, err)
+
+		select {
+		case sent := <-mailed:
+			assert.Equal(t, "Long@do.com", sent.To)
+		case <-time.After(time.Second):
+			t.Fatal("sign-in link was not mailed")
+		}
+	})
+
+	t.Run("Failed mail is not reported", func(t *testing.T) {
+		ms, mockUserRepository, mockTokenRepository, mockMailer := newService(false)
+
+		mockTokenRepository.On("IncrementMagicLinkRequests", mock.Anything, email, time.Hour).Return(int64(1), nil)
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UID: uid, Email: email}, nil)
+		mockTokenRepository.On("SetMagicLink", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*model.MagicLink"), 15*time.Minute).Return(nil)
+
+		attempted := make(chan struct{})
+		mockMailer.On("Send", mock.Anything, mock.AnythingOfType("*model.Email")).
+			Run(func(args mock.Arguments) { close(attempted) }).
+			Return(apperrors.NewInternal())
+
+		nonce, err := ms.Send(context.TODO(), email)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, nonce)
+
+		select {
+		case <-attempted:
+		case <-time.After(time.Second):
+			t.Fatal("sign-in link was not mailed")
+		}
+	})
+
+	t.Run("Rate limited", func(t *testing.T) {
+		ms, mockUserRepository, mockTokenRepository, mockMailer := newService(false)
+
+		mockTokenRepository.On("IncrementMagicLinkRequests", mock.Anything, email, time.Hour).Return(int64(4), nil)
+
+		nonce, err := ms.Send(context.TODO(), email)
+
+		// the response does not reveal that no email was sent
+		assert.NoError(t, err)
+		assert.NotEmpty(t, nonce)
+		mockUserRepository.AssertNotCalled(t, "FindByEmail")
+		mockTokenRepository.AssertNotCalled(t, "SetMagicLink")
+		mockMailer.AssertNotCalled(t, "Send")
+	})
+
+	t.Run("Unknown email without signup", func(t *testing.T) {
+		ms, mockUserRepository, mockTokenRepository, mockMailer := newService(false)
+
+		mockTokenRepository.On("IncrementMagicLinkRequests", mock.Anything, email, time.Hour).Return(int64(1), nil)
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(nil, apperrors.NewNotFound("email", email))
+
+		nonce, err := ms.Send(context.TODO(), email)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, nonce)
+		mockMailer.AssertNotCalled(t, "Send")
+	})
+}
+
+func TestMagicLinkVerify(t *testing.T) {
+	email := "long@do.com"
+	uid, _ := uuid.NewRandom()
+	link := &model.MagicLink{Email: email, NonceHash: hashNonce("nonce")}
+
+	newService := func(allowSignup bool) (model.MagicLinkService, *mocks.MockUserRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		ms := NewMagicLinkService(&MagicLinkServiceConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			AllowSignup:     allowSignup,
+		})
+
+		mockTokenRepository.On("GetMagicLink", mock.Anything, "token").Return(link, nil)
+
+		return ms, mockUserRepository
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ms, mockUserRepository := newService(false)
+
+		mockUser := &model.User{UID: uid, Email: email}
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(mockUser, nil)
+
+		user, err := ms.Verify(context.TODO(), "token", "nonce")
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUser, user)
+	})
+
+	t.Run("Other browser", func(t *testing.T) {
+		ms, mockUserRepository := newService(false)
+
+		user, err := ms.Verify(context.TODO(), "token", "othernonce")
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "FindByEmail")
+	})
+
+	t.Run("Creates account without password", func(t *testing.T) {
+		ms, mockUserRepository := newService(true)
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(nil, apperrors.NewNotFound("email", email))
+		mockUserRepository.
+			On("Create", mock.Anything, &model.User{Email: email}).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(*model.User).UID = uid
+			}).
+			Return(nil)
+
+		user, err := ms.Verify(context.TODO(), "token", "nonce")
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, user.UID)
+		assert.Empty(t, user.Password)
+	})
+
+	t.Run("Unknown email without signup", func(t *testing.T) {
+		ms, mockUserRepository := newService(false)
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(nil, apperrors.NewNotFound("email", email))
+
+		user, err := ms.Verify(context.TODO(), "token", "nonce")
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.NotFound, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "Create")
+	})
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/utils"
+
+	"github.com/google/uuid"
+	"log"
+	"time"
+)
+
+// recoveryCodeCount is the number of one-time codes issued on enrollment
+const recoveryCodeCount = 10
+
+// mfaService is used for enrolling authenticators and for
+// completing sign-ins which require a second factor
+type mfaService struct {
+	UserRepository       model.UserRepository
+	MFARepository        model.MFARepository
+	TokenRepository      model.TokenRepository
+	AuditRepository      model.AuditRepository
+	Issuer               string
+	ChallengeExpires     time.Duration
+	MaxChallengeAttempts int64
+}
+
+// MFAServiceConfig will hold repositories that will eventually be injected into
+// this service layer
+type MFAServiceConfig struct {
+	UserRepository       model.UserRepository
+	MFARepository        model.MFARepository
+	TokenRepository      model.TokenRepository
+	AuditRepository      model.AuditRepository
+	Issuer               string
+	ChallengeExpires     time.Duration
+	MaxChallengeAttempts int64
+}
+
+// NewMFAService is a factory function for
+// initializing a MFAService with its repository layer dependencies
+func NewMFAService(c *MFAServiceConfig) model.MFAService {
+	return &mfaService{
+		UserRepository:       c.UserRepository,
+		MFARepository:        c.MFARepository,
+		TokenRepository:      c.TokenRepository,
+		AuditRepository:      c.AuditRepository,
+		Issuer:               c.Issuer,
+		ChallengeExpires:     c.ChallengeExpires,
+		MaxChallengeAttempts: c.MaxChallengeAttempts,
+	}
+}
+
+// EnrollTOTP creates a new secret for the user. The enrollment does not
+// protect sign-in until it is confirmed with a first code
+func (s *mfaService) EnrollTOTP(ctx context.Context, uid uuid.UUID) (*model.TOTPEnrollment, error) {
+	user, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		log.Printf("Failed to generate totp secret for uid: %v. Error: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	// fails with a conflict if an enrollment is already confirmed
+	if err := s.MFARepository.SetTOTP(ctx, &model.TOTP{UID: uid, Secret: secret}); err != nil {
+		return nil, err
+	}
+
+	uri := utils.TOTPURI(s.Issuer, user.Email, secret)
+	qrCode, err := utils.QRCodePNG(uri)
+	if err != nil {
+		log.Printf("Failed to encode totp QR code for uid: %v. Error: %v\n", uid, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return &model.TOTPEnrollment{
+		Secret:    secret,
+		URI:       uri,
+		QRCodePNG: qrCode,
+	}, nil
+}
+
+// ConfirmTOTP activates a pending enrollment with a first code
+// It returns the recovery codes, which are only ever shown here
+func (s *mfaService) ConfirmTOTP(ctx context.Context, uid uuid.UUID, code string) ([]string, error) {
+	totp, err := s.MFARepository.FindTOTP(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if totp.Confirmed {
+		return nil, apperrors.NewConflict("totp", uid.String())
+	}
+
+	step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return nil, apperrors.NewBadRequest("Invalid authentication code")
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			log.Printf("Failed to generate recovery code for uid: %v. Error: %v\n", uid, err)
+			return nil, apperrors.NewInternal()
+		}
+
+		codes[i] = code
+		hashes[i] = utils.HashRecoveryCode(code)
+	}
+
+	if err := s.MFARepository.ConfirmTOTP(ctx, uid, step, hashes); err != nil {
+		return nil, err
+	}
+
+	audit(ctx, s.AuditRepository, &uid, model.AuditMFAEnable, model.AuditSuccess, "totp")
+	return codes, nil
+}
+
+// DisableTOTP removes the enrollment after checking the user's password
+func (s *mfaService) DisableTOTP(ctx context.Context, uid uuid.UUID, password string) error {
+	user, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	if user.Password == "" {
+		return apperrors.NewAuthorization("Invalid password")
+	}
+
+	match, err := utils.ComparePasswords(user.Password, password)
+	if err != nil {
+		return apperrors.NewInternal()
+	}
+
+	if !match {
+		audit(ctx, s.AuditRepository, &uid, model.AuditMFADisable, model.AuditFailure, "invalid password")
+		return apperrors.NewAuthorization("Invalid password")
+	}
+
+	if _, err := s.MFARepository.FindTOTP(ctx, uid); err != nil {
+		return err
+	}
+
+	if err := s.MFARepository.DeleteTOTP(ctx, uid); err != nil {
+		return err
+	}
+
+	audit(ctx, s.AuditRepository, &uid, model.AuditMFADisable, model.AuditSuccess, "totp")
+	return nil
+}
+
+// IsEnrolled reports whether sign-in requires a second factor for the user
+func (s *mfaService) IsEnrolled(ctx context.Context, uid uuid.UUID) (bool, error) {
+	totp, err := s.MFARepository.FindTOTP(ctx, uid)
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return totp.Confirmed, nil
+}
+
+// NewChallenge issues a short-lived token for a user who passed the first factor
+func (s *mfaService) NewChallenge(ctx context.Context, uid uuid.UUID) (string, error) {
+	challengeToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		log.Printf("Failed to generate mfa challenge for uid: %v. Error: %v\n", uid, err)
+		return "", apperrors.NewInternal()
+	}
+
+	if err := s.TokenRepository.SetMFAChallenge(ctx, challengeToken, uid.String(), s.ChallengeExpires); err != nil {
+		return "", err
+	}
+
+	return challengeToken, nil
+}
+
+// VerifyChallenge completes a sign-in with either an authenticator code
+// or a recovery code. The challenge is dropped once used or after
+// too many failed attempts
+func (s *mfaService) VerifyChallenge(ctx context.Context, challengeToken string, code string) (*model.User, error) {
+	uid, err := s.challengeUID(ctx, challengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.verifyCode(ctx, uid, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		audit(ctx, s.AuditRepository, &uid, model.AuditSignin, model.AuditFailure, "invalid second factor")
+
+		attempts, err := s.TokenRepository.IncrementMFAChallengeAttempts(ctx, challengeToken)
+		if err != nil {
+			return nil, err
+		}
+
+		if attempts >= s.MaxChallengeAttempts {
+			if err := s.TokenRepository.DeleteMFAChallenge(ctx, challengeToken); err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, apperrors.NewAuthorization("Invalid authentication code")
+	}
+
+	if err := s.TokenRepository.DeleteMFAChallenge(ctx, challengeToken); err != nil {
+		return nil, err
+	}
+
+	return s.UserRepository.FindByID(ctx, uid)
+}
+
+// ChallengeUser returns the user signing in with a challenge, leaving the
+// challenge as it is, so failed codes can be counted against the account
+func (s *mfaService) ChallengeUser(ctx context.Context, challengeToken string) (*model.User, error) {
+	uid, err := s.challengeUID(ctx, challengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.UserRepository.FindByID(ctx, uid)
+}
+
+// challengeUID returns the uid of the user a challenge was created for
+func (s *mfaService) challengeUID(ctx context.Context, challengeToken string) (uuid.UUID, error) {
+	userID, err := s.TokenRepository.GetMFAChallenge(ctx, challengeToken)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		log.Printf("Stored mfa challenge uid could not be parsed as UUID: %s\n%v\n", userID, err)
+		return uuid.Nil, apperrors.NewInternal()
+	}
+
+	return uid, nil
+}
+
+// verifyCode accepts a 6 digit authenticator code not used before,
+// anything else is checked against the unused recovery codes
+func (s *mfaService) verifyCode(ctx context.Context, uid uuid.UUID, code string) (bool, error) {
+	totp, err := s.MFARepository.FindTOTP(ctx, uid)
+	if err != nil {
+		return false, err
+	}
+
+	if !totp.Confirmed {
+		return false, apperrors.NewAuthorization("Two-factor authentication is not enabled")
+	}
+
+	if step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now()); ok {
+		return s.MFARepository.UpdateTOTPStep(ctx, uid, step)
+	}
+
+	return s.MFARepository.UseRecoveryCode(ctx, uid, utils.HashRecoveryCode(code))
+}
+
+// isNotFound reports whether a repository error means the record does not exist
+func isNotFound(err error) bool {
+	var appErr *apperrors.Error
+	return errors.As(err, &appErr) && appErr.Type == apperrors.NotFound
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+	"github.com/dolong2110/memorization-apps/account/utils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnrollTOTP(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	mockUserRepository := new(mocks.MockUserRepository)
+	mockMFARepository := new(mocks.MockMFARepository)
+	ms := NewMFAService(&MFAServiceConfig{
+		UserRepository: mockUserRepository,
+		MFARepository:  mockMFARepository,
+		Issuer:         "memorization-apps",
+	})
+
+	mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Email: "long@do.com"}, nil)
+	mockMFARepository.On("SetTOTP", mock.Anything, mock.AnythingOfType("*model.TOTP")).Return(nil)
+
+	enrollment, err := ms.EnrollTOTP(context.TODO(), uid)
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(enrollment.URI, "otpauth://totp/memorization-apps:long@do.com?"))
+	assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+	assert.Equal(t, []byte("\x89PNG"), enrollment.QRCodePNG[:4])
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	secret, _ := utils.GenerateTOTPSecret()
+
+	t.Run("Valid code", func(t *testing.T) {
+		mockMFARepository := new(mocks.MockMFARepository)
+		ms := NewMFAService(&MFAServiceConfig{
+			MFARepository: mockMFARepository,
+		})
+
+		step := utils.TOTPStep(time.Now())
+		code, _ := utils.TOTPCode(secret, step)
+
+		mockMFARepository.On("FindTOTP", mock.Anything, uid).Return(&model.TOTP{UID: uid, Secret: secret}, nil)
+		mockMFARepository.On("ConfirmTOTP", mock.Anything, uid, mock.AnythingOfType("int64"), mock.AnythingOfType("[]string")).Return(nil)
+
+		codes, err := ms.ConfirmTOTP(context.TODO(), uid, code)
+
+		assert.NoError(t, err)
+		assert.Len(t, codes, recoveryCodeCount)
+
+		// only hashes are stored
+		hashes := mockMFARepository.Calls[1].Arguments.Get(3).([]string)
+		assert.Equal(t, utils.HashRecoveryCode(codes[0]), hashes[0])
+		assert.NotContains(t, hashes, codes[0])
+	})
+
+	t.Run("Invalid code", func(t *testing.T) {
+		mockMFARepository := new(mocks.MockMFARepository)
+		ms := NewMFAService(&MFAServiceConfig{
+			MFARepository: mockMFARepository,
+		})
+
+		mockMFARepository.On("FindTOTP", mock.Anything, uid).Return(&model.TOTP{UID: uid, Secret: secret}, nil)
+
+		codes, err := ms.ConfirmTOTP(context.TODO(), uid, "notacode")
+
+		assert.Nil(t, codes)
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockMFARepository.AssertNotCalled(t, "ConfirmTOTP")
+	})
+}
+
+func TestDisableTOTP(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	hashedPW, _ := utils.HashPassword("howdyhoneighbor!")
+
+	mockUserRepository := new(mocks.MockUserRepository)
+	mockMFARepository := new(mocks.MockMFARepository)
+	ms := NewMFAService(&MFAServiceConfig{
+		UserRepository: mockUserRepository,
+		MFARepository:  mockMFARepository,
+	})
+
+	mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Password: hashedPW}, nil)
+
+	t.Run("Wrong password", func(t *testing.T) {
+		err := ms.DisableTOTP(context.TODO(), uid, "wrongpassword")
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockMFARepository.AssertNotCalled(t, "DeleteTOTP")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		mockMFARepository.On("FindTOTP", mock.Anything, uid).Return(&model.TOTP{UID: uid, Confirmed: true}, nil)
+		mockMFARepository.On("DeleteTOTP", mock.Anything, uid).Return(nil)
+
+		err := ms.DisableTOTP(context.TODO(), uid, "howdyhoneighbor!")
+
+		assert.NoError(t, err)
+		mockMFARepository.AssertCalled(t, "DeleteTOTP", mock.Anything, uid)
+	})
+}
+
+func TestIsEnrolled(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Not enrolled", func(t *testing.T) {
+		mockMFARepository := new(mocks.MockMFARepository)
+		ms := NewMFAService(&MFAServiceConfig{
+			MFARepository: mockMFARepository,
+		})
+
+		mockMFARepository.On("FindTOTP", mock.Anything, uid).Return(nil, apperrors.NewNotFound("totp", uid.String()))
+
+		enrolled, err := ms.IsEnrolled(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.False(t, enrolled)
+	})
+
+	t.Run("Unconfirmed enrollment", func(t *testing.T) {
+		mockMFARepository := new(mocks.MockMFARepository)
+		ms := NewMFAService(&MFAServiceConfig{
+			MFARepository: mockMFARepository,
+		})
+
+		mockMFARepository.On("FindTOTP", mock.Anything, uid).Return(&model.TOTP{UID: uid}, nil)
+
+		enrolled, err := ms.IsEnrolled(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.False(t, enrolled)
+	})
+}
+
+func TestVerifyChallenge(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	secret, _ := utils.GenerateTOTPSecret()
+	mockTOTP := &model.TOTP{UID: uid, Secret: secret, Confirmed: true}
+	mockUser := &model.User{UID: uid}
+
+	newService := func() (model.MFAService, *mocks.MockUserRepository, *mocks.MockMFARepository, *mocks.MockTokenRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockMFARepository := new(mocks.MockMFARepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		ms := NewMFAService(&MFAServiceConfig{
+			UserRepository:       mockUserRepository,
+			MFARepository:        mockMFARepository,
+			TokenRepository:      mockTokenRepository,
+			MaxChallengeAttempts: 3,
+		})
+
+		mockTokenRepository.On("GetMFAChallenge", mock.Anything, "challenge").Return(uid.String(), nil)
+		mockMFARepository.On("FindTOTP", mock.Anything, uid).Return(mockTOTP, nil)
+
+		return ms, mockUserRepository, mockMFARepository, mockTokenRepository
+	}
+
+	t.Run("Authenticator code", func(t *testing.T) {
+		ms, mockUserRepository, mockMFARepository, mockTokenRepository := newService()
+
+		step := utils.TOTPStep(time.Now())
+		code, _ := utils.TOTPCode(secret, step)
+
+		mockMFARepository.On("UpdateTOTPStep", mock.Anything, uid, mock.AnythingOfType("int64")).Return(true, nil)
+		mockTokenRepository.On("DeleteMFAChallenge", mock.Anything, "challenge").Return(nil)
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+
+		user, err := ms.VerifyChallenge(context.TODO(), "challenge", code)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUser, user)
+		mockMFARepository.AssertNotCalled(t, "UseRecoveryCode")
+	})
+
+	t.Run("Challenge user keeps the challenge", func(t *testing.T) {
+		ms, mockUserRepository, _, mockTokenRepository := newService()
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+
+		user, err := ms.ChallengeUser(context.TODO(), "challenge")
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUser, user)
+		mockTokenRepository.AssertNotCalled(t, "DeleteMFAChallenge")
+		mockTokenRepository.AssertNotCalled(t, "IncrementMFAChallengeAttempts")
+	})
+
+	t.Run("Replayed authenticator code", func(t *testing.T) {
+		ms, _, mockMFARepository, mockTokenRepository := newService()
+
+		step := utils.TOTPStep(time.Now())
+		code, _ := utils.TOTPCode(secret, step)
+
+		mockMFARepository.On("UpdateTOTPStep", mock.Anything, uid, mock.AnythingOfType("int64")).Return(false, nil)
+		mockTokenRepository.On("IncrementMFAChallengeAttempts", mock.Anything, "challenge").Return(int64(1), nil)
+
+		user, err := ms.VerifyChallenge(context.TODO(), "challenge", code)
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockTokenRepository.AssertNotCalled(t, "DeleteMFAChallenge")
+	})
+
+	t.Run("Recovery code", func(t *testing.T) {
+		ms, mockUserRepository, mockMFARepository, mockTokenRepository := newService()
+
+		mockMFARepository.On("UseRecoveryCode", mock.Anything, uid, utils.HashRecoveryCode("abcde-12345")).Return(true, nil)
+		mockTokenRepository.On("DeleteMFAChallenge", mock.Anything, "challenge").Return(nil)
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+
+		user, err := ms.VerifyChallenge(context.TODO(), "challenge", "ABCDE12345")
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUser, user)
+	})
+
+	t.Run("Too many attempts drops the challenge", func(t *testing.T) {
+		ms, _, mockMFARepository, mockTokenRepository := newService()
+
+		mockMFARepository.On("UseRecoveryCode", mock.Anything, uid, mock.AnythingOfType("string")).Return(false, nil)
+		mockTokenRepository.On("IncrementMFAChallengeAttempts", mock.Anything, "challenge").Return(int64(3), nil)
+		mockTokenRepository.On("DeleteMFAChallenge", mock.Anything, "challenge").Return(nil)
+
+		user, err := ms.VerifyChallenge(context.TODO(), "challenge", "wrong-code")
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockTokenRepository.AssertCalled(t, "DeleteMFAChallenge", mock.Anything, "challenge")
+	})
+}
@@ -0,0 +1,494 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/utils"
+
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// OAuthProvider configures an OAuth2 or OpenID Connect identity provider
+// Users are identified by the SubjectClaim of the provider's userinfo response,
+// "sub" for OIDC providers and e.g. "id" for GitHub.
+// TrustEmail treats emails as verified when the provider does not report
+// email_verified, which only providers sharing verified addresses may set
+type OAuthProvider struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	RedirectURL  string
+	Scopes       []string
+	SubjectClaim string
+	EmailClaim   string
+	TrustEmail   bool
+}
+
+// oauthService signs users in with the authorization code flow and PKCE
+type oauthService struct {
+	UserRepository     model.UserRepository
+	IdentityRepository model.IdentityRepository
+	PasskeyRepository  model.PasskeyRepository
+	TokenRepository    model.TokenRepository
+	SignupPolicy       model.SignupPolicy
+	AuditRepository    model.AuditRepository
+	UserService        model.UserService
+	ProviderConfigs    map[string]OAuthProvider
+	StateExpires       time.Duration
+	HTTPClient         *http.Client
+	EnumerationSafe    bool
+}
+
+// OAuthServiceConfig will hold repositories that will eventually be injected into
+// this service layer
+// With EnumerationSafe, the owner of an existing email a provider account
+// signs up with is notified through the UserService, as on sign-ups
+type OAuthServiceConfig struct {
+	UserRepository     model.UserRepository
+	IdentityRepository model.IdentityRepository
+	PasskeyRepository  model.PasskeyRepository
+	TokenRepository    model.TokenRepository
+	SignupPolicy       model.SignupPolicy
+	AuditRepository    model.AuditRepository
+	UserService        model.UserService
+	Providers          []OAuthProvider
+	StateExpires       time.Duration
+	HTTPClient         *http.Client
+	EnumerationSafe    bool
+}
+
+// NewOAuthService is a factory function for
+// initializing an OAuthService with its repository layer dependencies
+func NewOAuthService(c *OAuthServiceConfig) model.OAuthService {
+	providers := make(map[string]OAuthProvider, len(c.Providers))
+	for _, provider := range c.Providers {
+		if provider.SubjectClaim == "" {
+			provider.SubjectClaim = "sub"
+		}
+		if provider.EmailClaim == "" {
+			provider.EmailClaim = "email"
+		}
+		providers[provider.Name] = provider
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &oauthService{
+		UserRepository:     c.UserRepository,
+		IdentityRepository: c.IdentityRepository,
+		PasskeyRepository:  c.PasskeyRepository,
+		TokenRepository:    c.TokenRepository,
+		SignupPolicy:       c.SignupPolicy,
+		AuditRepository:    c.AuditRepository,
+		UserService:        c.UserService,
+		ProviderConfigs:    providers,
+		StateExpires:       c.StateExpires,
+		HTTPClient:         httpClient,
+		EnumerationSafe:    c.EnumerationSafe,
+	}
+}
+
+// Providers returns the names of the configured providers
+func (s *oauthService) Providers() []string {
+	names := make([]string, 0, len(s.ProviderConfigs))
+	for name := range s.ProviderConfigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// AuthCodeURL starts an authorization request and returns the provider URL
+// to redirect the browser to, with the nonce the browser must present along
+// with the callback's state. A uid starts linking instead of signing in
+func (s *oauthService) AuthCodeURL(ctx context.Context, provider string, uid uuid.UUID) (string, string, error) {
+	p, ok := s.ProviderConfigs[provider]
+	if !ok {
+		return "", "", apperrors.NewNotFound("provider", provider)
+	}
+
+	state, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		log.Printf("Failed to generate oauth state: %v\n", err)
+		return "", "", apperrors.NewInternal()
+	}
+
+	nonce, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		log.Printf("Failed to generate oauth nonce: %v\n", err)
+		return "", "", apperrors.NewInternal()
+	}
+
+	// PKCE - https://datatracker.ietf.org/doc/html/rfc7636#section-4.1
+	verifier, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		log.Printf("Failed to generate oauth code verifier: %v\n", err)
+		return "", "", apperrors.NewInternal()
+	}
+
+	oauthState := &model.OAuthState{
+		Provider:     provider,
+		CodeVerifier: verifier,
+		NonceHash:    hashNonce(nonce),
+		UID:          uid,
+	}
+
+	if err := s.TokenRepository.SetOAuthState(ctx, state, oauthState, s.StateExpires); err != nil {
+		return "", "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+
+	return oauth2Config(p).AuthCodeURL(
+		state,
+		oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	), nonce, nil
+}
+
+// Signin completes a sign-in at a provider and returns the linked user.
+// Unknown provider accounts get a new account without password, unless
+// their email belongs to an existing account, which must link it from /me
+func (s *oauthService) Signin(ctx context.Context, provider string, code string, state string, nonce string) (*model.User, error) {
+	oauthState, err := s.getState(ctx, state, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if oauthState.Provider != provider || oauthState.UID != uuid.Nil {
+		return nil, apperrors.NewAuthorization("Invalid or expired authorization request")
+	}
+
+	providerUser, err := s.exchange(ctx, provider, code, oauthState.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := s.IdentityRepository.Find(ctx, provider, providerUser.Subject)
+	if err == nil {
+		return s.UserRepository.FindByID(ctx, identity.UID)
+	}
+
+	if !isNotFound(err) {
+		return nil, err
+	}
+
+	if providerUser.Email == "" || !providerUser.EmailVerified {
+		return nil, apperrors.NewBadRequest(fmt.Sprintf("%s did not share a verified email address", provider))
+	}
+
+	// linking by email would let anyone who controls the provider
+	// account take over an existing account
+	if _, err := s.UserRepository.FindByEmail(ctx, providerUser.Email); err == nil {
+		return nil, s.emailTaken(ctx, providerUser.Email)
+	}
+
+	user := &model.User{
+		Email: providerUser.Email,
+		Name:  providerUser.Name,
+	}
+
+	invite, err := admit(ctx, s.SignupPolicy, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.UserRepository.Create(ctx, user); err != nil {
+		release(s.SignupPolicy, invite)
+		return nil, err
+	}
+
+	newIdentity := &model.Identity{
+		Provider: provider,
+		Subject:  providerUser.Subject,
+		UID:      user.UID,
+		Email:    providerUser.Email,
+	}
+
+	// an account without its identity could never be signed in to,
+	// and would keep the email from signing up again
+	if err := s.IdentityRepository.Create(ctx, newIdentity); err != nil {
+		if err := s.UserRepository.Delete(ctx, user.UID); err != nil {
+			log.Printf("Failed to delete user: %v after its identity could not be created. Error: %v\n", user.UID, err)
+		}
+
+		release(s.SignupPolicy, invite)
+		return nil, err
+	}
+
+	audit(ctx, s.AuditRepository, &user.UID, model.AuditSignup, model.AuditSuccess, provider)
+	return user, nil
+}
+
+// Link completes a linking request started by the same user
+func (s *oauthService) Link(ctx context.Context, uid uuid.UUID, provider string, code string, state string, nonce string) (*model.Identity, error) {
+	oauthState, err := s.getState(ctx, state, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if oauthState.Provider != provider || oauthState.UID != uid {
+		return nil, apperrors.NewAuthorization("Invalid or expired authorization request")
+	}
+
+	providerUser, err := s.exchange(ctx, provider, code, oauthState.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	// linking upgrades a guest, who takes over the provider's email
+	// under the same rules as a sign-up with the provider
+	var invite *model.Invite
+	if user.Guest {
+		invite, err = s.admitGuest(ctx, user, provider, providerUser)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	identity := &model.Identity{
+		Provider: provider,
+		Subject:  providerUser.Subject,
+		UID:      uid,
+		Email:    providerUser.Email,
+	}
+
+	if err := s.IdentityRepository.Create(ctx, identity); err != nil {
+		release(s.SignupPolicy, invite)
+		return nil, err
+	}
+
+	if user.Guest {
+		if err := s.UserRepository.UpgradeGuest(ctx, user); err != nil {
+			release(s.SignupPolicy, invite)
+			return nil, err
+		}
+
+		audit(ctx, s.AuditRepository, &uid, model.AuditGuestUpgrade, model.AuditSuccess, provider)
+	}
+
+	audit(ctx, s.AuditRepository, &uid, model.AuditIdentityLink, model.AuditSuccess, provider)
+	return identity, nil
+}
+
+// getState uses up a pending authorization request, which must be completed
+// by the browser it was started from, so a callback URL planted in another
+// browser can not sign that browser in to the attacker's provider account
+func (s *oauthService) getState(ctx context.Context, state string, nonce string) (*model.OAuthState, error) {
+	oauthState, err := s.TokenRepository.GetOAuthState(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(oauthState.NonceHash), []byte(hashNonce(nonce))) != 1 {
+		return nil, apperrors.NewAuthorization("Authorization must be completed in the browser it was started from")
+	}
+
+	return oauthState, nil
+}
+
+// admitGuest checks that the provider account of a guest may sign up
+// and sets the email of the guest to the provider's
+func (s *oauthService) admitGuest(ctx context.Context, user *model.User, provider string, providerUser *externalUser) (*model.Invite, error) {
+	if providerUser.Email == "" || !providerUser.EmailVerified {
+		return nil, apperrors.NewBadRequest(fmt.Sprintf("%s did not share a verified email address", provider))
+	}
+
+	if _, err := s.UserRepository.FindByEmail(ctx, providerUser.Email); err == nil {
+		return nil, s.emailTaken(ctx, providerUser.Email)
+	}
+
+	user.Email = providerUser.Email
+	return admit(ctx, s.SignupPolicy, user)
+}
+
+// emailTaken rejects a provider account whose email belongs to an existing
+// account. In enumeration-safe mode the owner of the email is notified, and
+// the conflict is not told apart from a sign-up by the handler
+func (s *oauthService) emailTaken(ctx context.Context, email string) error {
+	if s.EnumerationSafe && s.UserService != nil {
+		if err := s.UserService.NotifySignupAttempt(ctx, email); err != nil {
+			return err
+		}
+	}
+
+	return apperrors.NewConflict("email", email)
+}
+
+// ListIdentities returns the provider accounts linked to a user
+func (s *oauthService) ListIdentities(ctx context.Context, uid uuid.UUID) ([]*model.Identity, error) {
+	return s.IdentityRepository.FindByUID(ctx, uid)
+}
+
+// Unlink removes the provider account of a user, unless the user
+// could no longer sign in without it
+func (s *oauthService) Unlink(ctx context.Context, uid uuid.UUID, provider string) error {
+	otherMethods, err := s.hasOtherSigninMethod(ctx, uid, provider)
+	if err != nil {
+		return err
+	}
+
+	if !otherMethods {
+		return apperrors.NewBadRequest("Set a password, link another provider or add a passkey before unlinking your only sign-in method")
+	}
+
+	if err := s.IdentityRepository.Delete(ctx, uid, provider); err != nil {
+		return err
+	}
+
+	audit(ctx, s.AuditRepository, &uid, model.AuditIdentityUnlink, model.AuditSuccess, provider)
+	return nil
+}
+
+// hasOtherSigninMethod reports whether a user can sign in without
+// the identity at a provider
+func (s *oauthService) hasOtherSigninMethod(ctx context.Context, uid uuid.UUID, provider string) (bool, error) {
+	user, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return false, err
+	}
+
+	if user.Password != "" {
+		return true, nil
+	}
+
+	identities, err := s.IdentityRepository.FindByUID(ctx, uid)
+	if err != nil {
+		return false, err
+	}
+
+	for _, identity := range identities {
+		if identity.Provider != provider {
+			return true, nil
+		}
+	}
+
+	if s.PasskeyRepository == nil {
+		return false, nil
+	}
+
+	credentials, err := s.PasskeyRepository.FindByUID(ctx, uid)
+	if err != nil {
+		return false, err
+	}
+
+	return len(credentials) > 0, nil
+}
+
+// externalUser is the account of a user at a provider
+type externalUser struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// exchange redeems an authorization code and fetches the provider's userinfo
+func (s *oauthService) exchange(ctx context.Context, provider string, code string, verifier string) (*externalUser, error) {
+	p, ok := s.ProviderConfigs[provider]
+	if !ok {
+		return nil, apperrors.NewNotFound("provider", provider)
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, s.HTTPClient)
+	config := oauth2Config(p)
+
+	token, err := config.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", verifier))
+	if err != nil {
+		log.Printf("Failed to exchange %v authorization code: %v\n", provider, err)
+		return nil, apperrors.NewAuthorization("Invalid authorization code")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.UserInfoURL, nil)
+	if err != nil {
+		log.Printf("Invalid %v userinfo URL: %v\n", provider, err)
+		return nil, apperrors.NewInternal()
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := config.Client(ctx, token).Do(req)
+	if err != nil {
+		log.Printf("Failed to get %v userinfo: %v\n", provider, err)
+		return nil, apperrors.NewServiceUnavailable()
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Failed to get %v userinfo: status %v\n", provider, resp.StatusCode)
+		return nil, apperrors.NewServiceUnavailable()
+	}
+
+	var claims map[string]interface{}
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber() // numeric subjects like GitHub's must not become floats
+	if err := dec.Decode(&claims); err != nil {
+		log.Printf("Failed to decode %v userinfo: %v\n", provider, err)
+		return nil, apperrors.NewServiceUnavailable()
+	}
+
+	subject := claimString(claims, p.SubjectClaim)
+	if subject == "" {
+		log.Printf("%v userinfo has no %v claim\n", provider, p.SubjectClaim)
+		return nil, apperrors.NewServiceUnavailable()
+	}
+
+	// emails of providers which do not report email_verified are only trusted if configured
+	emailVerified := p.TrustEmail
+	if verified, ok := claims["email_verified"].(bool); ok {
+		emailVerified = verified
+	}
+
+	return &externalUser{
+		Subject:       subject,
+		Email:         utils.NormalizeEmail(claimString(claims, p.EmailClaim)),
+		EmailVerified: emailVerified,
+		Name:          claimString(claims, "name"),
+	}, nil
+}
+
+func oauth2Config(p OAuthProvider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  p.AuthURL,
+			TokenURL: p.TokenURL,
+		},
+		RedirectURL: p.RedirectURL,
+		Scopes:      p.Scopes,
+	}
+}
+
+func claimString(claims map[string]interface{}, name string) string {
+	switch value := claims[name].(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,415 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeIdP is an OAuth2 provider which issues a code for the claims of
+// the next authorization and checks the PKCE verifier on exchange
+type fakeIdP struct {
+	*httptest.Server
+	mu         sync.Mutex
+	challenges map[string]string
+	claims     map[string]interface{}
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	idp := &fakeIdP{challenges: map[string]string{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+
+		challenge, ok := idp.challenges[r.FormValue("code")]
+		delete(idp.challenges, r.FormValue("code"))
+
+		verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != challenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"accesstoken","token_type":"Bearer","expires_in":3600}`))
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer accesstoken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(idp.claims)
+	})
+
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+
+	return idp
+}
+
+// authorize plays the browser and the user at the authorization endpoint,
+// returning the code and state the provider redirects back with
+func (idp *fakeIdP) authorize(t *testing.T, authCodeURL string) (string, string) {
+	u, err := url.Parse(authCodeURL)
+	assert.NoError(t, err)
+
+	query := u.Query()
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	code := "code" + query.Get("state")[:8]
+	idp.challenges[code] = query.Get("code_challenge")
+
+	return code, query.Get("state")
+}
+
+func (idp *fakeIdP) provider() OAuthProvider {
+	return OAuthProvider{
+		Name:        "fake",
+		ClientID:    "client",
+		AuthURL:     idp.URL + "/authorize",
+		TokenURL:    idp.URL + "/token",
+		UserInfoURL: idp.URL + "/userinfo",
+		RedirectURL: "http://localhost:3000/oauth/fake/callback",
+		Scopes:      []string{"openid", "email"},
+	}
+}
+
+func TestOAuthSignin(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	idp := newFakeIdP(t)
+
+	newService := func(trustEmail bool) (model.OAuthService, *mocks.MockUserRepository, *mocks.MockIdentityRepository, *mocks.MockTokenRepository) {
+		provider := idp.provider()
+		provider.TrustEmail = trustEmail
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockIdentityRepository := new(mocks.MockIdentityRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		oas := NewOAuthService(&OAuthServiceConfig{
+			UserRepository:     mockUserRepository,
+			IdentityRepository: mockIdentityRepository,
+			TokenRepository:    mockTokenRepository,
+			Providers:          []OAuthProvider{provider},
+			StateExpires:       10 * time.Minute,
+		})
+
+		mockTokenRepository.On("SetOAuthState", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*model.OAuthState"), 10*time.Minute).Return(nil)
+
+		return oas, mockUserRepository, mockIdentityRepository, mockTokenRepository
+	}
+
+	// begin runs the redirect to the provider and back
+	begin := func(t *testing.T, oas model.OAuthService, mockTokenRepository *mocks.MockTokenRepository, uid uuid.UUID) (string, string, string) {
+		authCodeURL, nonce, err := oas.AuthCodeURL(context.TODO(), "fake", uid)
+		assert.NoError(t, err)
+
+		code, state := idp.authorize(t, authCodeURL)
+		oauthState := mockTokenRepository.Calls[0].Arguments.Get(2).(*model.OAuthState)
+		mockTokenRepository.On("GetOAuthState", mock.Anything, state).Return(oauthState, nil)
+
+		// the nonce is stored hashed and is not part of the provider URL
+		assert.Equal(t, hashNonce(nonce), oauthState.NonceHash)
+		assert.NotContains(t, authCodeURL, nonce)
+
+		return code, state, nonce
+	}
+
+	t.Run("Linked identity", func(t *testing.T) {
+		oas, mockUserRepository, mockIdentityRepository, mockTokenRepository := newService(false)
+		idp.claims = map[string]interface{}{"sub": "12345", "email": "long@do.com", "email_verified": true}
+
+		mockUser := &model.User{UID: uid, Email: "long@do.com"}
+		mockIdentityRepository.On("Find", mock.Anything, "fake", "12345").Return(&model.Identity{Provider: "fake", Subject: "12345", UID: uid}, nil)
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+
+		code, state, nonce := begin(t, oas, mockTokenRepository, uuid.Nil)
+		user, err := oas.Signin(context.TODO(), "fake", code, state, nonce)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUser, user)
+		mockUserRepository.AssertNotCalled(t, "Create")
+	})
+
+	t.Run("New account", func(t *testing.T) {
+		oas, mockUserRepository, mockIdentityRepository, mockTokenRepository := newService(true)
+		// numeric subjects as GitHub's must keep all digits, and GitHub
+		// does not report email_verified
+		idp.claims = map[string]interface{}{"sub": 9007199254740993, "email": "new@do.com", "name": "New"}
+
+		mockIdentityRepository.On("Find", mock.Anything, "fake", "9007199254740993").Return(nil, apperrors.NewNotFound("identity", "fake"))
+		mockUserRepository.On("FindByEmail", mock.Anything, "new@do.com").Return(nil, apperrors.NewNotFound("email", "new@do.com"))
+		mockUserRepository.
+			On("Create", mock.Anything, &model.User{Email: "new@do.com", Name: "New"}).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(*model.User).UID = uid
+			}).
+			Return(nil)
+		mockIdentityRepository.On("Create", mock.Anything, &model.Identity{Provider: "fake", Subject: "9007199254740993", UID: uid, Email: "new@do.com"}).Return(nil)
+
+		code, state, nonce := begin(t, oas, mockTokenRepository, uuid.Nil)
+		user, err := oas.Signin(context.TODO(), "fake", code, state, nonce)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, user.UID)
+		mockIdentityRepository.AssertExpectations(t)
+	})
+
+	t.Run("New account is removed when its identity fails", func(t *testing.T) {
+		oas, mockUserRepository, mockIdentityRepository, mockTokenRepository := newService(true)
+		idp.claims = map[string]interface{}{"sub": "24680", "email": "new@do.com"}
+
+		mockIdentityRepository.On("Find", mock.Anything, "fake", "24680").Return(nil, apperrors.NewNotFound("identity", "fake"))
+		mockUserRepository.On("FindByEmail", mock.Anything, "new@do.com").Return(nil, apperrors.NewNotFound("email", "new@do.com"))
+		mockUserRepository.
+			On("Create", mock.Anything, &model.User{Email: "new@do.com"}).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(*model.User).UID = uid
+			}).
+			Return(nil)
+		mockIdentityRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.Identity")).Return(apperrors.NewInternal())
+		mockUserRepository.On("Delete", mock.Anything, uid).Return(nil)
+
+		code, state, nonce := begin(t, oas, mockTokenRepository, uuid.Nil)
+		user, err := oas.Signin(context.TODO(), "fake", code, state, nonce)
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.Internal, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertCalled(t, "Delete", mock.Anything, uid)
+	})
+
+	t.Run("Email of an existing account", func(t *testing.T) {
+		oas, mockUserRepository, mockIdentityRepository, mockTokenRepository := newService(false)
+		// the domain is normalized, so its case does not make another account
+		idp.claims = map[string]interface{}{"sub": "67890", "email": "long@DO.com", "email_verified": true}
+
+		mockIdentityRepository.On("Find", mock.Anything, "fake", "67890").Return(nil, apperrors.NewNotFound("identity", "fake"))
+		mockUserRepository.On("FindByEmail", mock.Anything, "long@do.com").Return(&model.User{UID: uid, Email: "long@do.com"}, nil)
+
+		code, state, nonce := begin(t, oas, mockTokenRepository, uuid.Nil)
+		user, err := oas.Signin(context.TODO(), "fake", code, state, nonce)
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.Conflict, err.(*apperrors.Error).Type)
+		mockIdentityRepository.AssertNotCalled(t, "Create")
+	})
+
+	t.Run("Email of an existing account in enumeration-safe mode", func(t *testing.T) {
+		oas, mockUserRepository, mockIdentityRepository, mockTokenRepository := newService(false)
+		mockUserService := new(mocks.MockUserService)
+		oas.(*oauthService).UserService = mockUserService
+		oas.(*oauthService).EnumerationSafe = true
+		idp.claims = map[string]interface{}{"sub": "67890", "email": "long@do.com", "email_verified": true}
+
+		mockIdentityRepository.On("Find", mock.Anything, "fake", "67890").Return(nil, apperrors.NewNotFound("identity", "fake"))
+		mockUserRepository.On("FindByEmail", mock.Anything, "long@do.com").Return(&model.User{UID: uid, Email: "long@do.com"}, nil)
+		mockUserService.On("NotifySignupAttempt", mock.Anything, "long@do.com").Return(nil)
+
+		code, state, nonce := begin(t, oas, mockTokenRepository, uuid.Nil)
+		user, err := oas.Signin(context.TODO(), "fake", code, state, nonce)
+
+		// the handler answers the conflict like a sign-up
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.Conflict, err.(*apperrors.Error).Type)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Unverified email", func(t *testing.T) {
+		oas, mockUserRepository, mockIdentityRepository, mockTokenRepository := newService(false)
+		idp.claims = map[string]interface{}{"sub": "67890", "email": "long@do.com", "email_verified": false}
+
+		mockIdentityRepository.On("Find", mock.Anything, "fake", "67890").Return(nil, apperrors.NewNotFound("identity", "fake"))
+
+		code, state, nonce := begin(t, oas, mockTokenRepository, uuid.Nil)
+		user, err := oas.Signin(context.TODO(), "fake", code, state, nonce)
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "Create")
+	})
+
+	t.Run("Email not reported verified", func(t *testing.T) {
+		oas, mockUserRepository, mockIdentityRepository, mockTokenRepository := newService(false)
+		idp.claims = map[string]interface{}{"sub": "67890", "email": "new@do.com"}
+
+		mockIdentityRepository.On("Find", mock.Anything, "fake", "67890").Return(nil, apperrors.NewNotFound("identity", "fake"))
+
+		code, state, nonce := begin(t, oas, mockTokenRepository, uuid.Nil)
+		user, err := oas.Signin(context.TODO(), "fake", code, state, nonce)
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "Create")
+	})
+
+	t.Run("Other browser", func(t *testing.T) {
+		oas, _, mockIdentityRepository, mockTokenRepository := newService(false)
+
+		code, state, _ := begin(t, oas, mockTokenRepository, uuid.Nil)
+		user, err := oas.Signin(context.TODO(), "fake", code, state, "othernonce")
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockIdentityRepository.AssertNotCalled(t, "Find")
+	})
+
+	t.Run("Code without verifier", func(t *testing.T) {
+		oas, _, mockIdentityRepository, mockTokenRepository := newService(false)
+
+		_, state, nonce := begin(t, oas, mockTokenRepository, uuid.Nil)
+		user, err := oas.Signin(context.TODO(), "fake", "stolencode", state, nonce)
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockIdentityRepository.AssertNotCalled(t, "Find")
+	})
+
+	t.Run("Linking state can not sign in", func(t *testing.T) {
+		oas, _, mockIdentityRepository, mockTokenRepository := newService(false)
+
+		code, state, nonce := begin(t, oas, mockTokenRepository, uid)
+		user, err := oas.Signin(context.TODO(), "fake", code, state, nonce)
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockIdentityRepository.AssertNotCalled(t, "Find")
+	})
+
+	t.Run("Link", func(t *testing.T) {
+		oas, mockUserRepository, mockIdentityRepository, mockTokenRepository := newService(false)
+		idp.claims = map[string]interface{}{"sub": "12345", "email": "other@do.com"}
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Email: "long@do.com"}, nil)
+		mockIdentityRepository.On("Create", mock.Anything, &model.Identity{Provider: "fake", Subject: "12345", UID: uid, Email: "other@do.com"}).Return(nil)
+
+		code, state, nonce := begin(t, oas, mockTokenRepository, uid)
+		identity, err := oas.Link(context.TODO(), uid, "fake", code, state, nonce)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "12345", identity.Subject)
+		mockIdentityRepository.AssertExpectations(t)
+	})
+
+	t.Run("Link upgrades guest", func(t *testing.T) {
+		oas, mockUserRepository, mockIdentityRepository, mockTokenRepository := newService(false)
+		idp.claims = map[string]interface{}{"sub": "12345", "email": "guest@do.com", "email_verified": true}
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Guest: true}, nil)
+		mockUserRepository.On("FindByEmail", mock.Anything, "guest@do.com").Return(nil, apperrors.NewNotFound("email", "guest@do.com"))
+		mockIdentityRepository.On("Create", mock.Anything, &model.Identity{Provider: "fake", Subject: "12345", UID: uid, Email: "guest@do.com"}).Return(nil)
+		mockUserRepository.On("UpgradeGuest", mock.Anything, mock.MatchedBy(func(u *model.User) bool {
+			return u.UID == uid && u.Email == "guest@do.com" && u.Password == ""
+		})).Return(nil)
+
+		code, state, nonce := begin(t, oas, mockTokenRepository, uid)
+		_, err := oas.Link(context.TODO(), uid, "fake", code, state, nonce)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Guest link without verified email", func(t *testing.T) {
+		oas, mockUserRepository, mockIdentityRepository, mockTokenRepository := newService(false)
+		idp.claims = map[string]interface{}{"sub": "12345", "email": "guest@do.com", "email_verified": false}
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Guest: true}, nil)
+
+		code, state, nonce := begin(t, oas, mockTokenRepository, uid)
+		_, err := oas.Link(context.TODO(), uid, "fake", code, state, nonce)
+
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockIdentityRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown provider", func(t *testing.T) {
+		oas, _, _, mockTokenRepository := newService(false)
+
+		authCodeURL, _, err := oas.AuthCodeURL(context.TODO(), "unknown", uuid.Nil)
+
+		assert.Empty(t, authCodeURL)
+		assert.Equal(t, apperrors.NotFound, err.(*apperrors.Error).Type)
+		mockTokenRepository.AssertNotCalled(t, "SetOAuthState")
+	})
+}
+
+func TestOAuthUnlink(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	newService := func(mockUser *model.User, identities []*model.Identity, credentials []*model.PasskeyCredential) (model.OAuthService, *mocks.MockIdentityRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockIdentityRepository := new(mocks.MockIdentityRepository)
+		mockPasskeyRepository := new(mocks.MockPasskeyRepository)
+		oas := NewOAuthService(&OAuthServiceConfig{
+			UserRepository:     mockUserRepository,
+			IdentityRepository: mockIdentityRepository,
+			PasskeyRepository:  mockPasskeyRepository,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+		mockIdentityRepository.On("FindByUID", mock.Anything, uid).Return(identities, nil)
+		mockIdentityRepository.On("Delete", mock.Anything, uid, "github").Return(nil)
+		mockPasskeyRepository.On("FindByUID", mock.Anything, uid).Return(credentials, nil)
+
+		return oas, mockIdentityRepository
+	}
+
+	github := &model.Identity{Provider: "github", UID: uid}
+	google := &model.Identity{Provider: "google", UID: uid}
+
+	t.Run("With a password", func(t *testing.T) {
+		oas, mockIdentityRepository := newService(&model.User{UID: uid, Password: "hashed"}, []*model.Identity{github}, nil)
+
+		err := oas.Unlink(context.TODO(), uid, "github")
+
+		assert.NoError(t, err)
+		mockIdentityRepository.AssertCalled(t, "Delete", mock.Anything, uid, "github")
+	})
+
+	t.Run("With another identity", func(t *testing.T) {
+		oas, mockIdentityRepository := newService(&model.User{UID: uid}, []*model.Identity{github, google}, nil)
+
+		err := oas.Unlink(context.TODO(), uid, "github")
+
+		assert.NoError(t, err)
+		mockIdentityRepository.AssertCalled(t, "Delete", mock.Anything, uid, "github")
+	})
+
+	t.Run("With a passkey", func(t *testing.T) {
+		oas, mockIdentityRepository := newService(&model.User{UID: uid}, []*model.Identity{github}, []*model.PasskeyCredential{{UID: uid}})
+
+		err := oas.Unlink(context.TODO(), uid, "github")
+
+		assert.NoError(t, err)
+		mockIdentityRepository.AssertCalled(t, "Delete", mock.Anything, uid, "github")
+	})
+
+	t.Run("Last sign-in method", func(t *testing.T) {
+		oas, mockIdentityRepository := newService(&model.User{UID: uid}, []*model.Identity{github}, []*model.PasskeyCredential{})
+
+		err := oas.Unlink(context.TODO(), uid, "github")
+
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockIdentityRepository.AssertNotCalled(t, "Delete", mock.Anything, uid, "github")
+	})
+}
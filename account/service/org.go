@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/utils"
+
+	"github.com/google/uuid"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// orgService manages organizations, their members and invites
+type orgService struct {
+	OrgRepository   model.OrgRepository
+	UserRepository  model.UserRepository
+	AuditRepository model.AuditRepository
+	Mailer          model.Mailer
+	InviteURL       string
+	InviteExpires   time.Duration
+}
+
+// OrgServiceConfig will hold repositories that will eventually be injected into
+// this service layer
+// InviteURL is the client page which posts the token of an invite link to the API
+type OrgServiceConfig struct {
+	OrgRepository   model.OrgRepository
+	UserRepository  model.UserRepository
+	AuditRepository model.AuditRepository
+	Mailer          model.Mailer
+	InviteURL       string
+	InviteExpires   time.Duration
+}
+
+// NewOrgService is a factory function for
+// initializing an OrgService with its repository layer dependencies
+func NewOrgService(c *OrgServiceConfig) model.OrgService {
+	return &orgService{
+		OrgRepository:   c.OrgRepository,
+		UserRepository:  c.UserRepository,
+		AuditRepository: c.AuditRepository,
+		Mailer:          c.Mailer,
+		InviteURL:       c.InviteURL,
+		InviteExpires:   c.InviteExpires,
+	}
+}
+
+// Create starts an organization owned by the user
+func (s *orgService) Create(ctx context.Context, uid uuid.UUID, name string) (*model.Organization, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, apperrors.NewBadRequest("An organization needs a name")
+	}
+
+	org := &model.Organization{Name: name}
+	if err := s.OrgRepository.Create(ctx, org, uid); err != nil {
+		return nil, err
+	}
+
+	audit(ctx, s.AuditRepository, &uid, model.AuditOrgJoin, model.AuditSuccess, orgDetail(org.ID, model.OrgRoleOwner))
+	return org, nil
+}
+
+// List returns the organizations of a user
+func (s *orgService) List(ctx context.Context, uid uuid.UUID) ([]*model.Organization, error) {
+	return s.OrgRepository.FindByUID(ctx, uid)
+}
+
+// Members returns the members of an organization to one of them
+func (s *orgService) Members(ctx context.Context, uid uuid.UUID, orgID uuid.UUID) ([]*model.OrgMember, error) {
+	if _, err := s.member(ctx, orgID, uid); err != nil {
+		return nil, err
+	}
+
+	return s.OrgRepository.FindMembers(ctx, orgID)
+}
+
+// Invite mails an invite to join an organization with a role
+// Admins invite admins and members, only owners invite owners
+func (s *orgService) Invite(ctx context.Context, actor uuid.UUID, orgID uuid.UUID, email string, role string) (*model.OrgInvite, error) {
+	if !model.ValidOrgRole(role) {
+		return nil, apperrors.NewBadRequest("Invalid organization role")
+	}
+
+	actorMember, err := s.manager(ctx, orgID, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	if !model.OrgRoleAtLeast(actorMember.Role, role) {
+		return nil, apperrors.NewForbidden("Only owners may invite owners")
+	}
+
+	org, err := s.OrgRepository.FindByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		log.Printf("Failed to generate organization invite token: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	invite := &model.OrgInvite{
+		OrgID:     orgID,
+		Email:     utils.NormalizeEmail(email),
+		Role:      role,
+		Token:     token,
+		TokenHash: hashOrgInviteToken(token),
+		InvitedBy: &actor,
+		ExpiresAt: time.Now().Add(s.InviteExpires),
+	}
+
+	if err := s.OrgRepository.CreateInvite(ctx, invite); err != nil {
+		return nil, err
+	}
+
+	inviteURL, err := url.Parse(s.InviteURL)
+	if err != nil {
+		log.Printf("Invalid organization invite URL: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	query := inviteURL.Query()
+	query.Set("token", token)
+	inviteURL.RawQuery = query.Encode()
+
+	inviter := actorMember.Name
+	if inviter == "" {
+		inviter = actorMember.Email
+	}
+
+	if err := s.Mailer.Send(ctx, &model.Email{
+		To:      invite.Email,
+		Subject: fmt.Sprintf("You are invited to join %s", org.Name),
+		Body: fmt.Sprintf(
+			"%s invited you to join %s as %s. Accept the invite with this link, which expires in %d hours:\n\n%s\n\nSign up first with this email if you do not have an account yet.",
+			inviter,
+			org.Name,
+			role,
+			int(s.InviteExpires.Hours()),
+			inviteURL.String(),
+		),
+	}); err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+// AcceptInvite makes the user a member of the organization of an invite
+// Invites are only accepted by the user of the invited email
+func (s *orgService) AcceptInvite(ctx context.Context, uid uuid.UUID, token string) (*model.Organization, error) {
+	invite, err := s.OrgRepository.FindInvite(ctx, hashOrgInviteToken(token))
+	if err != nil {
+		if isNotFound(err) {
+			return nil, apperrors.NewBadRequest("Invalid or expired invite")
+		}
+		return nil, err
+	}
+
+	if time.Now().After(invite.ExpiresAt) {
+		return nil, apperrors.NewBadRequest("Invalid or expired invite")
+	}
+
+	user, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(utils.NormalizeEmail(user.Email), invite.Email) {
+		return nil, apperrors.NewForbidden("The invite is for another email")
+	}
+
+	member, err := s.OrgRepository.AcceptInvite(ctx, invite.ID, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := s.OrgRepository.FindByID(ctx, invite.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	org.Role = member.Role
+
+	auditAs(ctx, s.AuditRepository, invite.InvitedBy, &uid, model.AuditOrgJoin, model.AuditSuccess, orgDetail(org.ID, member.Role))
+	return org, nil
+}
+
+// RemoveMember takes a user out of an organization on behalf of the actor
+// Members may leave by themselves, admins remove admins and members and
+// owners remove anyone. The last owner stays, so every organization has one
+func (s *orgService) RemoveMember(ctx context.Context, actor uuid.UUID, orgID uuid.UUID, uid uuid.UUID) error {
+	target, err := s.member(ctx, orgID, uid)
+	if err != nil {
+		return err
+	}
+
+	if actor != uid {
+		actorMember, err := s.manager(ctx, orgID, actor)
+		if err != nil {
+			return err
+		}
+
+		if !model.OrgRoleAtLeast(actorMember.Role, target.Role) {
+			return apperrors.NewForbidden("Only owners may remove owners")
+		}
+	}
+
+	if err := s.keepLastOwner(ctx, target); err != nil {
+		return err
+	}
+
+	if err := s.OrgRepository.RemoveMember(ctx, orgID, uid); err != nil {
+		return err
+	}
+
+	auditAs(ctx, s.AuditRepository, &actor, &uid, model.AuditOrgLeave, model.AuditSuccess, orgDetail(orgID, target.Role))
+	return nil
+}
+
+// SetMemberRole changes the role of a member on behalf of the actor
+// Admins change roles between admin and member, only owners grant or take ownership
+func (s *orgService) SetMemberRole(ctx context.Context, actor uuid.UUID, orgID uuid.UUID, uid uuid.UUID, role string) error {
+	if !model.ValidOrgRole(role) {
+		return apperrors.NewBadRequest("Invalid organization role")
+	}
+
+	actorMember, err := s.manager(ctx, orgID, actor)
+	if err != nil {
+		return err
+	}
+
+	target, err := s.member(ctx, orgID, uid)
+	if err != nil {
+		return err
+	}
+
+	if !model.OrgRoleAtLeast(actorMember.Role, target.Role) || !model.OrgRoleAtLeast(actorMember.Role, role) {
+		return apperrors.NewForbidden("Only owners may grant or take ownership")
+	}
+
+	if target.Role == role {
+		return nil
+	}
+
+	if err := s.keepLastOwner(ctx, target); err != nil {
+		return err
+	}
+
+	if err := s.OrgRepository.UpdateMemberRole(ctx, orgID, uid, role); err != nil {
+		return err
+	}
+
+	auditAs(ctx, s.AuditRepository, &actor, &uid, model.AuditOrgRoleChange, model.AuditSuccess, orgDetail(orgID, role))
+	return nil
+}
+
+// Switch sets the organization the user acts for, nil to act for nobody but themselves
+// Access tokens carry the organization once the user refreshes them
+func (s *orgService) Switch(ctx context.Context, uid uuid.UUID, orgID *uuid.UUID) (*model.User, error) {
+	if orgID != nil {
+		if _, err := s.member(ctx, *orgID, uid); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.UserRepository.UpdateActiveOrg(ctx, uid, orgID)
+}
+
+// member fetches the membership of a user, organizations of other users are NotFound
+// so their existence is not leaked
+func (s *orgService) member(ctx context.Context, orgID uuid.UUID, uid uuid.UUID) (*model.OrgMember, error) {
+	member, err := s.OrgRepository.FindMember(ctx, orgID, uid)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, apperrors.NewNotFound("organization", orgID.String())
+		}
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// manager fetches the membership of a user who manages the members of an organization
+func (s *orgService) manager(ctx context.Context, orgID uuid.UUID, uid uuid.UUID) (*model.OrgMember, error) {
+	member, err := s.member(ctx, orgID, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !model.OrgRoleAtLeast(member.Role, model.OrgRoleAdmin) {
+		return nil, apperrors.NewForbidden("Only owners and admins may manage members")
+	}
+
+	return member, nil
+}
+
+// keepLastOwner refuses to take ownership from the last owner of an organization
+func (s *orgService) keepLastOwner(ctx context.Context, member *model.OrgMember) error {
+	if member.Role != model.OrgRoleOwner {
+		return nil
+	}
+
+	count, err := s.OrgRepository.CountOwners(ctx, member.OrgID)
+	if err != nil {
+		return err
+	}
+
+	if count <= 1 {
+		return apperrors.NewBadRequest("The last owner cannot leave the organization or be demoted")
+	}
+
+	return nil
+}
+
+// orgDetail describes an organization role for audit events
+func orgDetail(orgID uuid.UUID, role string) string {
+	return fmt.Sprintf("%v (%v)", orgID, role)
+}
+
+// hashOrgInviteToken hashes an organization invite token for storage
+func hashOrgInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
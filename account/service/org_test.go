@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOrgInvite(t *testing.T) {
+	actor, _ := uuid.NewRandom()
+	orgID, _ := uuid.NewRandom()
+
+	newService := func(mockOrgRepository *mocks.MockOrgRepository, mockMailer *mocks.MockMailer) model.OrgService {
+		return NewOrgService(&OrgServiceConfig{
+			OrgRepository: mockOrgRepository,
+			Mailer:        mockMailer,
+			InviteURL:     "http://localhost:3000/orgs/join",
+			InviteExpires: 24 * time.Hour,
+		})
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockOrgRepository := new(mocks.MockOrgRepository)
+		mockMailer := new(mocks.MockMailer)
+
+		mockOrgRepository.On("FindMember", mock.Anything, orgID, actor).Return(&model.OrgMember{OrgID: orgID, UID: actor, Role: model.OrgRoleAdmin, Name: "Alice"}, nil)
+		mockOrgRepository.On("FindByID", mock.Anything, orgID).Return(&model.Organization{ID: orgID, Name: "Word Club"}, nil)
+		mockOrgRepository.On("CreateInvite", mock.Anything, mock.MatchedBy(func(i *model.OrgInvite) bool {
+			return i.OrgID == orgID && i.Email == "Bob@bob.com" && i.Role == model.OrgRoleMember && i.TokenHash == hashOrgInviteToken(i.Token)
+		})).Return(nil)
+		mockMailer.On("Send", mock.Anything, mock.MatchedBy(func(e *model.Email) bool {
+			return e.To == "Bob@bob.com" && strings.Contains(e.Body, "http://localhost:3000/orgs/join?token=")
+		})).Return(nil)
+
+		invite, err := newService(mockOrgRepository, mockMailer).Invite(context.TODO(), actor, orgID, " Bob@BOB.com", model.OrgRoleMember)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, invite.Token)
+		mockOrgRepository.AssertExpectations(t)
+		mockMailer.AssertExpectations(t)
+	})
+
+	t.Run("Members may not invite", func(t *testing.T) {
+		mockOrgRepository := new(mocks.MockOrgRepository)
+		mockMailer := new(mocks.MockMailer)
+
+		mockOrgRepository.On("FindMember", mock.Anything, orgID, actor).Return(&model.OrgMember{OrgID: orgID, UID: actor, Role: model.OrgRoleMember}, nil)
+
+		_, err := newService(mockOrgRepository, mockMailer).Invite(context.TODO(), actor, orgID, "bob@bob.com", model.OrgRoleMember)
+
+		assert.Equal(t, apperrors.Forbidden, err.(*apperrors.Error).Type)
+		mockOrgRepository.AssertNotCalled(t, "CreateInvite", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Admins may not invite owners", func(t *testing.T) {
+		mockOrgRepository := new(mocks.MockOrgRepository)
+		mockMailer := new(mocks.MockMailer)
+
+		mockOrgRepository.On("FindMember", mock.Anything, orgID, actor).Return(&model.OrgMember{OrgID: orgID, UID: actor, Role: model.OrgRoleAdmin}, nil)
+
+		_, err := newService(mockOrgRepository, mockMailer).Invite(context.TODO(), actor, orgID, "bob@bob.com", model.OrgRoleOwner)
+
+		assert.Equal(t, apperrors.Forbidden, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("Non-members do not see the organization", func(t *testing.T) {
+		mockOrgRepository := new(mocks.MockOrgRepository)
+		mockMailer := new(mocks.MockMailer)
+
+		mockOrgRepository.On("FindMember", mock.Anything, orgID, actor).Return(nil, apperrors.NewNotFound("member", actor.String()))
+
+		_, err := newService(mockOrgRepository, mockMailer).Invite(context.TODO(), actor, orgID, "bob@bob.com", model.OrgRoleMember)
+
+		assert.Equal(t, apperrors.NotFound, err.(*apperrors.Error).Type)
+	})
+}
+
+func TestOrgAcceptInvite(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	inviterID, _ := uuid.NewRandom()
+	orgID, _ := uuid.NewRandom()
+	inviteID, _ := uuid.NewRandom()
+
+	newInvite := func(expiresAt time.Time) *model.OrgInvite {
+		return &model.OrgInvite{ID: inviteID, OrgID: orgID, Email: "bob@bob.com", Role: model.OrgRoleAdmin, InvitedBy: &inviterID, ExpiresAt: expiresAt}
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockOrgRepository := new(mocks.MockOrgRepository)
+		mockUserRepository := new(mocks.MockUserRepository)
+		os := NewOrgService(&OrgServiceConfig{
+			OrgRepository:  mockOrgRepository,
+			UserRepository: mockUserRepository,
+		})
+
+		mockOrgRepository.On("FindInvite", mock.Anything, hashOrgInviteToken("token")).Return(newInvite(time.Now().Add(time.Hour)), nil)
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Email: "Bob@bob.com"}, nil)
+		mockOrgRepository.On("AcceptInvite", mock.Anything, inviteID, uid).Return(&model.OrgMember{OrgID: orgID, UID: uid, Role: model.OrgRoleAdmin}, nil)
+		mockOrgRepository.On("FindByID", mock.Anything, orgID).Return(&model.Organization{ID: orgID, Name: "Word Club"}, nil)
+
+		org, err := os.AcceptInvite(context.TODO(), uid, "token")
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.OrgRoleAdmin, org.Role)
+		mockOrgRepository.AssertExpectations(t)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		mockOrgRepository := new(mocks.MockOrgRepository)
+		os := NewOrgService(&OrgServiceConfig{
+			OrgRepository: mockOrgRepository,
+		})
+
+		mockOrgRepository.On("FindInvite", mock.Anything, hashOrgInviteToken("token")).Return(newInvite(time.Now().Add(-time.Hour)), nil)
+
+		_, err := os.AcceptInvite(context.TODO(), uid, "token")
+
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockOrgRepository.AssertNotCalled(t, "AcceptInvite", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Other email", func(t *testing.T) {
+		mockOrgRepository := new(mocks.MockOrgRepository)
+		mockUserRepository := new(mocks.MockUserRepository)
+		os := NewOrgService(&OrgServiceConfig{
+			OrgRepository:  mockOrgRepository,
+			UserRepository: mockUserRepository,
+		})
+
+		mockOrgRepository.On("FindInvite", mock.Anything, hashOrgInviteToken("token")).Return(newInvite(time.Now().Add(time.Hour)), nil)
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Email: "eve@bob.com"}, nil)
+
+		_, err := os.AcceptInvite(context.TODO(), uid, "token")
+
+		assert.Equal(t, apperrors.Forbidden, err.(*apperrors.Error).Type)
+		mockOrgRepository.AssertNotCalled(t, "AcceptInvite", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestOrgMembers(t *testing.T) {
+	owner, _ := uuid.NewRandom()
+	admin, _ := uuid.NewRandom()
+	member, _ := uuid.NewRandom()
+	orgID, _ := uuid.NewRandom()
+
+	newService := func() (model.OrgService, *mocks.MockOrgRepository) {
+		mockOrgRepository := new(mocks.MockOrgRepository)
+		mockOrgRepository.On("FindMember", mock.Anything, orgID, owner).Return(&model.OrgMember{OrgID: orgID, UID: owner, Role: model.OrgRoleOwner}, nil)
+		mockOrgRepository.On("FindMember", mock.Anything, orgID, admin).Return(&model.OrgMember{OrgID: orgID, UID: admin, Role: model.OrgRoleAdmin}, nil)
+		mockOrgRepository.On("FindMember", mock.Anything, orgID, member).Return(&model.OrgMember{OrgID: orgID, UID: member, Role: model.OrgRoleMember}, nil)
+
+		return NewOrgService(&OrgServiceConfig{
+			OrgRepository: mockOrgRepository,
+		}), mockOrgRepository
+	}
+
+	t.Run("Admin removes member", func(t *testing.T) {
+		os, mockOrgRepository := newService()
+		mockOrgRepository.On("RemoveMember", mock.Anything, orgID, member).Return(nil)
+
+		err := os.RemoveMember(context.TODO(), admin, orgID, member)
+
+		assert.NoError(t, err)
+		mockOrgRepository.AssertCalled(t, "RemoveMember", mock.Anything, orgID, member)
+	})
+
+	t.Run("Member leaves", func(t *testing.T) {
+		os, mockOrgRepository := newService()
+		mockOrgRepository.On("RemoveMember", mock.Anything, orgID, member).Return(nil)
+
+		err := os.RemoveMember(context.TODO(), member, orgID, member)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Admin may not remove owner", func(t *testing.T) {
+		os, mockOrgRepository := newService()
+
+		err := os.RemoveMember(context.TODO(), admin, orgID, owner)
+
+		assert.Equal(t, apperrors.Forbidden, err.(*apperrors.Error).Type)
+		mockOrgRepository.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Last owner stays", func(t *testing.T) {
+		os, mockOrgRepository := newService()
+		mockOrgRepository.On("CountOwners", mock.Anything, orgID).Return(int64(1), nil)
+
+		err := os.RemoveMember(context.TODO(), owner, orgID, owner)
+
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockOrgRepository.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Owner promotes admin", func(t *testing.T) {
+		os, mockOrgRepository := newService()
+		mockOrgRepository.On("UpdateMemberRole", mock.Anything, orgID, admin, model.OrgRoleOwner).Return(nil)
+
+		err := os.SetMemberRole(context.TODO(), owner, orgID, admin, model.OrgRoleOwner)
+
+		assert.NoError(t, err)
+		mockOrgRepository.AssertCalled(t, "UpdateMemberRole", mock.Anything, orgID, admin, model.OrgRoleOwner)
+	})
+
+	t.Run("Admin may not grant ownership", func(t *testing.T) {
+		os, mockOrgRepository := newService()
+
+		err := os.SetMemberRole(context.TODO(), admin, orgID, member, model.OrgRoleOwner)
+
+		assert.Equal(t, apperrors.Forbidden, err.(*apperrors.Error).Type)
+		mockOrgRepository.AssertNotCalled(t, "UpdateMemberRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestOrgSwitch(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	orgID, _ := uuid.NewRandom()
+
+	t.Run("Member", func(t *testing.T) {
+		mockOrgRepository := new(mocks.MockOrgRepository)
+		mockUserRepository := new(mocks.MockUserRepository)
+		os := NewOrgService(&OrgServiceConfig{
+			OrgRepository:  mockOrgRepository,
+			UserRepository: mockUserRepository,
+		})
+
+		mockOrgRepository.On("FindMember", mock.Anything, orgID, uid).Return(&model.OrgMember{OrgID: orgID, UID: uid, Role: model.OrgRoleMember}, nil)
+		mockUserRepository.On("UpdateActiveOrg", mock.Anything, uid, &orgID).Return(&model.User{UID: uid, ActiveOrgID: &orgID}, nil)
+
+		user, err := os.Switch(context.TODO(), uid, &orgID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &orgID, user.ActiveOrgID)
+	})
+
+	t.Run("Not a member", func(t *testing.T) {
+		mockOrgRepository := new(mocks.MockOrgRepository)
+		mockUserRepository := new(mocks.MockUserRepository)
+		os := NewOrgService(&OrgServiceConfig{
+			OrgRepository:  mockOrgRepository,
+			UserRepository: mockUserRepository,
+		})
+
+		mockOrgRepository.On("FindMember", mock.Anything, orgID, uid).Return(nil, apperrors.NewNotFound("member", uid.String()))
+
+		_, err := os.Switch(context.TODO(), uid, &orgID)
+
+		assert.Equal(t, apperrors.NotFound, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "UpdateActiveOrg", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Personal", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		os := NewOrgService(&OrgServiceConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.On("UpdateActiveOrg", mock.Anything, uid, (*uuid.UUID)(nil)).Return(&model.User{UID: uid}, nil)
+
+		user, err := os.Switch(context.TODO(), uid, nil)
+
+		assert.NoError(t, err)
+		assert.Nil(t, user.ActiveOrgID)
+	})
+}
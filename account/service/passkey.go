@@ -0,0 +1,346 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/utils"
+
+	"github.com/google/uuid"
+	"log"
+	"time"
+)
+
+// passkeyCredentialType is the only WebAuthn credential type
+const passkeyCredentialType = "public-key"
+
+// passkeyService acts as a WebAuthn relying party, it keeps ceremony
+// challenges in the token repository between begin and finish
+type passkeyService struct {
+	UserRepository    model.UserRepository
+	PasskeyRepository model.PasskeyRepository
+	TokenRepository   model.TokenRepository
+	AuditRepository   model.AuditRepository
+	RPID              string
+	RPName            string
+	RPOrigins         []string
+	Timeout           time.Duration
+}
+
+// PasskeyServiceConfig will hold repositories that will eventually be injected into
+// this service layer
+type PasskeyServiceConfig struct {
+	UserRepository    model.UserRepository
+	PasskeyRepository model.PasskeyRepository
+	TokenRepository   model.TokenRepository
+	AuditRepository   model.AuditRepository
+	RPID              string
+	RPName            string
+	RPOrigins         []string
+	Timeout           time.Duration
+}
+
+// NewPasskeyService is a factory function for
+// initializing a PasskeyService with its repository layer dependencies
+func NewPasskeyService(c *PasskeyServiceConfig) model.PasskeyService {
+	return &passkeyService{
+		UserRepository:    c.UserRepository,
+		PasskeyRepository: c.PasskeyRepository,
+		TokenRepository:   c.TokenRepository,
+		AuditRepository:   c.AuditRepository,
+		RPID:              c.RPID,
+		RPName:            c.RPName,
+		RPOrigins:         c.RPOrigins,
+		Timeout:           c.Timeout,
+	}
+}
+
+// BeginRegistration creates the options for navigator.credentials.create
+// The returned session ID must be sent back with the new credential
+func (s *passkeyService) BeginRegistration(ctx context.Context, uid uuid.UUID) (string, *model.PasskeyCreationOptions, error) {
+	user, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return "", nil, err
+	}
+
+	existing, err := s.PasskeyRepository.FindByUID(ctx, uid)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sessionID, challenge, err := s.newSession(ctx, uid, model.WebAuthnCreate)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// the user handle is the uid, so it identifies the user on sign-in
+	// with a discoverable credential without revealing the email
+	uidBytes, _ := uid.MarshalBinary()
+
+	return sessionID, &model.PasskeyCreationOptions{
+		Challenge: challenge,
+		RP: model.RelyingParty{
+			ID:   s.RPID,
+			Name: s.RPName,
+		},
+		User: model.PasskeyUser{
+			ID:          uidBytes,
+			Name:        user.Email,
+			DisplayName: user.Name,
+		},
+		PubKeyCredParams: []model.PubKeyCredParam{
+			{Type: passkeyCredentialType, Alg: utils.COSEAlgES256},
+			{Type: passkeyCredentialType, Alg: utils.COSEAlgEdDSA},
+			{Type: passkeyCredentialType, Alg: utils.COSEAlgRS256},
+		},
+		Timeout:            s.Timeout.Milliseconds(),
+		ExcludeCredentials: descriptors(existing),
+		AuthenticatorSelection: model.AuthenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: "required",
+		},
+		Attestation: "none",
+	}, nil
+}
+
+// FinishRegistration verifies the new credential against the pending
+// session and stores its public key
+func (s *passkeyService) FinishRegistration(
+	ctx context.Context,
+	uid uuid.UUID,
+	sessionID string,
+	name string,
+	attestation *model.PasskeyAttestation,
+) (*model.PasskeyCredential, error) {
+	session, err := s.TokenRepository.GetWebAuthnSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Ceremony != model.WebAuthnCreate || session.UID != uid {
+		return nil, apperrors.NewAuthorization("Invalid or expired passkey session")
+	}
+
+	if attestation.Type != passkeyCredentialType {
+		return nil, apperrors.NewBadRequest("Unsupported credential type")
+	}
+
+	challenge := base64.RawURLEncoding.EncodeToString(session.Challenge)
+	if err := utils.VerifyClientData(attestation.Response.ClientDataJSON, model.WebAuthnCreate, challenge, s.RPOrigins); err != nil {
+		log.Printf("Failed to verify passkey registration for uid: %v. Error: %v\n", uid, err)
+		return nil, apperrors.NewBadRequest("Invalid passkey registration")
+	}
+
+	rawAuthData, err := utils.ParseAttestationObject(attestation.Response.AttestationObject)
+	if err != nil {
+		log.Printf("Failed to parse passkey attestation for uid: %v. Error: %v\n", uid, err)
+		return nil, apperrors.NewBadRequest("Invalid passkey registration")
+	}
+
+	authData, err := utils.ParseAuthenticatorData(rawAuthData, s.RPID)
+	if err != nil || authData.CredentialPublicKey == nil {
+		log.Printf("Failed to parse passkey authenticator data for uid: %v. Error: %v\n", uid, err)
+		return nil, apperrors.NewBadRequest("Invalid passkey registration")
+	}
+
+	if !bytes.Equal(authData.CredentialID, attestation.RawID) {
+		return nil, apperrors.NewBadRequest("Invalid passkey registration")
+	}
+
+	if authData.Flags&utils.AuthDataUserVerified == 0 {
+		return nil, apperrors.NewBadRequest("Passkey must verify the user")
+	}
+
+	if _, _, err := utils.ParseCOSEKey(authData.CredentialPublicKey); err != nil {
+		log.Printf("Unsupported passkey public key for uid: %v. Error: %v\n", uid, err)
+		return nil, apperrors.NewBadRequest("Unsupported passkey algorithm")
+	}
+
+	credential := &model.PasskeyCredential{
+		ID:         authData.CredentialID,
+		UID:        uid,
+		Name:       name,
+		PublicKey:  authData.CredentialPublicKey,
+		SignCount:  authData.SignCount,
+		Transports: attestation.Response.Transports,
+	}
+
+	if err := s.PasskeyRepository.Create(ctx, credential); err != nil {
+		return nil, err
+	}
+
+	audit(ctx, s.AuditRepository, &uid, model.AuditPasskeyAdd, model.AuditSuccess, name)
+	return credential, nil
+}
+
+// BeginLogin creates the options for navigator.credentials.get
+// Credentials are never listed, as registered passkeys are discoverable, so
+// the options are the same whether or not an email has passkeys or exists
+// With the email of a user, only credentials of that user are accepted
+func (s *passkeyService) BeginLogin(ctx context.Context, email string) (string, *model.PasskeyRequestOptions, error) {
+	uid := uuid.Nil
+
+	if email != "" {
+		if user, err := s.UserRepository.FindByEmail(ctx, utils.NormalizeEmail(email)); err == nil {
+			uid = user.UID
+		}
+	}
+
+	sessionID, challenge, err := s.newSession(ctx, uid, model.WebAuthnGet)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return sessionID, &model.PasskeyRequestOptions{
+		Challenge:        challenge,
+		Timeout:          s.Timeout.Milliseconds(),
+		RPID:             s.RPID,
+		UserVerification: "required",
+	}, nil
+}
+
+// FinishLogin verifies the assertion against the stored public key
+// and returns the credential's user
+func (s *passkeyService) FinishLogin(ctx context.Context, sessionID string, assertion *model.PasskeyAssertion) (*model.User, error) {
+	invalidErr := apperrors.NewAuthorization("Invalid passkey")
+
+	session, err := s.TokenRepository.GetWebAuthnSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Ceremony != model.WebAuthnGet || assertion.Type != passkeyCredentialType {
+		return nil, invalidErr
+	}
+
+	credential, err := s.PasskeyRepository.FindByID(ctx, assertion.RawID)
+	if err != nil {
+		return nil, invalidErr
+	}
+
+	signCount, ok := s.verifyAssertion(session, credential, assertion)
+	if !ok {
+		audit(ctx, s.AuditRepository, &credential.UID, model.AuditSignin, model.AuditFailure, "invalid passkey")
+		return nil, invalidErr
+	}
+
+	if err := s.PasskeyRepository.UpdateSignCount(ctx, credential.ID, signCount); err != nil {
+		return nil, err
+	}
+
+	return s.UserRepository.FindByID(ctx, credential.UID)
+}
+
+// verifyAssertion checks an assertion of a stored credential against
+// the pending session, returning the new sign count if it is valid
+func (s *passkeyService) verifyAssertion(session *model.WebAuthnSession, credential *model.PasskeyCredential, assertion *model.PasskeyAssertion) (uint32, bool) {
+	// the credential must belong to the user the ceremony was started for, if any,
+	// and to the user the authenticator reports
+	if session.UID != uuid.Nil && session.UID != credential.UID {
+		return 0, false
+	}
+
+	if len(assertion.Response.UserHandle) > 0 {
+		userHandle, err := uuid.FromBytes(assertion.Response.UserHandle)
+		if err != nil || userHandle != credential.UID {
+			return 0, false
+		}
+	}
+
+	challenge := base64.RawURLEncoding.EncodeToString(session.Challenge)
+	if err := utils.VerifyClientData(assertion.Response.ClientDataJSON, model.WebAuthnGet, challenge, s.RPOrigins); err != nil {
+		log.Printf("Failed to verify passkey assertion for uid: %v. Error: %v\n", credential.UID, err)
+		return 0, false
+	}
+
+	authData, err := utils.ParseAuthenticatorData(assertion.Response.AuthenticatorData, s.RPID)
+	if err != nil {
+		log.Printf("Failed to parse passkey authenticator data for uid: %v. Error: %v\n", credential.UID, err)
+		return 0, false
+	}
+
+	// a passkey replaces the password, so holding the authenticator is not
+	// enough without its PIN or biometrics
+	if authData.Flags&utils.AuthDataUserVerified == 0 {
+		log.Printf("Passkey assertion without user verification for uid: %v\n", credential.UID)
+		return 0, false
+	}
+
+	err = utils.VerifyAssertionSignature(
+		credential.PublicKey,
+		assertion.Response.AuthenticatorData,
+		assertion.Response.ClientDataJSON,
+		assertion.Response.Signature,
+	)
+	if err != nil {
+		log.Printf("Failed to verify passkey signature for uid: %v. Error: %v\n", credential.UID, err)
+		return 0, false
+	}
+
+	// authenticators which keep a counter must increase it, anything else
+	// hints at a cloned authenticator - https://www.w3.org/TR/webauthn-2/#sctn-sign-counter
+	if (authData.SignCount != 0 || credential.SignCount != 0) && authData.SignCount <= credential.SignCount {
+		log.Printf("Passkey sign count did not increase for uid: %v\n", credential.UID)
+		return 0, false
+	}
+
+	return authData.SignCount, true
+}
+
+// ListCredentials returns the credentials registered by a user
+func (s *passkeyService) ListCredentials(ctx context.Context, uid uuid.UUID) ([]*model.PasskeyCredential, error) {
+	return s.PasskeyRepository.FindByUID(ctx, uid)
+}
+
+// DeleteCredential removes a credential of a user
+func (s *passkeyService) DeleteCredential(ctx context.Context, uid uuid.UUID, credentialID []byte) error {
+	if err := s.PasskeyRepository.Delete(ctx, uid, credentialID); err != nil {
+		return err
+	}
+
+	audit(ctx, s.AuditRepository, &uid, model.AuditPasskeyRemove, model.AuditSuccess, base64.RawURLEncoding.EncodeToString(credentialID))
+	return nil
+}
+
+// newSession stores a fresh challenge for a ceremony
+func (s *passkeyService) newSession(ctx context.Context, uid uuid.UUID, ceremony string) (string, model.Base64URL, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		log.Printf("Failed to generate webauthn challenge: %v\n", err)
+		return "", nil, apperrors.NewInternal()
+	}
+
+	sessionID, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		log.Printf("Failed to generate webauthn session ID: %v\n", err)
+		return "", nil, apperrors.NewInternal()
+	}
+
+	session := &model.WebAuthnSession{
+		Challenge: challenge,
+		UID:       uid,
+		Ceremony:  ceremony,
+	}
+
+	if err := s.TokenRepository.SetWebAuthnSession(ctx, sessionID, session, s.Timeout); err != nil {
+		return "", nil, err
+	}
+
+	return sessionID, challenge, nil
+}
+
+func descriptors(credentials []*model.PasskeyCredential) []model.CredentialDescriptor {
+	result := make([]model.CredentialDescriptor, len(credentials))
+	for i, credential := range credentials {
+		result[i] = model.CredentialDescriptor{
+			Type:       passkeyCredentialType,
+			ID:         credential.ID,
+			Transports: credential.Transports,
+		}
+	}
+
+	return result
+}
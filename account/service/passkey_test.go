@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/fixture"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestPasskeyRegistration(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	mockUser := &model.User{UID: uid, Email: "long@do.com", Name: "Long"}
+
+	newService := func() (model.PasskeyService, *mocks.MockPasskeyRepository, *mocks.MockTokenRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockPasskeyRepository := new(mocks.MockPasskeyRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		ps := NewPasskeyService(&PasskeyServiceConfig{
+			UserRepository:    mockUserRepository,
+			PasskeyRepository: mockPasskeyRepository,
+			TokenRepository:   mockTokenRepository,
+			RPID:              "localhost",
+			RPName:            "memorization-apps",
+			RPOrigins:         []string{"http://localhost:3000"},
+			Timeout:           time.Minute,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+		mockPasskeyRepository.On("FindByUID", mock.Anything, uid).Return([]*model.PasskeyCredential{}, nil)
+		mockTokenRepository.On("SetWebAuthnSession", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*model.WebAuthnSession"), time.Minute).Return(nil)
+
+		return ps, mockPasskeyRepository, mockTokenRepository
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ps, mockPasskeyRepository, mockTokenRepository := newService()
+
+		sessionID, options, err := ps.BeginRegistration(context.TODO(), uid)
+		assert.NoError(t, err)
+		assert.Equal(t, "localhost", options.RP.ID)
+		assert.Equal(t, "long@do.com", options.User.Name)
+
+		session := mockTokenRepository.Calls[0].Arguments.Get(2).(*model.WebAuthnSession)
+		assert.Equal(t, model.WebAuthnCreate, session.Ceremony)
+
+		mockTokenRepository.On("GetWebAuthnSession", mock.Anything, sessionID).Return(session, nil)
+		mockPasskeyRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.PasskeyCredential")).Return(nil)
+
+		authenticator := fixture.NewAuthenticator("localhost", "http://localhost:3000")
+		attestation := authenticator.Create(options.Challenge, options.User.ID)
+
+		credential, err := ps.FinishRegistration(context.TODO(), uid, sessionID, "Laptop", attestation)
+
+		assert.NoError(t, err)
+		assert.Equal(t, authenticator.CredentialID, []byte(credential.ID))
+		assert.Equal(t, authenticator.PublicKey(), credential.PublicKey)
+		assert.Equal(t, uid, credential.UID)
+		assert.Equal(t, []string{"internal"}, credential.Transports)
+	})
+
+	t.Run("Wrong origin", func(t *testing.T) {
+		ps, mockPasskeyRepository, mockTokenRepository := newService()
+
+		sessionID, options, err := ps.BeginRegistration(context.TODO(), uid)
+		assert.NoError(t, err)
+
+		session := mockTokenRepository.Calls[0].Arguments.Get(2).(*model.WebAuthnSession)
+		mockTokenRepository.On("GetWebAuthnSession", mock.Anything, sessionID).Return(session, nil)
+
+		authenticator := fixture.NewAuthenticator("localhost", "https://evil.example")
+		attestation := authenticator.Create(options.Challenge, options.User.ID)
+
+		credential, err := ps.FinishRegistration(context.TODO(), uid, sessionID, "Laptop", attestation)
+
+		assert.Nil(t, credential)
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockPasskeyRepository.AssertNotCalled(t, "Create")
+	})
+
+	t.Run("Without user verification", func(t *testing.T) {
+		ps, mockPasskeyRepository, mockTokenRepository := newService()
+
+		sessionID, options, err := ps.BeginRegistration(context.TODO(), uid)
+		assert.NoError(t, err)
+		assert.Equal(t, "required", options.AuthenticatorSelection.UserVerification)
+
+		session := mockTokenRepository.Calls[0].Arguments.Get(2).(*model.WebAuthnSession)
+		mockTokenRepository.On("GetWebAuthnSession", mock.Anything, sessionID).Return(session, nil)
+
+		authenticator := fixture.NewAuthenticator("localhost", "http://localhost:3000")
+		authenticator.WithoutUV = true
+		attestation := authenticator.Create(options.Challenge, options.User.ID)
+
+		credential, err := ps.FinishRegistration(context.TODO(), uid, sessionID, "Security key", attestation)
+
+		assert.Nil(t, credential)
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockPasskeyRepository.AssertNotCalled(t, "Create")
+	})
+
+	t.Run("Session of another user", func(t *testing.T) {
+		ps, mockPasskeyRepository, mockTokenRepository := newService()
+
+		otherUID, _ := uuid.NewRandom()
+		session := &model.WebAuthnSession{Challenge: []byte("challenge"), UID: otherUID, Ceremony: model.WebAuthnCreate}
+		mockTokenRepository.On("GetWebAuthnSession", mock.Anything, "session").Return(session, nil)
+
+		authenticator := fixture.NewAuthenticator("localhost", "http://localhost:3000")
+		attestation := authenticator.Create(session.Challenge, nil)
+
+		credential, err := ps.FinishRegistration(context.TODO(), uid, "session", "Laptop", attestation)
+
+		assert.Nil(t, credential)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockPasskeyRepository.AssertNotCalled(t, "Create")
+	})
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	uidBytes, _ := uid.MarshalBinary()
+	mockUser := &model.User{UID: uid, Email: "long@do.com"}
+
+	authenticator := fixture.NewAuthenticator("localhost", "http://localhost:3000")
+	authenticator.UserHandle = uidBytes
+
+	newService := func(signCount uint32) (model.PasskeyService, *mocks.MockPasskeyRepository, *mocks.MockTokenRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockPasskeyRepository := new(mocks.MockPasskeyRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		ps := NewPasskeyService(&PasskeyServiceConfig{
+			UserRepository:    mockUserRepository,
+			PasskeyRepository: mockPasskeyRepository,
+			TokenRepository:   mockTokenRepository,
+			RPID:              "localhost",
+			RPOrigins:         []string{"http://localhost:3000"},
+			Timeout:           time.Minute,
+		})
+
+		mockCredential := &model.PasskeyCredential{
+			ID:        authenticator.CredentialID,
+			UID:       uid,
+			PublicKey: authenticator.PublicKey(),
+			SignCount: signCount,
+		}
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+		mockUserRepository.On("FindByEmail", mock.Anything, "long@do.com").Return(mockUser, nil)
+		mockUserRepository.On("FindByEmail", mock.Anything, "nobody@do.com").Return(nil, apperrors.NewNotFound("email", "nobody@do.com"))
+		mockPasskeyRepository.On("FindByID", mock.Anything, []byte(authenticator.CredentialID)).Return(mockCredential, nil)
+		mockTokenRepository.On("SetWebAuthnSession", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*model.WebAuthnSession"), time.Minute).Return(nil)
+
+		return ps, mockPasskeyRepository, mockTokenRepository
+	}
+
+	t.Run("Discoverable credential", func(t *testing.T) {
+		ps, mockPasskeyRepository, mockTokenRepository := newService(authenticator.SignCount)
+
+		sessionID, options, err := ps.BeginLogin(context.TODO(), "")
+		assert.NoError(t, err)
+		assert.Empty(t, options.AllowCredentials)
+
+		session := mockTokenRepository.Calls[0].Arguments.Get(2).(*model.WebAuthnSession)
+		assert.Equal(t, uuid.Nil, session.UID)
+		mockTokenRepository.On("GetWebAuthnSession", mock.Anything, sessionID).Return(session, nil)
+		mockPasskeyRepository.On("UpdateSignCount", mock.Anything, []byte(authenticator.CredentialID), authenticator.SignCount+1).Return(nil)
+
+		user, err := ps.FinishLogin(context.TODO(), sessionID, authenticator.Get(options.Challenge))
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUser, user)
+		mockPasskeyRepository.AssertExpectations(t)
+	})
+
+	t.Run("Credentials are not listed for an email", func(t *testing.T) {
+		for _, email := range []string{"long@do.com", "nobody@do.com"} {
+			ps, mockPasskeyRepository, mockTokenRepository := newService(authenticator.SignCount)
+
+			_, options, err := ps.BeginLogin(context.TODO(), email)
+			assert.NoError(t, err)
+			assert.Empty(t, options.AllowCredentials)
+			assert.Equal(t, "required", options.UserVerification)
+			mockPasskeyRepository.AssertNotCalled(t, "FindByUID")
+
+			// the ceremony only accepts credentials of the user of the email
+			session := mockTokenRepository.Calls[0].Arguments.Get(2).(*model.WebAuthnSession)
+			if email == "long@do.com" {
+				assert.Equal(t, uid, session.UID)
+			} else {
+				assert.Equal(t, uuid.Nil, session.UID)
+			}
+		}
+	})
+
+	t.Run("Normalized email", func(t *testing.T) {
+		ps, _, mockTokenRepository := newService(authenticator.SignCount)
+
+		_, _, err := ps.BeginLogin(context.TODO(), " long@DO.com ")
+		assert.NoError(t, err)
+
+		session := mockTokenRepository.Calls[0].Arguments.Get(2).(*model.WebAuthnSession)
+		assert.Equal(t, uid, session.UID)
+	})
+
+	t.Run("Without user verification", func(t *testing.T) {
+		ps, mockPasskeyRepository, mockTokenRepository := newService(authenticator.SignCount)
+
+		sessionID, options, err := ps.BeginLogin(context.TODO(), "")
+		assert.NoError(t, err)
+
+		session := mockTokenRepository.Calls[0].Arguments.Get(2).(*model.WebAuthnSession)
+		mockTokenRepository.On("GetWebAuthnSession", mock.Anything, sessionID).Return(session, nil)
+
+		authenticator.WithoutUV = true
+		defer func() { authenticator.WithoutUV = false }()
+
+		user, err := ps.FinishLogin(context.TODO(), sessionID, authenticator.Get(options.Challenge))
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockPasskeyRepository.AssertNotCalled(t, "UpdateSignCount")
+	})
+
+	t.Run("Tampered signature", func(t *testing.T) {
+		ps, mockPasskeyRepository, mockTokenRepository := newService(authenticator.SignCount)
+
+		sessionID, options, err := ps.BeginLogin(context.TODO(), "")
+		assert.NoError(t, err)
+
+		session := mockTokenRepository.Calls[0].Arguments.Get(2).(*model.WebAuthnSession)
+		mockTokenRepository.On("GetWebAuthnSession", mock.Anything, sessionID).Return(session, nil)
+
+		assertion := authenticator.Get(options.Challenge)
+		assertion.Response.Signature[len(assertion.Response.Signature)-1] ^= 0xff
+
+		user, err := ps.FinishLogin(context.TODO(), sessionID, assertion)
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockPasskeyRepository.AssertNotCalled(t, "UpdateSignCount")
+	})
+
+	t.Run("Sign count did not increase", func(t *testing.T) {
+		// the stored counter is ahead of the authenticator, as for a cloned key
+		ps, mockPasskeyRepository, mockTokenRepository := newService(authenticator.SignCount + 10)
+
+		sessionID, options, err := ps.BeginLogin(context.TODO(), "")
+		assert.NoError(t, err)
+
+		session := mockTokenRepository.Calls[0].Arguments.Get(2).(*model.WebAuthnSession)
+		mockTokenRepository.On("GetWebAuthnSession", mock.Anything, sessionID).Return(session, nil)
+
+		user, err := ps.FinishLogin(context.TODO(), sessionID, authenticator.Get(options.Challenge))
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockPasskeyRepository.AssertNotCalled(t, "UpdateSignCount")
+	})
+
+	t.Run("Credential of another user", func(t *testing.T) {
+		ps, mockPasskeyRepository, mockTokenRepository := newService(authenticator.SignCount)
+
+		otherUID, _ := uuid.NewRandom()
+		session := &model.WebAuthnSession{Challenge: []byte("challenge"), UID: otherUID, Ceremony: model.WebAuthnGet}
+		mockTokenRepository.On("GetWebAuthnSession", mock.Anything, "session").Return(session, nil)
+
+		user, err := ps.FinishLogin(context.TODO(), "session", authenticator.Get(session.Challenge))
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockPasskeyRepository.AssertNotCalled(t, "UpdateSignCount")
+	})
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/nbutton23/zxcvbn-go"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// passwordPolicy checks new passwords for their length, estimated
+// strength, presence in a breached password list and similarity
+// to the user's email address
+type passwordPolicy struct {
+	BreachedPasswordRepository model.BreachedPasswordRepository
+	MinLength                  int
+	MaxLength                  int
+	MinStrength                int
+}
+
+// PasswordPolicyConfig will hold repositories that will eventually be injected into
+// this service layer
+// MinStrength is a zxcvbn score from 0 (too guessable) to 4 (very unguessable)
+// The breached password check is skipped without a BreachedPasswordRepository
+type PasswordPolicyConfig struct {
+	BreachedPasswordRepository model.BreachedPasswordRepository
+	MinLength                  int
+	MaxLength                  int
+	MinStrength                int
+}
+
+// NewPasswordPolicy is a factory function for
+// initializing a PasswordPolicy with its repository layer dependencies
+func NewPasswordPolicy(c *PasswordPolicyConfig) model.PasswordPolicy {
+	return &passwordPolicy{
+		BreachedPasswordRepository: c.BreachedPasswordRepository,
+		MinLength:                  c.MinLength,
+		MaxLength:                  c.MaxLength,
+		MinStrength:                c.MinStrength,
+	}
+}
+
+// Validate returns every rule the password breaks as invalid arguments
+// of the "Password" field. The password itself is never echoed back
+func (p *passwordPolicy) Validate(ctx context.Context, password string, user *model.User) error {
+	var invalidArgs []apperrors.InvalidArgument
+	violate := func(tag string, param string) {
+		invalidArgs = append(invalidArgs, apperrors.InvalidArgument{
+			Field: "Password",
+			Tag:   tag,
+			Param: param,
+		})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violate("min", strconv.Itoa(p.MinLength))
+	}
+
+	// the strength of overlong passwords is not estimated,
+	// as the estimate gets slow for long inputs
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violate("max", strconv.Itoa(p.MaxLength))
+		return apperrors.NewInvalidArgs(invalidArgs)
+	}
+
+	var userInputs []string
+	if user != nil && user.Email != "" {
+		email := strings.ToLower(user.Email)
+		userInputs = append(userInputs, email)
+
+		localPart := email
+		if i := strings.LastIndexByte(email, '@'); i >= 0 {
+			localPart = email[:i]
+			userInputs = append(userInputs, localPart)
+		}
+
+		if len(localPart) >= 3 && strings.Contains(strings.ToLower(password), localPart) {
+			violate("email", "")
+		}
+	}
+
+	if zxcvbn.PasswordStrength(password, userInputs).Score < p.MinStrength {
+		violate("strength", strconv.Itoa(p.MinStrength))
+	}
+
+	if p.BreachedPasswordRepository != nil {
+		breached, err := p.BreachedPasswordRepository.IsBreached(ctx, password)
+		if err != nil {
+			return err
+		}
+
+		if breached {
+			violate("breached", "")
+		}
+	}
+
+	if len(invalidArgs) > 0 {
+		return apperrors.NewInvalidArgs(invalidArgs)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	user := &model.User{Email: "longdo@example.com"}
+
+	newPolicy := func() (model.PasswordPolicy, *mocks.MockBreachedPasswordRepository) {
+		mockBreachedPasswordRepository := new(mocks.MockBreachedPasswordRepository)
+		pp := NewPasswordPolicy(&PasswordPolicyConfig{
+			BreachedPasswordRepository: mockBreachedPasswordRepository,
+			MinLength:                  8,
+			MaxLength:                  64,
+			MinStrength:                3,
+		})
+
+		return pp, mockBreachedPasswordRepository
+	}
+
+	tags := func(err error) []string {
+		var tags []string
+		for _, invalidArg := range apperrors.InvalidArgs(err) {
+			assert.Equal(t, "Password", invalidArg.Field)
+			assert.Empty(t, invalidArg.Value)
+			tags = append(tags, invalidArg.Tag)
+		}
+		return tags
+	}
+
+	t.Run("Strong passphrase", func(t *testing.T) {
+		pp, mockBreachedPasswordRepository := newPolicy()
+		password := "correct horse battery staple on the moon"
+
+		mockBreachedPasswordRepository.On("IsBreached", mock.Anything, password).Return(false, nil)
+
+		err := pp.Validate(context.TODO(), password, user)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Too short and weak", func(t *testing.T) {
+		pp, mockBreachedPasswordRepository := newPolicy()
+
+		mockBreachedPasswordRepository.On("IsBreached", mock.Anything, "123456").Return(true, nil)
+
+		err := pp.Validate(context.TODO(), "123456", user)
+
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		assert.Equal(t, []string{"min", "strength", "breached"}, tags(err))
+	})
+
+	t.Run("Too long", func(t *testing.T) {
+		pp, mockBreachedPasswordRepository := newPolicy()
+
+		password := "this passphrase has gotten a lot longer than anyone should type!!"
+		err := pp.Validate(context.TODO(), password, user)
+
+		assert.Equal(t, []string{"max"}, tags(err))
+		assert.Equal(t, "64", apperrors.InvalidArgs(err)[0].Param)
+		mockBreachedPasswordRepository.AssertNotCalled(t, "IsBreached")
+	})
+
+	t.Run("Contains email", func(t *testing.T) {
+		pp, mockBreachedPasswordRepository := newPolicy()
+		password := "LongDo-quartz-nimbus-42"
+
+		mockBreachedPasswordRepository.On("IsBreached", mock.Anything, password).Return(false, nil)
+
+		err := pp.Validate(context.TODO(), password, user)
+
+		assert.Contains(t, tags(err), "email")
+	})
+
+	t.Run("Breached", func(t *testing.T) {
+		pp, mockBreachedPasswordRepository := newPolicy()
+		password := "Tr0ub4dor&3-horse-staple"
+
+		mockBreachedPasswordRepository.On("IsBreached", mock.Anything, password).Return(true, nil)
+
+		err := pp.Validate(context.TODO(), password, user)
+
+		assert.Equal(t, []string{"breached"}, tags(err))
+	})
+
+	t.Run("Without breached password list", func(t *testing.T) {
+		pp := NewPasswordPolicy(&PasswordPolicyConfig{
+			MinLength:   8,
+			MaxLength:   64,
+			MinStrength: 3,
+		})
+
+		err := pp.Validate(context.TODO(), "correct horse battery staple on the moon", nil)
+
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/google/uuid"
+	"log"
+	"regexp"
+)
+
+// role names are lowercase words joined by underscores
+var rolePattern = regexp.MustCompile(`^[a-z][a-z_]{1,29}$`)
+
+// roleService grants roles to users and keeps an audit log of the changes
+type roleService struct {
+	RoleRepository  model.RoleRepository
+	UserRepository  model.UserRepository
+	AuditRepository model.AuditRepository
+}
+
+// RoleServiceConfig will hold repositories that will eventually be injected into
+// this service layer
+type RoleServiceConfig struct {
+	RoleRepository  model.RoleRepository
+	UserRepository  model.UserRepository
+	AuditRepository model.AuditRepository
+}
+
+// NewRoleService is a factory function for
+// initializing a RoleService with its repository layer dependencies
+func NewRoleService(c *RoleServiceConfig) model.RoleService {
+	return &roleService{
+		RoleRepository:  c.RoleRepository,
+		UserRepository:  c.UserRepository,
+		AuditRepository: c.AuditRepository,
+	}
+}
+
+// List returns the roles of a user
+func (s *roleService) List(ctx context.Context, uid uuid.UUID) ([]string, error) {
+	return s.RoleRepository.FindByUID(ctx, uid)
+}
+
+// Grant gives a role to a user on behalf of the actor
+// Access tokens carry the new role once the user refreshes them
+func (s *roleService) Grant(ctx context.Context, actor uuid.UUID, uid uuid.UUID, role string) error {
+	if !rolePattern.MatchString(role) {
+		return apperrors.NewBadRequest("Invalid role name")
+	}
+
+	if err := s.RoleRepository.Grant(ctx, uid, role, &actor); err != nil {
+		return err
+	}
+
+	auditAs(ctx, s.AuditRepository, &actor, &uid, model.AuditRoleGrant, model.AuditSuccess, role)
+	return nil
+}
+
+// Revoke takes a role from a user on behalf of the actor
+// The last admin keeps the role, so there is always someone to grant it
+func (s *roleService) Revoke(ctx context.Context, actor uuid.UUID, uid uuid.UUID, role string) error {
+	if role == model.RoleAdmin {
+		count, err := s.RoleRepository.CountUsers(ctx, model.RoleAdmin)
+		if err != nil {
+			return err
+		}
+
+		if count <= 1 {
+			return apperrors.NewBadRequest("The last admin cannot be revoked")
+		}
+	}
+
+	if err := s.RoleRepository.Revoke(ctx, uid, role); err != nil {
+		return err
+	}
+
+	auditAs(ctx, s.AuditRepository, &actor, &uid, model.AuditRoleRevoke, model.AuditSuccess, role)
+	return nil
+}
+
+// Bootstrap makes the users of the emails admins, as long as there is no admin
+// yet. Emails without an account are skipped, they are made admins on a later
+// start if there is still no admin by then
+func (s *roleService) Bootstrap(ctx context.Context, adminEmails []string) error {
+	if len(adminEmails) == 0 {
+		return nil
+	}
+
+	count, err := s.RoleRepository.CountUsers(ctx, model.RoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	for _, email := range adminEmails {
+		user, err := s.UserRepository.FindByEmail(ctx, email)
+		if err != nil {
+			log.Printf("Skipping bootstrap admin without account: %v\n", email)
+			continue
+		}
+
+		if err := s.RoleRepository.Grant(ctx, user.UID, model.RoleAdmin, nil); err != nil {
+			return err
+		}
+
+		auditAs(ctx, s.AuditRepository, nil, &user.UID, model.AuditRoleGrant, model.AuditSuccess, model.RoleAdmin)
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func TestGrantRole(t *testing.T) {
+	actor, _ := uuid.NewRandom()
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Success", func(t *testing.T) {
+		mockRoleRepository := new(mocks.MockRoleRepository)
+		rs := NewRoleService(&RoleServiceConfig{
+			RoleRepository: mockRoleRepository,
+		})
+
+		mockRoleRepository.On("Grant", mock.Anything, uid, "support_agent", &actor).Return(nil)
+
+		err := rs.Grant(context.TODO(), actor, uid, "support_agent")
+
+		assert.NoError(t, err)
+		mockRoleRepository.AssertExpectations(t)
+	})
+
+	t.Run("Invalid role", func(t *testing.T) {
+		mockRoleRepository := new(mocks.MockRoleRepository)
+		rs := NewRoleService(&RoleServiceConfig{
+			RoleRepository: mockRoleRepository,
+		})
+
+		err := rs.Grant(context.TODO(), actor, uid, "Super Admin")
+
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockRoleRepository.AssertNotCalled(t, "Grant", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestRevokeRole(t *testing.T) {
+	actor, _ := uuid.NewRandom()
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Success", func(t *testing.T) {
+		mockRoleRepository := new(mocks.MockRoleRepository)
+		rs := NewRoleService(&RoleServiceConfig{
+			RoleRepository: mockRoleRepository,
+		})
+
+		mockRoleRepository.On("CountUsers", mock.Anything, model.RoleAdmin).Return(int64(2), nil)
+		mockRoleRepository.On("Revoke", mock.Anything, uid, model.RoleAdmin).Return(nil)
+
+		err := rs.Revoke(context.TODO(), actor, uid, model.RoleAdmin)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Last admin", func(t *testing.T) {
+		mockRoleRepository := new(mocks.MockRoleRepository)
+		rs := NewRoleService(&RoleServiceConfig{
+			RoleRepository: mockRoleRepository,
+		})
+
+		mockRoleRepository.On("CountUsers", mock.Anything, model.RoleAdmin).Return(int64(1), nil)
+
+		err := rs.Revoke(context.TODO(), actor, uid, model.RoleAdmin)
+
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockRoleRepository.AssertNotCalled(t, "Revoke", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Role not held", func(t *testing.T) {
+		mockRoleRepository := new(mocks.MockRoleRepository)
+		rs := NewRoleService(&RoleServiceConfig{
+			RoleRepository: mockRoleRepository,
+		})
+
+		mockErr := apperrors.NewNotFound("role", "support_agent")
+		mockRoleRepository.On("Revoke", mock.Anything, uid, "support_agent").Return(mockErr)
+
+		err := rs.Revoke(context.TODO(), actor, uid, "support_agent")
+
+		assert.Equal(t, mockErr, err)
+	})
+}
+
+func TestBootstrapAdmins(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	t.Run("First admin", func(t *testing.T) {
+		mockRoleRepository := new(mocks.MockRoleRepository)
+		mockUserRepository := new(mocks.MockUserRepository)
+		rs := NewRoleService(&RoleServiceConfig{
+			RoleRepository: mockRoleRepository,
+			UserRepository: mockUserRepository,
+		})
+
+		mockRoleRepository.On("CountUsers", mock.Anything, model.RoleAdmin).Return(int64(0), nil)
+		mockUserRepository.On("FindByEmail", mock.Anything, "admin@do.com").Return(&model.User{UID: uid}, nil)
+		mockUserRepository.On("FindByEmail", mock.Anything, "later@do.com").Return(nil, apperrors.NewNotFound("email", "later@do.com"))
+		mockRoleRepository.On("Grant", mock.Anything, uid, model.RoleAdmin, (*uuid.UUID)(nil)).Return(nil)
+
+		err := rs.Bootstrap(context.TODO(), []string{"admin@do.com", "later@do.com"})
+
+		assert.NoError(t, err)
+		mockRoleRepository.AssertNumberOfCalls(t, "Grant", 1)
+	})
+
+	t.Run("Admins exist", func(t *testing.T) {
+		mockRoleRepository := new(mocks.MockRoleRepository)
+		mockUserRepository := new(mocks.MockUserRepository)
+		rs := NewRoleService(&RoleServiceConfig{
+			RoleRepository: mockRoleRepository,
+			UserRepository: mockUserRepository,
+		})
+
+		mockRoleRepository.On("CountUsers", mock.Anything, model.RoleAdmin).Return(int64(1), nil)
+
+		err := rs.Bootstrap(context.TODO(), []string{"admin@do.com"})
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+		mockRoleRepository.AssertNotCalled(t, "Grant", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"log"
+	"strings"
+	"time"
+)
+
+// releaseTimeout bounds giving back an invite after a failed sign-up
+const releaseTimeout = 5 * time.Second
+
+// Sign-up modes of the signup policy
+const (
+	SignupModeOpen    = "open"    // anyone may sign up
+	SignupModeDomains = "domains" // emails of the allowed domains, or anyone with an invite
+	SignupModeInvite  = "invite"  // only with an invite
+)
+
+// signupPolicy decides who may create an account
+type signupPolicy struct {
+	InviteRepository model.InviteRepository
+	Mode             string
+	AllowedDomains   map[string]bool
+}
+
+// SignupPolicyConfig will hold repositories that will eventually be injected into
+// this service layer
+// Mode is one of the SignupMode constants, AllowedDomains are email
+// domains like "example.com" and only apply to SignupModeDomains
+type SignupPolicyConfig struct {
+	InviteRepository model.InviteRepository
+	Mode             string
+	AllowedDomains   []string
+}
+
+// NewSignupPolicy is a factory function for
+// initializing a SignupPolicy with its repository layer dependencies
+func NewSignupPolicy(c *SignupPolicyConfig) model.SignupPolicy {
+	allowedDomains := make(map[string]bool, len(c.AllowedDomains))
+	for _, domain := range c.AllowedDomains {
+		allowedDomains[normalizeDomain(domain)] = true
+	}
+
+	return &signupPolicy{
+		InviteRepository: c.InviteRepository,
+		Mode:             c.Mode,
+		AllowedDomains:   allowedDomains,
+	}
+}
+
+// Admit returns nil if the email may sign up without an invite, the redeemed
+// invite if one was needed, or a Forbidden error
+func (p *signupPolicy) Admit(ctx context.Context, email string, inviteCode string) (*model.Invite, error) {
+	switch p.Mode {
+	case SignupModeOpen:
+		return nil, nil
+	case SignupModeDomains:
+		if p.AllowedDomains[emailDomain(email)] {
+			return nil, nil
+		}
+
+		if inviteCode == "" {
+			return nil, apperrors.NewForbidden("Sign-up is restricted to some email domains, or requires an invite")
+		}
+	case SignupModeInvite:
+		if inviteCode == "" {
+			return nil, apperrors.NewForbidden("Sign-up requires an invite")
+		}
+	default:
+		log.Printf("Unknown sign-up mode: %v\n", p.Mode)
+		return nil, apperrors.NewInternal()
+	}
+
+	// without a repository, no invite was ever created
+	if p.InviteRepository == nil {
+		return nil, apperrors.NewForbidden("Invite is invalid, expired or used up")
+	}
+
+	invite, err := p.InviteRepository.Redeem(ctx, hashInviteCode(inviteCode))
+	if err != nil {
+		if isNotFound(err) {
+			return nil, apperrors.NewForbidden("Invite is invalid, expired or used up")
+		}
+
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+// Release gives back the use of an invite whose sign-up failed
+func (p *signupPolicy) Release(ctx context.Context, invite *model.Invite) error {
+	if invite == nil || p.InviteRepository == nil {
+		return nil
+	}
+
+	return p.InviteRepository.Release(ctx, invite.ID)
+}
+
+// admit applies the sign-up policy, if one is configured, to a new user
+// and records the invite the user signs up with
+func admit(ctx context.Context, policy model.SignupPolicy, user *model.User) (*model.Invite, error) {
+	if policy == nil {
+		return nil, nil
+	}
+
+	invite, err := policy.Admit(ctx, user.Email, user.InviteCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if invite != nil {
+		user.InviteID = &invite.ID
+	}
+
+	return invite, nil
+}
+
+// release gives back the invite of a sign-up that failed after admit
+// It does not use the request context, which may be what made the sign-up fail
+func release(policy model.SignupPolicy, invite *model.Invite) {
+	if policy == nil || invite == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+
+	if err := policy.Release(ctx, invite); err != nil {
+		log.Printf("Failed to release invite: %v: %v\n", invite.ID, err)
+	}
+}
+
+func emailDomain(email string) string {
+	i := strings.LastIndexByte(email, '@')
+	if i < 0 {
+		return ""
+	}
+
+	return normalizeDomain(email[i+1:])
+}
+
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func TestSignupPolicy(t *testing.T) {
+	newPolicy := func(mode string) (model.SignupPolicy, *mocks.MockInviteRepository) {
+		mockInviteRepository := new(mocks.MockInviteRepository)
+		sp := NewSignupPolicy(&SignupPolicyConfig{
+			InviteRepository: mockInviteRepository,
+			Mode:             mode,
+			AllowedDomains:   []string{"@Example.com"},
+		})
+
+		return sp, mockInviteRepository
+	}
+
+	assertForbidden := func(t *testing.T, err error) {
+		assert.Equal(t, apperrors.Forbidden, err.(*apperrors.Error).Type)
+	}
+
+	t.Run("Open", func(t *testing.T) {
+		sp, mockInviteRepository := newPolicy(SignupModeOpen)
+
+		invite, err := sp.Admit(context.TODO(), "long@do.com", "")
+
+		assert.NoError(t, err)
+		assert.Nil(t, invite)
+		mockInviteRepository.AssertNotCalled(t, "Redeem", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Allowed domain", func(t *testing.T) {
+		sp, mockInviteRepository := newPolicy(SignupModeDomains)
+
+		invite, err := sp.Admit(context.TODO(), "long@EXAMPLE.com", "")
+
+		assert.NoError(t, err)
+		assert.Nil(t, invite)
+		mockInviteRepository.AssertNotCalled(t, "Redeem", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Other domain without invite", func(t *testing.T) {
+		sp, _ := newPolicy(SignupModeDomains)
+
+		_, err := sp.Admit(context.TODO(), "long@example.com.evil", "")
+
+		assertForbidden(t, err)
+	})
+
+	t.Run("Other domain with invite", func(t *testing.T) {
+		sp, mockInviteRepository := newPolicy(SignupModeDomains)
+		mockInvite := &model.Invite{ID: uuid.New()}
+
+		mockInviteRepository.On("Redeem", mock.Anything, hashInviteCode("code")).Return(mockInvite, nil)
+
+		invite, err := sp.Admit(context.TODO(), "long@do.com", " CODE ")
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockInvite, invite)
+	})
+
+	t.Run("Invite required", func(t *testing.T) {
+		sp, _ := newPolicy(SignupModeInvite)
+
+		_, err := sp.Admit(context.TODO(), "long@example.com", "")
+
+		assertForbidden(t, err)
+	})
+
+	t.Run("Invalid invite", func(t *testing.T) {
+		sp, mockInviteRepository := newPolicy(SignupModeInvite)
+
+		mockInviteRepository.On("Redeem", mock.Anything, hashInviteCode("used")).Return(nil, apperrors.NewNotFound("invite", "code"))
+
+		_, err := sp.Admit(context.TODO(), "long@do.com", "used")
+
+		assertForbidden(t, err)
+	})
+
+	t.Run("Invite without repository", func(t *testing.T) {
+		sp := NewSignupPolicy(&SignupPolicyConfig{
+			Mode: SignupModeDomains,
+		})
+
+		_, err := sp.Admit(context.TODO(), "long@do.com", "code")
+
+		assertForbidden(t, err)
+		assert.NoError(t, sp.Release(context.TODO(), &model.Invite{ID: uuid.New()}))
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		sp, mockInviteRepository := newPolicy(SignupModeInvite)
+		mockInvite := &model.Invite{ID: uuid.New()}
+
+		mockInviteRepository.On("Release", mock.Anything, mockInvite.ID).Return(nil)
+
+		assert.NoError(t, sp.Release(context.TODO(), mockInvite))
+		assert.NoError(t, sp.Release(context.TODO(), nil))
+		mockInviteRepository.AssertNumberOfCalls(t, "Release", 1)
+	})
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+
+	"github.com/google/uuid"
+	"log"
+	"time"
+)
+
+// AcceptTerms records the user accepting the current version of the terms of service
+// Only the current version can be accepted, so clients cannot accept a version they did not show
+func (s *userService) AcceptTerms(ctx context.Context, uid uuid.UUID, version string) (*model.User, error) {
+	if s.TermsRepository == nil || s.TermsVersion == "" {
+		return nil, apperrors.NewBadRequest("There are no terms of service to accept")
+	}
+
+	if version != s.TermsVersion {
+		return nil, apperrors.NewBadRequest(fmt.Sprintf("Terms of service version %v is not the current version", version))
+	}
+
+	user, err := s.TermsRepository.Accept(ctx, s.termsAcceptance(ctx, uid))
+	if err != nil {
+		return nil, err
+	}
+
+	audit(ctx, s.AuditRepository, &uid, model.AuditTermsAccept, model.AuditSuccess, version)
+	return user, nil
+}
+
+// checkSignupTerms makes sure a sign-up accepts the current terms of service,
+// and answers with their version otherwise, so the client can show them
+func (s *userService) checkSignupTerms(user *model.User) error {
+	if s.TermsRepository == nil || s.TermsVersion == "" {
+		return nil
+	}
+
+	if user.AcceptedTerms != s.TermsVersion {
+		return apperrors.NewTermsNotAccepted(s.TermsVersion)
+	}
+
+	return nil
+}
+
+// acceptSignupTerms records a new user accepting the current terms of service,
+// which checkSignupTerms made sure of before the user was created
+// The user is already created, so a failure only means being asked to accept them later
+func (s *userService) acceptSignupTerms(ctx context.Context, user *model.User) {
+	if s.TermsRepository == nil || s.TermsVersion == "" {
+		return
+	}
+
+	accepted, err := s.TermsRepository.Accept(ctx, s.termsAcceptance(ctx, user.UID))
+	if err != nil {
+		log.Printf("Failed to record terms acceptance of uid: %v. Error: %v\n", user.UID, err)
+		return
+	}
+
+	user.TermsVersion = accepted.TermsVersion
+	user.TermsAcceptedAt = accepted.TermsAcceptedAt
+	audit(ctx, s.AuditRepository, &user.UID, model.AuditTermsAccept, model.AuditSuccess, s.TermsVersion)
+}
+
+// termsAcceptance of the current version by the client of the request
+func (s *userService) termsAcceptance(ctx context.Context, uid uuid.UUID) *model.TermsAcceptance {
+	client := model.ClientInfoFrom(ctx)
+
+	return &model.TermsAcceptance{
+		UID:        uid,
+		Version:    s.TermsVersion,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		AcceptedAt: time.Now().UTC(),
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"github.com/dolong2110/memorization-apps/account/model"
+	"github.com/dolong2110/memorization-apps/account/model/apperrors"
+	"github.com/dolong2110/memorization-apps/account/model/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestSignupTerms(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	acceptedAt := time.Now().UTC()
+
+	t.Run("Records acceptance with client", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTermsRepository := new(mocks.MockTermsRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			TermsRepository: mockTermsRepository,
+			TermsVersion:    "2024-06",
+		})
+
+		mockUserRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.User")).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(*model.User).UID = uid
+			}).
+			Return(nil)
+		mockTermsRepository.On("Accept", mock.Anything, mock.MatchedBy(func(a *model.TermsAcceptance) bool {
+			return a.UID == uid && a.Version == "2024-06" && a.IP == "203.0.113.7" && a.UserAgent == "curl/8.0"
+		})).Return(&model.User{UID: uid, TermsVersion: "2024-06", TermsAcceptedAt: &acceptedAt}, nil)
+
+		ctx := model.WithClientInfo(context.TODO(), model.ClientInfo{IP: "203.0.113.7", UserAgent: "curl/8.0"})
+		user := &model.User{Email: "bob@bob.com", Password: "avalidpassword123", AcceptedTerms: "2024-06"}
+		err := us.Signup(ctx, user)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "2024-06", user.TermsVersion)
+		assert.Equal(t, &acceptedAt, user.TermsAcceptedAt)
+		mockTermsRepository.AssertExpectations(t)
+	})
+
+	t.Run("Failed acceptance keeps sign-up", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTermsRepository := new(mocks.MockTermsRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			TermsRepository: mockTermsRepository,
+			TermsVersion:    "2024-06",
+		})
+
+		mockUserRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.User")).Return(nil)
+		mockTermsRepository.On("Accept", mock.Anything, mock.AnythingOfType("*model.TermsAcceptance")).Return(nil, apperrors.NewInternal())
+
+		user := &model.User{Email: "bob@bob.com", Password: "avalidpassword123", AcceptedTerms: "2024-06"}
+		err := us.Signup(context.TODO(), user)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "", user.TermsVersion)
+	})
+
+	for name, version := range map[string]string{"Terms not shown": "", "Outdated terms": "2024-01"} {
+		t.Run(name, func(t *testing.T) {
+			mockUserRepository := new(mocks.MockUserRepository)
+			mockTermsRepository := new(mocks.MockTermsRepository)
+			us := NewUserService(&USConfig{
+				UserRepository:  mockUserRepository,
+				TermsRepository: mockTermsRepository,
+				TermsVersion:    "2024-06",
+			})
+
+			user := &model.User{Email: "bob@bob.com", Password: "avalidpassword123", AcceptedTerms: version}
+			err := us.Signup(context.TODO(), user)
+
+			assert.Equal(t, apperrors.TermsNotAccepted, err.(*apperrors.Error).Type)
+			assert.Equal(t, "2024-06", err.(*apperrors.Error).Terms)
+			mockUserRepository.AssertNotCalled(t, "Create")
+			mockTermsRepository.AssertNotCalled(t, "Accept")
+		})
+	}
+
+	t.Run("Guests accept on upgrade", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTermsRepository := new(mocks.MockTermsRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			TermsRepository: mockTermsRepository,
+			TermsVersion:    "2024-06",
+		})
+
+		mockUserRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.User")).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(*model.User).UID = uid
+			}).
+			Return(nil)
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&model.User{UID: uid, Guest: true}, nil)
+		mockUserRepository.On("UpgradeGuest", mock.Anything, mock.AnythingOfType("*model.User")).Return(nil)
+		mockTermsRepository.On("Accept", mock.Anything, mock.MatchedBy(func(a *model.TermsAcceptance) bool {
+			return a.UID == uid && a.Version == "2024-06"
+		})).Return(&model.User{UID: uid, TermsVersion: "2024-06", TermsAcceptedAt: &acceptedAt}, nil)
+
+		guest, err := us.CreateGuest(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, "", guest.TermsVersion)
+		mockTermsRepository.AssertNotCalled(t, "Accept")
+
+		user := &model.User{UID: uid, Email: "bob@bob.com", Password: "avalidpassword123"}
+		err = us.UpgradeGuest(context.TODO(), user)
+		assert.Equal(t, apperrors.TermsNotAccepted, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "UpgradeGuest")
+
+		user.AcceptedTerms = "2024-06"
+		err = us.UpgradeGuest(context.TODO(), user)
+		assert.NoError(t, err)
+		assert.Equal(t, "2024-06", user.TermsVersion)
+		mockTermsRepository.AssertExpectations(t)
+	})
+}
+
+func TestAcceptTerms(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Current version", func(t *testing.T) {
+		mockTermsRepository := new(mocks.MockTermsRepository)
+		us := NewUserService(&USConfig{
+			TermsRepository: mockTermsRepository,
+			TermsVersion:    "2024-06",
+		})
+
+		accepted := &model.User{UID: uid, TermsVersion: "2024-06"}
+		mockTermsRepository.On("Accept", mock.Anything, mock.MatchedBy(func(a *model.TermsAcceptance) bool {
+			return a.UID == uid && a.Version == "2024-06"
+		})).Return(accepted, nil)
+
+		user, err := us.AcceptTerms(context.TODO(), uid, "2024-06")
+
+		assert.NoError(t, err)
+		assert.Equal(t, accepted, user)
+		mockTermsRepository.AssertExpectations(t)
+	})
+
+	t.Run("Outdated version", func(t *testing.T) {
+		mockTermsRepository := new(mocks.MockTermsRepository)
+		us := NewUserService(&USConfig{
+			TermsRepository: mockTermsRepository,
+			TermsVersion:    "2024-06",
+		})
+
+		user, err := us.AcceptTerms(context.TODO(), uid, "2024-01")
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockTermsRepository.AssertNotCalled(t, "Accept")
+	})
+}
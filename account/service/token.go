@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"log"
+	"time"
 )
 
 // tokenService used for injecting an implementation of TokenRepository
@@ -17,14 +18,26 @@ type tokenService struct {
 	AccessToken     model.AccessTokenInfo
 	RefreshToken    model.RefreshTokenInfo
 	TokenRepository model.TokenRepository
+	RoleRepository  model.RoleRepository
+	OrgRepository   model.OrgRepository
+	AuditRepository model.AuditRepository
+	UserRepository  model.UserRepository
 }
 
 // TokenServiceConfig will hold repositories that will eventually be injected into
 // this service layer
+// Without a RoleRepository, access tokens carry no roles,
+// without an OrgRepository no active organization
+// TokenRepository also holds the denylist of revoked access tokens
+// UserRepository records when users were last seen, which keeps guests from being purged
 type TokenServiceConfig struct {
 	AccessTokenInfo  model.AccessTokenInfo
 	RefreshTokenInfo model.RefreshTokenInfo
 	TokenRepository  model.TokenRepository
+	RoleRepository   model.RoleRepository
+	OrgRepository    model.OrgRepository
+	AuditRepository  model.AuditRepository
+	UserRepository   model.UserRepository
 }
 
 // NewTokenService is a factory function for
@@ -34,6 +47,10 @@ func NewTokenService(c *TokenServiceConfig) model.TokenService {
 		AccessToken:     c.AccessTokenInfo,
 		RefreshToken:    c.RefreshTokenInfo,
 		TokenRepository: c.TokenRepository,
+		RoleRepository:  c.RoleRepository,
+		OrgRepository:   c.OrgRepository,
+		AuditRepository: c.AuditRepository,
+		UserRepository:  c.UserRepository,
 	}
 }
 
@@ -43,12 +60,40 @@ func NewTokenService(c *TokenServiceConfig) model.TokenService {
 func (s *tokenService) NewPairFromUser(ctx context.Context, user *model.User, prevRefreshTokenID string) (*model.Token, error) {
 	// delete user's current refresh token (used when refreshing idToken)
 	if prevRefreshTokenID != "" {
-		if err := s.TokenRepository.DeleteRefreshToken(ctx, user.UID.String(), prevRefreshTokenID); err != nil {
+		err := s.TokenRepository.DeleteRefreshToken(ctx, user.UID.String(), prevRefreshTokenID)
+		audit(ctx, s.AuditRepository, &user.UID, model.AuditTokenRefresh, outcomeOf(err), "")
+		if err != nil {
 			log.Printf("Could not delete previous refreshToken for uid: %v, tokenID: %v\n", user.UID.String(), prevRefreshTokenID)
 			return nil, err
 		}
 	}
 
+	// roles are read on every new pair, so granted and revoked
+	// roles take effect when the access token is refreshed
+	if s.RoleRepository != nil {
+		roles, err := s.RoleRepository.FindByUID(ctx, user.UID)
+		if err != nil {
+			log.Printf("Error getting roles for uid: %v. Error: %v\n", user.UID, err.Error())
+			return nil, err
+		}
+		user.Roles = roles
+	}
+
+	// so is the membership of the active organization, users who
+	// were removed from it lose it on their next pair
+	if s.OrgRepository != nil && user.ActiveOrgID != nil {
+		member, err := s.OrgRepository.FindMember(ctx, *user.ActiveOrgID, user.UID)
+		switch {
+		case isNotFound(err):
+			user.ActiveOrgID = nil
+		case err != nil:
+			log.Printf("Error getting organization role for uid: %v. Error: %v\n", user.UID, err.Error())
+			return nil, err
+		default:
+			user.OrgRole = member.Role
+		}
+	}
+
 	// No need to use a repository for idToken as it is unrelated to any data source
 	idToken, err := utils.GenerateIDToken(user, s.AccessToken.PrivateKey, s.AccessToken.Expires)
 	if err != nil {
@@ -68,6 +113,13 @@ func (s *tokenService) NewPairFromUser(ctx context.Context, user *model.User, pr
 		return nil, apperrors.NewInternal()
 	}
 
+	// a failure only risks purging a guest who keeps using the account
+	if s.UserRepository != nil {
+		if err := s.UserRepository.UpdateLastSeen(ctx, user.UID, time.Now()); err != nil {
+			log.Printf("Could not record when uid: %v was last seen. Error: %v\n", user.UID, err.Error())
+		}
+	}
+
 	return &model.Token{
 		AccessToken:  model.AccessToken{SignedStringToken: idToken},
 		RefreshToken: model.RefreshToken{SignedStringToken: refreshToken.SignedStringToken, ID: refreshToken.ID, UID: user.UID},
@@ -76,12 +128,34 @@ func (s *tokenService) NewPairFromUser(ctx context.Context, user *model.User, pr
 
 // Signout reaches out to the repository layer to delete all valid tokens for a user
 func (s *tokenService) Signout(ctx context.Context, uid uuid.UUID) error {
-	return s.TokenRepository.DeleteUserRefreshToken(ctx, uid.String())
+	if err := s.TokenRepository.DeleteUserRefreshToken(ctx, uid.String()); err != nil {
+		return err
+	}
+
+	audit(ctx, s.AuditRepository, &uid, model.AuditSignout, model.AuditSuccess, "")
+	return nil
+}
+
+// Revoke signs a user out and denies the access tokens issued so far,
+// so they stop working before they expire
+func (s *tokenService) Revoke(ctx context.Context, uid uuid.UUID) error {
+	if err := s.TokenRepository.DeleteUserRefreshToken(ctx, uid.String()); err != nil {
+		return err
+	}
+
+	// the last of the denied tokens expires with the access token lifetime
+	expiresIn := time.Duration(s.AccessToken.Expires) * time.Second
+	if err := s.TokenRepository.SetAccessTokensRevokedAt(ctx, uid.String(), time.Now(), expiresIn); err != nil {
+		return err
+	}
+
+	audit(ctx, s.AuditRepository, &uid, model.AuditSignout, model.AuditSuccess, "access tokens revoked")
+	return nil
 }
 
 // ValidateIDToken validates the id token jwt string
 // It returns the user extract from the AccessTokenCustomClaims
-func (s *tokenService) ValidateIDToken(tokenString string) (*model.User, error) {
+func (s *tokenService) ValidateIDToken(ctx context.Context, tokenString string) (*model.User, error) {
 	claims, err := utils.ValidateIDToken(tokenString, s.AccessToken.PublicKey) // uses public RSA key
 	// We'll just return unauthorized error in all instances of failing to verify user
 	if err != nil {
@@ -89,6 +163,26 @@ func (s *tokenService) ValidateIDToken(tokenString string) (*model.User, error)
 		return nil, apperrors.NewAuthorization("Unable to verify user from idToken")
 	}
 
+	if s.TokenRepository != nil {
+		revokedAt, err := s.TokenRepository.GetAccessTokensRevokedAt(ctx, claims.User.UID.String())
+		if err != nil {
+			return nil, err
+		}
+
+		// compared in milliseconds, so the tokens issued right after a
+		// revocation, as on a password change, are not denied with the
+		// ones before. Tokens without iat_ms are denied to the second
+		issuedAt := claims.IssuedAtMs
+		if issuedAt == 0 {
+			issuedAt = claims.IssuedAt * 1000
+		}
+
+		if !revokedAt.IsZero() && issuedAt < revokedAt.UnixMilli() {
+			log.Printf("Access token of uid: %v was revoked\n", claims.User.UID)
+			return nil, apperrors.NewAuthorization("Unable to verify user from idToken")
+		}
+	}
+
 	return claims.User, nil
 }
 
@@ -99,7 +193,7 @@ func (s *tokenService) ValidateRefreshToken(tokenString string) (*model.RefreshT
 	claims, err := utils.ValidateRefreshToken(tokenString, s.RefreshToken.Secret)
 	// We'll just return unauthorized error in all instances of failing to verify user
 	if err != nil {
-		log.Printf("Unable to validate or parse refreshToken for token string: %s\n%v\n", tokenString, err)
+		log.Printf("Unable to validate or parse refreshToken: %v\n", err)
 		return nil, apperrors.NewAuthorization("Unable to verify user from refresh token")
 	}
 
@@ -10,6 +10,7 @@ import (
 
 	"github.com/stretchr/testify/mock"
 	"io/ioutil"
+	"net/http"
 	"testing"
 	"time"
 
@@ -283,7 +284,7 @@ func TestValidateIDToken(t *testing.T) {
 		// token will be valid for 15 minutes
 		ss, _ := utils.GenerateIDToken(user, privateKey, accessTokenExpires)
 
-		uFromToken, err := tokenService.ValidateIDToken(ss)
+		uFromToken, err := tokenService.ValidateIDToken(context.TODO(), ss)
 		assert.NoError(t, err)
 
 		assert.ElementsMatch(
@@ -300,7 +301,7 @@ func TestValidateIDToken(t *testing.T) {
 
 		expectedErr := apperrors.NewAuthorization("Unable to verify user from idToken")
 
-		_, err := tokenService.ValidateIDToken(ss)
+		_, err := tokenService.ValidateIDToken(context.TODO(), ss)
 		assert.EqualError(t, err, expectedErr.Message)
 	})
 
@@ -311,7 +312,7 @@ func TestValidateIDToken(t *testing.T) {
 
 		expectedErr := apperrors.NewAuthorization("Unable to verify user from idToken")
 
-		_, err := tokenService.ValidateIDToken(ss)
+		_, err := tokenService.ValidateIDToken(context.TODO(), ss)
 		assert.EqualError(t, err, expectedErr.Message)
 	})
 
@@ -401,3 +402,222 @@ func TestValidateRefreshToken(t *testing.T) {
 	//	assert.EqualError(t, err, expectedErr.Message)
 	//})
 }
+
+func TestNewPairFromUserRoles(t *testing.T) {
+	privateKey, _ := utils.GeneratePrivateKey(2048)
+
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	mockRoleRepository := new(mocks.MockRoleRepository)
+	tokenService := NewTokenService(&TokenServiceConfig{
+		AccessTokenInfo: model.AccessTokenInfo{
+			PrivateKey: privateKey,
+			PublicKey:  &privateKey.PublicKey,
+			Expires:    15 * 60,
+		},
+		RefreshTokenInfo: model.RefreshTokenInfo{
+			Secret:  "anotsorandomtestsecret",
+			Expires: 60 * 60,
+		},
+		TokenRepository: mockTokenRepository,
+		RoleRepository:  mockRoleRepository,
+	})
+
+	uid, _ := uuid.NewRandom()
+	user := &model.User{UID: uid, Email: "long@do.com"}
+
+	mockRoleRepository.On("FindByUID", mock.Anything, uid).Return([]string{model.RoleAdmin}, nil)
+	mockTokenRepository.On("SetRefreshToken", mock.Anything, uid.String(), mock.AnythingOfType("string"), mock.AnythingOfType("time.Duration")).Return(nil)
+	mockTokenRepository.On("GetAccessTokensRevokedAt", mock.Anything, uid.String()).Return(time.Time{}, nil)
+
+	tokenPair, err := tokenService.NewPairFromUser(context.TODO(), user, "")
+	assert.NoError(t, err)
+
+	authUser, err := tokenService.ValidateIDToken(context.TODO(), tokenPair.AccessToken.SignedStringToken)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{model.RoleAdmin}, authUser.Roles)
+}
+
+func TestNewPairFromUserLastSeen(t *testing.T) {
+	privateKey, _ := utils.GeneratePrivateKey(2048)
+
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	mockUserRepository := new(mocks.MockUserRepository)
+	tokenService := NewTokenService(&TokenServiceConfig{
+		AccessTokenInfo: model.AccessTokenInfo{
+			PrivateKey: privateKey,
+			PublicKey:  &privateKey.PublicKey,
+			Expires:    15 * 60,
+		},
+		RefreshTokenInfo: model.RefreshTokenInfo{
+			Secret:  "anotsorandomtestsecret",
+			Expires: 60 * 60,
+		},
+		TokenRepository: mockTokenRepository,
+		UserRepository:  mockUserRepository,
+	})
+
+	uid, _ := uuid.NewRandom()
+	user := &model.User{UID: uid, Guest: true}
+
+	mockTokenRepository.On("SetRefreshToken", mock.Anything, uid.String(), mock.AnythingOfType("string"), mock.AnythingOfType("time.Duration")).Return(nil)
+
+	t.Run("Recorded on a new pair", func(t *testing.T) {
+		mockUserRepository.On("UpdateLastSeen", mock.Anything, uid, mock.MatchedBy(func(seenAt time.Time) bool {
+			return time.Since(seenAt) < time.Minute
+		})).Return(nil).Once()
+
+		_, err := tokenService.NewPairFromUser(context.TODO(), user, "")
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Failure does not fail the pair", func(t *testing.T) {
+		mockUserRepository.On("UpdateLastSeen", mock.Anything, uid, mock.AnythingOfType("time.Time")).Return(apperrors.NewInternal()).Once()
+
+		tokens, err := tokenService.NewPairFromUser(context.TODO(), user, "")
+
+		assert.NoError(t, err)
+		assert.NotNil(t, tokens)
+	})
+}
+
+func TestNewPairFromUserOrg(t *testing.T) {
+	privateKey, _ := utils.GeneratePrivateKey(2048)
+
+	uid, _ := uuid.NewRandom()
+	orgID, _ := uuid.NewRandom()
+
+	newPair := func(mockOrgRepository *mocks.MockOrgRepository) *model.User {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		tokenService := NewTokenService(&TokenServiceConfig{
+			AccessTokenInfo: model.AccessTokenInfo{
+				PrivateKey: privateKey,
+				PublicKey:  &privateKey.PublicKey,
+				Expires:    15 * 60,
+			},
+			RefreshTokenInfo: model.RefreshTokenInfo{
+				Secret:  "anotsorandomtestsecret",
+				Expires: 60 * 60,
+			},
+			TokenRepository: mockTokenRepository,
+			OrgRepository:   mockOrgRepository,
+		})
+
+		mockTokenRepository.On("SetRefreshToken", mock.Anything, uid.String(), mock.AnythingOfType("string"), mock.AnythingOfType("time.Duration")).Return(nil)
+		mockTokenRepository.On("GetAccessTokensRevokedAt", mock.Anything, uid.String()).Return(time.Time{}, nil)
+
+		activeOrgID := orgID
+		user := &model.User{UID: uid, Email: "long@do.com", ActiveOrgID: &activeOrgID}
+
+		tokenPair, err := tokenService.NewPairFromUser(context.TODO(), user, "")
+		assert.NoError(t, err)
+
+		authUser, err := tokenService.ValidateIDToken(context.TODO(), tokenPair.AccessToken.SignedStringToken)
+		assert.NoError(t, err)
+
+		return authUser
+	}
+
+	t.Run("Member", func(t *testing.T) {
+		mockOrgRepository := new(mocks.MockOrgRepository)
+		mockOrgRepository.On("FindMember", mock.Anything, orgID, uid).Return(&model.OrgMember{OrgID: orgID, UID: uid, Role: model.OrgRoleAdmin}, nil)
+
+		authUser := newPair(mockOrgRepository)
+
+		assert.Equal(t, &orgID, authUser.ActiveOrgID)
+		assert.Equal(t, model.OrgRoleAdmin, authUser.OrgRole)
+	})
+
+	t.Run("Removed member", func(t *testing.T) {
+		mockOrgRepository := new(mocks.MockOrgRepository)
+		mockOrgRepository.On("FindMember", mock.Anything, orgID, uid).Return(nil, apperrors.NewNotFound("member", uid.String()))
+
+		authUser := newPair(mockOrgRepository)
+
+		assert.Nil(t, authUser.ActiveOrgID)
+		assert.Equal(t, "", authUser.OrgRole)
+	})
+}
+
+func TestRevoke(t *testing.T) {
+	privateKey, _ := utils.GeneratePrivateKey(2048)
+
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	tokenService := NewTokenService(&TokenServiceConfig{
+		AccessTokenInfo: model.AccessTokenInfo{
+			PrivateKey: privateKey,
+			PublicKey:  &privateKey.PublicKey,
+			Expires:    15 * 60,
+		},
+		TokenRepository: mockTokenRepository,
+	})
+
+	uid, _ := uuid.NewRandom()
+	user := &model.User{UID: uid, Email: "long@do.com"}
+
+	ss, err := utils.GenerateIDToken(user, privateKey, 15*60)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond)
+
+	var revokedAt time.Time
+	mockTokenRepository.On("DeleteUserRefreshToken", mock.Anything, uid.String()).Return(nil)
+	mockTokenRepository.
+		On("SetAccessTokensRevokedAt", mock.Anything, uid.String(), mock.AnythingOfType("time.Time"), 15*time.Minute).
+		Run(func(args mock.Arguments) {
+			revokedAt = args.Get(2).(time.Time)
+		}).
+		Return(nil)
+
+	err = tokenService.Revoke(context.TODO(), uid)
+	assert.NoError(t, err)
+	mockTokenRepository.AssertExpectations(t)
+
+	mockTokenRepository.On("GetAccessTokensRevokedAt", mock.Anything, uid.String()).Return(revokedAt, nil)
+
+	_, err = tokenService.ValidateIDToken(context.TODO(), ss)
+	assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+
+	// tokens issued after the revocation keep working, even within its second
+	time.Sleep(time.Millisecond)
+	ss, err = utils.GenerateIDToken(user, privateKey, 15*60)
+	assert.NoError(t, err)
+
+	validated, err := tokenService.ValidateIDToken(context.TODO(), ss)
+	assert.NoError(t, err)
+	assert.Equal(t, uid, validated.UID)
+}
+
+func TestTokenRefreshAudit(t *testing.T) {
+	privateKey, _ := utils.GeneratePrivateKey(2048)
+
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	mockAuditRepository := new(mocks.MockAuditRepository)
+	tokenService := NewTokenService(&TokenServiceConfig{
+		AccessTokenInfo: model.AccessTokenInfo{
+			PrivateKey: privateKey,
+			PublicKey:  &privateKey.PublicKey,
+			Expires:    15 * 60,
+		},
+		RefreshTokenInfo: model.RefreshTokenInfo{
+			Secret:  "anotsorandomtestsecret",
+			Expires: 60 * 60,
+		},
+		TokenRepository: mockTokenRepository,
+		AuditRepository: mockAuditRepository,
+	})
+
+	uid, _ := uuid.NewRandom()
+	user := &model.User{UID: uid, Email: "long@do.com"}
+
+	mockTokenRepository.On("DeleteRefreshToken", mock.Anything, uid.String(), "reused").Return(apperrors.NewAuthorization("Invalid refresh token"))
+	mockAuditRepository.On("Create", mock.Anything, mock.MatchedBy(func(e *model.AuditEvent) bool {
+		return e.Type == model.AuditTokenRefresh && e.Outcome == model.AuditFailure && *e.UID == uid
+	})).Return(nil)
+
+	_, err := tokenService.NewPairFromUser(context.TODO(), user, "reused")
+
+	assert.Error(t, err)
+	mockAuditRepository.AssertExpectations(t)
+	mockTokenRepository.AssertNotCalled(t, "SetRefreshToken")
+}
@@ -2,35 +2,110 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"github.com/dolong2110/memorization-apps/account/model"
 	"github.com/dolong2110/memorization-apps/account/model/apperrors"
 	"github.com/dolong2110/memorization-apps/account/utils"
 
 	"github.com/google/uuid"
+	"io"
 	"log"
 	"mime/multipart"
+	"net/url"
+	"strings"
+	"time"
 )
 
+// rehashTimeout bounds the background upgrade of a password hash after sign-in
+const rehashTimeout = 10 * time.Second
+
+// mailTimeout bounds sending an email in the background
+const mailTimeout = 30 * time.Second
+
 // userService acts as a struct for injecting an implementation of UserRepository
 // for use in service methods
 type userService struct {
-	UserRepository  model.UserRepository
-	ImageRepository model.ImageRepository
+	UserRepository       model.UserRepository
+	ImageRepository      model.ImageRepository
+	TokenRepository      model.TokenRepository
+	PasswordPolicy       model.PasswordPolicy
+	SignupPolicy         model.SignupPolicy
+	Mailer               model.Mailer
+	AuditRepository      model.AuditRepository
+	DeviceRepository     model.DeviceRepository
+	Notifier             model.Notifier
+	TermsRepository      model.TermsRepository
+	PasskeyRepository    model.PasskeyRepository
+	IdentityRepository   model.IdentityRepository
+	MFARepository        model.MFARepository
+	TokenService         model.TokenService
+	PasswordResetURL     string
+	PasswordResetExpires time.Duration
+	HandleChangeCooldown time.Duration
+	DeviceRevokeURL      string
+	DeviceRevokeExpires  time.Duration
+	TermsVersion         string
 }
 
 // USConfig will hold repositories that will eventually be injected into
 // this service layer
+// PasswordResetURL is the client page which posts the token of a reset link
+// with the new password to /password/reset/confirm
+// HandleChangeCooldown is the time users wait between changes of their handle
+// Without an AuditRepository, no audit events are recorded
+// With a DeviceRepository and Notifier, sign-ins from new devices are notified
+// with a DeviceRevokeURL, the client page which posts its token to /signin/disown
+// With a TermsRepository, sign-ups and guest upgrades must accept the current TermsVersion
+// A disowned sign-in ends the sessions of the user with the TokenService,
+// and removes the passkeys, linked identities and second factor of the
+// repositories given
 type USConfig struct {
-	UserRepository  model.UserRepository
-	ImageRepository model.ImageRepository
+	UserRepository       model.UserRepository
+	ImageRepository      model.ImageRepository
+	TokenRepository      model.TokenRepository
+	PasswordPolicy       model.PasswordPolicy
+	SignupPolicy         model.SignupPolicy
+	Mailer               model.Mailer
+	AuditRepository      model.AuditRepository
+	DeviceRepository     model.DeviceRepository
+	Notifier             model.Notifier
+	TermsRepository      model.TermsRepository
+	PasskeyRepository    model.PasskeyRepository
+	IdentityRepository   model.IdentityRepository
+	MFARepository        model.MFARepository
+	TokenService         model.TokenService
+	PasswordResetURL     string
+	PasswordResetExpires time.Duration
+	HandleChangeCooldown time.Duration
+	DeviceRevokeURL      string
+	DeviceRevokeExpires  time.Duration
+	TermsVersion         string
 }
 
 // NewUserService is a factory function for
 // initializing a UserService with its repository layer dependencies
 func NewUserService(c *USConfig) model.UserService {
 	return &userService{
-		UserRepository:  c.UserRepository,
-		ImageRepository: c.ImageRepository,
+		UserRepository:       c.UserRepository,
+		ImageRepository:      c.ImageRepository,
+		TokenRepository:      c.TokenRepository,
+		PasswordPolicy:       c.PasswordPolicy,
+		SignupPolicy:         c.SignupPolicy,
+		Mailer:               c.Mailer,
+		AuditRepository:      c.AuditRepository,
+		DeviceRepository:     c.DeviceRepository,
+		Notifier:             c.Notifier,
+		TermsRepository:      c.TermsRepository,
+		PasskeyRepository:    c.PasskeyRepository,
+		IdentityRepository:   c.IdentityRepository,
+		MFARepository:        c.MFARepository,
+		TokenService:         c.TokenService,
+		PasswordResetURL:     c.PasswordResetURL,
+		PasswordResetExpires: c.PasswordResetExpires,
+		HandleChangeCooldown: c.HandleChangeCooldown,
+		DeviceRevokeURL:      c.DeviceRevokeURL,
+		DeviceRevokeExpires:  c.DeviceRevokeExpires,
+		TermsVersion:         c.TermsVersion,
 	}
 }
 
@@ -42,33 +117,72 @@ func (s *userService) Get(ctx context.Context, uid uuid.UUID) (*model.User, erro
 }
 
 // Signup reaches out to a UserRepository to sign up the user.
-// UserRepository Create should handle checking for user exists conflicts
+// UserRepository Create should handle checking for user exists conflicts,
+// which it does regardless of the case of the email
 func (s *userService) Signup(ctx context.Context, user *model.User) error {
+	user.Email = utils.NormalizeEmail(user.Email)
+
+	if err := s.validatePassword(ctx, user.Password, user); err != nil {
+		return err
+	}
+
 	pwd, err := utils.HashPassword(user.Password)
 	if err != nil {
 		log.Printf("failed to hash password; email: %v\n", user.Email)
 		return apperrors.NewInternal()
 	}
 
+	if err := s.checkSignupTerms(user); err != nil {
+		return err
+	}
+
+	invite, err := admit(ctx, s.SignupPolicy, user)
+	if err != nil {
+		return err
+	}
+
 	// now I realize why I originally used Signup(ctx, email, password)
 	// then created a user. It's somewhat un-natural to mutate the user here
 	user.Password = pwd
 	if err := s.UserRepository.Create(ctx, user); err != nil {
+		release(s.SignupPolicy, invite)
 		return err
 	}
 
-	// ...
+	audit(ctx, s.AuditRepository, &user.UID, model.AuditSignup, model.AuditSuccess, "")
+	s.rememberDevice(ctx, user.UID)
+	s.acceptSignupTerms(ctx, user)
 
 	return nil
 }
 
 // Signin reaches out to a UserRepository check if the user exists
 // and then compares the supplied password with the provided password.
+// Users are found by their email, or their handle if no email is provided.
 // If a valid email/password combo is provided, u will hold all
 // available user fields
 func (s *userService) Signin(ctx context.Context, user *model.User) error {
-	uFetched, err := s.UserRepository.FindByEmail(ctx, user.Email)
+	user.Email = utils.NormalizeEmail(user.Email)
+
+	var uFetched *model.User
+	var err error
+	if user.Email == "" && user.Handle != "" {
+		uFetched, err = s.UserRepository.FindByHandle(ctx, user.Handle)
+	} else {
+		uFetched, err = s.UserRepository.FindByEmail(ctx, user.Email)
+	}
+
+	// unknown emails and accounts created through a sign-in link have no
+	// password, hashing anyway keeps them as slow to reject as a wrong password
 	if err != nil {
+		utils.CompareDummyPassword(user.Password)
+		audit(ctx, s.AuditRepository, nil, model.AuditSignin, model.AuditFailure, "unknown account")
+		return apperrors.NewAuthorization("Invalid email and password combination")
+	}
+
+	if uFetched.Password == "" {
+		utils.CompareDummyPassword(user.Password)
+		audit(ctx, s.AuditRepository, &uFetched.UID, model.AuditSignin, model.AuditFailure, "no password")
 		return apperrors.NewAuthorization("Invalid email and password combination")
 	}
 
@@ -79,20 +193,74 @@ func (s *userService) Signin(ctx context.Context, user *model.User) error {
 	}
 
 	if !match {
+		audit(ctx, s.AuditRepository, &uFetched.UID, model.AuditSignin, model.AuditFailure, "invalid password")
 		return apperrors.NewAuthorization("Invalid email and password combination")
 	}
 
+	// only told after the password matched, so suspensions are not revealed
+	// to someone guessing passwords
+	if uFetched.Suspended(time.Now()) {
+		audit(ctx, s.AuditRepository, &uFetched.UID, model.AuditSignin, model.AuditFailure, "suspended")
+		return apperrors.NewSuspended(uFetched.StatusReason, uFetched.StatusExpiresAt)
+	}
+
+	// upgrade legacy and outdated hashes while the plain password is at hand,
+	// without making the sign-in wait for it
+	if utils.NeedsRehash(uFetched.Password) {
+		go s.rehashPassword(uFetched.UID, user.Password)
+	}
+
 	*user = *uFetched
 	return nil
 }
 
+// RecordSignin logs the sign-in of a user once tokens are issued to them,
+// after any second factor, with the method the user signed in with
+// Sign-ins from new devices are notified whichever the method
+func (s *userService) RecordSignin(ctx context.Context, user *model.User, method string) {
+	audit(ctx, s.AuditRepository, &user.UID, model.AuditSignin, model.AuditSuccess, method)
+	s.checkDevice(ctx, user)
+}
+
+// rehashPassword replaces the stored hash of a password with one using the current
+// algorithm and parameters. It runs after the request, so failures are only logged
+func (s *userService) rehashPassword(uid uuid.UUID, password string) {
+	ctx, cancel := context.WithTimeout(context.Background(), rehashTimeout)
+	defer cancel()
+
+	pwd, err := utils.HashPassword(password)
+	if err != nil {
+		log.Printf("Failed to rehash password of user: %v: %v\n", uid, err)
+		return
+	}
+
+	if err := s.UserRepository.UpdatePassword(ctx, uid, pwd); err != nil {
+		log.Printf("Failed to store rehashed password of user: %v: %v\n", uid, err)
+	}
+}
+
 func (s *userService) UpdateDetails(ctx context.Context, user *model.User) error {
+	user.Email = utils.NormalizeEmail(user.Email)
+
+	// the previous email is only needed to audit email changes
+	var prevEmail string
+	if s.AuditRepository != nil {
+		if prev, err := s.UserRepository.FindByID(ctx, user.UID); err == nil {
+			prevEmail = prev.Email
+		}
+	}
+
 	// Update user in UserRepository
 	err := s.UserRepository.Update(ctx, user)
 	if err != nil {
 		return err
 	}
 
+	audit(ctx, s.AuditRepository, &user.UID, model.AuditProfileUpdate, model.AuditSuccess, "")
+	if prevEmail != "" && prevEmail != user.Email {
+		audit(ctx, s.AuditRepository, &user.UID, model.AuditEmailChange, model.AuditSuccess, fmt.Sprintf("previous email: %v", prevEmail))
+	}
+
 	// // Publish user updated
 	// err = s.EventsBroker.PublishUserUpdated(user, false)
 	// if err != nil {
@@ -137,6 +305,8 @@ func (s *userService) SetProfileImage(
 		return nil, err
 	}
 
+	audit(ctx, s.AuditRepository, &uid, model.AuditImageChange, model.AuditSuccess, "uploaded")
+
 	return updatedUser, nil
 }
 
@@ -169,5 +339,259 @@ func (s *userService) DeleteProfileImage(
 		return err
 	}
 
+	audit(ctx, s.AuditRepository, &uid, model.AuditImageChange, model.AuditSuccess, "removed")
+
+	return nil
+}
+
+// ProfileImage reads a profile image by its object name, for
+// image repositories whose images are served by the API
+func (s *userService) ProfileImage(ctx context.Context, objName string) (io.ReadCloser, error) {
+	return s.ImageRepository.GetProfile(ctx, objName)
+}
+
+// ChangePassword replaces the password of a user who knows the current one
+// Accounts without password, as created by sign-in links, may set one directly
+func (s *userService) ChangePassword(ctx context.Context, uid uuid.UUID, currentPassword string, newPassword string) error {
+	user, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	if user.Password != "" {
+		match, err := utils.ComparePasswords(user.Password, currentPassword)
+		if err != nil {
+			return apperrors.NewInternal()
+		}
+
+		if !match {
+			audit(ctx, s.AuditRepository, &uid, model.AuditPasswordChange, model.AuditFailure, "invalid password")
+			return apperrors.NewAuthorization("Invalid password")
+		}
+	}
+
+	if err := s.setPassword(ctx, user, newPassword); err != nil {
+		return err
+	}
+
+	audit(ctx, s.AuditRepository, &uid, model.AuditPasswordChange, model.AuditSuccess, "changed")
+	return nil
+}
+
+// RequestPasswordReset emails a single-use password reset link
+// Unknown emails are ignored, and the link is mailed in the background,
+// so neither the response nor its timing reveals which accounts exist
+func (s *userService) RequestPasswordReset(ctx context.Context, email string) error {
+	email = utils.NormalizeEmail(email)
+
+	user, err := s.UserRepository.FindByEmail(ctx, email)
+	if err != nil {
+		return nil
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+
+		if err := s.sendPasswordReset(ctx, user); err != nil {
+			log.Printf("Failed to send password reset for uid: %v: %v\n", user.UID, err)
+		}
+	}()
+
+	return nil
+}
+
+// sendPasswordReset emails a single-use password reset link to a user
+func (s *userService) sendPasswordReset(ctx context.Context, user *model.User) error {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		log.Printf("Failed to generate password reset token: %v\n", err)
+		return apperrors.NewInternal()
+	}
+
+	if err := s.TokenRepository.SetPasswordResetToken(ctx, token, user.UID.String(), s.PasswordResetExpires); err != nil {
+		return err
+	}
+
+	resetURL, err := url.Parse(s.PasswordResetURL)
+	if err != nil {
+		log.Printf("Invalid password reset URL: %v\n", err)
+		return apperrors.NewInternal()
+	}
+
+	query := resetURL.Query()
+	query.Set("token", token)
+	resetURL.RawQuery = query.Encode()
+
+	return s.Mailer.Send(ctx, &model.Email{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Choose a new password with this link, which expires in %d minutes:\n\n%s\n\nIf you did not ask to reset your password, you can ignore this email.",
+			int(s.PasswordResetExpires.Minutes()),
+			resetURL.String(),
+		),
+	})
+}
+
+// ResetPassword sets a new password with the token of a reset link
+// and returns the user, whose sessions the caller should end
+// The link is only used up by a password which meets the policy,
+// so a rejected password can be retried with the same link
+func (s *userService) ResetPassword(ctx context.Context, token string, password string) (*model.User, error) {
+	userID, err := s.TokenRepository.PeekPasswordResetToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		log.Printf("Invalid uid of password reset token: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	user, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validatePassword(ctx, password, user); err != nil {
+		return nil, err
+	}
+
+	// taken only now, and atomically, so concurrent resets use the link once
+	if _, err := s.TokenRepository.GetPasswordResetToken(ctx, token); err != nil {
+		return nil, err
+	}
+
+	if err := s.updatePassword(ctx, user, password); err != nil {
+		return nil, err
+	}
+
+	audit(ctx, s.AuditRepository, &uid, model.AuditPasswordChange, model.AuditSuccess, "reset")
+	return user, nil
+}
+
+// NotifySignupAttempt tells the owner of an email address that someone tried
+// to sign up with it, in place of answering the sign-up with a conflict
+// The email is sent in the background, so the response is not slower for it
+func (s *userService) NotifySignupAttempt(ctx context.Context, email string) error {
+	message := &model.Email{
+		To:      email,
+		Subject: "You already have an account",
+		Body: fmt.Sprintf(
+			"Someone tried to create an account with this email address, which already has one.\n\nIf it was you, you can sign in with your password, or choose a new one here:\n\n%s\n\nIf it was not, you can ignore this email.",
+			s.PasswordResetURL,
+		),
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+
+		if err := s.Mailer.Send(ctx, message); err != nil {
+			log.Printf("Failed to notify of sign-up attempt: %v\n", err)
+		}
+	}()
+
+	return nil
+}
+
+// List returns a page of the users matching a filter and
+// the number of matching users on all pages
+func (s *userService) List(ctx context.Context, filter *model.UserFilter) ([]*model.User, int64, error) {
+	users, err := s.UserRepository.List(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.UserRepository.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// Search finds up to limit users by a part of their email, name or handle
+func (s *userService) Search(ctx context.Context, query string, limit int) ([]*model.User, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, apperrors.NewBadRequest("Search query must not be empty")
+	}
+
+	return s.UserRepository.Search(ctx, query, limit)
+}
+
+// setPassword checks a new password against the policy and stores its hash
+func (s *userService) setPassword(ctx context.Context, user *model.User, password string) error {
+	if err := s.validatePassword(ctx, password, user); err != nil {
+		return err
+	}
+
+	return s.updatePassword(ctx, user, password)
+}
+
+// updatePassword hashes and stores a password which has been validated
+func (s *userService) updatePassword(ctx context.Context, user *model.User, password string) error {
+	pwd, err := utils.HashPassword(password)
+	if err != nil {
+		log.Printf("failed to hash password; uid: %v\n", user.UID)
+		return apperrors.NewInternal()
+	}
+
+	if err := s.UserRepository.UpdatePassword(ctx, user.UID, pwd); err != nil {
+		return err
+	}
+
+	user.Password = pwd
 	return nil
 }
+
+// validatePassword applies the password policy, if one is configured
+func (s *userService) validatePassword(ctx context.Context, password string, user *model.User) error {
+	if s.PasswordPolicy == nil {
+		return nil
+	}
+
+	return s.PasswordPolicy.Validate(ctx, password, user)
+}
+
+// SetStatus changes the status of a user, clearing the reason and expiry
+// of active users. Revoking the tokens of a suspended user is up to the caller
+func (s *userService) SetStatus(ctx context.Context, uid uuid.UUID, status string, reason string, expiresAt *time.Time) (*model.User, error) {
+	switch status {
+	case model.UserStatusActive:
+		reason = ""
+		expiresAt = nil
+	case model.UserStatusSuspended, model.UserStatusPendingDeletion:
+		if expiresAt != nil && !expiresAt.After(time.Now()) {
+			return nil, apperrors.NewBadRequest("Status expiry must be in the future")
+		}
+	default:
+		return nil, apperrors.NewBadRequest(fmt.Sprintf("Unknown status: %v", status))
+	}
+
+	user := &model.User{
+		UID:             uid,
+		Status:          status,
+		StatusReason:    strings.TrimSpace(reason),
+		StatusExpiresAt: expiresAt,
+	}
+
+	if err := s.UserRepository.UpdateStatus(ctx, user); err != nil {
+		return nil, err
+	}
+
+	audit(ctx, s.AuditRepository, &uid, model.AuditStatusChange, model.AuditSuccess, status)
+	return user, nil
+}
+
+// Activity returns a page of the audit events of a user, newest first
+func (s *userService) Activity(ctx context.Context, uid uuid.UUID, limit int, offset int) ([]*model.AuditEvent, error) {
+	if s.AuditRepository == nil {
+		return []*model.AuditEvent{}, nil
+	}
+
+	return s.AuditRepository.FindByUID(ctx, uid, limit, offset)
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 	"github.com/dolong2110/memorization-apps/account/model"
 	"github.com/dolong2110/memorization-apps/account/model/apperrors"
@@ -13,7 +14,12 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/scrypt"
+	"net/http"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGet(t *testing.T) {
@@ -124,6 +130,30 @@ func TestSignup(t *testing.T) {
 
 		mockUserRepository.AssertExpectations(t)
 	})
+
+	t.Run("Password policy violation", func(t *testing.T) {
+		mockUser := &model.User{
+			Email:    "long@do.com",
+			Password: "123456",
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockPasswordPolicy := new(mocks.MockPasswordPolicy)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+			PasswordPolicy: mockPasswordPolicy,
+		})
+
+		mockErr := apperrors.NewInvalidArgs([]apperrors.InvalidArgument{
+			{Field: "Password", Tag: "min", Param: "8"},
+		})
+		mockPasswordPolicy.On("Validate", mock.Anything, "123456", mockUser).Return(mockErr)
+
+		err := us.Signup(context.TODO(), mockUser)
+
+		assert.Equal(t, mockErr, err)
+		mockUserRepository.AssertNotCalled(t, "Create")
+	})
 }
 
 func TestSignin(t *testing.T) {
@@ -203,6 +233,146 @@ func TestSignin(t *testing.T) {
 	})
 }
 
+func TestSigninRehash(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	email := "long@do.com"
+	password := "howdyhoneighbor!"
+
+	newService := func(storedPassword string) (model.UserService, *mocks.MockUserRepository) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UID: uid, Email: email, Password: storedPassword}, nil)
+
+		return us, mockUserRepository
+	}
+
+	t.Run("Legacy hash is upgraded", func(t *testing.T) {
+		// hex(hash).hex(salt) as stored before hashes were versioned
+		salt := []byte("0123456789abcdef0123456789abcdef")
+		key, _ := scrypt.Key([]byte(password), salt, 32768, 8, 1, 32)
+		us, mockUserRepository := newService(hex.EncodeToString(key) + "." + hex.EncodeToString(salt))
+
+		rehashed := make(chan string, 1)
+		mockUserRepository.
+			On("UpdatePassword", mock.Anything, uid, mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) {
+				rehashed <- args.String(2)
+			}).
+			Return(nil)
+
+		user := &model.User{Email: email, Password: password}
+		err := us.Signin(context.TODO(), user)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, user.UID)
+
+		select {
+		case stored := <-rehashed:
+			assert.True(t, strings.HasPrefix(stored, "$argon2id$"))
+			assert.False(t, utils.NeedsRehash(stored))
+
+			match, err := utils.ComparePasswords(stored, password)
+			assert.NoError(t, err)
+			assert.True(t, match)
+		case <-time.After(5 * time.Second):
+			t.Fatal("password was not rehashed")
+		}
+	})
+
+	t.Run("Current hash is kept", func(t *testing.T) {
+		hashedPW, _ := utils.HashPassword(password)
+		us, mockUserRepository := newService(hashedPW)
+
+		err := us.Signin(context.TODO(), &model.User{Email: email, Password: password})
+
+		assert.NoError(t, err)
+		assert.False(t, utils.NeedsRehash(hashedPW))
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword")
+	})
+
+	t.Run("Wrong password is not rehashed", func(t *testing.T) {
+		salt := []byte("0123456789abcdef0123456789abcdef")
+		key, _ := scrypt.Key([]byte(password), salt, 32768, 8, 1, 32)
+		us, mockUserRepository := newService(hex.EncodeToString(key) + "." + hex.EncodeToString(salt))
+
+		err := us.Signin(context.TODO(), &model.User{Email: email, Password: "howdyhodufus!"})
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword")
+	})
+
+	t.Run("Malformed stored hash", func(t *testing.T) {
+		us, _ := newService("nodelimiter")
+
+		err := us.Signin(context.TODO(), &model.User{Email: email, Password: password})
+
+		assert.Equal(t, apperrors.Internal, err.(*apperrors.Error).Type)
+	})
+}
+
+func TestSigninUnknownAccount(t *testing.T) {
+	email := "long@do.com"
+
+	t.Run("Unknown email", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(nil, apperrors.NewNotFound("email", email))
+
+		err := us.Signin(context.TODO(), &model.User{Email: email, Password: "howdyhoneighbor!"})
+
+		// the same error as for a wrong password
+		assert.Equal(t, apperrors.NewAuthorization("Invalid email and password combination"), err)
+	})
+
+	t.Run("Account without password", func(t *testing.T) {
+		uid, _ := uuid.NewRandom()
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UID: uid, Email: email}, nil)
+
+		err := us.Signin(context.TODO(), &model.User{Email: email, Password: ""})
+
+		assert.Equal(t, apperrors.NewAuthorization("Invalid email and password combination"), err)
+	})
+}
+
+func TestNotifySignupAttempt(t *testing.T) {
+	mockMailer := new(mocks.MockMailer)
+	us := NewUserService(&USConfig{
+		Mailer:           mockMailer,
+		PasswordResetURL: "http://localhost:3000/password/reset",
+	})
+
+	sent := make(chan *model.Email, 1)
+	mockMailer.
+		On("Send", mock.Anything, mock.AnythingOfType("*model.Email")).
+		Run(func(args mock.Arguments) {
+			sent <- args.Get(1).(*model.Email)
+		}).
+		Return(nil)
+
+	err := us.NotifySignupAttempt(context.TODO(), "long@do.com")
+
+	assert.NoError(t, err)
+
+	select {
+	case email := <-sent:
+		assert.Equal(t, "long@do.com", email.To)
+		assert.Contains(t, email.Body, "http://localhost:3000/password/reset")
+	case <-time.After(5 * time.Second):
+		t.Fatal("email was not sent")
+	}
+}
+
 func TestUpdateDetails(t *testing.T) {
 	mockUserRepository := new(mocks.MockUserRepository)
 	us := NewUserService(&USConfig{
@@ -534,3 +704,556 @@ func TestSetProfileImage(t *testing.T) {
 		mockUserRepository.AssertCalled(t, "UpdateImage", updateImageArgs...)
 	})
 }
+
+func TestChangePassword(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	currentPW := "howdyhoneighbor!"
+	hashedCurrentPW, _ := utils.HashPassword(currentPW)
+	newPW := "correct horse battery staple"
+
+	newService := func(mockUser *model.User) (model.UserService, *mocks.MockUserRepository, *mocks.MockPasswordPolicy) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockPasswordPolicy := new(mocks.MockPasswordPolicy)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+			PasswordPolicy: mockPasswordPolicy,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+
+		return us, mockUserRepository, mockPasswordPolicy
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockUser := &model.User{UID: uid, Email: "long@do.com", Password: hashedCurrentPW}
+		us, mockUserRepository, mockPasswordPolicy := newService(mockUser)
+
+		mockPasswordPolicy.On("Validate", mock.Anything, newPW, mockUser).Return(nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, uid, mock.AnythingOfType("string")).Return(nil)
+
+		err := us.ChangePassword(context.TODO(), uid, currentPW, newPW)
+
+		assert.NoError(t, err)
+
+		// the new password is stored hashed
+		stored := mockUserRepository.Calls[1].Arguments.String(2)
+		match, err := utils.ComparePasswords(stored, newPW)
+		assert.NoError(t, err)
+		assert.True(t, match)
+	})
+
+	t.Run("Wrong current password", func(t *testing.T) {
+		mockUser := &model.User{UID: uid, Email: "long@do.com", Password: hashedCurrentPW}
+		us, mockUserRepository, mockPasswordPolicy := newService(mockUser)
+
+		err := us.ChangePassword(context.TODO(), uid, "howdyhodufus!", newPW)
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockPasswordPolicy.AssertNotCalled(t, "Validate")
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword")
+	})
+
+	t.Run("Account without password", func(t *testing.T) {
+		mockUser := &model.User{UID: uid, Email: "long@do.com"}
+		us, mockUserRepository, mockPasswordPolicy := newService(mockUser)
+
+		mockPasswordPolicy.On("Validate", mock.Anything, newPW, mockUser).Return(nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, uid, mock.AnythingOfType("string")).Return(nil)
+
+		err := us.ChangePassword(context.TODO(), uid, "", newPW)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Password policy violation", func(t *testing.T) {
+		mockUser := &model.User{UID: uid, Email: "long@do.com", Password: hashedCurrentPW}
+		us, mockUserRepository, mockPasswordPolicy := newService(mockUser)
+
+		mockErr := apperrors.NewInvalidArgs([]apperrors.InvalidArgument{
+			{Field: "Password", Tag: "breached"},
+		})
+		mockPasswordPolicy.On("Validate", mock.Anything, "password123", mockUser).Return(mockErr)
+
+		err := us.ChangePassword(context.TODO(), uid, currentPW, "password123")
+
+		assert.Equal(t, mockErr, err)
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword")
+	})
+}
+
+func TestRequestPasswordReset(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	email := "long@do.com"
+
+	newService := func() (model.UserService, *mocks.MockUserRepository, *mocks.MockTokenRepository, *mocks.MockMailer) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockMailer := new(mocks.MockMailer)
+		us := NewUserService(&USConfig{
+			UserRepository:       mockUserRepository,
+			TokenRepository:      mockTokenRepository,
+			Mailer:               mockMailer,
+			PasswordResetURL:     "http://localhost:3000/password/reset",
+			PasswordResetExpires: time.Hour,
+		})
+
+		return us, mockUserRepository, mockTokenRepository, mockMailer
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		us, mockUserRepository, mockTokenRepository, mockMailer := newService()
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UID: uid, Email: email}, nil)
+		mockTokenRepository.On("SetPasswordResetToken", mock.Anything, mock.AnythingOfType("string"), uid.String(), time.Hour).Return(nil)
+
+		mailed := make(chan *model.Email, 1)
+		mockMailer.On("Send", mock.Anything, mock.AnythingOfType("*model.Email")).
+			Run(func(args mock.Arguments) {
+				mailed <- args.Get(1).(*model.Email)
+			}).
+			Return(nil)
+
+		// the domain is normalized before looking up the account
+		err := us.RequestPasswordReset(context.TODO(), " long@DO.com ")
+
+		assert.NoError(t, err)
+
+		select {
+		case sent := <-mailed:
+			token := mockTokenRepository.Calls[0].Arguments.String(1)
+			assert.Equal(t, email, sent.To)
+			assert.Contains(t, sent.Body, "http://localhost:3000/password/reset?token="+url.QueryEscape(token))
+		case <-time.After(time.Second):
+			t.Fatal("password reset was not mailed")
+		}
+	})
+
+	t.Run("Failed mail is not reported", func(t *testing.T) {
+		us, mockUserRepository, mockTokenRepository, mockMailer := newService()
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UID: uid, Email: email}, nil)
+		mockTokenRepository.On("SetPasswordResetToken", mock.Anything, mock.AnythingOfType("string"), uid.String(), time.Hour).Return(nil)
+
+		attempted := make(chan struct{})
+		mockMailer.On("Send", mock.Anything, mock.AnythingOfType("*model.Email")).
+			Run(func(args mock.Arguments) { close(attempted) }).
+			Return(apperrors.NewInternal())
+
+		err := us.RequestPasswordReset(context.TODO(), email)
+
+		assert.NoError(t, err)
+
+		select {
+		case <-attempted:
+		case <-time.After(time.Second):
+			t.Fatal("password reset was not mailed")
+		}
+	})
+
+	t.Run("Unknown email", func(t *testing.T) {
+		us, mockUserRepository, mockTokenRepository, mockMailer := newService()
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(nil, apperrors.NewNotFound("email", email))
+
+		err := us.RequestPasswordReset(context.TODO(), email)
+
+		assert.NoError(t, err)
+		mockTokenRepository.AssertNotCalled(t, "SetPasswordResetToken")
+		mockMailer.AssertNotCalled(t, "Send")
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	newPW := "correct horse battery staple"
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockPasswordPolicy := new(mocks.MockPasswordPolicy)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			PasswordPolicy:  mockPasswordPolicy,
+		})
+
+		mockUser := &model.User{UID: uid, Email: "long@do.com"}
+		mockTokenRepository.On("PeekPasswordResetToken", mock.Anything, "token").Return(uid.String(), nil)
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+		mockPasswordPolicy.On("Validate", mock.Anything, newPW, mockUser).Return(nil)
+		mockTokenRepository.On("GetPasswordResetToken", mock.Anything, "token").Return(uid.String(), nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, uid, mock.AnythingOfType("string")).Return(nil)
+
+		user, err := us.ResetPassword(context.TODO(), "token", newPW)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, user.UID)
+		mockUserRepository.AssertExpectations(t)
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("Rejected password keeps the link", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockPasswordPolicy := new(mocks.MockPasswordPolicy)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			PasswordPolicy:  mockPasswordPolicy,
+		})
+
+		mockUser := &model.User{UID: uid, Email: "long@do.com"}
+		mockTokenRepository.On("PeekPasswordResetToken", mock.Anything, "token").Return(uid.String(), nil)
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(mockUser, nil)
+		mockPasswordPolicy.On("Validate", mock.Anything, "short", mockUser).Return(apperrors.NewBadRequest("Password is too short"))
+
+		user, err := us.ResetPassword(context.TODO(), "token", "short")
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		mockTokenRepository.AssertNotCalled(t, "GetPasswordResetToken")
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword")
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		us := NewUserService(&USConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+		})
+
+		mockTokenRepository.On("PeekPasswordResetToken", mock.Anything, "token").Return("", apperrors.NewAuthorization("Invalid or expired password reset link"))
+
+		user, err := us.ResetPassword(context.TODO(), "token", newPW)
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword")
+	})
+}
+
+func TestSignupWithInvite(t *testing.T) {
+	mockInvite := &model.Invite{ID: uuid.New()}
+
+	t.Run("Records invite", func(t *testing.T) {
+		mockUser := &model.User{
+			Email:      "long@do.com",
+			Password:   "howdyhoneighbor!",
+			InviteCode: "code",
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockSignupPolicy := new(mocks.MockSignupPolicy)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+			SignupPolicy:   mockSignupPolicy,
+		})
+
+		mockSignupPolicy.On("Admit", mock.Anything, mockUser.Email, "code").Return(mockInvite, nil)
+		mockUserRepository.On("Create", mock.Anything, mockUser).Return(nil)
+
+		err := us.Signup(context.TODO(), mockUser)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockInvite.ID, *mockUser.InviteID)
+		mockSignupPolicy.AssertNotCalled(t, "Release", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		mockUser := &model.User{
+			Email:    "long@do.com",
+			Password: "howdyhoneighbor!",
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockSignupPolicy := new(mocks.MockSignupPolicy)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+			SignupPolicy:   mockSignupPolicy,
+		})
+
+		mockErr := apperrors.NewForbidden("Sign-up requires an invite")
+		mockSignupPolicy.On("Admit", mock.Anything, mockUser.Email, "").Return(nil, mockErr)
+
+		err := us.Signup(context.TODO(), mockUser)
+
+		assert.Equal(t, mockErr, err)
+		mockUserRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Releases invite on conflict", func(t *testing.T) {
+		mockUser := &model.User{
+			Email:      "long@do.com",
+			Password:   "howdyhoneighbor!",
+			InviteCode: "code",
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockSignupPolicy := new(mocks.MockSignupPolicy)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+			SignupPolicy:   mockSignupPolicy,
+		})
+
+		mockErr := apperrors.NewConflict("email", mockUser.Email)
+		mockSignupPolicy.On("Admit", mock.Anything, mockUser.Email, "code").Return(mockInvite, nil)
+		mockSignupPolicy.On("Release", mock.Anything, mockInvite).Return(nil)
+		mockUserRepository.On("Create", mock.Anything, mockUser).Return(mockErr)
+
+		err := us.Signup(context.TODO(), mockUser)
+
+		assert.Equal(t, mockErr, err)
+		mockSignupPolicy.AssertCalled(t, "Release", mock.Anything, mockInvite)
+	})
+}
+
+func TestSigninWithHandle(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	password := "howdyhoneighbor!"
+
+	storedPassword, err := utils.HashPassword(password)
+	assert.NoError(t, err)
+
+	mockUserRepository := new(mocks.MockUserRepository)
+	us := NewUserService(&USConfig{
+		UserRepository: mockUserRepository,
+	})
+
+	mockUserRepository.On("FindByHandle", mock.Anything, "long_do").Return(&model.User{UID: uid, Email: "long@do.com", Handle: "long_do", Password: storedPassword}, nil)
+
+	user := &model.User{Handle: "long_do", Password: password}
+	err = us.Signin(context.TODO(), user)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uid, user.UID)
+	mockUserRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+}
+
+func TestEmailNormalization(t *testing.T) {
+	// "é" composed of "e" and a combining acute accent, which NFC turns into one code point
+	email := " René@Example.COM "
+	normalized := "René@example.com"
+
+	t.Run("Signup", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.User")).Return(nil)
+
+		user := &model.User{Email: email, Password: "howdyhoneighbor!"}
+		err := us.Signup(context.TODO(), user)
+
+		assert.NoError(t, err)
+		assert.Equal(t, normalized, user.Email)
+	})
+
+	t.Run("Signin", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, normalized).Return(nil, apperrors.NewNotFound("email", normalized))
+
+		err := us.Signin(context.TODO(), &model.User{Email: email, Password: "howdyhoneighbor!"})
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertCalled(t, "FindByEmail", mock.Anything, normalized)
+	})
+
+	t.Run("UpdateDetails", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.On("Update", mock.Anything, mock.AnythingOfType("*model.User")).Return(nil)
+
+		user := &model.User{UID: uuid.New(), Email: email}
+		err := us.UpdateDetails(context.TODO(), user)
+
+		assert.NoError(t, err)
+		assert.Equal(t, normalized, user.Email)
+	})
+}
+
+func TestListUsers(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+	mockUsers := []*model.User{{UID: uid, Email: "bob@bob.com"}}
+	filter := &model.UserFilter{Email: "bob", Limit: 20}
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.On("List", mock.Anything, filter).Return(mockUsers, nil)
+		mockUserRepository.On("Count", mock.Anything, filter).Return(int64(1), nil)
+
+		users, total, err := us.List(context.TODO(), filter)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUsers, users)
+		assert.Equal(t, int64(1), total)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Count error", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserRepository.On("List", mock.Anything, filter).Return(mockUsers, nil)
+		mockUserRepository.On("Count", mock.Anything, filter).Return(int64(0), apperrors.NewInternal())
+
+		users, _, err := us.List(context.TODO(), filter)
+
+		assert.Nil(t, users)
+		assert.Error(t, err)
+	})
+}
+
+func TestSearchUsers(t *testing.T) {
+	mockUserRepository := new(mocks.MockUserRepository)
+	us := NewUserService(&USConfig{
+		UserRepository: mockUserRepository,
+	})
+
+	t.Run("Trims query", func(t *testing.T) {
+		mockUserRepository.On("Search", mock.Anything, "bob", 10).Return([]*model.User{}, nil)
+
+		_, err := us.Search(context.TODO(), "  bob ", 10)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Empty query", func(t *testing.T) {
+		_, err := us.Search(context.TODO(), "   ", 10)
+
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
+}
+
+func TestSigninSuspended(t *testing.T) {
+	password := "howdyhoneighbor!"
+	hashedPassword, _ := utils.HashPassword(password)
+	until := time.Now().Add(time.Hour)
+
+	newService := func(status string, expiresAt *time.Time) model.UserService {
+		uid, _ := uuid.NewRandom()
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockUserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(&model.User{
+			UID:             uid,
+			Email:           "bob@bob.com",
+			Password:        hashedPassword,
+			Status:          status,
+			StatusReason:    "spam",
+			StatusExpiresAt: expiresAt,
+		}, nil)
+
+		return NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+	}
+
+	t.Run("Suspended", func(t *testing.T) {
+		us := newService(model.UserStatusSuspended, &until)
+
+		err := us.Signin(context.TODO(), &model.User{Email: "bob@bob.com", Password: password})
+
+		assert.Equal(t, apperrors.NewSuspended("spam", &until), err)
+		assert.Equal(t, http.StatusForbidden, apperrors.Status(err))
+	})
+
+	t.Run("Suspension expired", func(t *testing.T) {
+		expired := time.Now().Add(-time.Minute)
+		us := newService(model.UserStatusSuspended, &expired)
+
+		err := us.Signin(context.TODO(), &model.User{Email: "bob@bob.com", Password: password})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Wrong password does not reveal suspension", func(t *testing.T) {
+		us := newService(model.UserStatusSuspended, nil)
+
+		err := us.Signin(context.TODO(), &model.User{Email: "bob@bob.com", Password: "wrong password"})
+
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+}
+
+func TestSetStatus(t *testing.T) {
+	uid, _ := uuid.NewRandom()
+
+	t.Run("Suspend", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		until := time.Now().Add(24 * time.Hour)
+		mockUserRepository.On("UpdateStatus", mock.Anything, &model.User{
+			UID:             uid,
+			Status:          model.UserStatusSuspended,
+			StatusReason:    "spam",
+			StatusExpiresAt: &until,
+		}).Return(nil)
+
+		user, err := us.SetStatus(context.TODO(), uid, model.UserStatusSuspended, " spam ", &until)
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.UserStatusSuspended, user.Status)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Reactivate clears reason and expiry", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		until := time.Now().Add(24 * time.Hour)
+		mockUserRepository.On("UpdateStatus", mock.Anything, &model.User{
+			UID:    uid,
+			Status: model.UserStatusActive,
+		}).Return(nil)
+
+		_, err := us.SetStatus(context.TODO(), uid, model.UserStatusActive, "spam", &until)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Expiry in the past", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		past := time.Now().Add(-time.Hour)
+		_, err := us.SetStatus(context.TODO(), uid, model.UserStatusSuspended, "spam", &past)
+
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		mockUserRepository.AssertNotCalled(t, "UpdateStatus")
+	})
+
+	t.Run("Unknown status", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		us := NewUserService(&USConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		_, err := us.SetStatus(context.TODO(), uid, "banned", "", nil)
+
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		mockUserRepository.AssertNotCalled(t, "UpdateStatus")
+	})
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+)
+
+// IPPrefix returns the network of an IP, the /24 of IPv4 and the /48 of IPv6
+// addresses, which stays the same when a provider hands out a new address
+// Unparsable IPs are returned as they are
+func IPPrefix(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+// DeviceFingerprint identifies a device by its user agent and the network of its IP
+func DeviceFingerprint(userAgent string, ipPrefix string) string {
+	uaHash := sha256.Sum256([]byte(userAgent))
+	fingerprint := sha256.Sum256([]byte(hex.EncodeToString(uaHash[:]) + "|" + ipPrefix))
+
+	return hex.EncodeToString(fingerprint[:])
+}
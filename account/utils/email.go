@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeEmail trims an email address, applies Unicode NFC and lowercases
+// its domain. The local part keeps its case, as some mail servers respect it,
+// accounts are told apart regardless of case by the database instead
+func NormalizeEmail(email string) string {
+	email = norm.NFC.String(strings.TrimSpace(email))
+
+	i := strings.LastIndexByte(email, '@')
+	if i < 0 {
+		return email
+	}
+
+	return email[:i+1] + strings.ToLower(email[i+1:])
+}
@@ -3,7 +3,10 @@ package utils
 import (
 	"github.com/dolong2110/memorization-apps/account/model/apperrors"
 	"github.com/google/uuid"
+	"io"
 	"log"
+	"mime/multipart"
+	"net/http"
 	"net/url"
 	"path"
 )
@@ -21,6 +24,40 @@ func IsAllowedImageType(mimeType string) bool {
 	return exists
 }
 
+// ImageContentType sniffs the type of an image from its first bytes, which is
+// application/octet-stream unless it is an allowed image type, so that no
+// other content is ever stored or served with a type browsers render
+func ImageContentType(head []byte) string {
+	if len(head) > 512 {
+		head = head[:512]
+	}
+
+	mimeType := http.DetectContentType(head)
+	if !IsAllowedImageType(mimeType) {
+		return "application/octet-stream"
+	}
+
+	return mimeType
+}
+
+// SniffImageFile returns the type sniffed from the content of an uploaded
+// file, whatever type the client claims it to be
+func SniffImageFile(fileHeader *multipart.FileHeader) (string, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+
+	return ImageContentType(head[:n]), nil
+}
+
 // ObjNameFromURL get base image from given url
 func ObjNameFromURL(imageURL string) (string, error) {
 	// if user doesn't have imageURL - create one
@@ -2,38 +2,172 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/scrypt"
 )
 
-// HashPassword receive a string password and hash it with salt
+// Argon2idParams are the cost parameters of an argon2id hash
+type Argon2idParams struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  int
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams are used for new hashes, following the second
+// recommended option of RFC 9106. Hashes with other parameters are
+// reported by NeedsRehash
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// legacy hashes are "hex(hash).hex(salt)" with these scrypt parameters
+const (
+	legacyScryptN      = 32768
+	legacyScryptR      = 8
+	legacyScryptP      = 1
+	legacyScryptKeyLen = 32
+)
+
+// HashPassword receive a string password and hash it with a random salt
+// The hash is stored in the PHC string format, which records the algorithm
+// and its parameters - https://github.com/P-H-C/phc-string-format
+// e.g. $argon2id$v=19$m=65536,t=3,p=4$<base64 salt>$<base64 hash>
 func HashPassword(password string) (string, error) {
-	// example for making salt - https://play.golang.org/p/_Aw6WeWC42I
-	salt := make([]byte, 32)
-	_, err := rand.Read(salt)
-	if err != nil {
+	params := DefaultArgon2idParams
+
+	salt := make([]byte, params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
 
-	// using recommended cost parameters from - https://godoc.org/golang.org/x/crypto/scrypt
-	sHash, err := scrypt.Key([]byte(password), salt, 32768, 8, 1, 32)
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		params.Memory,
+		params.Iterations,
+		params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// ComparePasswords hashes the supplied password with the algorithm, parameters
+// and salt of the stored password and compares both hashes
+// Stored passwords may be argon2id PHC strings or legacy scrypt hashes
+func ComparePasswords(storedPassword string, suppliedPassword string) (bool, error) {
+	if strings.HasPrefix(storedPassword, "$argon2id$") {
+		return compareArgon2id(storedPassword, suppliedPassword)
+	}
+
+	return compareLegacyScrypt(storedPassword, suppliedPassword)
+}
+
+// CompareDummyPassword does the work of comparing a password with a hash
+// of the default parameters, for when there is no stored password to compare
+// with. Responses then take as long as for existing accounts, so their
+// timing does not reveal which accounts exist
+func CompareDummyPassword(suppliedPassword string) {
+	params := DefaultArgon2idParams
+
+	// the salt and hash do not matter, as the comparison never matches
+	dummyHash := fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		params.Memory,
+		params.Iterations,
+		params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(make([]byte, params.SaltLength)),
+		base64.RawStdEncoding.EncodeToString(make([]byte, params.KeyLength)),
+	)
+
+	_, _ = compareArgon2id(dummyHash, suppliedPassword)
+}
+
+// NeedsRehash reports whether a stored password should be hashed again,
+// because it does not use argon2id with the default parameters
+func NeedsRehash(storedPassword string) bool {
+	var version int
+	var params Argon2idParams
+
+	parts := strings.Split(storedPassword, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return true
+	}
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return true
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return true
+	}
+
+	salt, saltErr := base64.RawStdEncoding.DecodeString(parts[4])
+	key, keyErr := base64.RawStdEncoding.DecodeString(parts[5])
+	if saltErr != nil || keyErr != nil {
+		return true
+	}
+
+	defaults := DefaultArgon2idParams
+	return params.Memory != defaults.Memory ||
+		params.Iterations != defaults.Iterations ||
+		params.Parallelism != defaults.Parallelism ||
+		len(salt) < defaults.SaltLength ||
+		uint32(len(key)) != defaults.KeyLength
+}
+
+func compareArgon2id(storedPassword string, suppliedPassword string) (bool, error) {
+	var version int
+	var memory, iterations uint32
+	var parallelism uint8
+
+	parts := strings.Split(storedPassword, "$")
+	if len(parts) != 6 {
+		return false, fmt.Errorf("invalid argon2id hash")
+	}
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, fmt.Errorf("unsupported argon2id version: %v", parts[2])
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism); err != nil {
+		return false, fmt.Errorf("invalid argon2id parameters: %v", parts[3])
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
 	if err != nil {
-		return "", err
+		return false, fmt.Errorf("unable to verify user password")
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, fmt.Errorf("unable to verify user password")
 	}
 
-	// return hex-encoded string with salt appended to password
-	hashedPW := fmt.Sprintf("%s.%s", hex.EncodeToString(sHash), hex.EncodeToString(salt))
+	suppliedKey := argon2.IDKey([]byte(suppliedPassword), salt, iterations, memory, parallelism, uint32(len(key)))
 
-	return hashedPW, nil
+	return subtle.ConstantTimeCompare(suppliedKey, key) == 1, nil
 }
 
-// ComparePasswords get the string password and hashed it with salt
-// get from stored password and compare both hashed passwords
-func ComparePasswords(storedPassword string, suppliedPassword string) (bool, error) {
+func compareLegacyScrypt(storedPassword string, suppliedPassword string) (bool, error) {
 	pwSalt := strings.Split(storedPassword, ".")
+	if len(pwSalt) != 2 {
+		return false, fmt.Errorf("unrecognized password hash format")
+	}
 
 	// check supplied password salted with hash
 	salt, err := hex.DecodeString(pwSalt[1])
@@ -41,10 +175,15 @@ func ComparePasswords(storedPassword string, suppliedPassword string) (bool, err
 		return false, fmt.Errorf("unable to verify user password")
 	}
 
-	sHash, err := scrypt.Key([]byte(suppliedPassword), salt, 32768, 8, 1, 32)
+	sHash, err := scrypt.Key([]byte(suppliedPassword), salt, legacyScryptN, legacyScryptR, legacyScryptP, legacyScryptKeyLen)
 	if err != nil {
 		return false, fmt.Errorf("unable to hash user password")
 	}
 
-	return hex.EncodeToString(sHash) == pwSalt[0], nil
+	storedHash, err := hex.DecodeString(pwSalt[0])
+	if err != nil {
+		return false, fmt.Errorf("unable to verify user password")
+	}
+
+	return subtle.ConstantTimeCompare(sHash, storedHash) == 1, nil
 }
@@ -16,11 +16,13 @@ import (
 // GenerateIDToken generates an AccessToken which is a jwt with myCustomClaims
 // Could call this GenerateIDTokenString, but the signature makes this fairly clear
 func GenerateIDToken(user *model.User, key *rsa.PrivateKey, exp int64) (string, error) {
-	unixTime := time.Now().Unix()
+	currentTime := time.Now()
+	unixTime := currentTime.Unix()
 	tokenExp := unixTime + exp
 
 	claims := model.AccessTokenCustomClaims{
-		User: user,
+		User:       user,
+		IssuedAtMs: currentTime.UnixMilli(),
 		StandardClaims: jwt.StandardClaims{
 			IssuedAt:  unixTime,
 			ExpiresAt: tokenExp,
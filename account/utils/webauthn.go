@@ -0,0 +1,249 @@
+package utils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// COSE algorithms accepted for passkeys - https://www.iana.org/assignments/cose/cose.xhtml#algorithms
+const (
+	COSEAlgES256 int64 = -7
+	COSEAlgEdDSA int64 = -8
+	COSEAlgRS256 int64 = -257
+)
+
+// authenticator data flags - https://www.w3.org/TR/webauthn-2/#authenticator-data
+const (
+	AuthDataUserPresent          byte = 0x01
+	AuthDataUserVerified         byte = 0x04
+	AuthDataAttestedCredential   byte = 0x40
+	authDataMinLength                 = 37
+	attestedCredentialDataOffset      = authDataMinLength + 16 // after the aaguid
+)
+
+// ClientData is the subset of CollectedClientData the relying party verifies
+type ClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// AuthenticatorData is the parsed authenticator data of a ceremony
+// CredentialID and CredentialPublicKey are only set during registration
+type AuthenticatorData struct {
+	RPIDHash            []byte
+	Flags               byte
+	SignCount           uint32
+	CredentialID        []byte
+	CredentialPublicKey []byte
+}
+
+// VerifyClientData checks the ceremony type, challenge and origin of clientDataJSON
+// The challenge is compared with its base64url encoding as sent by the client
+func VerifyClientData(clientDataJSON []byte, ceremony string, challenge string, origins []string) error {
+	var clientData ClientData
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return fmt.Errorf("invalid client data: %w", err)
+	}
+
+	if clientData.Type != ceremony {
+		return fmt.Errorf("unexpected ceremony: %s", clientData.Type)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(clientData.Challenge), []byte(challenge)) != 1 {
+		return errors.New("challenge mismatch")
+	}
+
+	if clientData.CrossOrigin {
+		return errors.New("cross origin ceremonies are not allowed")
+	}
+
+	for _, origin := range origins {
+		if clientData.Origin == origin {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unexpected origin: %s", clientData.Origin)
+}
+
+// ParseAuthenticatorData decodes raw authenticator data and checks it was
+// produced for rpID with the user present
+func ParseAuthenticatorData(raw []byte, rpID string) (*AuthenticatorData, error) {
+	if len(raw) < authDataMinLength {
+		return nil, errors.New("authenticator data too short")
+	}
+
+	authData := &AuthenticatorData{
+		RPIDHash:  raw[:32],
+		Flags:     raw[32],
+		SignCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	if subtle.ConstantTimeCompare(authData.RPIDHash, rpIDHash[:]) != 1 {
+		return nil, errors.New("rp ID hash mismatch")
+	}
+
+	if authData.Flags&AuthDataUserPresent == 0 {
+		return nil, errors.New("user not present")
+	}
+
+	if authData.Flags&AuthDataAttestedCredential == 0 {
+		return authData, nil
+	}
+
+	// aaguid (16) | credentialIdLength (2) | credentialId | credentialPublicKey (COSE)
+	if len(raw) < attestedCredentialDataOffset+2 {
+		return nil, errors.New("attested credential data too short")
+	}
+
+	idLength := int(binary.BigEndian.Uint16(raw[attestedCredentialDataOffset:]))
+	idStart := attestedCredentialDataOffset + 2
+	if len(raw) < idStart+idLength {
+		return nil, errors.New("credential ID too short")
+	}
+	authData.CredentialID = raw[idStart : idStart+idLength]
+
+	// the public key length is only known once it is decoded
+	var key cbor.RawMessage
+	dec := cbor.NewDecoder(bytes.NewReader(raw[idStart+idLength:]))
+	if err := dec.Decode(&key); err != nil {
+		return nil, fmt.Errorf("invalid credential public key: %w", err)
+	}
+	authData.CredentialPublicKey = []byte(key)
+
+	return authData, nil
+}
+
+// ParseAttestationObject returns the authenticator data of an attestation object
+// Attestation statements are not verified, as the service requests "none" conveyance
+func ParseAttestationObject(raw []byte) ([]byte, error) {
+	var attestation struct {
+		Fmt      string          `cbor:"fmt"`
+		AttStmt  cbor.RawMessage `cbor:"attStmt"`
+		AuthData []byte          `cbor:"authData"`
+	}
+
+	if err := cbor.Unmarshal(raw, &attestation); err != nil {
+		return nil, fmt.Errorf("invalid attestation object: %w", err)
+	}
+
+	if len(attestation.AuthData) == 0 {
+		return nil, errors.New("attestation object without authenticator data")
+	}
+
+	return attestation.AuthData, nil
+}
+
+// coseKey holds the COSE_Key members of the supported key types
+type coseKey struct {
+	Kty int64  `cbor:"1,keyasint"`
+	Alg int64  `cbor:"3,keyasint"`
+	Crv int64  `cbor:"-1,keyasint,omitempty"` // n for RSA keys
+	X   []byte `cbor:"-2,keyasint,omitempty"` // e for RSA keys
+	Y   []byte `cbor:"-3,keyasint,omitempty"`
+}
+
+// ParseCOSEKey decodes a COSE encoded public key of one of the accepted algorithms
+func ParseCOSEKey(raw []byte) (crypto.PublicKey, int64, error) {
+	// RSA keys hold bytes where EC keys hold the curve, decode them separately
+	var generic map[int64]interface{}
+	if err := cbor.Unmarshal(raw, &generic); err != nil {
+		return nil, 0, fmt.Errorf("invalid COSE key: %w", err)
+	}
+
+	alg, _ := generic[3].(int64)
+
+	switch alg {
+	case COSEAlgES256:
+		var key coseKey
+		if err := cbor.Unmarshal(raw, &key); err != nil {
+			return nil, 0, fmt.Errorf("invalid EC2 key: %w", err)
+		}
+		if key.Kty != 2 || key.Crv != 1 || len(key.X) != 32 || len(key.Y) != 32 {
+			return nil, 0, errors.New("ES256 key must be an EC2 key on P-256")
+		}
+
+		pub := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(key.X),
+			Y:     new(big.Int).SetBytes(key.Y),
+		}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, 0, errors.New("EC2 key is not on the curve")
+		}
+		return pub, alg, nil
+	case COSEAlgEdDSA:
+		var key coseKey
+		if err := cbor.Unmarshal(raw, &key); err != nil {
+			return nil, 0, fmt.Errorf("invalid OKP key: %w", err)
+		}
+		if key.Kty != 1 || key.Crv != 6 || len(key.X) != ed25519.PublicKeySize {
+			return nil, 0, errors.New("EdDSA key must be an OKP key on Ed25519")
+		}
+		return ed25519.PublicKey(key.X), alg, nil
+	case COSEAlgRS256:
+		n, nOK := generic[-1].([]byte)
+		e, eOK := generic[-2].([]byte)
+		// unsigned integers are decoded as uint64
+		if generic[1] != uint64(3) || !nOK || !eOK || len(e) > 4 {
+			return nil, 0, errors.New("RS256 key must be an RSA key")
+		}
+
+		pub := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		if pub.N.BitLen() < 2048 {
+			return nil, 0, errors.New("RSA key is too short")
+		}
+		return pub, alg, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported COSE algorithm: %d", alg)
+	}
+}
+
+// VerifyAssertionSignature checks sig over authenticatorData || SHA-256(clientDataJSON)
+func VerifyAssertionSignature(publicKey []byte, authData []byte, clientDataJSON []byte, sig []byte) error {
+	key, alg, err := ParseCOSEKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+
+	switch alg {
+	case COSEAlgES256:
+		digest := sha256.Sum256(signed)
+		if !ecdsa.VerifyASN1(key.(*ecdsa.PublicKey), digest[:], sig) {
+			return errors.New("invalid signature")
+		}
+	case COSEAlgEdDSA:
+		if !ed25519.Verify(key.(ed25519.PublicKey), signed, sig) {
+			return errors.New("invalid signature")
+		}
+	case COSEAlgRS256:
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), crypto.SHA256, digest[:], sig); err != nil {
+			return errors.New("invalid signature")
+		}
+	}
+
+	return nil
+}